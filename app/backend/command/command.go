@@ -0,0 +1,67 @@
+package command
+
+import "Dr.uml/backend/utils/duerror"
+
+// Command is a reversible edit, Unexecute must restore exactly what Execute changed.
+type Command interface {
+	Execute() duerror.DUError
+	Unexecute() duerror.DUError
+}
+
+// macroCommand is a committed transaction, it runs its commands in order and reverts them backwards
+type macroCommand struct {
+	commands []Command
+}
+
+// Execute is all or nothing, if a command fails the ones before it are reverted
+func (c *macroCommand) Execute() duerror.DUError {
+	for i, sub := range c.commands {
+		if err := sub.Execute(); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				if undoErr := c.commands[j].Unexecute(); undoErr != nil {
+					return undoErr
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *macroCommand) Unexecute() duerror.DUError {
+	for i := len(c.commands) - 1; i >= 0; i-- {
+		if err := c.commands[i].Unexecute(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// funcCommand builds a Command out of two closures, handy for simple property edits
+type funcCommand struct {
+	execute   func() duerror.DUError
+	unexecute func() duerror.DUError
+}
+
+// NewFuncCommand wraps execute and unexecute into a Command.
+// When execute fails, unexecute runs right away to restore whatever was partially changed,
+// so it has to be safe to call in that state.
+func NewFuncCommand(execute func() duerror.DUError, unexecute func() duerror.DUError) (Command, duerror.DUError) {
+	if execute == nil || unexecute == nil {
+		return nil, duerror.NewInvalidArgumentError("command functions cannot be nil")
+	}
+	return &funcCommand{execute: execute, unexecute: unexecute}, nil
+}
+
+func (c *funcCommand) Execute() duerror.DUError {
+	if err := c.execute(); err != nil {
+		// best effort, the original error is the one worth reporting
+		_ = c.unexecute()
+		return err
+	}
+	return nil
+}
+
+func (c *funcCommand) Unexecute() duerror.DUError {
+	return c.unexecute()
+}
@@ -1,4 +1,124 @@
 package command
 
+import "Dr.uml/backend/utils/duerror"
+
+// Manager keeps the undo and redo history of one diagram.
+// Commands executed between Begin and Commit are recorded as a single step,
+// transactions can be nested.
 type Manager struct {
+	undoStack    []Command
+	redoStack    []Command
+	transactions []*macroCommand // open transactions, innermost last
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		undoStack:    make([]Command, 0),
+		redoStack:    make([]Command, 0),
+		transactions: make([]*macroCommand, 0),
+	}
+}
+
+// Execute runs c and records it, a new edit drops everything that could be redone.
+// A command that fails is not recorded. Inside a transaction the redo history goes
+// only once the transaction is committed, a rolled back one leaves it as it was.
+func (m *Manager) Execute(c Command) duerror.DUError {
+	if c == nil {
+		return duerror.NewInvalidArgumentError("command is nil")
+	}
+	if err := c.Execute(); err != nil {
+		return err
+	}
+	if m.InTransaction() {
+		current := m.transactions[len(m.transactions)-1]
+		current.commands = append(current.commands, c)
+		return nil
+	}
+	m.record(c)
+	return nil
+}
+
+// record adds a step to the undo history, what could be redone before it cannot anymore
+func (m *Manager) record(c Command) {
+	m.undoStack = append(m.undoStack, c)
+	m.redoStack = m.redoStack[:0]
+}
+
+// Begin opens a transaction, everything executed until the matching Commit undoes as one step
+func (m *Manager) Begin() {
+	m.transactions = append(m.transactions, &macroCommand{})
+}
+
+// Commit closes the innermost transaction and records it in the enclosing one, or in the history.
+// An empty transaction leaves no trace.
+func (m *Manager) Commit() duerror.DUError {
+	if !m.InTransaction() {
+		return duerror.NewInvalidArgumentError("no transaction to commit")
+	}
+	current := m.transactions[len(m.transactions)-1]
+	m.transactions = m.transactions[:len(m.transactions)-1]
+	if len(current.commands) == 0 {
+		return nil
+	}
+	if m.InTransaction() {
+		parent := m.transactions[len(m.transactions)-1]
+		parent.commands = append(parent.commands, current)
+		return nil
+	}
+	m.record(current)
+	return nil
+}
+
+// Rollback closes the innermost transaction and reverts everything executed in it
+func (m *Manager) Rollback() duerror.DUError {
+	if !m.InTransaction() {
+		return duerror.NewInvalidArgumentError("no transaction to roll back")
+	}
+	current := m.transactions[len(m.transactions)-1]
+	m.transactions = m.transactions[:len(m.transactions)-1]
+	return current.Unexecute()
+}
+
+func (m *Manager) InTransaction() bool {
+	return len(m.transactions) > 0
+}
+
+func (m *Manager) Undo() duerror.DUError {
+	if m.InTransaction() {
+		return duerror.NewInvalidArgumentError("cannot undo during a transaction")
+	}
+	if !m.CanUndo() {
+		return duerror.NewInvalidArgumentError("nothing to undo")
+	}
+	c := m.undoStack[len(m.undoStack)-1]
+	if err := c.Unexecute(); err != nil {
+		return err
+	}
+	m.undoStack = m.undoStack[:len(m.undoStack)-1]
+	m.redoStack = append(m.redoStack, c)
+	return nil
+}
+
+func (m *Manager) Redo() duerror.DUError {
+	if m.InTransaction() {
+		return duerror.NewInvalidArgumentError("cannot redo during a transaction")
+	}
+	if !m.CanRedo() {
+		return duerror.NewInvalidArgumentError("nothing to redo")
+	}
+	c := m.redoStack[len(m.redoStack)-1]
+	if err := c.Execute(); err != nil {
+		return err
+	}
+	m.redoStack = m.redoStack[:len(m.redoStack)-1]
+	m.undoStack = append(m.undoStack, c)
+	return nil
+}
+
+func (m *Manager) CanUndo() bool {
+	return len(m.undoStack) > 0
+}
+
+func (m *Manager) CanRedo() bool {
+	return len(m.redoStack) > 0
 }
@@ -0,0 +1,171 @@
+package command
+
+import (
+	"testing"
+
+	"Dr.uml/backend/utils/duerror"
+	"github.com/stretchr/testify/assert"
+)
+
+// counterCommand adds delta to value
+func newCounterCommand(t *testing.T, value *int, delta int) Command {
+	c, err := NewFuncCommand(
+		func() duerror.DUError { *value += delta; return nil },
+		func() duerror.DUError { *value -= delta; return nil },
+	)
+	assert.NoError(t, err)
+	return c
+}
+
+func TestNewFuncCommand(t *testing.T) {
+	_, err := NewFuncCommand(nil, func() duerror.DUError { return nil })
+	assert.Error(t, err)
+	_, err = NewFuncCommand(func() duerror.DUError { return nil }, nil)
+	assert.Error(t, err)
+}
+
+func TestManager_ExecuteUndoRedo(t *testing.T) {
+	m := NewManager()
+	value := 0
+	assert.False(t, m.CanUndo())
+	assert.False(t, m.CanRedo())
+	assert.Error(t, m.Undo())
+	assert.Error(t, m.Redo())
+
+	assert.NoError(t, m.Execute(newCounterCommand(t, &value, 1)))
+	assert.NoError(t, m.Execute(newCounterCommand(t, &value, 10)))
+	assert.Equal(t, 11, value)
+	assert.True(t, m.CanUndo())
+
+	assert.NoError(t, m.Undo())
+	assert.Equal(t, 1, value)
+	assert.True(t, m.CanRedo())
+	assert.NoError(t, m.Undo())
+	assert.Equal(t, 0, value)
+	assert.False(t, m.CanUndo())
+
+	assert.NoError(t, m.Redo())
+	assert.Equal(t, 1, value)
+
+	// a new edit drops the redo history
+	assert.NoError(t, m.Execute(newCounterCommand(t, &value, 100)))
+	assert.Equal(t, 101, value)
+	assert.False(t, m.CanRedo())
+
+	// nil command
+	assert.Error(t, m.Execute(nil))
+}
+
+func TestManager_FailedCommand(t *testing.T) {
+	m := NewManager()
+	failing, err := NewFuncCommand(
+		func() duerror.DUError { return duerror.NewInvalidArgumentError("fail") },
+		func() duerror.DUError { return nil },
+	)
+	assert.NoError(t, err)
+	assert.Error(t, m.Execute(failing))
+	assert.False(t, m.CanUndo())
+
+	// a failing undo keeps the command on the undo stack
+	failUndo, err := NewFuncCommand(
+		func() duerror.DUError { return nil },
+		func() duerror.DUError { return duerror.NewInvalidArgumentError("fail") },
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, m.Execute(failUndo))
+	assert.Error(t, m.Undo())
+	assert.True(t, m.CanUndo())
+	assert.False(t, m.CanRedo())
+}
+
+func TestManager_Transaction(t *testing.T) {
+	m := NewManager()
+	value := 0
+	assert.Error(t, m.Commit())
+	assert.Error(t, m.Rollback())
+
+	// commit records one step
+	m.Begin()
+	assert.True(t, m.InTransaction())
+	assert.NoError(t, m.Execute(newCounterCommand(t, &value, 1)))
+	assert.NoError(t, m.Execute(newCounterCommand(t, &value, 2)))
+	assert.False(t, m.CanUndo())
+	assert.Error(t, m.Undo())
+	assert.NoError(t, m.Commit())
+	assert.False(t, m.InTransaction())
+	assert.Equal(t, 3, value)
+	assert.NoError(t, m.Undo())
+	assert.Equal(t, 0, value)
+	assert.False(t, m.CanUndo())
+	assert.NoError(t, m.Redo())
+	assert.Equal(t, 3, value)
+
+	// rollback reverts without recording
+	m.Begin()
+	assert.NoError(t, m.Execute(newCounterCommand(t, &value, 10)))
+	assert.NoError(t, m.Execute(newCounterCommand(t, &value, 20)))
+	assert.NoError(t, m.Rollback())
+	assert.Equal(t, 3, value)
+	assert.NoError(t, m.Undo())
+	assert.Equal(t, 0, value)
+
+	// empty transaction leaves no trace
+	m.Begin()
+	assert.NoError(t, m.Commit())
+	assert.False(t, m.CanUndo())
+	assert.True(t, m.CanRedo())
+
+	// what could be redone still can after a rollback, and cannot after a commit
+	m.Begin()
+	assert.NoError(t, m.Execute(newCounterCommand(t, &value, 100)))
+	assert.NoError(t, m.Rollback())
+	assert.True(t, m.CanRedo())
+	assert.NoError(t, m.Redo())
+	assert.Equal(t, 3, value)
+	assert.NoError(t, m.Undo())
+	m.Begin()
+	assert.NoError(t, m.Execute(newCounterCommand(t, &value, 100)))
+	assert.True(t, m.CanRedo())
+	assert.NoError(t, m.Commit())
+	assert.False(t, m.CanRedo())
+	assert.Equal(t, 100, value)
+}
+
+func TestManager_NestedTransaction(t *testing.T) {
+	m := NewManager()
+	value := 0
+
+	m.Begin()
+	assert.NoError(t, m.Execute(newCounterCommand(t, &value, 1)))
+	m.Begin()
+	assert.NoError(t, m.Execute(newCounterCommand(t, &value, 10)))
+	assert.NoError(t, m.Commit())
+	m.Begin()
+	assert.NoError(t, m.Execute(newCounterCommand(t, &value, 100)))
+	// only the inner transaction is reverted
+	assert.NoError(t, m.Rollback())
+	assert.Equal(t, 11, value)
+	assert.NoError(t, m.Commit())
+
+	assert.NoError(t, m.Undo())
+	assert.Equal(t, 0, value)
+	assert.False(t, m.CanUndo())
+	assert.NoError(t, m.Redo())
+	assert.Equal(t, 11, value)
+}
+
+func TestMacroCommand_ExecuteIsAllOrNothing(t *testing.T) {
+	value := 0
+	failing, err := NewFuncCommand(
+		func() duerror.DUError { return duerror.NewInvalidArgumentError("fail") },
+		func() duerror.DUError { return nil },
+	)
+	assert.NoError(t, err)
+	macro := &macroCommand{commands: []Command{
+		newCounterCommand(t, &value, 1),
+		newCounterCommand(t, &value, 2),
+		failing,
+	}}
+	assert.Error(t, macro.Execute())
+	assert.Equal(t, 0, value)
+}
@@ -2,9 +2,11 @@ package component
 
 import (
 	"math"
+	"slices"
 
 	"Dr.uml/backend/component/attribute"
 	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/filedata"
 	"Dr.uml/backend/utils"
 	"Dr.uml/backend/utils/duerror"
 )
@@ -16,14 +18,107 @@ const (
 	Implementation           = 1 << iota // 0x02
 	Composition              = 1 << iota // 0x04
 	Dependency               = 1 << iota // 0x08
-	supportedAssociationType = Extension | Implementation | Composition | Dependency
+	PlainAssociation         = 1 << iota // 0x10
+	Aggregation              = 1 << iota // 0x20
+	DirectedAssociation      = 1 << iota // 0x40
+	AssociationClass         = 1 << iota // 0x80
+	supportedAssociationType = Extension | Implementation | Composition | Dependency |
+		PlainAssociation | Aggregation | DirectedAssociation | AssociationClass
 )
 
+var AllAssociationTypes = []struct {
+	Value  AssociationType
+	TSName string
+}{
+	{Extension, "Extension"},
+	{Implementation, "Implementation"},
+	{Composition, "Composition"},
+	{Dependency, "Dependency"},
+	{PlainAssociation, "PlainAssociation"},
+	{Aggregation, "Aggregation"},
+	{DirectedAssociation, "DirectedAssociation"},
+	{AssociationClass, "AssociationClass"},
+}
+
+// Navigability tells whether the object at an end can be reached from the other end
+type Navigability int
+
+const (
+	NavigabilityUnspecified Navigability = iota
+	Navigable
+	NonNavigable
+)
+
+var AllNavigabilities = []struct {
+	Value  Navigability
+	TSName string
+}{
+	{NavigabilityUnspecified, "NavigabilityUnspecified"},
+	{Navigable, "Navigable"},
+	{NonNavigable, "NonNavigable"},
+}
+
+// Routing is how the line of an association finds its way from the start to the end
+type Routing int
+
+const (
+	RoutingStraight   Routing = iota // straight, or through the waypoints
+	RoutingOrthogonal                // horizontal and vertical segments around the gadgets, waypoints are ignored
+)
+
+var AllRoutings = []struct {
+	Value  Routing
+	TSName string
+}{
+	{RoutingStraight, "RoutingStraight"},
+	{RoutingOrthogonal, "RoutingOrthogonal"},
+}
+
+const (
+	routeMargin     = 12  // between an orthogonal route and the gadgets it goes around
+	routeBendCost   = 40  // how much longer a route may get to save a bend
+	routeSearchZone = 200 // how far around its ends an orthogonal route looks for gadgets in the way
+)
+
+// associationStyles gives the arrowheads drawn at {start, end} of each association type and
+// whether its line is dashed. On the association-like types, an end without a head of its own
+// shows its navigability instead.
+var associationStyles = map[AssociationType]struct {
+	heads      [2]int
+	dashed     bool
+	navigation bool
+}{
+	Extension:           {[2]int{drawdata.ArrowheadNone, drawdata.ArrowheadHollowTriangle}, false, false},
+	Implementation:      {[2]int{drawdata.ArrowheadNone, drawdata.ArrowheadHollowTriangle}, true, false},
+	Composition:         {[2]int{drawdata.ArrowheadNone, drawdata.ArrowheadFilledDiamond}, false, true},
+	Dependency:          {[2]int{drawdata.ArrowheadNone, drawdata.ArrowheadOpen}, true, false},
+	PlainAssociation:    {[2]int{drawdata.ArrowheadNone, drawdata.ArrowheadNone}, false, true},
+	Aggregation:         {[2]int{drawdata.ArrowheadNone, drawdata.ArrowheadHollowDiamond}, false, true},
+	DirectedAssociation: {[2]int{drawdata.ArrowheadNone, drawdata.ArrowheadOpen}, false, true},
+	AssociationClass:    {[2]int{drawdata.ArrowheadNone, drawdata.ArrowheadNone}, false, true},
+}
+
+var navigabilityHeads = map[Navigability]int{
+	NavigabilityUnspecified: drawdata.ArrowheadNone,
+	Navigable:               drawdata.ArrowheadOpen,
+	NonNavigable:            drawdata.ArrowheadCross,
+}
+
 type Association struct {
+	id               string
 	assType          AssociationType
 	layer            int
+	isSelected       bool
 	attributes       []*attribute.AssAttribute
 	parents          [2]*Gadget
+	navigability     [2]Navigability // of {start, end}
+	class            *Gadget         // the association class, only on AssociationClass
+	labels           [labelKindCount]label
+	readingDirection ReadingDirection
+	waypoints        []utils.Point
+	routing          Routing
+	route            []utils.Point // the bends of the orthogonal route, nil when there is none
+	searchObstacles  func(area utils.Rect) []utils.Rect
 	drawdata         drawdata.Association
 	updateParentDraw func() duerror.DUError
 
@@ -33,8 +128,8 @@ type Association struct {
 
 // Constructor
 func NewAssociation(parents [2]*Gadget, assType AssociationType, stPoint utils.Point, enPoint utils.Point) (*Association, duerror.DUError) {
-	if assType&supportedAssociationType != assType || assType == 0 {
-		return nil, duerror.NewInvalidArgumentError("unsupported association type")
+	if err := validateAssociationType(assType); err != nil {
+		return nil, err
 	}
 	if parents[0] == nil || parents[1] == nil {
 		return nil, duerror.NewInvalidArgumentError("parents are nil")
@@ -42,6 +137,7 @@ func NewAssociation(parents [2]*Gadget, assType AssociationType, stPoint utils.P
 	stGdd := parents[0].GetDrawData().(drawdata.Gadget)
 	enGdd := parents[1].GetDrawData().(drawdata.Gadget)
 	a := &Association{
+		id:      utils.NewID(),
 		assType: assType,
 		parents: [2]*Gadget{parents[0], parents[1]},
 		startPointRatio: [2]float64{
@@ -57,7 +153,93 @@ func NewAssociation(parents [2]*Gadget, assType AssociationType, stPoint utils.P
 	return a, nil
 }
 
+// NewAssociationFromFileData rebuilds an Association between the given parents from its saved form.
+// The saved parent indices are resolved by the caller.
+func NewAssociationFromFileData(fd filedata.Association, parents [2]*Gadget) (*Association, duerror.DUError) {
+	assType := AssociationType(fd.AssType)
+	if err := validateAssociationType(assType); err != nil {
+		return nil, err
+	}
+	if parents[0] == nil || parents[1] == nil {
+		return nil, duerror.NewInvalidArgumentError("parents are nil")
+	}
+	a := &Association{
+		id:              fd.ID,
+		assType:         assType,
+		layer:           fd.Layer,
+		parents:         parents,
+		startPointRatio: fd.StartRatio,
+		endPointRatio:   fd.EndRatio,
+	}
+	if a.id == "" {
+		a.id = utils.NewID()
+	}
+	a.drawdata.Layer = fd.Layer
+	for kind, content := range []string{
+		fd.Labels.StartMultiplicity, fd.Labels.StartRole, fd.Labels.EndMultiplicity, fd.Labels.EndRole, fd.Labels.Name,
+	} {
+		l, err := newLabel(LabelKind(kind), content)
+		if err != nil {
+			return nil, err
+		}
+		a.labels[kind] = l
+	}
+	if err := validateReadingDirection(ReadingDirection(fd.Labels.ReadingDirection)); err != nil {
+		return nil, err
+	}
+	a.readingDirection = ReadingDirection(fd.Labels.ReadingDirection)
+	if err := validateRouting(Routing(fd.Routing)); err != nil {
+		return nil, err
+	}
+	a.routing = Routing(fd.Routing)
+	for _, wp := range fd.Waypoints {
+		a.waypoints = append(a.waypoints, utils.Point{X: wp[0], Y: wp[1]})
+	}
+	for i, n := range fd.Navigability {
+		if err := a.validateNavigability(i, Navigability(n)); err != nil {
+			return nil, err
+		}
+		a.navigability[i] = Navigability(n)
+	}
+	for _, attFd := range fd.Attributes {
+		att, err := attribute.NewAssAttributeFromFileData(attFd)
+		if err != nil {
+			return nil, err
+		}
+		if err = att.RegisterUpdateParentDraw(a.updateDrawData); err != nil {
+			return nil, err
+		}
+		a.attributes = append(a.attributes, att)
+	}
+	if err := a.updateDrawData(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
 // other function
+func validateAssociationType(assType AssociationType) duerror.DUError {
+	// exactly one supported flag
+	if !(assType&supportedAssociationType == assType && assType != 0 && assType&(assType-1) == 0) {
+		return duerror.NewInvalidArgumentError("unsupported association type")
+	}
+	return nil
+}
+
+func validateRouting(routing Routing) duerror.DUError {
+	if routing != RoutingStraight && routing != RoutingOrthogonal {
+		return duerror.NewInvalidArgumentError("unsupported routing")
+	}
+	return nil
+}
+
+func validateReadingDirection(direction ReadingDirection) duerror.DUError {
+	if direction < ReadingNone || direction > ReadingTowardStart {
+		return duerror.NewInvalidArgumentError("unsupported reading direction")
+	}
+	return nil
+}
+
 func snapToEdge(rec utils.Point, width int, height int, ratio [2]float64) utils.Point {
 	// snap a point onto the edge of a rectangle, the point is float {xRatio, yRatio}
 	leftDist := ratio[0]
@@ -78,6 +260,13 @@ func snapToEdge(rec utils.Point, width int, height int, ratio [2]float64) utils.
 	}
 }
 
+func validateRatio(ratio [2]float64) duerror.DUError {
+	if ratio[0] < 0 || ratio[0] > 1 || ratio[1] < 0 || ratio[1] > 1 {
+		return duerror.NewInvalidArgumentError("ratio should be between 0 and 1")
+	}
+	return nil
+}
+
 func dist(st utils.Point, en utils.Point, p utils.Point) float64 {
 	stX, stY := float64(st.X), float64(st.Y)
 	enX, enY := float64(en.X), float64(en.Y)
@@ -98,18 +287,42 @@ func dist(st utils.Point, en utils.Point, p utils.Point) float64 {
 }
 
 // Getters
+func (this *Association) GetID() string {
+	return this.id
+}
+
 func (this *Association) GetAssType() AssociationType {
 	return this.assType
 }
 
 func (this *Association) GetAttributes() ([]*attribute.AssAttribute, duerror.DUError) {
-	// TODO: should not do this
-	if len(this.attributes) == 0 {
-		return nil, duerror.NewInvalidArgumentError("no attributes found")
-	}
 	return this.attributes, nil
 }
 
+// GetLabel returns the label of kind, empty when it is not shown
+func (this *Association) GetLabel(kind LabelKind) (string, duerror.DUError) {
+	if kind < 0 || kind >= labelKindCount {
+		return "", duerror.NewInvalidArgumentError("unsupported label kind")
+	}
+	return this.labels[kind].content, nil
+}
+
+func (this *Association) GetReadingDirection() ReadingDirection {
+	return this.readingDirection
+}
+
+func (this *Association) GetWaypoints() []utils.Point {
+	return slices.Clone(this.waypoints)
+}
+
+func (this *Association) GetRouting() Routing {
+	return this.routing
+}
+
+func (this *Association) GetAttributesLen() int {
+	return len(this.attributes)
+}
+
 func (this *Association) GetDrawData() any {
 	return this.drawdata
 }
@@ -118,6 +331,10 @@ func (this *Association) GetLayer() int {
 	return this.layer
 }
 
+func (this *Association) GetIsSelected() bool {
+	return this.isSelected
+}
+
 func (this *Association) GetParentEnd() *Gadget {
 	return this.parents[1]
 }
@@ -126,6 +343,19 @@ func (this *Association) GetParentStart() *Gadget {
 	return this.parents[0]
 }
 
+func (this *Association) GetStartNavigability() Navigability {
+	return this.navigability[0]
+}
+
+func (this *Association) GetEndNavigability() Navigability {
+	return this.navigability[1]
+}
+
+// GetAssociationClass returns the class holding the attributes of the association, or nil
+func (this *Association) GetAssociationClass() *Gadget {
+	return this.class
+}
+
 func (this *Association) GetStartRatio() [2]float64 {
 	return this.startPointRatio
 }
@@ -134,17 +364,67 @@ func (this *Association) GetEndRatio() [2]float64 {
 	return this.endPointRatio
 }
 
+// GetFileData returns the persistent form of the Association.
+// gadgetIndex maps every gadget of the diagram to its index in the saved gadget list.
+// An association class missing from gadgetIndex is left out, e.g. when copying without it.
+func (this *Association) GetFileData(gadgetIndex map[*Gadget]int) (filedata.Association, duerror.DUError) {
+	st, ok := gadgetIndex[this.parents[0]]
+	if !ok {
+		return filedata.Association{}, duerror.NewInvalidArgumentError("start parent is not in the gadget index")
+	}
+	en, ok := gadgetIndex[this.parents[1]]
+	if !ok {
+		return filedata.Association{}, duerror.NewInvalidArgumentError("end parent is not in the gadget index")
+	}
+	atts := make([]filedata.AssAttribute, 0, len(this.attributes))
+	for _, att := range this.attributes {
+		atts = append(atts, att.GetAssFileData())
+	}
+	fd := filedata.Association{
+		ID:           this.id,
+		AssType:      int(this.assType),
+		Layer:        this.layer,
+		Parents:      [2]int{st, en},
+		StartRatio:   this.startPointRatio,
+		EndRatio:     this.endPointRatio,
+		Navigability: [2]int{int(this.navigability[0]), int(this.navigability[1])},
+		Attributes:   atts,
+		Labels: filedata.AssLabels{
+			StartMultiplicity: this.labels[StartMultiplicity].content,
+			StartRole:         this.labels[StartRole].content,
+			EndMultiplicity:   this.labels[EndMultiplicity].content,
+			EndRole:           this.labels[EndRole].content,
+			Name:              this.labels[MiddleName].content,
+			ReadingDirection:  int(this.readingDirection),
+		},
+	}
+	for _, wp := range this.waypoints {
+		fd.Waypoints = append(fd.Waypoints, [2]int{wp.X, wp.Y})
+	}
+	fd.Routing = int(this.routing)
+	if index, ok := gadgetIndex[this.class]; ok && this.class != nil {
+		fd.Class = &index
+	}
+	return fd, nil
+}
+
 // Setters
 func (this *Association) SetAssType(assType AssociationType) duerror.DUError {
-	if assType&supportedAssociationType != assType || assType == 0 {
-		return duerror.NewInvalidArgumentError("unsupported association type")
+	if err := validateAssociationType(assType); err != nil {
+		return err
+	}
+	if this.class != nil && assType != AssociationClass {
+		return duerror.NewInvalidArgumentError("detach the association class first")
 	}
 	this.assType = assType
-	this.drawdata.AssType = int(assType)
-	if this.updateParentDraw == nil {
-		return nil
+	// a navigability the new type does not show goes, it could not be loaded again
+	for i, navigability := range this.navigability {
+		if this.validateNavigability(i, navigability) != nil {
+			this.navigability[i] = NavigabilityUnspecified
+		}
 	}
-	return this.updateParentDraw()
+	// the arrowheads change with the type
+	return this.updateDrawData()
 }
 
 func (this *Association) SetLayer(layer int) duerror.DUError {
@@ -156,34 +436,94 @@ func (this *Association) SetLayer(layer int) duerror.DUError {
 	return this.updateParentDraw()
 }
 
-func (this *Association) SetParentStart(gadget *Gadget, point utils.Point) duerror.DUError {
-	// TODO: make sure update diagram's associations too
-	if gadget == nil {
-		return duerror.NewInvalidArgumentError("gadget is nil")
+func (this *Association) SetIsSelected(isSelected bool) duerror.DUError {
+	this.isSelected = isSelected
+	this.drawdata.IsSelected = isSelected
+	if this.updateParentDraw == nil {
+		return nil
 	}
-	this.parents[0] = gadget
-	return this.SetStartPoint(point)
+	return this.updateParentDraw()
 }
 
-func (this *Association) SetParentEnd(gadget *Gadget, point utils.Point) duerror.DUError {
-	// TODO: make sure update diagram's associations too
-	if gadget == nil {
-		return duerror.NewInvalidArgumentError("gadget is nil")
+func (this *Association) SetStartNavigability(navigability Navigability) duerror.DUError {
+	return this.setNavigability(0, navigability)
+}
+
+func (this *Association) SetEndNavigability(navigability Navigability) duerror.DUError {
+	return this.setNavigability(1, navigability)
+}
+
+// SetLabel shows content as the label of kind, an empty content hides it.
+// A multiplicity must be valid, see ValidateMultiplicity.
+func (this *Association) SetLabel(kind LabelKind, content string) duerror.DUError {
+	l, err := newLabel(kind, content)
+	if err != nil {
+		return err
 	}
-	this.parents[1] = gadget
-	return this.SetEndPoint(point)
+	this.labels[kind] = l
+	return this.updateDrawData()
+}
+
+// SetWaypoints makes the line bend at each of the waypoints in turn, none makes it straight again
+func (this *Association) SetWaypoints(waypoints []utils.Point) duerror.DUError {
+	this.waypoints = slices.Clone(waypoints)
+	return this.updateDrawData()
+}
+
+func (this *Association) SetRouting(routing Routing) duerror.DUError {
+	if err := validateRouting(routing); err != nil {
+		return err
+	}
+	this.routing = routing
+	return this.updateDrawData()
+}
+
+// SetReadingDirection points the middle name toward an end, or toward none
+func (this *Association) SetReadingDirection(direction ReadingDirection) duerror.DUError {
+	if err := validateReadingDirection(direction); err != nil {
+		return err
+	}
+	this.readingDirection = direction
+	return this.updateDrawData()
+}
+
+// SetAssociationClass links the association to the class holding its attributes, nil unlinks it.
+// Only an AssociationClass can have one, and the class cannot be one of its own ends.
+func (this *Association) SetAssociationClass(class *Gadget) duerror.DUError {
+	if class != nil {
+		if this.assType != AssociationClass {
+			return duerror.NewInvalidArgumentError("only an association class can link a class")
+		}
+		if class.GetGadgetType() != Class {
+			return duerror.NewInvalidArgumentError("an association class must be a class")
+		}
+		if class == this.parents[0] || class == this.parents[1] {
+			return duerror.NewInvalidArgumentError("an association class cannot be an end of its association")
+		}
+	}
+	this.class = class
+	return this.updateDrawData()
+}
+
+// SetParentStart moves the start onto gadget at point. It only re-points the association,
+// reconnecting through the diagram keeps the diagram's association index in step.
+func (this *Association) SetParentStart(gadget *Gadget, point utils.Point) duerror.DUError {
+	return this.setParent(0, gadget, point)
+}
+
+func (this *Association) SetParentEnd(gadget *Gadget, point utils.Point) duerror.DUError {
+	return this.setParent(1, gadget, point)
 }
 
 func (this *Association) SetStartPoint(point utils.Point) duerror.DUError {
 	if this.parents[0] == nil {
 		return duerror.NewInvalidArgumentError("parent is nil")
 	}
-	gdd := this.parents[0].GetDrawData().(drawdata.Gadget)
-	if point.X < gdd.X || point.X > gdd.X+gdd.Width || point.Y < gdd.Y || point.Y > gdd.Y+gdd.Height {
-		return duerror.NewInvalidArgumentError("point is out of range")
+	ratio, err := ratioOnGadget(this.parents[0], point)
+	if err != nil {
+		return err
 	}
-	this.startPointRatio[0] = float64(point.X-gdd.X) / float64(gdd.Width)
-	this.startPointRatio[1] = float64(point.Y-gdd.Y) / float64(gdd.Height)
+	this.startPointRatio = ratio
 	return this.updateDrawData()
 }
 
@@ -191,15 +531,92 @@ func (this *Association) SetEndPoint(point utils.Point) duerror.DUError {
 	if this.parents[1] == nil {
 		return duerror.NewInvalidArgumentError("parent is nil")
 	}
-	gdd := this.parents[1].GetDrawData().(drawdata.Gadget)
+	ratio, err := ratioOnGadget(this.parents[1], point)
+	if err != nil {
+		return err
+	}
+	this.endPointRatio = ratio
+	return this.updateDrawData()
+}
+
+// SetStartRatio puts the start back where GetStartRatio was read, SetStartPoint can only snap a point
+func (this *Association) SetStartRatio(ratio [2]float64) duerror.DUError {
+	if err := validateRatio(ratio); err != nil {
+		return err
+	}
+	this.startPointRatio = ratio
+	return this.updateDrawData()
+}
+
+func (this *Association) SetEndRatio(ratio [2]float64) duerror.DUError {
+	if err := validateRatio(ratio); err != nil {
+		return err
+	}
+	this.endPointRatio = ratio
+	return this.updateDrawData()
+}
+
+// setParent validates the new end before touching anything, and puts the old one back when the
+// association cannot be drawn there, so a refused reconnect leaves the association as it was
+func (this *Association) setParent(end int, gadget *Gadget, point utils.Point) duerror.DUError {
+	if gadget == nil {
+		return duerror.NewInvalidArgumentError("gadget is nil")
+	}
+	if gadget == this.class {
+		return duerror.NewInvalidArgumentError("an association class cannot be an end of its association")
+	}
+	ratio, err := ratioOnGadget(gadget, point)
+	if err != nil {
+		return err
+	}
+	ratios := [2]*[2]float64{&this.startPointRatio, &this.endPointRatio}
+	oldParent, oldRatio := this.parents[end], *ratios[end]
+	this.parents[end], *ratios[end] = gadget, ratio
+	if err := this.updateDrawData(); err != nil {
+		// e.g. both ends on the same point of one gadget
+		this.parents[end], *ratios[end] = oldParent, oldRatio
+		_ = this.updateDrawData()
+		return err
+	}
+	return nil
+}
+
+// ratioOnGadget gives where point lies on gadget, as fractions of its width and height
+func ratioOnGadget(gadget *Gadget, point utils.Point) ([2]float64, duerror.DUError) {
+	gdd := gadget.GetDrawData().(drawdata.Gadget)
 	if point.X < gdd.X || point.X > gdd.X+gdd.Width || point.Y < gdd.Y || point.Y > gdd.Y+gdd.Height {
-		return duerror.NewInvalidArgumentError("point is out of range")
+		return [2]float64{}, duerror.NewInvalidArgumentError("point is out of range")
+	}
+	return [2]float64{
+		float64(point.X-gdd.X) / float64(gdd.Width),
+		float64(point.Y-gdd.Y) / float64(gdd.Height),
+	}, nil
+}
+
+func (this *Association) setNavigability(end int, navigability Navigability) duerror.DUError {
+	if err := this.validateNavigability(end, navigability); err != nil {
+		return err
 	}
-	this.endPointRatio[0] = float64(point.X-gdd.X) / float64(gdd.Width)
-	this.endPointRatio[1] = float64(point.Y-gdd.Y) / float64(gdd.Height)
+	this.navigability[end] = navigability
 	return this.updateDrawData()
 }
 
+// validateNavigability only lets an end be marked if the association type shows navigability there.
+// SetAssType clears the ends the new type does not show it at.
+func (this *Association) validateNavigability(end int, navigability Navigability) duerror.DUError {
+	if _, ok := navigabilityHeads[navigability]; !ok {
+		return duerror.NewInvalidArgumentError("unsupported navigability")
+	}
+	if navigability == NavigabilityUnspecified {
+		return nil
+	}
+	style := associationStyles[this.assType]
+	if !style.navigation || style.heads[end] != drawdata.ArrowheadNone {
+		return duerror.NewInvalidArgumentError("this end of the association does not show navigability")
+	}
+	return nil
+}
+
 // Other methods
 func (this *Association) AddAttribute(attribute *attribute.AssAttribute) duerror.DUError {
 	if attribute == nil {
@@ -211,21 +628,130 @@ func (this *Association) AddAttribute(attribute *attribute.AssAttribute) duerror
 	return this.updateDrawData()
 }
 
+// InsertAttribute puts an attribute back at index, e.g. one taken out by RemoveAttribute
+func (this *Association) InsertAttribute(index int, attribute *attribute.AssAttribute) duerror.DUError {
+	if index < 0 || index > len(this.attributes) {
+		return duerror.NewInvalidArgumentError("index out of range")
+	}
+	if attribute == nil {
+		return duerror.NewInvalidArgumentError("attribute is nil")
+	}
+	if err := attribute.RegisterUpdateParentDraw(this.updateDrawData); err != nil {
+		return err
+	}
+	this.attributes = slices.Insert(this.attributes, index, attribute)
+	return this.updateDrawData()
+}
+
 func (this *Association) Cover(p utils.Point) (bool, duerror.DUError) {
 	if this.parents[0] == nil || this.parents[1] == nil {
 		return false, duerror.NewInvalidArgumentError("parents are nil")
 	}
 
-	st := utils.Point{this.drawdata.StartX, this.drawdata.StartY}
-	en := utils.Point{this.drawdata.EndX, this.drawdata.EndY}
-	delta := utils.Point{this.drawdata.DeltaX, this.drawdata.DeltaY}
-	stDelta := utils.AddPoints(st, delta)
-	enDelta := utils.AddPoints(en, delta)
-
 	threshold := float64(4)
-	return dist(stDelta, enDelta, p) <= threshold ||
-		dist(st, stDelta, p) <= threshold ||
-		dist(en, enDelta, p) <= threshold, nil
+	line := this.getPolyline()
+	for i := 1; i < len(line); i++ {
+		if dist(line[i-1], line[i], p) <= threshold {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (this *Association) CoverRect(r utils.Rect) (bool, duerror.DUError) {
+	if this.parents[0] == nil || this.parents[1] == nil {
+		return false, duerror.NewInvalidArgumentError("parents are nil")
+	}
+	line := this.getPolyline()
+	for i := 1; i < len(line); i++ {
+		if r.IntersectsSegment(line[i-1], line[i]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (this *Association) GetBounds() utils.Rect {
+	line := this.getPolyline()
+	bounds := utils.NewRect(line[0], line[0])
+	for _, p := range line[1:] {
+		bounds = bounds.Union(utils.NewRect(p, p))
+	}
+	return bounds
+}
+
+// getPolyline returns the drawn line from start to end: along the orthogonal route or through the waypoints
+// if there are any, otherwise straight, self associations going around through the delta
+func (this *Association) getPolyline() []utils.Point {
+	st := utils.Point{X: this.drawdata.StartX, Y: this.drawdata.StartY}
+	en := utils.Point{X: this.drawdata.EndX, Y: this.drawdata.EndY}
+	if bends := this.getBends(); bends != nil {
+		return append(append([]utils.Point{st}, bends...), en)
+	}
+	delta := utils.Point{X: this.drawdata.DeltaX, Y: this.drawdata.DeltaY}
+	if utils.EqualPoints(delta, utils.Point{}) {
+		return []utils.Point{st, en}
+	}
+	return []utils.Point{st, utils.AddPoints(st, delta), utils.AddPoints(en, delta), en}
+}
+
+// getBends returns the points the line bends at between its ends, nil to leave the line to its defaults
+func (this *Association) getBends() []utils.Point {
+	if this.routing == RoutingOrthogonal && this.route != nil {
+		return this.route
+	}
+	if len(this.waypoints) > 0 {
+		return this.waypoints
+	}
+	return nil
+}
+
+// getRoute finds the orthogonal route between the ends, around the parents and the gadgets
+// searchObstacles returns. It is nil when the ends are walled in.
+func (this *Association) getRoute(st utils.Point, en utils.Point) []utils.Point {
+	stGdd := this.parents[0].GetDrawData().(drawdata.Gadget)
+	enGdd := this.parents[1].GetDrawData().(drawdata.Gadget)
+	obstacles := []utils.Rect{this.parents[0].GetBounds(), this.parents[1].GetBounds()}
+	if this.searchObstacles != nil {
+		area := utils.NewRect(st, en)
+		area = utils.Rect{
+			Min: utils.Point{X: area.Min.X - routeSearchZone, Y: area.Min.Y - routeSearchZone},
+			Max: utils.Point{X: area.Max.X + routeSearchZone, Y: area.Max.Y + routeSearchZone},
+		}
+		obstacles = append(obstacles, this.searchObstacles(area)...)
+	}
+	return utils.RouteOrthogonal(st, getOutward(st, stGdd), en, getOutward(en, enGdd), obstacles, routeMargin, routeBendCost)
+}
+
+// getOutward returns the unit vector pointing out of the side of the gadget p lies on
+func getOutward(p utils.Point, gdd drawdata.Gadget) utils.Point {
+	switch {
+	case p.X == gdd.X:
+		return utils.Point{X: -1}
+	case p.X == gdd.X+gdd.Width:
+		return utils.Point{X: 1}
+	case p.Y == gdd.Y:
+		return utils.Point{Y: -1}
+	default:
+		return utils.Point{Y: 1}
+	}
+}
+
+// getClassLink returns the dashed line from the middle of the association to the nearest point
+// of its association class, or nil without one
+func (this *Association) getClassLink() *drawdata.Segment {
+	if this.class == nil {
+		return nil
+	}
+	a, b := this.getMiddleSegment()
+	mid := utils.Point{X: (a.X + b.X) / 2, Y: (a.Y + b.Y) / 2}
+	gdd := this.class.GetDrawData().(drawdata.Gadget)
+	ratio := [2]float64{
+		float64(min(max(mid.X, gdd.X), gdd.X+gdd.Width)-gdd.X) / float64(gdd.Width),
+		float64(min(max(mid.Y, gdd.Y), gdd.Y+gdd.Height)-gdd.Y) / float64(gdd.Height),
+	}
+	end := snapToEdge(utils.Point{X: gdd.X, Y: gdd.Y}, gdd.Width, gdd.Height, ratio)
+	return &drawdata.Segment{StartX: mid.X, StartY: mid.Y, EndX: end.X, EndY: end.Y}
 }
 
 func (this *Association) MoveAttribute(index int, ratio float64) duerror.DUError {
@@ -243,6 +769,11 @@ func (this *Association) RemoveAttribute(index int) duerror.DUError {
 	return this.updateDrawData()
 }
 
+// UpdateDrawData re-snaps both ends onto the parents, call it after a parent moved or resized
+func (this *Association) UpdateDrawData() duerror.DUError {
+	return this.updateDrawData()
+}
+
 func (this *Association) updateDrawData() duerror.DUError {
 	if this == nil || this.parents[0] == nil || this.parents[1] == nil {
 		return duerror.NewInvalidArgumentError("association or parents are nil")
@@ -289,8 +820,37 @@ func (this *Association) updateDrawData() duerror.DUError {
 	this.drawdata.StartY = startPoint.Y
 	this.drawdata.EndX = endPoint.X
 	this.drawdata.EndY = endPoint.Y
+	this.route = nil
+	if this.routing == RoutingOrthogonal {
+		this.route = this.getRoute(startPoint, endPoint)
+	}
+	if this.getBends() != nil {
+		// the bends take the place of the loop of a self association
+		this.drawdata.DeltaX = 0
+		this.drawdata.DeltaY = 0
+	}
+	line := this.getPolyline()
+	this.drawdata.Path = make([]drawdata.PathPoint, len(line))
+	for i, p := range line {
+		this.drawdata.Path[i] = drawdata.PathPoint{X: p.X, Y: p.Y}
+	}
 
+	this.drawdata.ID = this.id
 	this.drawdata.AssType = int(this.assType)
+	style := associationStyles[this.assType]
+	this.drawdata.Dashed = style.dashed
+	for i, head := range style.heads {
+		if head == drawdata.ArrowheadNone && style.navigation {
+			head = navigabilityHeads[this.navigability[i]]
+		}
+		if i == 0 {
+			this.drawdata.StartHead = head
+		} else {
+			this.drawdata.EndHead = head
+		}
+	}
+	this.drawdata.ClassLink = this.getClassLink()
+	this.drawdata.Labels = this.getLabelsDrawData()
 	this.drawdata.Attributes = make([]drawdata.AssAttribute, len(this.attributes))
 
 	for i, att := range this.attributes {
@@ -305,6 +865,16 @@ func (this *Association) updateDrawData() duerror.DUError {
 	return this.updateParentDraw()
 }
 
+// RegisterObstacleSearch lets orthogonal routes go around more gadgets than the parents,
+// search returns the bounds of the gadgets in an area
+func (this *Association) RegisterObstacleSearch(search func(area utils.Rect) []utils.Rect) duerror.DUError {
+	if search == nil {
+		return duerror.NewInvalidArgumentError("search function is nil")
+	}
+	this.searchObstacles = search
+	return nil
+}
+
 func (this *Association) RegisterUpdateParentDraw(update func() duerror.DUError) duerror.DUError {
 	if update == nil {
 		return duerror.NewInvalidArgumentError("update function is nil")
@@ -0,0 +1,198 @@
+package component
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/utils"
+	"Dr.uml/backend/utils/duerror"
+)
+
+// LabelKind is one of the labels an association can carry, anchored to an end or to its middle
+type LabelKind int
+
+const (
+	StartMultiplicity LabelKind = iota
+	StartRole
+	EndMultiplicity
+	EndRole
+	MiddleName
+	labelKindCount
+)
+
+var AllLabelKinds = []struct {
+	Value  LabelKind
+	TSName string
+}{
+	{StartMultiplicity, "StartMultiplicity"},
+	{StartRole, "StartRole"},
+	{EndMultiplicity, "EndMultiplicity"},
+	{EndRole, "EndRole"},
+	{MiddleName, "MiddleName"},
+}
+
+// ReadingDirection tells which way the middle name of an association reads
+type ReadingDirection int
+
+const (
+	ReadingNone ReadingDirection = iota
+	ReadingTowardEnd
+	ReadingTowardStart
+)
+
+var AllReadingDirections = []struct {
+	Value  ReadingDirection
+	TSName string
+}{
+	{ReadingNone, "ReadingNone"},
+	{ReadingTowardEnd, "ReadingTowardEnd"},
+	{ReadingTowardStart, "ReadingTowardStart"},
+}
+
+const (
+	labelAlong = 24 // how far along the line an end label sits, past the arrowheads
+	labelGap   = 4  // between a label and the line
+)
+
+// a bound, i.e. "0", "12" or "*"
+var multiplicityBound = regexp.MustCompile(`^(\*|0|[1-9][0-9]*)$`)
+
+// ValidateMultiplicity accepts a bound, like "1" or "*", or a range of two, like "0..*" or "2..5".
+// The lower end of a range cannot be "*" nor greater than the upper one.
+func ValidateMultiplicity(multiplicity string) duerror.DUError {
+	bounds := strings.Split(multiplicity, "..")
+	if len(bounds) > 2 {
+		return duerror.NewInvalidArgumentError("multiplicity has more than two bounds: " + multiplicity)
+	}
+	for _, b := range bounds {
+		if !multiplicityBound.MatchString(b) {
+			return duerror.NewInvalidArgumentError("invalid multiplicity: " + multiplicity)
+		}
+	}
+	if len(bounds) == 1 {
+		return nil
+	}
+	if bounds[0] == "*" {
+		return duerror.NewInvalidArgumentError("the lower bound of a multiplicity cannot be *: " + multiplicity)
+	}
+	if bounds[1] == "*" {
+		return nil
+	}
+	lower, err := strconv.Atoi(bounds[0])
+	if err != nil {
+		return duerror.NewInvalidArgumentError("invalid multiplicity: " + multiplicity)
+	}
+	upper, err := strconv.Atoi(bounds[1])
+	if err != nil {
+		return duerror.NewInvalidArgumentError("invalid multiplicity: " + multiplicity)
+	}
+	if lower > upper {
+		return duerror.NewInvalidArgumentError("the lower bound of a multiplicity is above the upper one: " + multiplicity)
+	}
+	return nil
+}
+
+// label is the text of a label, with its size measured once when it is set
+type label struct {
+	content string
+	width   int
+	height  int
+}
+
+func newLabel(kind LabelKind, content string) (label, duerror.DUError) {
+	if kind < 0 || kind >= labelKindCount {
+		return label{}, duerror.NewInvalidArgumentError("unsupported label kind")
+	}
+	if content == "" {
+		return label{}, nil
+	}
+	if strings.ContainsAny(content, "\r\n") {
+		return label{}, duerror.NewInvalidArgumentError("a label cannot span lines")
+	}
+	if kind == StartMultiplicity || kind == EndMultiplicity {
+		if err := ValidateMultiplicity(content); err != nil {
+			return label{}, err
+		}
+	}
+	height, width, err := utils.GetTextSize(content, drawdata.DefaultAttributeFontSize, "")
+	if err != nil {
+		return label{}, err
+	}
+	return label{content: content, width: width, height: height}, nil
+}
+
+// getLabelsDrawData places the labels around the drawn line: the ones of an end just past its
+// arrowhead, multiplicity on one side of the line and role on the other, the name beside the middle.
+func (this *Association) getLabelsDrawData() []drawdata.AssLabel {
+	line := this.getPolyline()
+	dds := make([]drawdata.AssLabel, 0)
+	for kind, l := range this.labels {
+		if l.content == "" {
+			continue
+		}
+		var anchor, dir [2]float64
+		side := 1.0
+		switch LabelKind(kind) {
+		case StartMultiplicity, StartRole:
+			anchor, dir = getPoint(line[0]), getDirection(line[0], line[1])
+			anchor = [2]float64{anchor[0] + dir[0]*labelAlong, anchor[1] + dir[1]*labelAlong}
+		case EndMultiplicity, EndRole:
+			anchor, dir = getPoint(line[len(line)-1]), getDirection(line[len(line)-1], line[len(line)-2])
+			anchor = [2]float64{anchor[0] + dir[0]*labelAlong, anchor[1] + dir[1]*labelAlong}
+		case MiddleName:
+			a, b := this.getMiddleSegment()
+			anchor = [2]float64{float64(a.X+b.X) / 2, float64(a.Y+b.Y) / 2}
+			dir = getDirection(a, b)
+		}
+		// the normal, turned to the upper side of the line or, for a vertical one, to its right
+		normal := [2]float64{-dir[1], dir[0]}
+		if normal[1] > 0 || (normal[1] == 0 && normal[0] < 0) {
+			normal = [2]float64{-normal[0], -normal[1]}
+		}
+		if LabelKind(kind) == StartRole || LabelKind(kind) == EndRole {
+			side = -1
+		}
+		// push the box off the line by half its extent along the normal
+		half := math.Abs(normal[0])*float64(l.width)/2 + math.Abs(normal[1])*float64(l.height)/2
+		cx := anchor[0] + side*normal[0]*(half+labelGap)
+		cy := anchor[1] + side*normal[1]*(half+labelGap)
+		dd := drawdata.AssLabel{
+			Kind:     kind,
+			Content:  l.content,
+			X:        int(math.Round(cx - float64(l.width)/2)),
+			Y:        int(math.Round(cy - float64(l.height)/2)),
+			Width:    l.width,
+			Height:   l.height,
+			FontSize: drawdata.DefaultAttributeFontSize,
+		}
+		if LabelKind(kind) == MiddleName {
+			dd.ReadingDirection = int(this.readingDirection)
+		}
+		dds = append(dds, dd)
+	}
+	return dds
+}
+
+// getMiddleSegment returns the segment in the middle of the drawn line,
+// for a self association the one away from the gadget
+func (this *Association) getMiddleSegment() (utils.Point, utils.Point) {
+	line := this.getPolyline()
+	return line[len(line)/2-1], line[len(line)/2]
+}
+
+func getPoint(p utils.Point) [2]float64 {
+	return [2]float64{float64(p.X), float64(p.Y)}
+}
+
+// getDirection returns the unit vector from a to b
+func getDirection(a, b utils.Point) [2]float64 {
+	dx, dy := float64(b.X-a.X), float64(b.Y-a.Y)
+	l := math.Hypot(dx, dy)
+	if l == 0 {
+		return [2]float64{1, 0}
+	}
+	return [2]float64{dx / l, dy / l}
+}
@@ -3,6 +3,7 @@
 package component
 
 import (
+	"reflect"
 	"testing"
 
 	"Dr.uml/backend/component/attribute"
@@ -24,6 +25,10 @@ func Test_NewAssociation(t *testing.T) {
 		{"same point", [2]*Gadget{gadget, gadget}, Extension, utils.Point{X: 0, Y: 0}, utils.Point{X: 0, Y: 0}, true},
 		{"nil parent", [2]*Gadget{nil, gadget}, Extension, utils.Point{X: 0, Y: 0}, utils.Point{X: 1, Y: 1}, true},
 		{"invalid assType", [2]*Gadget{gadget, gadget}, 0, utils.Point{X: 0, Y: 0}, utils.Point{X: 1, Y: 1}, true},
+		{"aggregation", [2]*Gadget{gadget, gadget}, Aggregation, utils.Point{X: 0, Y: 0}, utils.Point{X: 1, Y: 1}, false},
+		{"association class", [2]*Gadget{gadget, gadget}, AssociationClass, utils.Point{X: 0, Y: 0}, utils.Point{X: 1, Y: 1}, false},
+		{"two types at once", [2]*Gadget{gadget, gadget}, PlainAssociation | Dependency, utils.Point{X: 0, Y: 0}, utils.Point{X: 1, Y: 1}, true},
+		{"unknown flag", [2]*Gadget{gadget, gadget}, 0x100, utils.Point{X: 0, Y: 0}, utils.Point{X: 1, Y: 1}, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -97,6 +102,34 @@ func Test_Association_Setters(t *testing.T) {
 		}
 	})
 
+	t.Run("SetIsSelected", func(t *testing.T) {
+		err := ass.SetIsSelected(true)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if !ass.GetIsSelected() || !ass.GetDrawData().(drawdata.Association).IsSelected {
+			t.Errorf("expected association to be selected")
+		}
+	})
+
+	t.Run("SetStartRatio and SetEndRatio", func(t *testing.T) {
+		if err := ass.SetStartRatio([2]float64{0, 0.5}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if err := ass.SetEndRatio([2]float64{0.5, 1}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if ass.GetStartRatio() != [2]float64{0, 0.5} || ass.GetEndRatio() != [2]float64{0.5, 1} {
+			t.Errorf("unexpected ratios %v %v", ass.GetStartRatio(), ass.GetEndRatio())
+		}
+		if err := ass.SetStartRatio([2]float64{-0.1, 0.5}); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+		if err := ass.SetEndRatio([2]float64{0.5, 1.1}); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+
 	t.Run("SetParentStart", func(t *testing.T) {
 		err := ass.SetParentStart(gadget, utils.Point{})
 		if err != nil {
@@ -230,6 +263,59 @@ func Test_Association_Cover(t *testing.T) {
 	})
 }
 
+func Test_Association_CoverRect(t *testing.T) {
+	gadget := newEmptyGadget(Class, utils.Point{X: 0, Y: 0})
+	ass := &Association{
+		parents: [2]*Gadget{gadget, gadget},
+		drawdata: drawdata.Association{
+			StartX: 0, StartY: 0,
+			EndX: 10, EndY: 10,
+		},
+	}
+	loop := &Association{
+		parents: [2]*Gadget{gadget, gadget},
+		drawdata: drawdata.Association{
+			StartX: 0, StartY: 0,
+			EndX: 0, EndY: 10,
+			DeltaX: -5,
+		},
+	}
+
+	t.Run("GetBounds", func(t *testing.T) {
+		want := utils.NewRect(utils.Point{X: 0, Y: 0}, utils.Point{X: 10, Y: 10})
+		if ass.GetBounds() != want {
+			t.Errorf("expected %v, got %v", want, ass.GetBounds())
+		}
+		want = utils.NewRect(utils.Point{X: -5, Y: 0}, utils.Point{X: 0, Y: 10})
+		if loop.GetBounds() != want {
+			t.Errorf("expected %v, got %v", want, loop.GetBounds())
+		}
+	})
+
+	tests := []struct {
+		name     string
+		ass      *Association
+		r        utils.Rect
+		expected bool
+	}{
+		{"crossing the line", ass, utils.NewRect(utils.Point{X: 4, Y: 0}, utils.Point{X: 6, Y: 10}), true},
+		{"inside the bounds only", ass, utils.NewRect(utils.Point{X: 6, Y: 0}, utils.Point{X: 10, Y: 3}), false},
+		{"crossing the loop", loop, utils.NewRect(utils.Point{X: -6, Y: 4}, utils.Point{X: -4, Y: 6}), true},
+		{"inside the loop", loop, utils.NewRect(utils.Point{X: -3, Y: 4}, utils.Point{X: -2, Y: 6}), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			covered, err := tt.ass.CoverRect(tt.r)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if covered != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, covered)
+			}
+		})
+	}
+}
+
 func Test_Association_UpdateDrawData(t *testing.T) {
 	g1, _ := NewGadget(Class, utils.Point{X: 0, Y: 0}, 0, "#FF00FF", "sample header")
 	g2, _ := NewGadget(Class, utils.Point{X: 10, Y: 10}, 0, "#FF00FF", "sample header")
@@ -267,3 +353,593 @@ func Test_Association_RegisterUpdateParentDraw(t *testing.T) {
 		}
 	})
 }
+
+func Test_Association_FileData(t *testing.T) {
+	g1, _ := NewGadget(Class, utils.Point{X: 0, Y: 0}, 0, "#FF00FF", "sample header")
+	g2, _ := NewGadget(Class, utils.Point{X: 100, Y: 100}, 0, "#FF00FF", "sample header")
+	ass, err := NewAssociation([2]*Gadget{g1, g2}, Composition, utils.Point{X: 5, Y: 0}, utils.Point{X: 100, Y: 105})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	att, _ := attribute.NewAssAttribute(0.25)
+	if err = att.SetContent("0..*"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = ass.AddAttribute(att); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		fd, err := ass.GetFileData(map[*Gadget]int{g1: 0, g2: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if fd.Parents != [2]int{0, 1} {
+			t.Errorf("expected parents %v, got %v", [2]int{0, 1}, fd.Parents)
+		}
+		loaded, err := NewAssociationFromFileData(fd, [2]*Gadget{g1, g2})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if loaded.GetStartRatio() != ass.GetStartRatio() || loaded.GetEndRatio() != ass.GetEndRatio() {
+			t.Errorf("ratios differ after round trip")
+		}
+		got := loaded.GetDrawData().(drawdata.Association)
+		want := ass.GetDrawData().(drawdata.Association)
+		if got.StartX != want.StartX || got.StartY != want.StartY || got.EndX != want.EndX || got.EndY != want.EndY {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		if len(got.Attributes) != 1 || got.Attributes[0] != want.Attributes[0] {
+			t.Errorf("expected attributes %v, got %v", want.Attributes, got.Attributes)
+		}
+		if loaded.GetID() != ass.GetID() || got.ID != ass.GetID() {
+			t.Errorf("expected id %v, got %v", ass.GetID(), loaded.GetID())
+		}
+	})
+
+	t.Run("missing id", func(t *testing.T) {
+		fd, _ := ass.GetFileData(map[*Gadget]int{g1: 0, g2: 1})
+		fd.ID = ""
+		loaded, err := NewAssociationFromFileData(fd, [2]*Gadget{g1, g2})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if loaded.GetID() == "" || loaded.GetID() == ass.GetID() {
+			t.Errorf("expected a fresh id, got %q", loaded.GetID())
+		}
+	})
+
+	t.Run("navigability and class", func(t *testing.T) {
+		class, _ := NewGadget(Class, utils.Point{X: 200, Y: 0}, 0, "#FF00FF", "class")
+		a, _ := NewAssociation([2]*Gadget{g1, g2}, AssociationClass, utils.Point{X: 5, Y: 0}, utils.Point{X: 100, Y: 105})
+		if err := a.SetStartNavigability(Navigable); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := a.SetAssociationClass(class); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		fd, err := a.GetFileData(map[*Gadget]int{g1: 0, g2: 1, class: 2})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if fd.Navigability != [2]int{int(Navigable), int(NavigabilityUnspecified)} || fd.Class == nil || *fd.Class != 2 {
+			t.Errorf("unexpected navigability %v or class %v", fd.Navigability, fd.Class)
+		}
+		loaded, err := NewAssociationFromFileData(fd, [2]*Gadget{g1, g2})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if loaded.GetStartNavigability() != Navigable || loaded.GetDrawData().(drawdata.Association).StartHead != drawdata.ArrowheadOpen {
+			t.Errorf("navigability lost in round trip")
+		}
+
+		// copied without its class
+		fd, _ = a.GetFileData(map[*Gadget]int{g1: 0, g2: 1})
+		if fd.Class != nil {
+			t.Errorf("expected no class, got %v", *fd.Class)
+		}
+
+		fd.Navigability[1] = int(NonNavigable)
+		fd.AssType = Composition
+		if _, err = NewAssociationFromFileData(fd, [2]*Gadget{g1, g2}); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+
+	t.Run("parent not indexed", func(t *testing.T) {
+		if _, err := ass.GetFileData(map[*Gadget]int{g1: 0}); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+
+	t.Run("invalid saved data", func(t *testing.T) {
+		fd, _ := ass.GetFileData(map[*Gadget]int{g1: 0, g2: 1})
+		if _, err := NewAssociationFromFileData(fd, [2]*Gadget{g1, nil}); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+		fd.AssType = 0
+		if _, err := NewAssociationFromFileData(fd, [2]*Gadget{g1, g2}); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	})
+}
+
+func Test_Association_InsertAttribute(t *testing.T) {
+	g1, _ := NewGadget(Class, utils.Point{X: 0, Y: 0}, 0, "#FF00FF", "sample header")
+	g2, _ := NewGadget(Class, utils.Point{X: 100, Y: 100}, 0, "#FF00FF", "sample header")
+	ass, _ := NewAssociation([2]*Gadget{g1, g2}, Composition, utils.Point{X: 5, Y: 0}, utils.Point{X: 100, Y: 105})
+	first, _ := attribute.NewAssAttribute(0.1)
+	second, _ := attribute.NewAssAttribute(0.9)
+
+	if err := ass.InsertAttribute(0, second); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ass.InsertAttribute(0, first); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if ass.GetAttributesLen() != 2 {
+		t.Fatalf("expected 2 attributes, got %v", ass.GetAttributesLen())
+	}
+	atts, _ := ass.GetAttributes()
+	if atts[0] != first || atts[1] != second {
+		t.Errorf("attributes are not in insertion order")
+	}
+	if err := ass.InsertAttribute(3, first); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if err := ass.InsertAttribute(0, nil); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func Test_Association_Heads(t *testing.T) {
+	g1, _ := NewGadget(Class, utils.Point{X: 0, Y: 0}, 0, "#FF00FF", "sample header")
+	g2, _ := NewGadget(Class, utils.Point{X: 100, Y: 100}, 0, "#FF00FF", "sample header")
+	tests := []struct {
+		assType            AssociationType
+		startHead, endHead int
+		dashed             bool
+	}{
+		{Extension, drawdata.ArrowheadNone, drawdata.ArrowheadHollowTriangle, false},
+		{Implementation, drawdata.ArrowheadNone, drawdata.ArrowheadHollowTriangle, true},
+		{Composition, drawdata.ArrowheadNone, drawdata.ArrowheadFilledDiamond, false},
+		{Dependency, drawdata.ArrowheadNone, drawdata.ArrowheadOpen, true},
+		{PlainAssociation, drawdata.ArrowheadNone, drawdata.ArrowheadNone, false},
+		{Aggregation, drawdata.ArrowheadNone, drawdata.ArrowheadHollowDiamond, false},
+		{DirectedAssociation, drawdata.ArrowheadNone, drawdata.ArrowheadOpen, false},
+		{AssociationClass, drawdata.ArrowheadNone, drawdata.ArrowheadNone, false},
+	}
+	if len(tests) != len(AllAssociationTypes) {
+		t.Fatalf("expected a case for each of the %v association types", len(AllAssociationTypes))
+	}
+	for _, tt := range tests {
+		ass, err := NewAssociation([2]*Gadget{g1, g2}, tt.assType, utils.Point{X: 5, Y: 0}, utils.Point{X: 100, Y: 105})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		dd := ass.GetDrawData().(drawdata.Association)
+		if dd.StartHead != tt.startHead || dd.EndHead != tt.endHead || dd.Dashed != tt.dashed {
+			t.Errorf("type %v: expected heads %v %v dashed %v, got %v %v %v",
+				tt.assType, tt.startHead, tt.endHead, tt.dashed, dd.StartHead, dd.EndHead, dd.Dashed)
+		}
+	}
+}
+
+func Test_Association_Navigability(t *testing.T) {
+	g1, _ := NewGadget(Class, utils.Point{X: 0, Y: 0}, 0, "#FF00FF", "sample header")
+	g2, _ := NewGadget(Class, utils.Point{X: 100, Y: 100}, 0, "#FF00FF", "sample header")
+	ass, _ := NewAssociation([2]*Gadget{g1, g2}, PlainAssociation, utils.Point{X: 5, Y: 0}, utils.Point{X: 100, Y: 105})
+
+	if err := ass.SetStartNavigability(NonNavigable); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ass.SetEndNavigability(Navigable); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	dd := ass.GetDrawData().(drawdata.Association)
+	if ass.GetStartNavigability() != NonNavigable || dd.StartHead != drawdata.ArrowheadCross {
+		t.Errorf("expected a non-navigable start, got %v drawn as %v", ass.GetStartNavigability(), dd.StartHead)
+	}
+	if ass.GetEndNavigability() != Navigable || dd.EndHead != drawdata.ArrowheadOpen {
+		t.Errorf("expected a navigable end, got %v drawn as %v", ass.GetEndNavigability(), dd.EndHead)
+	}
+	if err := ass.SetEndNavigability(Navigability(7)); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+
+	// the diamond takes the end, the start still shows navigability
+	if err := ass.SetAssType(Aggregation); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	dd = ass.GetDrawData().(drawdata.Association)
+	if dd.StartHead != drawdata.ArrowheadCross || dd.EndHead != drawdata.ArrowheadHollowDiamond {
+		t.Errorf("unexpected heads %v %v", dd.StartHead, dd.EndHead)
+	}
+	if ass.GetEndNavigability() != NavigabilityUnspecified {
+		t.Errorf("expected the end navigability to be cleared, got %v", ass.GetEndNavigability())
+	}
+	if err := ass.SetEndNavigability(NonNavigable); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if err := ass.SetEndNavigability(NavigabilityUnspecified); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// no association-like type, no navigability
+	if err := ass.SetAssType(Extension); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	dd = ass.GetDrawData().(drawdata.Association)
+	if dd.StartHead != drawdata.ArrowheadNone {
+		t.Errorf("expected no start head, got %v", dd.StartHead)
+	}
+	if ass.GetStartNavigability() != NavigabilityUnspecified {
+		t.Errorf("expected the start navigability to be cleared, got %v", ass.GetStartNavigability())
+	}
+	if err := ass.SetStartNavigability(Navigable); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func Test_Association_Class(t *testing.T) {
+	g1, _ := NewGadget(Class, utils.Point{X: 0, Y: 0}, 0, "#FF00FF", "sample header")
+	g2, _ := NewGadget(Class, utils.Point{X: 300, Y: 0}, 0, "#FF00FF", "sample header")
+	class, _ := NewGadget(Class, utils.Point{X: 100, Y: 200}, 0, "#FF00FF", "sample header")
+	note, _ := NewGadget(Note, utils.Point{X: 100, Y: 300}, 0, "#FF00FF", "sample header")
+	ass, _ := NewAssociation([2]*Gadget{g1, g2}, PlainAssociation, utils.Point{X: 5, Y: 0}, utils.Point{X: 300, Y: 5})
+
+	if err := ass.SetAssociationClass(class); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if err := ass.SetAssType(AssociationClass); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, g := range []*Gadget{note, g1, g2} {
+		if err := ass.SetAssociationClass(g); err == nil {
+			t.Errorf("expected error, got nil")
+		}
+	}
+	if err := ass.SetAssociationClass(class); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ass.GetAssociationClass() != class {
+		t.Errorf("expected the class to be linked")
+	}
+
+	// from the middle of the line to the top of the class below it
+	dd := ass.GetDrawData().(drawdata.Association)
+	if dd.ClassLink == nil {
+		t.Fatalf("expected a class link")
+	}
+	if dd.ClassLink.StartX != (dd.StartX+dd.EndX)/2 || dd.ClassLink.StartY != (dd.StartY+dd.EndY)/2 {
+		t.Errorf("expected the link to start in the middle of the line, got %v", *dd.ClassLink)
+	}
+	if dd.ClassLink.EndY != 200 {
+		t.Errorf("expected the link to end on top of the class, got %v", *dd.ClassLink)
+	}
+
+	if err := ass.SetAssType(PlainAssociation); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if err := ass.SetAssociationClass(nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if ass.GetDrawData().(drawdata.Association).ClassLink != nil {
+		t.Errorf("expected no class link")
+	}
+	if err := ass.SetAssType(PlainAssociation); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func Test_Association_SetParent(t *testing.T) {
+	g1, _ := NewGadget(Class, utils.Point{X: 0, Y: 0}, 0, "#FF00FF", "sample header")
+	g2, _ := NewGadget(Class, utils.Point{X: 300, Y: 0}, 0, "#FF00FF", "sample header")
+	g3, _ := NewGadget(Class, utils.Point{X: 300, Y: 300}, 0, "#FF00FF", "sample header")
+	class, _ := NewGadget(Class, utils.Point{X: 100, Y: 200}, 0, "#FF00FF", "sample header")
+	ass, _ := NewAssociation([2]*Gadget{g1, g2}, AssociationClass, utils.Point{X: 5, Y: 0}, utils.Point{X: 300, Y: 5})
+	if err := ass.SetAssociationClass(class); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ratio := ass.GetEndRatio()
+
+	// refused moves leave the end where it was
+	if err := ass.SetParentEnd(g3, utils.Point{X: 0, Y: 0}); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if err := ass.SetParentEnd(class, utils.Point{X: 100, Y: 200}); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if err := ass.SetParentEnd(nil, utils.Point{X: 300, Y: 300}); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	// onto the very point the start is drawn at
+	before := ass.GetDrawData()
+	if err := ass.SetParentEnd(g1, utils.Point{X: 5, Y: 0}); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if !reflect.DeepEqual(before, ass.GetDrawData()) {
+		t.Errorf("expected the draw data to stay as it was")
+	}
+	if ass.GetParentEnd() != g2 || ass.GetEndRatio() != ratio {
+		t.Errorf("expected the end to stay on its gadget")
+	}
+
+	if err := ass.SetParentEnd(g3, utils.Point{X: 300, Y: 300}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ass.GetParentEnd() != g3 || ass.GetEndRatio() != [2]float64{0, 0} {
+		t.Errorf("expected the end on the new gadget, got %v", ass.GetEndRatio())
+	}
+}
+
+func Test_ValidateMultiplicity(t *testing.T) {
+	tests := []struct {
+		multiplicity string
+		wantErr      bool
+	}{
+		{"1", false},
+		{"*", false},
+		{"0..*", false},
+		{"0..1", false},
+		{"2..5", false},
+		{"3..3", false},
+		{"", true},
+		{"5..2", true},
+		{"*..1", true},
+		{"1..", true},
+		{"..1", true},
+		{"1..2..3", true},
+		{"01", true},
+		{"-1", true},
+		{"n", true},
+		{" 1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.multiplicity, func(t *testing.T) {
+			if err := ValidateMultiplicity(tt.multiplicity); (err != nil) != tt.wantErr {
+				t.Errorf("error mismatch: got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_Association_Labels(t *testing.T) {
+	g1, _ := NewGadget(Class, utils.Point{X: 0, Y: 0}, 0, "#FF00FF", "sample header")
+	g2, _ := NewGadget(Class, utils.Point{X: 300, Y: 0}, 0, "#FF00FF", "sample header")
+	ass, _ := NewAssociation([2]*Gadget{g1, g2}, PlainAssociation, utils.Point{X: 5, Y: 0}, utils.Point{X: 300, Y: 5})
+
+	if atts, err := ass.GetAttributes(); err != nil || len(atts) != 0 {
+		t.Errorf("expected no attributes and no error, got %v %v", atts, err)
+	}
+	if err := ass.SetLabel(StartMultiplicity, "many"); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if err := ass.SetLabel(EndRole, "two\nlines"); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if err := ass.SetLabel(labelKindCount, "x"); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if err := ass.SetReadingDirection(ReadingDirection(3)); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	for kind, content := range map[LabelKind]string{
+		StartMultiplicity: "1", StartRole: "owner", EndMultiplicity: "0..*", EndRole: "+items", MiddleName: "holds",
+	} {
+		if err := ass.SetLabel(kind, content); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := ass.SetReadingDirection(ReadingTowardEnd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if role, _ := ass.GetLabel(StartRole); role != "owner" {
+		t.Errorf("expected owner, got %v", role)
+	}
+
+	dd := ass.GetDrawData().(drawdata.Association)
+	if len(dd.Labels) != 5 {
+		t.Fatalf("expected 5 labels, got %v", len(dd.Labels))
+	}
+	labels := make(map[LabelKind]drawdata.AssLabel)
+	for _, l := range dd.Labels {
+		labels[LabelKind(l.Kind)] = l
+		if l.Width <= 0 || l.Height <= 0 {
+			t.Errorf("label %v has no size", l.Content)
+		}
+	}
+	lineY := dd.StartY
+	// on a horizontal line, multiplicities above it and roles below it, next to their own end
+	if labels[StartMultiplicity].Y+labels[StartMultiplicity].Height > lineY || labels[StartRole].Y < lineY {
+		t.Errorf("unexpected start labels %v %v around y %v", labels[StartMultiplicity], labels[StartRole], lineY)
+	}
+	if labels[StartMultiplicity].X > dd.StartX+labelAlong || labels[EndMultiplicity].X < dd.EndX-labelAlong-labels[EndMultiplicity].Width {
+		t.Errorf("labels are not next to their ends: %v %v", labels[StartMultiplicity], labels[EndMultiplicity])
+	}
+	name := labels[MiddleName]
+	if name.X+name.Width/2 < (dd.StartX+dd.EndX)/2-1 || name.X+name.Width/2 > (dd.StartX+dd.EndX)/2+1 || name.Y+name.Height > lineY {
+		t.Errorf("unexpected name %v", name)
+	}
+	if name.ReadingDirection != int(ReadingTowardEnd) {
+		t.Errorf("expected the name to read toward the end, got %v", name.ReadingDirection)
+	}
+
+	// the labels follow their end
+	before := labels[EndMultiplicity]
+	_ = g2.RegisterUpdateParentDraw(func() duerror.DUError { return nil })
+	if err := g2.SetPoint(utils.Point{X: 300, Y: 200}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ass.UpdateDrawData(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, l := range ass.GetDrawData().(drawdata.Association).Labels {
+		if LabelKind(l.Kind) == EndMultiplicity && l.Y-before.Y < 150 {
+			t.Errorf("expected the end multiplicity to move down with its end, got %v from %v", l, before)
+		}
+	}
+
+	// saved and loaded back
+	fd, _ := ass.GetFileData(map[*Gadget]int{g1: 0, g2: 1})
+	if fd.Labels.EndRole != "+items" || fd.Labels.ReadingDirection != int(ReadingTowardEnd) {
+		t.Errorf("unexpected saved labels %v", fd.Labels)
+	}
+	loaded, err := NewAssociationFromFileData(fd, [2]*Gadget{g1, g2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(loaded.GetDrawData().(drawdata.Association).Labels) != 5 || loaded.GetReadingDirection() != ReadingTowardEnd {
+		t.Errorf("labels lost in round trip")
+	}
+	fd.Labels.StartMultiplicity = "3..1"
+	if _, err = NewAssociationFromFileData(fd, [2]*Gadget{g1, g2}); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+
+	// an empty label is hidden
+	if err := ass.SetLabel(MiddleName, ""); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(ass.GetDrawData().(drawdata.Association).Labels) != 4 {
+		t.Errorf("expected 4 labels")
+	}
+}
+
+func Test_Association_Waypoints(t *testing.T) {
+	g1, _ := NewGadget(Class, utils.Point{X: 0, Y: 0}, 0, "#FF00FF", "sample header")
+	g2, _ := NewGadget(Class, utils.Point{X: 300, Y: 0}, 0, "#FF00FF", "sample header")
+	ass, _ := NewAssociation([2]*Gadget{g1, g2}, PlainAssociation, utils.Point{X: 5, Y: 0}, utils.Point{X: 300, Y: 5})
+	dd := ass.GetDrawData().(drawdata.Association)
+	if len(dd.Path) != 2 {
+		t.Fatalf("expected a straight path, got %v", dd.Path)
+	}
+
+	waypoints := []utils.Point{{X: 5, Y: -100}, {X: 305, Y: -100}}
+	if err := ass.SetWaypoints(waypoints); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waypoints[0].X = 50
+	if ass.GetWaypoints()[0].X != 5 {
+		t.Errorf("the waypoints are shared with the caller")
+	}
+	dd = ass.GetDrawData().(drawdata.Association)
+	if len(dd.Path) != 4 || dd.Path[1] != (drawdata.PathPoint{X: 5, Y: -100}) {
+		t.Fatalf("expected the path to go through the waypoints, got %v", dd.Path)
+	}
+
+	// hit testing follows the bends, not the chord between the ends
+	if covered, _ := ass.Cover(utils.Point{X: 150, Y: -98}); !covered {
+		t.Errorf("expected the bend segment to be covered")
+	}
+	if covered, _ := ass.Cover(utils.Point{X: 150, Y: dd.StartY}); covered {
+		t.Errorf("expected the chord not to be covered")
+	}
+	if covered, _ := ass.CoverRect(utils.NewRect(utils.Point{X: 100, Y: -110}, utils.Point{X: 120, Y: -90})); !covered {
+		t.Errorf("expected the rectangle to catch the bend segment")
+	}
+	if bounds := ass.GetBounds(); bounds.Min.Y != -100 {
+		t.Errorf("expected the bounds to reach the waypoints, got %v", bounds)
+	}
+
+	// saved and loaded back
+	fd, _ := ass.GetFileData(map[*Gadget]int{g1: 0, g2: 1})
+	loaded, err := NewAssociationFromFileData(fd, [2]*Gadget{g1, g2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(loaded.GetWaypoints()) != 2 || loaded.GetWaypoints()[1] != (utils.Point{X: 305, Y: -100}) {
+		t.Errorf("waypoints lost in round trip: %v", loaded.GetWaypoints())
+	}
+
+	// a self association bends through its waypoints instead of its loop
+	self, _ := NewAssociation([2]*Gadget{g1, g1}, PlainAssociation, utils.Point{X: 0, Y: 5}, utils.Point{X: 0, Y: 15})
+	if self.GetDrawData().(drawdata.Association).DeltaX == 0 {
+		t.Fatalf("expected a loop")
+	}
+	if err = self.SetWaypoints([]utils.Point{{X: -30, Y: 5}, {X: -30, Y: 15}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dd = self.GetDrawData().(drawdata.Association)
+	if dd.DeltaX != 0 || len(dd.Path) != 4 {
+		t.Errorf("expected the waypoints to replace the loop, got %v", dd)
+	}
+	if err = self.SetWaypoints(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if self.GetDrawData().(drawdata.Association).DeltaX == 0 {
+		t.Errorf("expected the loop back")
+	}
+}
+
+func Test_Association_Orthogonal(t *testing.T) {
+	g1, _ := NewGadget(Class, utils.Point{X: 0, Y: 0}, 0, "#FF00FF", "sample header")
+	g2, _ := NewGadget(Class, utils.Point{X: 400, Y: 0}, 0, "#FF00FF", "sample header")
+	wall := utils.NewRect(utils.Point{X: 200, Y: -50}, utils.Point{X: 250, Y: 150})
+	ass, _ := NewAssociation([2]*Gadget{g1, g2}, PlainAssociation, utils.Point{X: 5, Y: 0}, utils.Point{X: 400, Y: 5})
+	if err := ass.SetWaypoints([]utils.Point{{X: 200, Y: 300}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ass.SetRouting(Routing(5)); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+
+	// only the parents are in the way without a search
+	if err := ass.SetRouting(RoutingOrthogonal); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ass.GetRouting() != RoutingOrthogonal {
+		t.Errorf("expected orthogonal routing")
+	}
+	for _, p := range ass.GetDrawData().(drawdata.Association).Path {
+		if p.Y == 300 {
+			t.Errorf("expected the waypoints to be ignored, got %v", ass.GetDrawData().(drawdata.Association).Path)
+		}
+	}
+
+	if err := ass.RegisterObstacleSearch(nil); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if err := ass.RegisterObstacleSearch(func(area utils.Rect) []utils.Rect {
+		if area.Intersects(wall) {
+			return []utils.Rect{wall}
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ass.UpdateDrawData(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path := ass.GetDrawData().(drawdata.Association).Path
+	inner := utils.NewRect(utils.Point{X: wall.Min.X + 1, Y: wall.Min.Y + 1}, utils.Point{X: wall.Max.X - 1, Y: wall.Max.Y - 1})
+	for i := 1; i < len(path); i++ {
+		a, b := utils.Point{X: path[i-1].X, Y: path[i-1].Y}, utils.Point{X: path[i].X, Y: path[i].Y}
+		if a.X != b.X && a.Y != b.Y {
+			t.Errorf("segment %v-%v is not orthogonal", a, b)
+		}
+		if inner.IntersectsSegment(a, b) {
+			t.Errorf("segment %v-%v crosses the wall", a, b)
+		}
+	}
+	if covered, _ := ass.Cover(utils.Point{X: 225, Y: 5}); covered {
+		t.Errorf("expected the straight line through the wall not to be covered")
+	}
+
+	fd, _ := ass.GetFileData(map[*Gadget]int{g1: 0, g2: 1})
+	if fd.Routing != int(RoutingOrthogonal) {
+		t.Errorf("expected the routing to be saved")
+	}
+	fd.Routing = 9
+	if _, err := NewAssociationFromFileData(fd, [2]*Gadget{g1, g2}); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+
+	// back to the waypoints
+	if err := ass.SetRouting(RoutingStraight); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path = ass.GetDrawData().(drawdata.Association).Path; len(path) != 3 || path[1].Y != 300 {
+		t.Errorf("expected the path through the waypoint, got %v", path)
+	}
+}
@@ -2,6 +2,7 @@ package attribute
 
 import (
 	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/filedata"
 	"Dr.uml/backend/utils/duerror"
 )
 
@@ -20,12 +21,39 @@ func NewAssAttribute(ratio float64) (*AssAttribute, duerror.DUError) {
 		return nil, duerror.NewInvalidArgumentError("ratio should be between 0 and 1")
 	}
 	att := &AssAttribute{
+		Attribute: Attribute{
+			size:     drawdata.DefaultAttributeFontSize,
+			style:    drawdata.DefaultAttributeFontStyle,
+			fontFile: defaultFontFile(),
+		},
 		ratio: ratio,
 	}
 	att.UpdateDrawData()
 	return att, nil
 }
 
+// NewAssAttributeFromFileData rebuilds an AssAttribute from its saved form.
+// It returns an error if the saved ratio is not between 0 and 1
+func NewAssAttributeFromFileData(fd filedata.AssAttribute) (*AssAttribute, duerror.DUError) {
+	if fd.Ratio < 0 || fd.Ratio > 1 {
+		return nil, duerror.NewInvalidArgumentError("ratio should be between 0 and 1")
+	}
+	if Textstyle(fd.Style) & ^supportedTextStyleFlags != 0 {
+		return nil, duerror.NewInvalidArgumentError("style contains unsupported flags")
+	}
+	att := &AssAttribute{
+		Attribute: Attribute{
+			content:  fd.Content,
+			size:     fd.Size,
+			style:    Textstyle(fd.Style),
+			fontFile: fontFileFromFileData(fd.FontFile),
+		},
+		ratio: fd.Ratio,
+	}
+	att.UpdateDrawData()
+	return att, nil
+}
+
 // GetRatio retrieves the ratio value of the AssAttribute
 func (att *AssAttribute) GetRatio() float64 {
 	return att.ratio
@@ -35,6 +63,17 @@ func (att *AssAttribute) GetAssDD() drawdata.AssAttribute {
 	return att.assDD
 }
 
+// GetAssFileData returns the persistent form of the AssAttribute.
+func (att *AssAttribute) GetAssFileData() filedata.AssAttribute {
+	return filedata.AssAttribute{
+		Content:  att.content,
+		Size:     att.size,
+		Style:    int(att.style),
+		FontFile: fontFileToFileData(att.fontFile),
+		Ratio:    att.ratio,
+	}
+}
+
 func (att *AssAttribute) SetContent(content string) duerror.DUError {
 	if err := att.Attribute.SetContent(content); err != nil {
 		return err
@@ -144,3 +144,24 @@ func TestNewAssAttribute(t *testing.T) {
 		})
 	}
 }
+
+func TestAssAttribute_FileData(t *testing.T) {
+	attr, _ := NewAssAttribute(0.3)
+	if err := attr.SetContent("role"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fd := attr.GetAssFileData()
+	loaded, err := NewAssAttributeFromFileData(fd)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded.GetAssDD() != attr.GetAssDD() {
+		t.Errorf("GetAssDD() = %v, want %v", loaded.GetAssDD(), attr.GetAssDD())
+	}
+
+	fd.Ratio = 1.5
+	if _, err = NewAssAttributeFromFileData(fd); err == nil {
+		t.Errorf("NewAssAttributeFromFileData() expected error but got nil")
+	}
+}
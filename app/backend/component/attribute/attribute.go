@@ -2,6 +2,7 @@ package attribute
 
 import (
 	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/filedata"
 	"Dr.uml/backend/utils"
 	"Dr.uml/backend/utils/duerror"
 	"os"
@@ -22,7 +23,7 @@ func NewAttribute(content string) (*Attribute, duerror.DUError) {
 		content:  content,
 		size:     drawdata.DefaultAttributeFontSize,
 		style:    drawdata.DefaultAttributeFontStyle,
-		fontFile: os.Getenv("APP_ROOT") + drawdata.DefaultAttributeFontFile,
+		fontFile: defaultFontFile(),
 	}
 	if err := att.updateDrawData(); err != nil {
 		return nil, err
@@ -30,6 +31,46 @@ func NewAttribute(content string) (*Attribute, duerror.DUError) {
 	return att, nil
 }
 
+// NewAttributeFromFileData rebuilds an Attribute from its saved form.
+func NewAttributeFromFileData(fd filedata.Attribute) (*Attribute, duerror.DUError) {
+	if fd.Size <= 0 {
+		return nil, duerror.NewInvalidArgumentError("size must be greater than 0")
+	}
+	if Textstyle(fd.Style) & ^supportedTextStyleFlags != 0 {
+		return nil, duerror.NewInvalidArgumentError("style contains unsupported flags")
+	}
+	att := &Attribute{
+		content:  fd.Content,
+		size:     fd.Size,
+		style:    Textstyle(fd.Style),
+		fontFile: fontFileFromFileData(fd.FontFile),
+	}
+	if err := att.updateDrawData(); err != nil {
+		return nil, err
+	}
+	return att, nil
+}
+
+func defaultFontFile() string {
+	return os.Getenv("APP_ROOT") + drawdata.DefaultAttributeFontFile
+}
+
+// the default font lives under APP_ROOT, which differs between machines,
+// so it is saved as an empty string and resolved again on load
+func fontFileToFileData(fontFile string) string {
+	if fontFile == defaultFontFile() {
+		return ""
+	}
+	return fontFile
+}
+
+func fontFileFromFileData(fontFile string) string {
+	if fontFile == "" {
+		return defaultFontFile()
+	}
+	return fontFile
+}
+
 // GetContent retrieves the content of the Attribute as a string along with an error if applicable.
 func (att *Attribute) GetContent() string {
 	return att.content
@@ -127,15 +168,28 @@ func (att *Attribute) IsUnderline() bool {
 	return att.style&Underline != 0
 }
 
-// Copy creates and returns a deep copy of the Attribute with identical content, size, and style. It returns an error if any occurs.
+// Copy creates and returns a deep copy of the Attribute with identical content, size, style, font and draw data.
+// The copy belongs to no parent until it is registered to one. It returns an error if any occurs.
 func (att *Attribute) Copy() (*Attribute, duerror.DUError) {
 	return &Attribute{
-		content: att.content,
-		size:    att.size,
-		style:   att.style,
+		content:  att.content,
+		size:     att.size,
+		style:    att.style,
+		fontFile: att.fontFile,
+		drawData: att.drawData,
 	}, nil
 }
 
+// GetFileData returns the persistent form of the Attribute.
+func (att *Attribute) GetFileData() filedata.Attribute {
+	return filedata.Attribute{
+		Content:  att.content,
+		Size:     att.size,
+		Style:    int(att.style),
+		FontFile: fontFileToFileData(att.fontFile),
+	}
+}
+
 func (att *Attribute) GetDrawData() drawdata.Attribute {
 	return att.drawData
 }
@@ -453,6 +453,41 @@ func TestAttribute_Copy(t *testing.T) {
 	}
 }
 
+func TestAttribute_CopyKeepsFontAndDrawData(t *testing.T) {
+	parent := 0
+	att, _ := NewAttribute("test content")
+	if err := att.SetFontFile(att.fontFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = att.SetSize(20)
+	_ = att.RegisterUpdateParentDraw(func() duerror.DUError {
+		parent++
+		return nil
+	})
+
+	copy, err := att.Copy()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if copy.fontFile != att.fontFile {
+		t.Errorf("fontFile: expected %v, got %v", att.fontFile, copy.fontFile)
+	}
+	if copy.GetDrawData() != att.GetDrawData() {
+		t.Errorf("drawData: expected %v, got %v", att.GetDrawData(), copy.GetDrawData())
+	}
+
+	// the copy is independent of the original and of its parent
+	if err = copy.SetContent("changed"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if att.GetContent() != "test content" {
+		t.Errorf("content of the original changed to %v", att.GetContent())
+	}
+	if parent != 0 {
+		t.Errorf("the parent of the original was notified %v times", parent)
+	}
+}
+
 func TestAttribute_GetDrawData(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -721,3 +756,35 @@ func TestAttribute_updateDrawData(t *testing.T) {
 		})
 	}
 }
+
+func TestAttribute_FileData(t *testing.T) {
+	att, err := NewAttribute("content")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = att.SetStyle(Bold | Underline); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fd := att.GetFileData()
+	if fd.FontFile != "" {
+		t.Errorf("default font should be saved as empty, got %v", fd.FontFile)
+	}
+	loaded, err := NewAttributeFromFileData(fd)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded.GetDrawData() != att.GetDrawData() {
+		t.Errorf("expected %v, got %v", att.GetDrawData(), loaded.GetDrawData())
+	}
+
+	fd.Size = 0
+	if _, err = NewAttributeFromFileData(fd); err == nil {
+		t.Errorf("expected error for invalid size")
+	}
+	fd.Size = drawdata.DefaultAttributeFontSize
+	fd.Style = 8
+	if _, err = NewAttributeFromFileData(fd); err == nil {
+		t.Errorf("expected error for invalid style")
+	}
+}
@@ -0,0 +1,381 @@
+package attribute
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"Dr.uml/backend/utils/duerror"
+)
+
+type Visibility int
+
+const (
+	VisibilityNone Visibility = iota
+	Public                    // +
+	Private                   // -
+	Protected                 // #
+	PackagePrivate            // ~
+)
+
+var AllVisibilities = []struct {
+	Value  Visibility
+	TSName string
+}{
+	{VisibilityNone, "VisibilityNone"},
+	{Public, "Public"},
+	{Private, "Private"},
+	{Protected, "Protected"},
+	{PackagePrivate, "PackagePrivate"},
+}
+
+// visibilitySymbols is indexed by Visibility
+var visibilitySymbols = []rune{0, '+', '-', '#', '~'}
+
+type MemberKind int
+
+const (
+	Field MemberKind = iota
+	Operation
+)
+
+var AllMemberKinds = []struct {
+	Value  MemberKind
+	TSName string
+}{
+	{Field, "Field"},
+	{Operation, "Operation"},
+}
+
+// modifiers written in front of a member, as in "{static} + count: int"
+const (
+	staticModifier   = "{static}"
+	abstractModifier = "{abstract}"
+)
+
+// Parameter is one parameter of an operation, "name: type = default" with type and default optional
+type Parameter struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Default string `json:"default"`
+}
+
+// Member is the structured form of one line of a gadget section, either
+// a field "+ name: type = default" or an operation "+ name(params): type".
+// Type and Default are left empty when the line does not give them.
+type Member struct {
+	Kind       MemberKind  `json:"kind"`
+	Visibility Visibility  `json:"visibility"`
+	Name       string      `json:"name"`
+	Type       string      `json:"type"`
+	Default    string      `json:"default"`
+	Parameters []Parameter `json:"parameters"`
+	Static     bool        `json:"static"`
+	Abstract   bool        `json:"abstract"`
+}
+
+// MemberError is a syntax error in a member, Line is the index of the attribute in its section
+// and Column counts runes from 1
+type MemberError struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+func (e *MemberError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line+1, e.Column, e.Message)
+}
+
+// MemberSection is a parsed gadget section. Members holds one entry per attribute,
+// nil for those with a syntax error, which are listed in Errors.
+type MemberSection struct {
+	Members []*Member     `json:"members"`
+	Errors  []MemberError `json:"errors"`
+}
+
+// ParseMember parses the content of one attribute, the error returned is a *MemberError on line 0
+func ParseMember(content string) (Member, duerror.DUError) {
+	p := memberParser{src: []rune(content)}
+	m, err := p.parse()
+	if err != nil {
+		return Member{}, err
+	}
+	return m, nil
+}
+
+// ParseSection parses every attribute of a section. Underlined attributes are static and
+// italic operations abstract, as UML draws them, on top of the modifiers in their content.
+func ParseSection(atts []*Attribute) MemberSection {
+	section := MemberSection{Members: make([]*Member, len(atts)), Errors: []MemberError{}}
+	for i, att := range atts {
+		m, err := ParseMember(att.GetContent())
+		if err != nil {
+			e := *err.(*MemberError)
+			e.Line = i
+			section.Errors = append(section.Errors, e)
+			continue
+		}
+		m.Static = m.Static || att.GetStyle()&Underline != 0
+		m.Abstract = m.Abstract || (m.Kind == Operation && att.GetStyle()&Italic != 0)
+		section.Members[i] = &m
+	}
+	return section
+}
+
+// String renders the member canonically, ParseMember gives back the same member
+func (m Member) String() string {
+	var sb strings.Builder
+	if m.Static {
+		sb.WriteString(staticModifier + " ")
+	}
+	if m.Abstract {
+		sb.WriteString(abstractModifier + " ")
+	}
+	if m.Visibility != VisibilityNone {
+		sb.WriteString(string(visibilitySymbols[m.Visibility]) + " ")
+	}
+	sb.WriteString(m.Name)
+	if m.Kind == Operation {
+		params := make([]string, 0, len(m.Parameters))
+		for _, p := range m.Parameters {
+			params = append(params, joinTyped(p.Name, p.Type, p.Default))
+		}
+		sb.WriteString("(" + strings.Join(params, ", ") + ")")
+		if m.Type != "" {
+			sb.WriteString(": " + m.Type)
+		}
+		return sb.String()
+	}
+	sb.WriteString(joinTyped("", m.Type, m.Default))
+	return sb.String()
+}
+
+func joinTyped(name, typ, def string) string {
+	s := name
+	if typ != "" {
+		s += ": " + typ
+	}
+	if def != "" {
+		s += " = " + def
+	}
+	return s
+}
+
+type memberParser struct {
+	src []rune
+	pos int
+}
+
+func (p *memberParser) errorf(pos int, format string, args ...any) *MemberError {
+	return &MemberError{Column: pos + 1, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *memberParser) skipSpaces() {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *memberParser) peek() rune {
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *memberParser) consume(s string) bool {
+	if strings.HasPrefix(string(p.src[p.pos:]), s) {
+		p.pos += len([]rune(s))
+		return true
+	}
+	return false
+}
+
+func (p *memberParser) parse() (Member, *MemberError) {
+	m := Member{}
+	p.parseModifiers(&m)
+	if v := slices.Index(visibilitySymbols[1:], p.peek()); v >= 0 {
+		m.Visibility = Visibility(v + 1)
+		p.pos++
+		p.parseModifiers(&m)
+	}
+
+	var err *MemberError
+	if m.Name, err = p.parseName(); err != nil {
+		return Member{}, err
+	}
+	p.skipSpaces()
+	if p.peek() == '(' {
+		m.Kind = Operation
+		p.pos++
+		if m.Parameters, err = p.parseParameters(); err != nil {
+			return Member{}, err
+		}
+		p.skipSpaces()
+	}
+	if p.peek() == ':' {
+		p.pos++
+		if m.Type, err = p.parseType("="); err != nil {
+			return Member{}, err
+		}
+	}
+	if p.peek() == '=' {
+		if m.Kind == Operation {
+			return Member{}, p.errorf(p.pos, "an operation cannot have a default value")
+		}
+		p.pos++
+		if m.Default, err = p.parseDefault(""); err != nil {
+			return Member{}, err
+		}
+	}
+	p.skipSpaces()
+	if p.pos < len(p.src) {
+		return Member{}, p.errorf(p.pos, "unexpected %q", p.src[p.pos])
+	}
+	if m.Abstract && m.Kind != Operation {
+		return Member{}, p.errorf(0, "only an operation can be abstract")
+	}
+	return m, nil
+}
+
+// parseModifiers reads the modifiers, they may come before or after the visibility
+func (p *memberParser) parseModifiers(m *Member) {
+	for p.skipSpaces(); ; p.skipSpaces() {
+		if p.consume(staticModifier) {
+			m.Static = true
+		} else if p.consume(abstractModifier) {
+			m.Abstract = true
+		} else {
+			return
+		}
+	}
+}
+
+func (p *memberParser) parseName() (string, *MemberError) {
+	start := p.pos
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		if !(r == '_' || unicode.IsLetter(r) || (p.pos > start && unicode.IsDigit(r))) {
+			break
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		if p.pos == len(p.src) {
+			return "", p.errorf(p.pos, "expected a name")
+		}
+		return "", p.errorf(p.pos, "expected a name, got %q", p.src[p.pos])
+	}
+	return string(p.src[start:p.pos]), nil
+}
+
+// parseParameters reads the parameters after the opening parenthesis, up to and including the closing one
+func (p *memberParser) parseParameters() ([]Parameter, *MemberError) {
+	params := []Parameter{}
+	p.skipSpaces()
+	if p.peek() == ')' {
+		p.pos++
+		return params, nil
+	}
+	for {
+		p.skipSpaces()
+		var param Parameter
+		var err *MemberError
+		if param.Name, err = p.parseName(); err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if p.peek() == ':' {
+			p.pos++
+			if param.Type, err = p.parseType(",)="); err != nil {
+				return nil, err
+			}
+		}
+		if p.peek() == '=' {
+			p.pos++
+			if param.Default, err = p.parseDefault(",)"); err != nil {
+				return nil, err
+			}
+		}
+		params = append(params, param)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return params, nil
+		case 0:
+			return nil, p.errorf(p.pos, "missing closing parenthesis")
+		default:
+			return nil, p.errorf(p.pos, "unexpected %q", p.src[p.pos])
+		}
+	}
+}
+
+// parseType reads a type up to one of stops outside brackets, generics included
+func (p *memberParser) parseType(stops string) (string, *MemberError) {
+	typ, err := p.scan(stops, "<[({", ">])}", false)
+	if err != nil {
+		return "", err
+	}
+	if typ == "" {
+		return "", p.errorf(p.pos, "expected a type")
+	}
+	return typ, nil
+}
+
+// parseDefault reads a value up to one of stops outside brackets and quotes
+func (p *memberParser) parseDefault(stops string) (string, *MemberError) {
+	def, err := p.scan(stops, "[({", "])}", true)
+	if err != nil {
+		return "", err
+	}
+	if def == "" {
+		return "", p.errorf(p.pos, "expected a default value")
+	}
+	return def, nil
+}
+
+// scan reads up to one of stops at depth 0, or to the end, and returns the text trimmed
+func (p *memberParser) scan(stops, opens, closes string, quotes bool) (string, *MemberError) {
+	start := p.pos
+	var stack []rune
+	for ; p.pos < len(p.src); p.pos++ {
+		r := p.src[p.pos]
+		if len(stack) == 0 && strings.ContainsRune(stops, r) {
+			break
+		}
+		if quotes && (r == '"' || r == '\'') {
+			end := p.pos + 1
+			for end < len(p.src) && p.src[end] != r {
+				if p.src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(p.src) {
+				return "", p.errorf(p.pos, "unterminated string")
+			}
+			p.pos = end
+			continue
+		}
+		if r == '<' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '-' {
+			// the arrow of a channel type, as in "<-chan int", not a bracket
+			p.pos++
+			continue
+		}
+		if i := strings.IndexRune(opens, r); i >= 0 {
+			stack = append(stack, rune(closes[i]))
+		} else if strings.ContainsRune(closes, r) {
+			if len(stack) == 0 || stack[len(stack)-1] != r {
+				return "", p.errorf(p.pos, "unbalanced %q", r)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(stack) > 0 {
+		return "", p.errorf(p.pos, "missing %q", stack[len(stack)-1])
+	}
+	return strings.TrimSpace(string(p.src[start:p.pos])), nil
+}
@@ -0,0 +1,144 @@
+package attribute
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMember(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected Member
+	}{
+		{
+			name:     "operation",
+			content:  "+ getName(id: int): string",
+			expected: Member{Kind: Operation, Visibility: Public, Name: "getName", Type: "string", Parameters: []Parameter{{Name: "id", Type: "int"}}},
+		},
+		{
+			name:     "field with default",
+			content:  "- count: int = 0",
+			expected: Member{Kind: Field, Visibility: Private, Name: "count", Type: "int", Default: "0"},
+		},
+		{
+			name:     "enumeration literal",
+			content:  "RED",
+			expected: Member{Kind: Field, Name: "RED"},
+		},
+		{
+			name:     "modifiers either side of the visibility",
+			content:  "{static} # {abstract} run()",
+			expected: Member{Kind: Operation, Visibility: Protected, Name: "run", Parameters: []Parameter{}, Static: true, Abstract: true},
+		},
+		{
+			name:    "generic types and defaults with commas",
+			content: "~put(key: Map<String, List<int>>, sep: string = \", \", pair: (int, int) = (1, 2)): map[string]int",
+			expected: Member{Kind: Operation, Visibility: PackagePrivate, Name: "put", Type: "map[string]int", Parameters: []Parameter{
+				{Name: "key", Type: "Map<String, List<int>>"},
+				{Name: "sep", Type: "string", Default: "\", \""},
+				{Name: "pair", Type: "(int, int)", Default: "(1, 2)"},
+			}},
+		},
+		{
+			name:     "channel types",
+			content:  "pipe(in: <-chan int, out: chan<- int)",
+			expected: Member{Kind: Operation, Name: "pipe", Parameters: []Parameter{{Name: "in", Type: "<-chan int"}, {Name: "out", Type: "chan<- int"}}},
+		},
+		{
+			name:     "untyped parameters",
+			content:  "f(a, b = 2)",
+			expected: Member{Kind: Operation, Name: "f", Parameters: []Parameter{{Name: "a"}, {Name: "b", Default: "2"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseMember(tt.content)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(m, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, m)
+			}
+			// the canonical form parses back to the same member
+			again, err := ParseMember(m.String())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(again, m) {
+				t.Errorf("expected %+v, got %+v", m, again)
+			}
+		})
+	}
+}
+
+func TestParseMember_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		column  int
+	}{
+		{name: "empty", content: "", column: 1},
+		{name: "no name", content: "+ : int", column: 3},
+		{name: "missing type", content: "count: = 1", column: 8},
+		{name: "missing default", content: "count: int =", column: 13},
+		{name: "unclosed parameters", content: "f(a: int", column: 9},
+		{name: "unbalanced type", content: "f(a: List<int)", column: 14},
+		{name: "default of an operation", content: "f(): int = 1", column: 10},
+		{name: "abstract field", content: "{abstract} count: int", column: 1},
+		{name: "unterminated string", content: "f(s = \"a)", column: 7},
+		{name: "trailing text", content: "f() g", column: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseMember(tt.content)
+			if err == nil {
+				t.Fatalf("expected error, got nil")
+			}
+			if column := err.(*MemberError).Column; column != tt.column {
+				t.Errorf("expected column %v, got %v: %v", tt.column, column, err)
+			}
+		})
+	}
+}
+
+func TestMember_String(t *testing.T) {
+	m, err := ParseMember("  {static}+count :int=  0 ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.String() != "{static} + count: int = 0" {
+		t.Errorf("unexpected canonical form %q", m.String())
+	}
+	m, err = ParseMember("-  op ( a:int,b : string ) :  void")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.String() != "- op(a: int, b: string): void" {
+		t.Errorf("unexpected canonical form %q", m.String())
+	}
+}
+
+func TestParseSection(t *testing.T) {
+	field, _ := NewAttribute("+ count: int")
+	_ = field.SetUnderline(true)
+	broken, _ := NewAttribute("+ (")
+	op, _ := NewAttribute("draw()")
+	_ = op.SetItalic(true)
+
+	section := ParseSection([]*Attribute{field, broken, op})
+	if len(section.Members) != 3 || section.Members[1] != nil {
+		t.Fatalf("expected a member per line, nil for the broken one, got %v", section.Members)
+	}
+	if !section.Members[0].Static || section.Members[0].Abstract {
+		t.Errorf("expected the underlined field to be static")
+	}
+	if !section.Members[2].Abstract {
+		t.Errorf("expected the italic operation to be abstract")
+	}
+	if len(section.Errors) != 1 || section.Errors[0].Line != 1 || section.Errors[0].Column != 3 {
+		t.Errorf("unexpected errors %v", section.Errors)
+	}
+}
@@ -10,8 +10,13 @@ type Component interface {
 	// CreatePropertyTree() (PropertyTree, duerror.DUError)
 	// Copy() (Component, duerror.DUError)
 	Cover(p utils.Point) (bool, duerror.DUError)
+	CoverRect(r utils.Rect) (bool, duerror.DUError) // any part of the component lies inside r
+	GetBounds() utils.Rect
+	GetID() string
 	GetLayer() int
 	SetLayer(layer int) duerror.DUError
+	GetIsSelected() bool
+	SetIsSelected(isSelected bool) duerror.DUError
 	GetDrawData() any
 	RegisterUpdateParentDraw(update func() duerror.DUError) duerror.DUError
 }
@@ -1,8 +1,11 @@
 package component
 
 import (
+	"slices"
+
 	"Dr.uml/backend/component/attribute"
 	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/filedata"
 	"Dr.uml/backend/utils"
 	"Dr.uml/backend/utils/duerror"
 )
@@ -11,7 +14,12 @@ type GadgetType int
 
 const (
 	Class               GadgetType = 1 << iota // 0x01
-	supportedGadgetType            = Class
+	Interface                                  // 0x02
+	AbstractClass                              // 0x04
+	Enumeration                                // 0x08
+	Note                                       // 0x10
+	Package                                    // 0x20
+	supportedGadgetType = Class | Interface | AbstractClass | Enumeration | Note | Package
 )
 
 var AllGadgetTypes = []struct {
@@ -19,14 +27,36 @@ var AllGadgetTypes = []struct {
 	TSName string
 }{
 	{Class, "Class"},
+	{Interface, "Interface"},
+	{AbstractClass, "AbstractClass"},
+	{Enumeration, "Enumeration"},
+	{Note, "Note"},
+	{Package, "Package"},
+}
+
+// gadgetLayouts gives the number of attribute sections of each gadget type,
+// the stereotype drawn above its header, if any, and whether the sections after the header list members
+var gadgetLayouts = map[GadgetType]struct {
+	sections   int
+	stereotype string
+	members    bool
+}{
+	Class:         {3, "", true},              // name, attributes, methods
+	Interface:     {3, "«interface»", true},   // name, constants, methods
+	AbstractClass: {3, "", true},              // name in italic, attributes, methods
+	Enumeration:   {2, "«enumeration»", true}, // name, literals
+	Note:          {1, "", false},             // text, in a box with a folded corner
+	Package:       {2, "", false},             // name in a tab, contents
 }
 
 type Gadget struct {
+	id               string
 	gadgetType       GadgetType
 	point            utils.Point
 	layer            int
 	attributes       [][]*attribute.Attribute // Gadget has multiple sections, each section has multiple attributes
 	color            string
+	stereotype       *attribute.Attribute // nil for gadget types without one
 	IsSelected       bool
 	drawData         drawdata.Gadget
 	updateParentDraw func() duerror.DUError
@@ -34,7 +64,8 @@ type Gadget struct {
 
 // Other functions
 func validateGadgetType(input GadgetType) duerror.DUError {
-	if !(input&supportedGadgetType == input && input != 0) {
+	// exactly one supported flag
+	if !(input&supportedGadgetType == input && input != 0 && input&(input-1) == 0) {
 		return duerror.NewInvalidArgumentError("gadget type is not supported")
 	}
 	return nil
@@ -46,27 +77,64 @@ func NewGadget(gadgetType GadgetType, point utils.Point, layer int, colorHexStr
 		return nil, err
 	}
 	g := Gadget{
+		id:         utils.NewID(),
 		gadgetType: gadgetType,
 		point:      point,
 		layer:      layer,
 		color:      colorHexStr,
 	}
 
-	// Init attributes with three sections
-	g.attributes = make([][]*attribute.Attribute, 3)
+	// Init attributes with the sections of the gadget type, all empty
+	g.attributes = make([][]*attribute.Attribute, gadgetLayouts[gadgetType].sections)
+	for i := range g.attributes {
+		g.attributes[i] = make([]*attribute.Attribute, 0)
+	}
 
 	// The first section contains the header
-	g.attributes[0] = make([]*attribute.Attribute, 0, 1)
 	if header != "" {
 		if err := g.AddAttribute(0, header); err != nil {
 			return nil, err
 		}
 	}
 
-	// The second and third sections are empty
-	g.attributes[1] = make([]*attribute.Attribute, 0)
-	g.attributes[2] = make([]*attribute.Attribute, 0)
+	if err := g.updateDrawData(); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
 
+// NewGadgetFromFileData rebuilds a Gadget, including every attribute section, from its saved form.
+func NewGadgetFromFileData(fd filedata.Gadget) (*Gadget, duerror.DUError) {
+	if err := validateGadgetType(GadgetType(fd.GadgetType)); err != nil {
+		return nil, err
+	}
+	g := Gadget{
+		id:         fd.ID,
+		gadgetType: GadgetType(fd.GadgetType),
+		point:      utils.Point{X: fd.X, Y: fd.Y},
+		layer:      fd.Layer,
+		color:      fd.Color,
+	}
+	if g.id == "" {
+		g.id = utils.NewID()
+	}
+	if len(fd.Attributes) != gadgetLayouts[g.gadgetType].sections {
+		return nil, duerror.NewInvalidArgumentError("wrong number of attribute sections for the gadget type")
+	}
+	g.attributes = make([][]*attribute.Attribute, len(fd.Attributes))
+	for i, section := range fd.Attributes {
+		g.attributes[i] = make([]*attribute.Attribute, 0, len(section))
+		for _, attFd := range section {
+			att, err := attribute.NewAttributeFromFileData(attFd)
+			if err != nil {
+				return nil, err
+			}
+			if err = att.RegisterUpdateParentDraw(g.updateDrawData); err != nil {
+				return nil, err
+			}
+			g.attributes[i] = append(g.attributes[i], att)
+		}
+	}
 	if err := g.updateDrawData(); err != nil {
 		return nil, err
 	}
@@ -74,6 +142,10 @@ func NewGadget(gadgetType GadgetType, point utils.Point, layer int, colorHexStr
 }
 
 // Getter
+func (g *Gadget) GetID() string {
+	return g.id
+}
+
 func (g *Gadget) GetPoint() utils.Point {
 	return g.point
 }
@@ -97,6 +169,24 @@ func (g *Gadget) GetAttributesLen() []int {
 	}
 	return lengths
 }
+func (g *Gadget) GetAttribute(section int, index int) (*attribute.Attribute, duerror.DUError) {
+	if err := g.validateSection(section); err != nil {
+		return nil, err
+	}
+	if err := g.validateIndex(index, section); err != nil {
+		return nil, err
+	}
+	return g.attributes[section][index], nil
+}
+
+// GetMembers parses a section listing members, e.g. the attributes or methods of a class
+func (g *Gadget) GetMembers(section int) (attribute.MemberSection, duerror.DUError) {
+	if err := g.validateMemberSection(section); err != nil {
+		return attribute.MemberSection{}, err
+	}
+	return attribute.ParseSection(g.attributes[section]), nil
+}
+
 func (g *Gadget) GetIsSelected() bool {
 	return g.IsSelected
 }
@@ -167,9 +257,36 @@ func (g *Gadget) SetIsSelected(isSelected bool) duerror.DUError {
 
 // Methods
 func (g *Gadget) Cover(p utils.Point) (bool, duerror.DUError) {
-	tl := g.point                                                                          // top-left
-	br := utils.AddPoints(g.point, utils.Point{X: g.drawData.Width, Y: g.drawData.Height}) // bottom-right
-	return p.X >= tl.X && p.X <= br.X && p.Y >= tl.Y && p.Y <= br.Y, nil
+	if !g.GetBounds().Contains(p) {
+		return false, nil
+	}
+	switch g.gadgetType {
+	case Package:
+		// the corner right of the tab is empty
+		return g.getTab().Contains(p) || p.Y >= g.point.Y+g.drawData.TabHeight, nil
+	case Note:
+		// the folded corner is cut off along its diagonal
+		right := g.point.X + g.drawData.Width
+		return (right-p.X)+(p.Y-g.point.Y) >= g.drawData.FoldSize, nil
+	}
+	return true, nil
+}
+
+// CoverRect treats a note as its whole bounding box
+func (g *Gadget) CoverRect(r utils.Rect) (bool, duerror.DUError) {
+	if g.gadgetType == Package {
+		body := g.GetBounds()
+		body.Min.Y += g.drawData.TabHeight
+		return r.Intersects(g.getTab()) || r.Intersects(body), nil
+	}
+	return r.Intersects(g.GetBounds()), nil
+}
+
+func (g *Gadget) GetBounds() utils.Rect {
+	return utils.Rect{
+		Min: g.point,
+		Max: utils.AddPoints(g.point, utils.Point{X: g.drawData.Width, Y: g.drawData.Height}),
+	}
 }
 
 func (g *Gadget) AddAttribute(section int, content string) duerror.DUError {
@@ -187,6 +304,24 @@ func (g *Gadget) AddAttribute(section int, content string) duerror.DUError {
 	return g.updateDrawData()
 }
 
+// InsertAttribute puts an existing attribute back at index, e.g. one taken out by RemoveAttribute
+func (g *Gadget) InsertAttribute(section int, index int, att *attribute.Attribute) duerror.DUError {
+	if err := g.validateSection(section); err != nil {
+		return err
+	}
+	if index < 0 || index > len(g.attributes[section]) {
+		return duerror.NewInvalidArgumentError("index out of range")
+	}
+	if att == nil {
+		return duerror.NewInvalidArgumentError("attribute is nil")
+	}
+	if err := att.RegisterUpdateParentDraw(g.updateDrawData); err != nil {
+		return err
+	}
+	g.attributes[section] = slices.Insert(g.attributes[section], index, att)
+	return g.updateDrawData()
+}
+
 func (g *Gadget) RemoveAttribute(section int, index int) duerror.DUError {
 	if err := g.validateSection(section); err != nil {
 		return err
@@ -204,6 +339,16 @@ func (g *Gadget) validateSection(section int) duerror.DUError {
 	return nil
 }
 
+func (g *Gadget) validateMemberSection(section int) duerror.DUError {
+	if err := g.validateSection(section); err != nil {
+		return err
+	}
+	if !gadgetLayouts[g.gadgetType].members || section == 0 {
+		return duerror.NewInvalidArgumentError("section does not list members")
+	}
+	return nil
+}
+
 func (g *Gadget) validateIndex(index, section int) duerror.DUError {
 	if index < 0 || index >= len(g.attributes[section]) {
 		return duerror.NewInvalidArgumentError("index out of range")
@@ -211,29 +356,110 @@ func (g *Gadget) validateIndex(index, section int) duerror.DUError {
 	return nil
 }
 
+// GetFileData returns the persistent form of the Gadget.
+func (g *Gadget) GetFileData() filedata.Gadget {
+	atts := make([][]filedata.Attribute, len(g.attributes))
+	for i, section := range g.attributes {
+		atts[i] = make([]filedata.Attribute, 0, len(section))
+		for _, att := range section {
+			atts[i] = append(atts[i], att.GetFileData())
+		}
+	}
+	return filedata.Gadget{
+		ID:         g.id,
+		GadgetType: int(g.gadgetType),
+		X:          g.point.X,
+		Y:          g.point.Y,
+		Layer:      g.layer,
+		Color:      g.color,
+		Attributes: atts,
+	}
+}
+
 // Draw
 func (g *Gadget) GetDrawData() any {
 	return g.drawData
 }
 
+// getTab returns the tab of a package, which holds its name
+func (g *Gadget) getTab() utils.Rect {
+	return utils.Rect{
+		Min: g.point,
+		Max: utils.AddPoints(g.point, utils.Point{X: g.drawData.TabWidth, Y: g.drawData.TabHeight}),
+	}
+}
+
+// getStereotypeDrawData returns nil for gadget types without a stereotype
+func (g *Gadget) getStereotypeDrawData() (*drawdata.Attribute, duerror.DUError) {
+	text := gadgetLayouts[g.gadgetType].stereotype
+	if text == "" {
+		return nil, nil
+	}
+	if g.stereotype == nil {
+		stereotype, err := attribute.NewAttribute(text)
+		if err != nil {
+			return nil, err
+		}
+		g.stereotype = stereotype
+	}
+	dd := g.stereotype.GetDrawData()
+	return &dd, nil
+}
+
 func (g *Gadget) updateDrawData() duerror.DUError {
-	height := drawdata.LineWidth
-	maxAttWidth := 0
+	sectionHeights := make([]int, len(g.attributes))
+	sectionWidths := make([]int, len(g.attributes))
 	atts := make([][]drawdata.Attribute, len(g.attributes))
 	for i, attsRow := range g.attributes {
 		atts[i] = make([]drawdata.Attribute, 0, len(attsRow))
 		for _, att := range attsRow {
 			attDrawData := att.GetDrawData()
-			atts[i] = append(atts[i], attDrawData)
-			if attDrawData.Width > maxAttWidth {
-				maxAttWidth = attDrawData.Width
+			if i == 0 && g.gadgetType == AbstractClass {
+				// the name of an abstract class is always italic
+				attDrawData.FontStyle |= attribute.Italic
 			}
-			height += drawdata.Margin + attDrawData.Height
+			atts[i] = append(atts[i], attDrawData)
+			sectionWidths[i] = max(sectionWidths[i], attDrawData.Width)
+			sectionHeights[i] += drawdata.Margin + attDrawData.Height
+		}
+		sectionHeights[i] += drawdata.Margin + drawdata.LineWidth
+	}
+	stereotype, err := g.getStereotypeDrawData()
+	if err != nil {
+		return err
+	}
+	if stereotype != nil && len(g.attributes) > 0 {
+		sectionWidths[0] = max(sectionWidths[0], stereotype.Width)
+		sectionHeights[0] += drawdata.Margin + stereotype.Height
+	}
+
+	padding := drawdata.Margin*2 + drawdata.LineWidth*2
+	height := drawdata.LineWidth
+	for _, h := range sectionHeights {
+		height += h
+	}
+	width := padding
+	if len(sectionWidths) > 0 {
+		width += slices.Max(sectionWidths)
+	}
+	tabWidth, tabHeight, foldSize := 0, 0, 0
+	switch {
+	case g.gadgetType == Package && len(g.attributes) > 0:
+		// the name sits in a tab on top of the body, which is wider than the tab
+		tabWidth = sectionWidths[0] + padding
+		tabHeight = drawdata.LineWidth + sectionHeights[0]
+		width = padding
+		if len(sectionWidths) > 1 {
+			width += slices.Max(sectionWidths[1:])
 		}
-		height += drawdata.Margin + drawdata.LineWidth
+		width = max(width, tabWidth+padding)
+	case g.gadgetType == Note:
+		foldSize = drawdata.NoteFoldSize
+		width += foldSize
+		height = max(height, foldSize*2)
 	}
-	width := maxAttWidth + drawdata.Margin*2 + drawdata.LineWidth*2
 
+	g.drawData.ID = g.id
 	g.drawData.GadgetType = int(g.gadgetType)
 	g.drawData.X = g.point.X
 	g.drawData.Y = g.point.Y
@@ -242,6 +468,10 @@ func (g *Gadget) updateDrawData() duerror.DUError {
 	g.drawData.Width = width
 	g.drawData.Color = g.color
 	g.drawData.Attributes = atts
+	g.drawData.Stereotype = stereotype
+	g.drawData.TabWidth = tabWidth
+	g.drawData.TabHeight = tabHeight
+	g.drawData.FoldSize = foldSize
 
 	if g.updateParentDraw == nil {
 		return nil
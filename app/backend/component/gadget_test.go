@@ -6,6 +6,7 @@ import (
 
 	"Dr.uml/backend/component/attribute"
 	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/filedata"
 	"Dr.uml/backend/utils"
 	"Dr.uml/backend/utils/duerror"
 	"github.com/stretchr/testify/assert"
@@ -18,11 +19,33 @@ var gadgetDefaultAtts = map[GadgetType][][]string{
 		{"id: String", "name: String", "lastModified: Date"},
 		{"GetAvailableDiagrams(): List<String>", "GetLastOpenedDiagrams(): List<String>", "SelectDiagram(diagramName: String): DUError", "CreateDiagram(diagramName: String): DUError"},
 	},
+	Interface: {
+		{"Component"},
+		{},
+		{"Cover(p: Point): bool", "GetLayer(): int"},
+	},
+	AbstractClass: {
+		{"Shape"},
+		{"color: String"},
+		{"Area(): float"},
+	},
+	Enumeration: {
+		{"DiagramType"},
+		{"ClassDiagram", "UseCaseDiagram", "SequenceDiagram"},
+	},
+	Note: {
+		{"Associations follow", "their parents."},
+	},
+	Package: {
+		{"umldiagram"},
+		{"UMLDiagram", "DiagramType"},
+	},
 }
 
 // test util
 func newEmptyGadget(gadgetType GadgetType, point utils.Point) *Gadget {
 	g := &Gadget{
+		id:         utils.NewID(),
 		gadgetType: gadgetType,
 		point:      point,
 		layer:      0,
@@ -70,11 +93,40 @@ func TestNewGadget(t *testing.T) {
 	// invalid gadget type
 	g, err = NewGadget(-1, utils.Point{X: 1, Y: 1}, 0, drawdata.DefaultGadgetColor, "")
 	assert.Error(t, err)
+	// more than one type at once
+	g, err = NewGadget(Class|Interface, utils.Point{X: 1, Y: 1}, 0, drawdata.DefaultGadgetColor, "")
+	assert.Error(t, err)
+
+	// every exported type, with its own sections
+	sections := map[GadgetType]int{Class: 3, Interface: 3, AbstractClass: 3, Enumeration: 2, Note: 1, Package: 2}
+	assert.Len(t, AllGadgetTypes, len(sections))
+	for _, gt := range AllGadgetTypes {
+		g, err = NewGadget(gt.Value, utils.Point{X: 1, Y: 1}, 0, drawdata.DefaultGadgetColor, "Header")
+		assert.NoError(t, err, gt.TSName)
+		assert.Len(t, g.GetAttributesLen(), sections[gt.Value], gt.TSName)
+		assert.Equal(t, 1, g.GetAttributesLen()[0], gt.TSName)
+	}
 
 	// some errors are hard to test :(
 }
 
 // Getter
+func TestGetID(t *testing.T) {
+	g1, err := NewGadget(Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A")
+	assert.NoError(t, err)
+	g2, err := NewGadget(Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, g1.GetID())
+	assert.NotEqual(t, g1.GetID(), g2.GetID())
+	assert.Equal(t, g1.GetID(), g1.GetDrawData().(drawdata.Gadget).ID)
+
+	// the id stays the same through edits
+	id := g1.GetID()
+	assert.NoError(t, g1.AddAttribute(1, "field: int"))
+	assert.Equal(t, id, g1.GetID())
+	assert.Equal(t, id, g1.GetDrawData().(drawdata.Gadget).ID)
+}
+
 func TestGetPoint(t *testing.T) {
 	g := newEmptyGadget(Class, utils.Point{X: 1, Y: 1})
 	assert.Equal(t, utils.Point{X: 1, Y: 1}, g.GetPoint())
@@ -255,6 +307,31 @@ func TestCover(t *testing.T) {
 	}
 }
 
+func TestCoverRect(t *testing.T) {
+	g := newEmptyGadget(Class, utils.Point{X: 10, Y: 10})
+	width := g.GetDrawData().(drawdata.Gadget).Width
+	height := g.GetDrawData().(drawdata.Gadget).Height
+	assert.Equal(t, utils.NewRect(utils.Point{X: 10, Y: 10}, utils.Point{X: 10 + width, Y: 10 + height}), g.GetBounds())
+
+	tests := []struct {
+		name     string
+		r        utils.Rect
+		expected bool
+	}{
+		{"around", utils.NewRect(utils.Point{X: 0, Y: 0}, utils.Point{X: 20 + width, Y: 20 + height}), true},
+		{"partly", utils.NewRect(utils.Point{X: 0, Y: 0}, utils.Point{X: 15, Y: 15}), true},
+		{"inside", utils.NewRect(utils.Point{X: 12, Y: 12}, utils.Point{X: 13, Y: 13}), true},
+		{"left of", utils.NewRect(utils.Point{X: 0, Y: 0}, utils.Point{X: 9, Y: 100}), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := g.CoverRect(tt.r)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+}
+
 func TestAddAttribute(t *testing.T) {
 	g := newEmptyGadget(Class, utils.Point{X: 1, Y: 1})
 	mp := mockParent{}
@@ -445,3 +522,205 @@ func TestValidateIndex(t *testing.T) {
 		})
 	}
 }
+
+func TestGadgetFileData(t *testing.T) {
+	g := newEmptyGadget(Class, utils.Point{X: 3, Y: 4})
+	assert.NoError(t, g.SetAttrStyle(1, 0, int(attribute.Bold)))
+
+	fd := g.GetFileData()
+	assert.Equal(t, int(Class), fd.GadgetType)
+	assert.Equal(t, 3, fd.X)
+	assert.Equal(t, 4, fd.Y)
+	assert.Equal(t, []int{1, 3, 4}, []int{len(fd.Attributes[0]), len(fd.Attributes[1]), len(fd.Attributes[2])})
+
+	loaded, err := NewGadgetFromFileData(fd)
+	assert.NoError(t, err)
+	assert.Equal(t, g.GetDrawData(), loaded.GetDrawData())
+	assert.Equal(t, fd, loaded.GetFileData())
+
+	// a file without ids gets fresh ones
+	fd.ID = ""
+	loaded, err = NewGadgetFromFileData(fd)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, loaded.GetID())
+
+	// the sections must match the gadget type
+	fd.GadgetType = int(Note)
+	_, err = NewGadgetFromFileData(fd)
+	assert.Error(t, err)
+
+	// invalid gadget type
+	fd.GadgetType = 0
+	_, err = NewGadgetFromFileData(fd)
+	assert.Error(t, err)
+
+	for gadgetType := range gadgetDefaultAtts {
+		g = newEmptyGadget(gadgetType, utils.Point{X: 3, Y: 4})
+		loaded, err = NewGadgetFromFileData(g.GetFileData())
+		assert.NoError(t, err)
+		assert.Equal(t, g.GetDrawData(), loaded.GetDrawData())
+	}
+}
+
+func TestGadgetTypeLayouts(t *testing.T) {
+	class := newEmptyGadget(Class, utils.Point{X: 0, Y: 0}).GetDrawData().(drawdata.Gadget)
+	assert.Nil(t, class.Stereotype)
+	assert.Zero(t, class.TabWidth)
+	assert.Zero(t, class.FoldSize)
+
+	// interfaces and enumerations have a stereotype above the name, which makes the header taller
+	for gadgetType, text := range map[GadgetType]string{Interface: "«interface»", Enumeration: "«enumeration»"} {
+		g := newEmptyGadget(gadgetType, utils.Point{X: 0, Y: 0})
+		dd := g.GetDrawData().(drawdata.Gadget)
+		assert.NotNil(t, dd.Stereotype)
+		assert.Equal(t, text, dd.Stereotype.Content)
+		plain := newEmptyGadget(gadgetType, utils.Point{X: 0, Y: 0})
+		plain.gadgetType = Class
+		assert.NoError(t, plain.updateDrawData())
+		assert.Equal(t, plain.GetDrawData().(drawdata.Gadget).Height+drawdata.Margin+dd.Stereotype.Height, dd.Height)
+		// the stereotype is not an attribute and is not saved
+		assert.Equal(t, []filedata.Attribute{g.attributes[0][0].GetFileData()}, g.GetFileData().Attributes[0])
+	}
+
+	// the name of an abstract class is drawn in italic, without changing its style
+	abstract := newEmptyGadget(AbstractClass, utils.Point{X: 0, Y: 0})
+	dd := abstract.GetDrawData().(drawdata.Gadget)
+	assert.Equal(t, attribute.Italic, dd.Attributes[0][0].FontStyle&attribute.Italic)
+	assert.Zero(t, dd.Attributes[1][0].FontStyle&attribute.Italic)
+	att, err := abstract.GetAttribute(0, 0)
+	assert.NoError(t, err)
+	assert.False(t, att.IsItalic())
+	assert.NoError(t, abstract.SetAttrStyle(0, 0, int(attribute.Bold)))
+	dd = abstract.GetDrawData().(drawdata.Gadget)
+	assert.Equal(t, attribute.Bold|attribute.Italic, dd.Attributes[0][0].FontStyle)
+
+	// a note has a folded corner
+	note := newEmptyGadget(Note, utils.Point{X: 0, Y: 0}).GetDrawData().(drawdata.Gadget)
+	assert.Equal(t, drawdata.NoteFoldSize, note.FoldSize)
+	assert.GreaterOrEqual(t, note.Height, 2*drawdata.NoteFoldSize)
+
+	// a package has its name in a tab narrower than its body
+	pkg := newEmptyGadget(Package, utils.Point{X: 0, Y: 0}).GetDrawData().(drawdata.Gadget)
+	assert.Greater(t, pkg.TabWidth, pkg.Attributes[0][0].Width)
+	assert.Greater(t, pkg.Width, pkg.TabWidth)
+	assert.Greater(t, pkg.TabHeight, pkg.Attributes[0][0].Height)
+	assert.Greater(t, pkg.Height, pkg.TabHeight)
+}
+
+func TestCoverShapes(t *testing.T) {
+	// nothing to the right of the tab of a package
+	pkg := newEmptyGadget(Package, utils.Point{X: 10, Y: 10})
+	dd := pkg.GetDrawData().(drawdata.Gadget)
+	tests := []struct {
+		name     string
+		p        utils.Point
+		expected bool
+	}{
+		{"tab", utils.Point{X: 12, Y: 12}, true},
+		{"right of the tab", utils.Point{X: 10 + dd.Width - 1, Y: 12}, false},
+		{"body", utils.Point{X: 10 + dd.Width - 1, Y: 10 + dd.TabHeight + 1}, true},
+		{"below", utils.Point{X: 12, Y: 10 + dd.Height + 1}, false},
+	}
+	for _, tt := range tests {
+		t.Run("package "+tt.name, func(t *testing.T) {
+			val, err := pkg.Cover(tt.p)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+	val, err := pkg.CoverRect(utils.NewRect(utils.Point{X: 10 + dd.TabWidth + 2, Y: 0}, utils.Point{X: 10 + dd.Width, Y: 10 + dd.TabHeight - 1}))
+	assert.NoError(t, err)
+	assert.False(t, val)
+	val, err = pkg.CoverRect(utils.NewRect(utils.Point{X: 0, Y: 0}, utils.Point{X: 11, Y: 11}))
+	assert.NoError(t, err)
+	assert.True(t, val)
+
+	// the folded corner of a note is cut off
+	note := newEmptyGadget(Note, utils.Point{X: 10, Y: 10})
+	dd = note.GetDrawData().(drawdata.Gadget)
+	right := 10 + dd.Width
+	tests = []struct {
+		name     string
+		p        utils.Point
+		expected bool
+	}{
+		{"top-left", utils.Point{X: 10, Y: 10}, true},
+		{"top-right corner", utils.Point{X: right, Y: 10}, false},
+		{"inside the fold", utils.Point{X: right - 2, Y: 12}, false},
+		{"on the fold", utils.Point{X: right - dd.FoldSize, Y: 10}, true},
+		{"below the fold", utils.Point{X: right, Y: 10 + dd.FoldSize}, true},
+	}
+	for _, tt := range tests {
+		t.Run("note "+tt.name, func(t *testing.T) {
+			val, err := note.Cover(tt.p)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, val)
+		})
+	}
+
+	// the other types are boxes
+	for _, gadgetType := range []GadgetType{Interface, AbstractClass, Enumeration} {
+		g := newEmptyGadget(gadgetType, utils.Point{X: 10, Y: 10})
+		b := g.GetBounds()
+		val, err := g.Cover(utils.Point{X: b.Max.X, Y: b.Min.Y})
+		assert.NoError(t, err)
+		assert.True(t, val)
+	}
+}
+
+func TestGetAttribute(t *testing.T) {
+	g := newEmptyGadget(Class, utils.Point{X: 1, Y: 1})
+	att, err := g.GetAttribute(1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "lastModified: Date", att.GetContent())
+
+	_, err = g.GetAttribute(3, 0)
+	assert.Error(t, err)
+	_, err = g.GetAttribute(1, 3)
+	assert.Error(t, err)
+}
+
+func TestInsertAttribute(t *testing.T) {
+	g := newEmptyGadget(Class, utils.Point{X: 1, Y: 1})
+	att, err := g.GetAttribute(1, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, g.RemoveAttribute(1, 0))
+	assert.Equal(t, []int{1, 2, 4}, g.GetAttributesLen())
+
+	assert.NoError(t, g.InsertAttribute(1, 0, att))
+	assert.Equal(t, []int{1, 3, 4}, g.GetAttributesLen())
+	first, _ := g.GetAttribute(1, 0)
+	assert.Same(t, att, first)
+
+	// append at the end is allowed
+	assert.NoError(t, g.InsertAttribute(1, 3, att))
+	// errors
+	assert.Error(t, g.InsertAttribute(1, 5, att))
+	assert.Error(t, g.InsertAttribute(5, 0, att))
+	assert.Error(t, g.InsertAttribute(1, 0, nil))
+}
+
+func TestGetMembers(t *testing.T) {
+	g := newEmptyGadget(Class, utils.Point{X: 1, Y: 1})
+	methods, err := g.GetMembers(2)
+	assert.NoError(t, err)
+	assert.Len(t, methods.Members, 4)
+	assert.Empty(t, methods.Errors)
+	assert.Equal(t, "SelectDiagram", methods.Members[2].Name)
+	assert.Equal(t, "DUError", methods.Members[2].Type)
+	assert.Equal(t, []attribute.Parameter{{Name: "diagramName", Type: "String"}}, methods.Members[2].Parameters)
+
+	assert.NoError(t, g.SetAttrContent(1, 1, "name String"))
+	fields, err := g.GetMembers(1)
+	assert.NoError(t, err)
+	assert.Nil(t, fields.Members[1])
+	assert.Equal(t, 1, fields.Errors[0].Line)
+
+	// the header and free text sections are not members
+	_, err = g.GetMembers(0)
+	assert.Error(t, err)
+	_, err = g.GetMembers(3)
+	assert.Error(t, err)
+	_, err = newEmptyGadget(Note, utils.Point{X: 1, Y: 1}).GetMembers(0)
+	assert.Error(t, err)
+}
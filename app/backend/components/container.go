@@ -6,11 +6,28 @@ import (
 	"Dr.uml/backend/utils/duerror"
 )
 
+// Container keeps the components of a diagram in z-order: a component is drawn above every component
+// on a lower layer, and above those on its own layer that were inserted before it.
 type Container interface {
-	Insert(c component.Component) duerror.DUError
+	Insert(c component.Component) duerror.DUError // on top of the components on its layer
+	// InsertWithSequence puts c where seq, as GetSequence gave it, places it among the components on its layer.
+	// Undo and loading use it to put components back where they were.
+	InsertWithSequence(c component.Component, seq int) duerror.DUError
+	GetSequence(c component.Component) int // 0 when c is not in the container
 	Remove(c component.Component) duerror.DUError
-	Search(p utils.Point) (component.Component, duerror.DUError)
-	SearchGadget(p utils.Point) (*component.Gadget, duerror.DUError)
-	GetAll() []component.Component
+	Update(c component.Component) duerror.DUError                    // the bounds of c may have changed
+	Search(p utils.Point) (component.Component, duerror.DUError)     // the topmost component covering p
+	SearchGadget(p utils.Point) (*component.Gadget, duerror.DUError) // the topmost gadget covering p
+	SearchRange(r utils.Rect) ([]component.Component, duerror.DUError)
+	GetByID(id string) (component.Component, duerror.DUError)
+	GetAll() []component.Component // from the bottom to the top
 	Len() (int, duerror.DUError)
 }
+
+// compareZOrder is negative when a is drawn below b, seq holds the insertion sequence of both
+func compareZOrder(a, b component.Component, seq map[component.Component]int) int {
+	if la, lb := a.GetLayer(), b.GetLayer(); la != lb {
+		return la - lb
+	}
+	return seq[a] - seq[b]
+}
@@ -0,0 +1,265 @@
+package components
+
+import (
+	"maps"
+	"slices"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/utils"
+	"Dr.uml/backend/utils/duerror"
+)
+
+const (
+	gridCellSize = 128 // side of a grid cell, in pixels
+	gridMargin   = 4   // associations cover points up to this far from their line
+	gridMaxCells = 64  // components spanning more cells are kept aside and always checked
+)
+
+type gridCell struct {
+	x, y int
+}
+
+// implement ComponentsContainer using a uniform grid, components are found through the cells their bounds overlap
+type containerGrid struct {
+	cells     map[gridCell]map[component.Component]bool
+	compCells map[component.Component][]gridCell // nil for components kept in large
+	large     map[component.Component]bool
+	order     map[component.Component]int // insertion sequence, orders components on the same layer
+	ids       map[string]component.Component
+	seq       int
+}
+
+func NewContainerGrid() Container {
+	return &containerGrid{
+		cells:     make(map[gridCell]map[component.Component]bool),
+		compCells: make(map[component.Component][]gridCell),
+		large:     make(map[component.Component]bool),
+		order:     make(map[component.Component]int),
+		ids:       make(map[string]component.Component),
+	}
+}
+
+func (cg *containerGrid) Insert(c component.Component) duerror.DUError {
+	if c == nil {
+		return duerror.NewInvalidArgumentError("component is nil")
+	}
+	if _, ok := cg.compCells[c]; ok {
+		return cg.Update(c)
+	}
+	cg.seq++
+	cg.order[c] = cg.seq
+	cg.ids[c.GetID()] = c
+	cg.index(c, getGridCells(c.GetBounds()))
+	return nil
+}
+
+func (cg *containerGrid) InsertWithSequence(c component.Component, seq int) duerror.DUError {
+	if c == nil {
+		return duerror.NewInvalidArgumentError("component is nil")
+	}
+	if seq <= 0 {
+		return duerror.NewInvalidArgumentError("sequence must be positive")
+	}
+	cg.order[c] = seq
+	cg.seq = max(cg.seq, seq)
+	if _, ok := cg.compCells[c]; ok {
+		return cg.Update(c)
+	}
+	cg.ids[c.GetID()] = c
+	cg.index(c, getGridCells(c.GetBounds()))
+	return nil
+}
+
+func (cg *containerGrid) GetSequence(c component.Component) int {
+	return cg.order[c]
+}
+
+func (cg *containerGrid) Remove(c component.Component) duerror.DUError {
+	if _, ok := cg.compCells[c]; !ok {
+		return nil
+	}
+	cg.unindex(c)
+	delete(cg.compCells, c)
+	delete(cg.order, c)
+	delete(cg.ids, c.GetID())
+	return nil
+}
+
+// Update moves c to the cells its current bounds overlap, components not in the grid are ignored
+func (cg *containerGrid) Update(c component.Component) duerror.DUError {
+	if c == nil {
+		return duerror.NewInvalidArgumentError("component is nil")
+	}
+	old, ok := cg.compCells[c]
+	if !ok {
+		return nil
+	}
+	cells := getGridCells(c.GetBounds())
+	if slices.Equal(old, cells) {
+		return nil
+	}
+	cg.unindex(c)
+	cg.index(c, cells)
+	return nil
+}
+
+func (cg *containerGrid) Search(p utils.Point) (component.Component, duerror.DUError) {
+	var candidate component.Component
+	for c := range cg.candidatesAt(p) {
+		cover, err := c.Cover(p)
+		if err != nil {
+			return nil, err
+		}
+		if !cover {
+			continue
+		}
+		if candidate == nil || compareZOrder(c, candidate, cg.order) > 0 {
+			candidate = c
+		}
+	}
+	return candidate, nil
+}
+
+func (cg *containerGrid) SearchGadget(p utils.Point) (*component.Gadget, duerror.DUError) {
+	var candidate *component.Gadget
+	for c := range cg.candidatesAt(p) {
+		g, ok := c.(*component.Gadget)
+		if !ok {
+			continue
+		}
+		cover, err := g.Cover(p)
+		if err != nil {
+			return nil, err
+		}
+		if !cover {
+			continue
+		}
+		if candidate == nil || compareZOrder(g, candidate, cg.order) > 0 {
+			candidate = g
+		}
+	}
+	return candidate, nil
+}
+
+// SearchRange returns every component fully or partly inside r
+func (cg *containerGrid) SearchRange(r utils.Rect) ([]component.Component, duerror.DUError) {
+	candidates := maps.Clone(cg.large)
+	lo, hi := getGridCell(r.Min), getGridCell(r.Max)
+	if (hi.x-lo.x+1)*(hi.y-lo.y+1) > len(cg.cells) {
+		// a range wider than the used area, visiting the used cells is cheaper
+		for cell, cs := range cg.cells {
+			if cell.x >= lo.x && cell.x <= hi.x && cell.y >= lo.y && cell.y <= hi.y {
+				maps.Copy(candidates, cs)
+			}
+		}
+	} else {
+		for x := lo.x; x <= hi.x; x++ {
+			for y := lo.y; y <= hi.y; y++ {
+				maps.Copy(candidates, cg.cells[gridCell{x: x, y: y}])
+			}
+		}
+	}
+
+	found := make([]component.Component, 0)
+	for c := range candidates {
+		cover, err := c.CoverRect(r)
+		if err != nil {
+			return nil, err
+		}
+		if cover {
+			found = append(found, c)
+		}
+	}
+	return found, nil
+}
+
+// GetByID returns nil if no component has the id
+func (cg *containerGrid) GetByID(id string) (component.Component, duerror.DUError) {
+	if id == "" {
+		return nil, duerror.NewInvalidArgumentError("id is empty")
+	}
+	return cg.ids[id], nil
+}
+
+func (cg *containerGrid) GetAll() []component.Component {
+	all := slices.Collect(maps.Keys(cg.order))
+	slices.SortFunc(all, func(a, b component.Component) int {
+		return compareZOrder(a, b, cg.order)
+	})
+	return all
+}
+
+func (cg *containerGrid) Len() (int, duerror.DUError) {
+	return len(cg.compCells), nil
+}
+
+// index records c in cells, or in large if cells is nil
+func (cg *containerGrid) index(c component.Component, cells []gridCell) {
+	if cells == nil {
+		cg.large[c] = true
+		cg.compCells[c] = nil
+		return
+	}
+	for _, cell := range cells {
+		if cg.cells[cell] == nil {
+			cg.cells[cell] = make(map[component.Component]bool)
+		}
+		cg.cells[cell][c] = true
+	}
+	cg.compCells[c] = cells
+}
+
+// unindex takes c out of every cell, it stays known to the grid
+func (cg *containerGrid) unindex(c component.Component) {
+	delete(cg.large, c)
+	for _, cell := range cg.compCells[c] {
+		delete(cg.cells[cell], c)
+		if len(cg.cells[cell]) == 0 {
+			delete(cg.cells, cell)
+		}
+	}
+	cg.compCells[c] = nil
+}
+
+// candidatesAt returns the components that may cover p
+func (cg *containerGrid) candidatesAt(p utils.Point) map[component.Component]bool {
+	cell := cg.cells[getGridCell(p)]
+	if len(cg.large) == 0 {
+		return cell
+	}
+	candidates := maps.Clone(cg.large)
+	maps.Copy(candidates, cell)
+	return candidates
+}
+
+// getGridCells returns the cells overlapped by bounds grown by gridMargin, in a fixed order.
+// It returns nil when there are more than gridMaxCells of them.
+func getGridCells(bounds utils.Rect) []gridCell {
+	margin := utils.Point{X: gridMargin, Y: gridMargin}
+	lo := getGridCell(utils.SubPoints(bounds.Min, margin))
+	hi := getGridCell(utils.AddPoints(bounds.Max, margin))
+	count := (hi.x - lo.x + 1) * (hi.y - lo.y + 1)
+	if count > gridMaxCells {
+		return nil
+	}
+	cells := make([]gridCell, 0, count)
+	for x := lo.x; x <= hi.x; x++ {
+		for y := lo.y; y <= hi.y; y++ {
+			cells = append(cells, gridCell{x: x, y: y})
+		}
+	}
+	return cells
+}
+
+func getGridCell(p utils.Point) gridCell {
+	return gridCell{x: floorDiv(p.X, gridCellSize), y: floorDiv(p.Y, gridCellSize)}
+}
+
+// floorDiv rounds towards negative infinity, so that cells do not overlap around 0
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,284 @@
+package components
+
+import (
+	"math/rand"
+	"testing"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/utils"
+	"Dr.uml/backend/utils/duerror"
+	"github.com/stretchr/testify/assert"
+)
+
+// newIndexedGadget returns a gadget that keeps its place in cc up to date, as a diagram does
+func newIndexedGadget(t testing.TB, cc Container, point utils.Point, layer int) *component.Gadget {
+	g, err := component.NewGadget(component.Class, point, layer, drawdata.DefaultGadgetColor, "G")
+	assert.NoError(t, err)
+	assert.NoError(t, g.RegisterUpdateParentDraw(func() duerror.DUError { return cc.Update(g) }))
+	assert.NoError(t, cc.Insert(g))
+	return g
+}
+
+func getGadgetCenter(g *component.Gadget) utils.Point {
+	b := g.GetBounds()
+	return utils.Point{X: (b.Min.X + b.Max.X) / 2, Y: (b.Min.Y + b.Max.Y) / 2}
+}
+
+func Test_NewContainerGrid(t *testing.T) {
+	cg := NewContainerGrid()
+	assert.NotNil(t, cg)
+	assert.IsType(t, &containerGrid{}, cg)
+}
+
+func TestContainerGrid_InsertRemove(t *testing.T) {
+	cg := NewContainerGrid()
+	g1 := newIndexedGadget(t, cg, utils.Point{X: 0, Y: 0}, 0)
+	g2 := newIndexedGadget(t, cg, utils.Point{X: 1000, Y: 1000}, 0)
+	assert.Error(t, cg.Insert(nil))
+	assert.Error(t, cg.Update(nil))
+
+	// inserting twice keeps one copy
+	assert.NoError(t, cg.Insert(g1))
+	l, err := cg.Len()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, l)
+	assert.ElementsMatch(t, []component.Component{g1, g2}, cg.GetAll())
+
+	c, err := cg.GetByID(g2.GetID())
+	assert.NoError(t, err)
+	assert.Equal(t, g2, c)
+	_, err = cg.GetByID("")
+	assert.Error(t, err)
+
+	assert.NoError(t, cg.Remove(g1))
+	assert.NoError(t, cg.Remove(g1))
+	l, _ = cg.Len()
+	assert.Equal(t, 1, l)
+	c, err = cg.Search(getGadgetCenter(g1))
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+	c, err = cg.GetByID(g1.GetID())
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+
+	// an update of a component not in the grid does nothing
+	assert.NoError(t, g1.SetPoint(utils.Point{X: 1000, Y: 1000}))
+	c, err = cg.Search(getGadgetCenter(g2))
+	assert.NoError(t, err)
+	assert.Equal(t, g2, c)
+
+	assert.NoError(t, cg.Remove(g2))
+	assert.Empty(t, cg.(*containerGrid).cells)
+	assert.Empty(t, cg.(*containerGrid).ids)
+
+	// components put back with their sequence are found by id again
+	assert.NoError(t, cg.InsertWithSequence(g1, 3))
+	c, err = cg.GetByID(g1.GetID())
+	assert.NoError(t, err)
+	assert.Equal(t, g1, c)
+}
+
+func TestContainerGrid_InsertWithSequence(t *testing.T) {
+	cg := NewContainerGrid()
+	g1 := newIndexedGadget(t, cg, utils.Point{X: 0, Y: 0}, 0)
+	g2 := newIndexedGadget(t, cg, utils.Point{X: 5, Y: 5}, 0)
+	g3 := newIndexedGadget(t, cg, utils.Point{X: 10, Y: 10}, 0)
+	assert.Error(t, cg.InsertWithSequence(nil, 1))
+	assert.Error(t, cg.InsertWithSequence(g1, -1))
+
+	seq := cg.GetSequence(g3)
+	assert.NoError(t, cg.Remove(g3))
+	assert.Equal(t, 0, cg.GetSequence(g3))
+	g4 := newIndexedGadget(t, cg, utils.Point{X: 15, Y: 15}, 0)
+	assert.NoError(t, cg.InsertWithSequence(g3, seq))
+	assert.Equal(t, []component.Component{g1, g2, g3, g4}, cg.GetAll())
+	// found again where it is, below g4
+	c, err := cg.Search(utils.Point{X: 20, Y: 20})
+	assert.NoError(t, err)
+	assert.Equal(t, g4, c)
+	c, err = cg.Search(utils.Point{X: 12, Y: 12})
+	assert.NoError(t, err)
+	assert.Equal(t, g3, c)
+}
+
+func TestContainerGrid_Search(t *testing.T) {
+	cg := NewContainerGrid()
+	below := newIndexedGadget(t, cg, utils.Point{X: 120, Y: 120}, 0)
+	above := newIndexedGadget(t, cg, utils.Point{X: 125, Y: 125}, 1)
+	p := utils.Point{X: 130, Y: 130}
+
+	c, err := cg.Search(p)
+	assert.NoError(t, err)
+	assert.Equal(t, above, c)
+	g, err := cg.SearchGadget(p)
+	assert.NoError(t, err)
+	assert.Equal(t, above, g)
+	c, err = cg.Search(utils.Point{X: 121, Y: 121})
+	assert.NoError(t, err)
+	assert.Equal(t, below, c)
+
+	// on the same layer the one inserted last is on top
+	same := newIndexedGadget(t, cg, utils.Point{X: 126, Y: 126}, 1)
+	c, err = cg.Search(p)
+	assert.NoError(t, err)
+	assert.Equal(t, same, c)
+	assert.Equal(t, []component.Component{below, above, same}, cg.GetAll())
+	assert.NoError(t, cg.Remove(same))
+
+	c, err = cg.Search(utils.Point{X: 5000, Y: 5000})
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+	g, err = cg.SearchGadget(utils.Point{X: 5000, Y: 5000})
+	assert.NoError(t, err)
+	assert.Nil(t, g)
+
+	// an association is found near its line, also where no gadget is
+	ass, err := component.NewAssociation([2]*component.Gadget{below, above}, component.Composition,
+		getGadgetCenter(below), getGadgetCenter(above))
+	assert.NoError(t, err)
+	assert.NoError(t, cg.Insert(ass))
+	assert.NoError(t, ass.SetLayer(2))
+	dd := ass.GetDrawData().(drawdata.Association)
+	c, err = cg.Search(utils.Point{X: dd.StartX, Y: dd.StartY})
+	assert.NoError(t, err)
+	assert.Equal(t, ass, c)
+	g, err = cg.SearchGadget(utils.Point{X: dd.StartX, Y: dd.StartY})
+	assert.NoError(t, err)
+	assert.NotNil(t, g)
+}
+
+func TestContainerGrid_Update(t *testing.T) {
+	cg := NewContainerGrid()
+	g := newIndexedGadget(t, cg, utils.Point{X: 0, Y: 0}, 0)
+	old := getGadgetCenter(g)
+
+	// moving to another cell
+	assert.NoError(t, g.SetPoint(utils.Point{X: 700, Y: 900}))
+	c, err := cg.Search(old)
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+	c, err = cg.Search(getGadgetCenter(g))
+	assert.NoError(t, err)
+	assert.Equal(t, g, c)
+
+	// growing into the next cells
+	assert.NoError(t, g.SetPoint(utils.Point{X: 120, Y: 0}))
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, g.AddAttribute(1, "a rather long attribute that makes the gadget wide"))
+	}
+	br := g.GetBounds().Max
+	assert.Greater(t, br.X, 2*gridCellSize)
+	assert.Greater(t, br.Y, 2*gridCellSize)
+	c, err = cg.Search(br)
+	assert.NoError(t, err)
+	assert.Equal(t, g, c)
+	cs, err := cg.SearchRange(utils.NewRect(br, br))
+	assert.NoError(t, err)
+	assert.Equal(t, []component.Component{g}, cs)
+}
+
+func TestContainerGrid_Large(t *testing.T) {
+	cg := NewContainerGrid()
+	st := newIndexedGadget(t, cg, utils.Point{X: 0, Y: 0}, 0)
+	en := newIndexedGadget(t, cg, utils.Point{X: 5000, Y: 5000}, 0)
+	ass, err := component.NewAssociation([2]*component.Gadget{st, en}, component.Composition,
+		getGadgetCenter(st), getGadgetCenter(en))
+	assert.NoError(t, err)
+	assert.NoError(t, cg.Insert(ass))
+	assert.True(t, cg.(*containerGrid).large[ass])
+
+	dd := ass.GetDrawData().(drawdata.Association)
+	mid := utils.Point{X: (dd.StartX + dd.EndX) / 2, Y: (dd.StartY + dd.EndY) / 2}
+	c, err := cg.Search(mid)
+	assert.NoError(t, err)
+	assert.Equal(t, ass, c)
+
+	// once the line is short it goes back into the cells
+	assert.NoError(t, en.SetPoint(utils.Point{X: 100, Y: 0}))
+	assert.NoError(t, ass.UpdateDrawData())
+	assert.NoError(t, cg.Update(ass))
+	assert.False(t, cg.(*containerGrid).large[ass])
+	assert.NoError(t, cg.Remove(ass))
+	assert.Empty(t, cg.(*containerGrid).large)
+}
+
+func TestContainerGrid_SearchRange(t *testing.T) {
+	cg := NewContainerGrid()
+	g1 := newIndexedGadget(t, cg, utils.Point{X: 0, Y: 0}, 0)
+	g2 := newIndexedGadget(t, cg, utils.Point{X: 1000, Y: 0}, 0)
+	g3 := newIndexedGadget(t, cg, utils.Point{X: 100000, Y: 100000}, 0)
+
+	cs, err := cg.SearchRange(utils.NewRect(utils.Point{X: 0, Y: 0}, utils.Point{X: 1100, Y: 50}))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []component.Component{g1, g2}, cs)
+
+	// a range far wider than the used cells
+	cs, err = cg.SearchRange(utils.NewRect(utils.Point{X: -1000000, Y: -1000000}, utils.Point{X: 1000000, Y: 1000000}))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []component.Component{g1, g2, g3}, cs)
+
+	cs, err = cg.SearchRange(utils.NewRect(utils.Point{X: 2000, Y: 2000}, utils.Point{X: 3000, Y: 3000}))
+	assert.NoError(t, err)
+	assert.Len(t, cs, 0)
+}
+
+// the grid must answer like the map, which checks every component
+func TestContainerGrid_MatchesContainerMap(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	cg, cm := NewContainerGrid(), NewContainerMap()
+	gadgets := make([]*component.Gadget, 0)
+	for i := 0; i < 200; i++ {
+		g := newIndexedGadget(t, cg, utils.Point{X: r.Intn(3000), Y: r.Intn(3000)}, i)
+		assert.NoError(t, cm.Insert(g))
+		gadgets = append(gadgets, g)
+	}
+	for i := 0; i < 50; i++ {
+		st, en := gadgets[r.Intn(len(gadgets))], gadgets[r.Intn(len(gadgets))]
+		if st == en {
+			continue
+		}
+		ass, err := component.NewAssociation([2]*component.Gadget{st, en}, component.Dependency,
+			getGadgetCenter(st), getGadgetCenter(en))
+		if err != nil {
+			continue
+		}
+		assert.NoError(t, ass.SetLayer(1000+i))
+		assert.NoError(t, cg.Insert(ass))
+		assert.NoError(t, cm.Insert(ass))
+	}
+	for _, g := range gadgets[:50] {
+		assert.NoError(t, g.SetPoint(utils.Point{X: r.Intn(3000), Y: r.Intn(3000)}))
+	}
+
+	for i := 0; i < 500; i++ {
+		p := utils.Point{X: r.Intn(3200), Y: r.Intn(3200)}
+		want, err := cm.Search(p)
+		assert.NoError(t, err)
+		got, err := cg.Search(p)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, p)
+
+		wantG, err := cm.SearchGadget(p)
+		assert.NoError(t, err)
+		gotG, err := cg.SearchGadget(p)
+		assert.NoError(t, err)
+		assert.Equal(t, wantG, gotG, p)
+
+		rect := utils.NewRect(p, utils.Point{X: r.Intn(3200), Y: r.Intn(3200)})
+		wantR, err := cm.SearchRange(rect)
+		assert.NoError(t, err)
+		gotR, err := cg.SearchRange(rect)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, wantR, gotR, rect)
+	}
+}
+
+func TestFloorDiv(t *testing.T) {
+	assert.Equal(t, 0, floorDiv(0, 128))
+	assert.Equal(t, 0, floorDiv(127, 128))
+	assert.Equal(t, 1, floorDiv(128, 128))
+	assert.Equal(t, -1, floorDiv(-1, 128))
+	assert.Equal(t, -1, floorDiv(-128, 128))
+	assert.Equal(t, -2, floorDiv(-129, 128))
+}
@@ -11,26 +11,55 @@ import (
 
 // implement ComponentsContainer using map
 type containerMap struct {
-	compMap map[component.Component]bool
+	compMap map[component.Component]int // insertion sequence, orders components on the same layer
+	seq     int
 }
 
 func NewContainerMap() Container {
-	return &containerMap{compMap: make(map[component.Component]bool)}
+	return &containerMap{compMap: make(map[component.Component]int)}
 }
 
 func (cp *containerMap) Insert(c component.Component) duerror.DUError {
 	if c == nil {
 		return duerror.NewInvalidArgumentError("component is nil")
 	}
-	cp.compMap[c] = true
+	if _, ok := cp.compMap[c]; ok {
+		return nil
+	}
+	cp.seq++
+	cp.compMap[c] = cp.seq
 	return nil
 }
 
+func (cp *containerMap) InsertWithSequence(c component.Component, seq int) duerror.DUError {
+	if c == nil {
+		return duerror.NewInvalidArgumentError("component is nil")
+	}
+	if seq <= 0 {
+		return duerror.NewInvalidArgumentError("sequence must be positive")
+	}
+	cp.compMap[c] = seq
+	cp.seq = max(cp.seq, seq)
+	return nil
+}
+
+func (cp *containerMap) GetSequence(c component.Component) int {
+	return cp.compMap[c]
+}
+
 func (cp *containerMap) Remove(c component.Component) duerror.DUError {
 	delete(cp.compMap, c)
 	return nil
 }
 
+// Update has nothing to do, the map does not depend on where components are
+func (cp *containerMap) Update(c component.Component) duerror.DUError {
+	if c == nil {
+		return duerror.NewInvalidArgumentError("component is nil")
+	}
+	return nil
+}
+
 func (cp *containerMap) Search(p utils.Point) (component.Component, duerror.DUError) {
 	var candidate component.Component
 	for c := range cp.compMap {
@@ -41,11 +70,7 @@ func (cp *containerMap) Search(p utils.Point) (component.Component, duerror.DUEr
 		if !cover {
 			continue
 		}
-		if candidate == nil {
-			candidate = c
-			continue
-		}
-		if c.GetLayer() > candidate.GetLayer() {
+		if candidate == nil || compareZOrder(c, candidate, cp.compMap) > 0 {
 			candidate = c
 		}
 	}
@@ -64,11 +89,7 @@ func (cp *containerMap) SearchGadget(p utils.Point) (*component.Gadget, duerror.
 			if !cover {
 				continue
 			}
-			if candidate == nil {
-				candidate = c
-				continue
-			}
-			if c.GetLayer() < candidate.GetLayer() {
+			if candidate == nil || compareZOrder(c, candidate, cp.compMap) > 0 {
 				candidate = c
 			}
 		default:
@@ -78,8 +99,40 @@ func (cp *containerMap) SearchGadget(p utils.Point) (*component.Gadget, duerror.
 	return candidate, nil
 }
 
+// SearchRange returns every component fully or partly inside r
+func (cp *containerMap) SearchRange(r utils.Rect) ([]component.Component, duerror.DUError) {
+	found := make([]component.Component, 0)
+	for c := range cp.compMap {
+		cover, err := c.CoverRect(r)
+		if err != nil {
+			return nil, err
+		}
+		if cover {
+			found = append(found, c)
+		}
+	}
+	return found, nil
+}
+
+// GetByID returns nil if no component has the id
+func (cp *containerMap) GetByID(id string) (component.Component, duerror.DUError) {
+	if id == "" {
+		return nil, duerror.NewInvalidArgumentError("id is empty")
+	}
+	for c := range cp.compMap {
+		if c.GetID() == id {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
 func (cp *containerMap) GetAll() []component.Component {
-	return slices.Collect(maps.Keys(cp.compMap))
+	all := slices.Collect(maps.Keys(cp.compMap))
+	slices.SortFunc(all, func(a, b component.Component) int {
+		return compareZOrder(a, b, cp.compMap)
+	})
+	return all
 }
 
 func (cp *containerMap) Len() (int, duerror.DUError) {
@@ -3,6 +3,7 @@ package components
 import (
 	"testing"
 
+	"Dr.uml/backend/component"
 	"Dr.uml/backend/mocks"
 	"Dr.uml/backend/utils"
 	"Dr.uml/backend/utils/duerror"
@@ -144,6 +145,15 @@ func TestContainerMap_Search(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, c, c2)
 
+	// on the same layer the one inserted last is on top
+	c1.EXPECT().Cover(p).Return(true, nil).Times(1)
+	c2.EXPECT().Cover(p).Return(true, nil).Times(1)
+	c1.EXPECT().GetLayer().Return(1).Times(1)
+	c2.EXPECT().GetLayer().Return(1).Times(1)
+	c, err = cm.Search(p)
+	assert.NoError(t, err)
+	assert.Equal(t, c, c2)
+
 	// error in cover
 	cm.Remove(c2)
 	c1.EXPECT().Cover(p).Return(false, duerror.NewInvalidArgumentError("")).Times(1)
@@ -156,15 +166,23 @@ func TestContainerMap_GetAll(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	c1 := mocks.NewMockComponent(ctrl)
 	c2 := mocks.NewMockComponent(ctrl)
+	c3 := mocks.NewMockComponent(ctrl)
 	cm := NewContainerMap()
 
 	// get all in empty map
 	cs := cm.GetAll()
 	assert.Equal(t, len(cs), 0)
 
-	// get all in {c1, c2}
+	// get all in {c1, c2, c3}, from the bottom to the top
+	c1.EXPECT().GetLayer().Return(0).AnyTimes()
+	c2.EXPECT().GetLayer().Return(0).AnyTimes()
+	c3.EXPECT().GetLayer().Return(-1).AnyTimes()
 	cm.Insert(c1)
 	cm.Insert(c2)
+	cm.Insert(c3)
+	cs = cm.GetAll()
+	assert.Equal(t, []component.Component{c3, c1, c2}, cs)
+	cm.Remove(c3)
 	cs = cm.GetAll()
 	assert.Equal(t, len(cs), 2)
 
@@ -178,3 +196,98 @@ func TestContainerMap_GetAll(t *testing.T) {
 	cs = cm.GetAll()
 	assert.Equal(t, len(cs), 0)
 }
+
+func TestContainerMap_InsertWithSequence(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	c1 := mocks.NewMockComponent(ctrl)
+	c2 := mocks.NewMockComponent(ctrl)
+	c3 := mocks.NewMockComponent(ctrl)
+	cm := NewContainerMap()
+	for _, c := range []*mocks.MockComponent{c1, c2, c3} {
+		c.EXPECT().GetLayer().Return(0).AnyTimes()
+	}
+	assert.Error(t, cm.InsertWithSequence(nil, 1))
+	assert.Error(t, cm.InsertWithSequence(c1, 0))
+
+	cm.Insert(c1)
+	cm.Insert(c2)
+	cm.Insert(c3)
+	seq := cm.GetSequence(c2)
+	cm.Remove(c2)
+	assert.Equal(t, 0, cm.GetSequence(c2))
+
+	// back between c1 and c3, and later ones still go on top
+	assert.NoError(t, cm.InsertWithSequence(c2, seq))
+	assert.Equal(t, []component.Component{c1, c2, c3}, cm.GetAll())
+	cm.Remove(c1)
+	cm.Insert(c1)
+	assert.Equal(t, []component.Component{c2, c3, c1}, cm.GetAll())
+	assert.NoError(t, cm.InsertWithSequence(c3, 100))
+	cm.Remove(c2)
+	cm.Insert(c2)
+	assert.Equal(t, []component.Component{c1, c3, c2}, cm.GetAll())
+}
+
+func TestContainerMap_GetByID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	c1 := mocks.NewMockComponent(ctrl)
+	c2 := mocks.NewMockComponent(ctrl)
+	cm := NewContainerMap()
+	c1.EXPECT().GetID().Return("id1").AnyTimes()
+	c2.EXPECT().GetID().Return("id2").AnyTimes()
+
+	// get in empty map
+	c, err := cm.GetByID("id1")
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+
+	cm.Insert(c1)
+	cm.Insert(c2)
+	c, err = cm.GetByID("id1")
+	assert.NoError(t, err)
+	assert.Equal(t, c1, c)
+	c, err = cm.GetByID("id2")
+	assert.NoError(t, err)
+	assert.Equal(t, c2, c)
+
+	// unknown and empty id
+	c, err = cm.GetByID("id3")
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+	_, err = cm.GetByID("")
+	assert.Error(t, err)
+}
+
+func TestContainerMap_SearchRange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	c1 := mocks.NewMockComponent(ctrl)
+	c2 := mocks.NewMockComponent(ctrl)
+	cm := NewContainerMap()
+	r := utils.NewRect(utils.Point{X: 0, Y: 0}, utils.Point{X: 10, Y: 10})
+
+	// search in empty map
+	cs, err := cm.SearchRange(r)
+	assert.NoError(t, err)
+	assert.Len(t, cs, 0)
+
+	cm.Insert(c1)
+	cm.Insert(c2)
+	c1.EXPECT().CoverRect(r).Return(true, nil).Times(1)
+	c2.EXPECT().CoverRect(r).Return(false, nil).Times(1)
+	cs, err = cm.SearchRange(r)
+	assert.NoError(t, err)
+	assert.Equal(t, cs, []component.Component{c1})
+
+	c1.EXPECT().CoverRect(r).Return(true, nil).Times(1)
+	c2.EXPECT().CoverRect(r).Return(true, nil).Times(1)
+	cs, err = cm.SearchRange(r)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, cs, []component.Component{c1, c2})
+
+	// error in cover
+	cm.Remove(c2)
+	c1.EXPECT().CoverRect(r).Return(false, duerror.NewInvalidArgumentError("")).Times(1)
+	cs, err = cm.SearchRange(r)
+	assert.Error(t, err)
+	assert.Nil(t, cs)
+}
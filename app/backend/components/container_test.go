@@ -0,0 +1,127 @@
+package components
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/utils"
+)
+
+var benchmarkContainers = []struct {
+	name string
+	new  func() Container
+}{
+	{"Map", NewContainerMap},
+	{"Grid", NewContainerGrid},
+}
+
+var benchmarkSizes = []int{1000, 10000}
+
+// fillBenchmarkContainer lays out n components like a large class diagram:
+// four fifths are gadgets on a loose lattice, the rest associate neighbours
+func fillBenchmarkContainer(b *testing.B, cc Container, n int) ([]*component.Gadget, utils.Rect) {
+	r := rand.New(rand.NewSource(1))
+	gadgetCount := n * 4 / 5
+	side := int(math.Ceil(math.Sqrt(float64(gadgetCount))))
+	gadgets := make([]*component.Gadget, 0, gadgetCount)
+	for i := 0; i < gadgetCount; i++ {
+		point := utils.Point{X: (i%side)*200 + r.Intn(50), Y: (i/side)*150 + r.Intn(50)}
+		gadgets = append(gadgets, newIndexedGadget(b, cc, point, i))
+	}
+	for i := 0; i < n-gadgetCount; i++ {
+		st, en := gadgets[i], gadgets[i+1]
+		ass, err := component.NewAssociation([2]*component.Gadget{st, en}, component.Dependency,
+			getGadgetCenter(st), getGadgetCenter(en))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := cc.Insert(ass); err != nil {
+			b.Fatal(err)
+		}
+	}
+	area := utils.NewRect(utils.Point{}, utils.Point{X: side * 200, Y: side * 150})
+	return gadgets, area
+}
+
+func randomPoint(r *rand.Rand, area utils.Rect) utils.Point {
+	return utils.Point{X: area.Min.X + r.Intn(area.Max.X-area.Min.X), Y: area.Min.Y + r.Intn(area.Max.Y-area.Min.Y)}
+}
+
+func BenchmarkContainer_Search(b *testing.B) {
+	for _, bc := range benchmarkContainers {
+		for _, n := range benchmarkSizes {
+			b.Run(fmt.Sprintf("%s/%d", bc.name, n), func(b *testing.B) {
+				cc := bc.new()
+				_, area := fillBenchmarkContainer(b, cc, n)
+				r := rand.New(rand.NewSource(2))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := cc.Search(randomPoint(r, area)); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkContainer_SearchGadget(b *testing.B) {
+	for _, bc := range benchmarkContainers {
+		for _, n := range benchmarkSizes {
+			b.Run(fmt.Sprintf("%s/%d", bc.name, n), func(b *testing.B) {
+				cc := bc.new()
+				_, area := fillBenchmarkContainer(b, cc, n)
+				r := rand.New(rand.NewSource(2))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := cc.SearchGadget(randomPoint(r, area)); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// a rubber band selection of a few hundred pixels
+func BenchmarkContainer_SearchRange(b *testing.B) {
+	for _, bc := range benchmarkContainers {
+		for _, n := range benchmarkSizes {
+			b.Run(fmt.Sprintf("%s/%d", bc.name, n), func(b *testing.B) {
+				cc := bc.new()
+				_, area := fillBenchmarkContainer(b, cc, n)
+				r := rand.New(rand.NewSource(2))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					p := randomPoint(r, area)
+					rect := utils.NewRect(p, utils.AddPoints(p, utils.Point{X: 400, Y: 300}))
+					if _, err := cc.SearchRange(rect); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// moving a gadget, which keeps the grid up to date
+func BenchmarkContainer_Move(b *testing.B) {
+	for _, bc := range benchmarkContainers {
+		for _, n := range benchmarkSizes {
+			b.Run(fmt.Sprintf("%s/%d", bc.name, n), func(b *testing.B) {
+				cc := bc.new()
+				gadgets, area := fillBenchmarkContainer(b, cc, n)
+				r := rand.New(rand.NewSource(2))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if err := gadgets[i%len(gadgets)].SetPoint(randomPoint(r, area)); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -7,3 +7,15 @@ type AssAttribute struct {
 	FontFile  string  `json:"fontFile"`
 	Ratio     float64 `json:"ratio"`
 }
+
+// AssLabel is a label of an association, placed by the backend: X and Y are the top-left of its box
+type AssLabel struct {
+	Kind             int    `json:"kind"`
+	Content          string `json:"content"`
+	X                int    `json:"x"`
+	Y                int    `json:"y"`
+	Width            int    `json:"width"`
+	Height           int    `json:"height"`
+	FontSize         int    `json:"fontSize"`
+	ReadingDirection int    `json:"readingDirection"` // only on the middle name
+}
@@ -1,6 +1,17 @@
 package drawdata
 
+// Arrowheads drawn at an end of an association
+const (
+	ArrowheadNone           = iota
+	ArrowheadOpen           // a navigable end, or the target of a dependency
+	ArrowheadHollowTriangle // the parent of an extension or implementation
+	ArrowheadFilledDiamond  // the whole of a composition
+	ArrowheadHollowDiamond  // the whole of an aggregation
+	ArrowheadCross          // a non-navigable end
+)
+
 type Association struct {
+	ID         string         `json:"id"`
 	AssType    int            `json:"assType"`
 	Layer      int            `json:"layer"`
 	StartX     int            `json:"startX"`
@@ -9,5 +20,24 @@ type Association struct {
 	EndY       int            `json:"endY"`
 	DeltaX     int            `json:"deltaX"`
 	DeltaY     int            `json:"deltaY"`
+	StartHead  int            `json:"startHead"`
+	EndHead    int            `json:"endHead"`
+	Dashed     bool           `json:"dashed"`
+	Path       []PathPoint    `json:"path"`                // the whole line, from start to end
+	ClassLink  *Segment       `json:"classLink,omitempty"` // dashed, from the middle of the line to the association class
+	IsSelected bool           `json:"isSelected"`
 	Attributes []AssAttribute `json:"attributes"`
+	Labels     []AssLabel     `json:"labels"`
+}
+
+type PathPoint struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type Segment struct {
+	StartX int `json:"startX"`
+	StartY int `json:"startY"`
+	EndX   int `json:"endX"`
+	EndY   int `json:"endY"`
 }
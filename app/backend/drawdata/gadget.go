@@ -3,7 +3,11 @@ package drawdata
 // a default gadget color (grey)
 const DefaultGadgetColor = "#808080"
 
+// the size of the folded top-right corner of a note
+const NoteFoldSize = 12
+
 type Gadget struct {
+	ID         string        `json:"id"`
 	GadgetType int           `json:"gadgetType"`
 	X          int           `json:"x"`
 	Y          int           `json:"y"`
@@ -13,4 +17,8 @@ type Gadget struct {
 	Color      string        `json:"color"`
 	IsSelected bool          `json:"isSelected"`
 	Attributes [][]Attribute `json:"attributes"`
+	Stereotype *Attribute    `json:"stereotype,omitempty"` // drawn above the header, e.g. «interface»
+	TabWidth   int           `json:"tabWidth"`             // packages: the tab holding the name, at the top-left
+	TabHeight  int           `json:"tabHeight"`
+	FoldSize   int           `json:"foldSize"` // notes: the folded top-right corner
 }
@@ -0,0 +1,82 @@
+package export
+
+import (
+	"os"
+
+	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/utils/duerror"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+)
+
+// fontDPI is the resolution utils.GetTextSize measures text at, a font size in points
+// is drawn fontDPI/72 times as many pixels high
+const fontDPI = 100
+
+// defaultFontFile is where attribute.NewAttribute finds its font
+func defaultFontFile() string {
+	return os.Getenv("APP_ROOT") + drawdata.DefaultAttributeFontFile
+}
+
+type faceKey struct {
+	file string
+	size float64
+	dpi  float64
+}
+
+// fontCache loads each font file once, and each face of it once.
+// Faces are loaded the way utils.GetTextSize loads them, so text measures the same.
+type fontCache struct {
+	fonts map[string]*opentype.Font
+	faces map[faceKey]font.Face
+	files []string // in the order they were first loaded
+}
+
+func newFontCache() *fontCache {
+	return &fontCache{fonts: make(map[string]*opentype.Font), faces: make(map[faceKey]font.Face)}
+}
+
+func (c *fontCache) close() {
+	for _, face := range c.faces {
+		face.Close()
+	}
+}
+
+// face returns the face of file, the default font when empty, at size points and dpi
+func (c *fontCache) face(file string, size, dpi float64) (font.Face, duerror.DUError) {
+	if file == "" {
+		file = defaultFontFile()
+	}
+	key := faceKey{file, size, dpi}
+	if face, ok := c.faces[key]; ok {
+		return face, nil
+	}
+	fnt, err := c.font(file)
+	if err != nil {
+		return nil, err
+	}
+	face, faceErr := opentype.NewFace(fnt, &opentype.FaceOptions{Size: size, DPI: dpi, Hinting: font.HintingFull})
+	if faceErr != nil {
+		return nil, duerror.NewFileIOError(faceErr.Error())
+	}
+	c.faces[key] = face
+	return face, nil
+}
+
+// font returns the parsed font file
+func (c *fontCache) font(file string) (*opentype.Font, duerror.DUError) {
+	if fnt, ok := c.fonts[file]; ok {
+		return fnt, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, duerror.NewFileIOError(err.Error())
+	}
+	fnt, err := opentype.Parse(data)
+	if err != nil {
+		return nil, duerror.NewFileIOError(err.Error())
+	}
+	c.fonts[file] = fnt
+	c.files = append(c.files, file)
+	return fnt, nil
+}
@@ -0,0 +1,299 @@
+package export
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"Dr.uml/backend/component/attribute"
+	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/utils/duerror"
+)
+
+type PaperSize int
+
+const (
+	PaperA4 PaperSize = iota
+	PaperA3
+	PaperA5
+	PaperLetter
+	PaperLegal
+	PaperTabloid
+)
+
+var AllPaperSizes = []struct {
+	Value  PaperSize
+	TSName string
+}{
+	{PaperA4, "PaperA4"},
+	{PaperA3, "PaperA3"},
+	{PaperA5, "PaperA5"},
+	{PaperLetter, "PaperLetter"},
+	{PaperLegal, "PaperLegal"},
+	{PaperTabloid, "PaperTabloid"},
+}
+
+// paperSizes is indexed by PaperSize, in points, portrait
+var paperSizes = [][2]float64{
+	{595.28, 841.89},
+	{841.89, 1190.55},
+	{419.53, 595.28},
+	{612, 792},
+	{612, 1008},
+	{792, 1224},
+}
+
+// a PDF point is 1/72 inch, a diagram pixel 1/96 inch
+const (
+	pointsPerPixel = 72.0 / cssDPI
+	pointsPerMM    = 72 / 25.4
+)
+
+// the page labels, in Helvetica
+const (
+	labelSize = 8
+	labelGap  = 4
+)
+
+// PDFOptions chooses the pages of a PDF.
+// At Scale 1, 0 meaning 1 too, the diagram prints at the size it has on a 96 DPI screen, over as
+// many pages as it takes. Neighbouring pages repeat Overlap millimetres of it, to glue them together,
+// and each page is labelled with Title and its place. FitToPage instead scales it onto a single page.
+type PDFOptions struct {
+	Paper     PaperSize `json:"paper"`
+	Landscape bool      `json:"landscape"`
+	FitToPage bool      `json:"fitToPage"`
+	Scale     float64   `json:"scale"`
+	Margin    float64   `json:"margin"`  // in millimetres, around the printed area
+	Overlap   float64   `json:"overlap"` // in millimetres
+	Title     string    `json:"title"`
+}
+
+// page is the size of the paper and the area printed on, in points
+func (o PDFOptions) page() (w, h float64, area rect, err duerror.DUError) {
+	if o.Paper < 0 || int(o.Paper) >= len(paperSizes) {
+		return 0, 0, rect{}, duerror.NewInvalidArgumentError("paper size is not supported")
+	}
+	if o.Scale < 0 || o.Margin < 0 || o.Overlap < 0 {
+		return 0, 0, rect{}, duerror.NewInvalidArgumentError("scale, margin and overlap cannot be negative")
+	}
+	w, h = paperSizes[o.Paper][0], paperSizes[o.Paper][1]
+	if o.Landscape {
+		w, h = h, w
+	}
+	margin := o.Margin * pointsPerMM
+	// the label sits under the printed area
+	area = rect{Min: point{margin, margin + labelSize + labelGap}, Max: point{w - margin, h - margin}}
+	if area.Max.X-area.Min.X <= o.Overlap*pointsPerMM || area.Max.Y-area.Min.Y <= o.Overlap*pointsPerMM {
+		return 0, 0, rect{}, duerror.NewInvalidArgumentError("the margin and overlap leave no room on the page")
+	}
+	return w, h, area, nil
+}
+
+// PDF renders a diagram as vector graphics on pages of the chosen paper, its fonts embedded
+func PDF(dd drawdata.Diagram, opts PDFOptions) ([]byte, duerror.DUError) {
+	pageW, pageH, area, err := opts.page()
+	if err != nil {
+		return nil, err
+	}
+	sc, err := newScene(dd)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &pdfWriter{}
+	catalogID, pagesID, formID, labelFontID := w.reserve(), w.reserve(), w.reserve(), w.reserve()
+	fonts := newFontCache()
+	defer fonts.close()
+	pf := make(map[string]*pdfFont)
+	var fontIDs []int
+	for i, file := range sc.FontFiles {
+		fnt, err := fonts.font(file)
+		if err != nil {
+			return nil, err
+		}
+		pf[file] = newPDFFont(file, fmt.Sprintf("/F%d", i), fnt)
+		fontIDs = append(fontIDs, w.reserve())
+	}
+
+	// the diagram is drawn once, into a form every page shows a part of
+	content, err := sceneContent(sc, pf)
+	if err != nil {
+		return nil, err
+	}
+	var fontRes strings.Builder
+	for i, file := range sc.FontFiles {
+		if err := pf[file].write(w, fontIDs[i]); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&fontRes, "%s %d 0 R ", pf[file].resource, fontIDs[i])
+	}
+	w.setStream(formID, fmt.Sprintf("/Type /XObject /Subtype /Form /BBox [0 0 %s %s] /Resources << /Font << %s>> >>",
+		num(sc.Width), num(sc.Height), fontRes.String()), content)
+	w.set(labelFontID, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+
+	areaW, areaH := area.Max.X-area.Min.X, area.Max.Y-area.Min.Y
+	scale := opts.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	k := pointsPerPixel * scale
+	if opts.FitToPage {
+		k = math.Min(areaW/sc.Width, areaH/sc.Height)
+	}
+	// the tiles step by the printed area less the overlap, and the last one may be partly empty
+	overlap := opts.Overlap * pointsPerMM
+	if opts.FitToPage {
+		overlap = 0
+	}
+	stepX, stepY := areaW-overlap, areaH-overlap
+	cols := max(1, int(math.Ceil((sc.Width*k-overlap)/stepX-1e-9)))
+	rows := max(1, int(math.Ceil((sc.Height*k-overlap)/stepY-1e-9)))
+
+	var kids []string
+	for r := range rows {
+		for c := range cols {
+			var page strings.Builder
+			// clip to the printed area, then show the form with this tile at its top-left
+			fmt.Fprintf(&page, "q %s %s %s %s re W n\n", num(area.Min.X), num(area.Min.Y), num(areaW), num(areaH))
+			x := area.Min.X - float64(c)*stepX
+			y := area.Max.Y + float64(r)*stepY
+			if opts.FitToPage {
+				// centered
+				x = area.Min.X + (areaW-sc.Width*k)/2
+				y = area.Max.Y - (areaH-sc.Height*k)/2
+			}
+			fmt.Fprintf(&page, "%s 0 0 %s %s %s cm /D Do Q\n", num4(k), num4(-k), num(x), num(y))
+			label := pageLabel(opts.Title, r, c, rows, cols)
+			if label != "" {
+				fmt.Fprintf(&page, "BT /L %d Tf 0.4 g %s %s Td %s Tj ET\n", labelSize, num(area.Min.X), num(area.Min.Y-labelSize-labelGap+2), pdfString(label))
+			}
+			pageID, contentID := w.reserve(), w.reserve()
+			w.setStream(contentID, "", []byte(page.String()))
+			w.set(pageID, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] "+
+				"/Resources << /XObject << /D %d 0 R >> /Font << /L %d 0 R >> >> /Contents %d 0 R >>",
+				pagesID, num(pageW), num(pageH), formID, labelFontID, contentID))
+			kids = append(kids, fmt.Sprintf("%d 0 R", pageID))
+		}
+	}
+	w.set(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	w.set(catalogID, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+	return w.bytes(catalogID), nil
+}
+
+// pageLabel names a tile by its row, a letter, and its column, a number, as on a map
+func pageLabel(title string, r, c, rows, cols int) string {
+	if rows*cols == 1 {
+		return title
+	}
+	place := fmt.Sprintf("%s%d, page %d of %d", rowName(r), c+1, r*cols+c+1, rows*cols)
+	if title == "" {
+		return place
+	}
+	return title + " - " + place
+}
+
+// rowName counts A to Z, then AA, AB and so on
+func rowName(r int) string {
+	name := ""
+	for r++; r > 0; r = (r - 1) / 26 {
+		name = string(rune('A'+(r-1)%26)) + name
+	}
+	return name
+}
+
+// sceneContent is the content stream drawing sc, in its own pixels, y going down
+func sceneContent(sc *scene, fonts map[string]*pdfFont) ([]byte, duerror.DUError) {
+	var b strings.Builder
+	bg, err := parseColor(sc.Background)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&b, "%s rg 0 0 %s %s re f\n1 j 1 J\n", pdfColor(bg), num(sc.Width), num(sc.Height))
+	for _, s := range sc.Shapes {
+		switch s := s.(type) {
+		case *pathShape:
+			if err := pdfPath(&b, s); err != nil {
+				return nil, err
+			}
+		case *textShape:
+			pdfText(&b, s, fonts[s.FontFile])
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+func pdfPath(b *strings.Builder, p *pathShape) duerror.DUError {
+	fill := p.Fill != "" && p.Closed
+	stroke := p.Stroke != "" && p.Width > 0
+	if !fill && !stroke {
+		return nil
+	}
+	if fill {
+		c, err := parseColor(p.Fill)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "%s rg ", pdfColor(c))
+	}
+	if stroke {
+		c, err := parseColor(p.Stroke)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "%s RG %s w ", pdfColor(c), num(p.Width))
+		if p.Dashed {
+			fmt.Fprintf(b, "[%d %d] 0 d ", dashOn, dashOff)
+		}
+	}
+	for i, pt := range p.Points {
+		op := "l"
+		if i == 0 {
+			op = "m"
+		}
+		fmt.Fprintf(b, "%s %s %s ", num(pt.X), num(pt.Y), op)
+	}
+	if p.Closed {
+		b.WriteString("h ")
+	}
+	switch {
+	case fill && stroke:
+		b.WriteString("B")
+	case fill:
+		b.WriteString("f")
+	default:
+		b.WriteString("S")
+	}
+	if stroke && p.Dashed {
+		b.WriteString(" [] 0 d")
+	}
+	b.WriteString("\n")
+	return nil
+}
+
+// pdfText writes t upright in the flipped space of the scene, leaning when italic
+// and outlined as well as filled when bold
+func pdfText(b *strings.Builder, t *textShape, f *pdfFont) {
+	slant := 0.0
+	if t.Style&attribute.Italic != 0 {
+		slant = italicSlant
+	}
+	mode := 0
+	if t.Style&attribute.Bold != 0 {
+		mode = 2
+	}
+	fmt.Fprintf(b, "BT %s %s Tf 0 g 0 G %s w %d Tr 1 0 %s -1 %s %s Tm %s Tj ET\n",
+		f.resource, num(t.Size), num(boldOffset), mode, num(slant), num(t.X), num(t.Y), f.encode(t.Content))
+}
+
+func pdfColor(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("%s %s %s", num(float64(n.R)/255), num(float64(n.G)/255), num(float64(n.B)/255))
+}
+
+// num4 writes v with at most four decimals, for the scale of a page
+func num4(v float64) string {
+	s := fmt.Sprintf("%.4f", v)
+	return strings.TrimRight(strings.TrimRight(s, "0"), ".")
+}
@@ -0,0 +1,135 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"Dr.uml/backend/drawdata"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	startXref = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+	xrefEntry = regexp.MustCompile(`(\d{10}) 00000 n `)
+	streamObj = regexp.MustCompile(`(?s)^(\d+) 0 obj\n<< ([^\n]*?)/Filter /FlateDecode /Length (\d+) >>\nstream\n`)
+)
+
+// pdfObjects checks the cross-reference table of a PDF and returns its objects,
+// the streams decompressed, by number from 1
+func pdfObjects(t *testing.T, out []byte) []string {
+	m := startXref.FindSubmatch(out)
+	if !assert.NotNil(t, m) {
+		return nil
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	var objects []string
+	for i, e := range xrefEntry.FindAllSubmatch(out[xref:], -1) {
+		offset, _ := strconv.Atoi(string(e[1]))
+		obj := out[offset:]
+		assert.True(t, bytes.HasPrefix(obj, []byte(strconv.Itoa(i+1)+" 0 obj\n")), "object %d", i+1)
+		if s := streamObj.FindSubmatchIndex(obj); s != nil {
+			length, _ := strconv.Atoi(string(obj[s[6]:s[7]]))
+			r, err := zlib.NewReader(bytes.NewReader(obj[s[1] : s[1]+length]))
+			assert.NoError(t, err)
+			data, err := io.ReadAll(r)
+			assert.NoError(t, err)
+			objects = append(objects, string(obj[s[4]:s[5]])+"\n"+string(data))
+			continue
+		}
+		end := bytes.Index(obj, []byte("\nendobj\n"))
+		objects = append(objects, string(obj[:end]))
+	}
+	return objects
+}
+
+// pageContents returns the content streams of the pages, in order
+func pageContents(objects []string) []string {
+	var pages []string
+	for _, obj := range objects {
+		if bytes.Contains([]byte(obj), []byte("/D Do Q")) {
+			pages = append(pages, obj)
+		}
+	}
+	return pages
+}
+
+func TestPDF_Tiles(t *testing.T) {
+	dd := testDiagram(t)
+	sc, err := newScene(dd)
+	assert.NoError(t, err)
+
+	out, err := PDF(dd, PDFOptions{Paper: PaperA5, Margin: 10, Overlap: 10, Title: "Shop (draft)"})
+	assert.NoError(t, err)
+	objects := pdfObjects(t, out)
+	pages := pageContents(objects)
+	// the diagram is wider than the printed area of A5, but not higher
+	assert.Len(t, pages, 2)
+	assert.Contains(t, pages[0], `(Shop \(draft\) - A1, page 1 of 2) Tj`)
+	assert.Contains(t, pages[1], `(Shop \(draft\) - A2, page 2 of 2) Tj`)
+	// the second tile starts where the first ends, less the overlap
+	assert.Contains(t, pages[0], "0.75 0 0 -0.75 28.35 566.93 cm")
+	assert.Contains(t, pages[1], "0.75 0 0 -0.75 "+num(28.35-(362.84-10*pointsPerMM))+" 566.93 cm")
+	assert.Contains(t, string(out), "/MediaBox [0 0 419.53 595.28]")
+	assert.Contains(t, string(out), "/Count 2")
+
+	// the form draws the whole scene once, its text in the embedded font, copyable
+	assert.Contains(t, string(out), "/BBox [0 0 "+num(sc.Width)+" "+num(sc.Height)+"]")
+	assert.Contains(t, string(out), "/FontFile2")
+	assert.Contains(t, string(out), "/Subtype /CIDFontType2")
+	found := false
+	for _, obj := range objects {
+		found = found || bytes.Contains([]byte(obj), []byte("beginbfchar\n<0003> <0020>"))
+	}
+	assert.True(t, found)
+
+	// at twice the scale, twice as many pages each way at least
+	out, err = PDF(dd, PDFOptions{Paper: PaperA5, Margin: 10, Overlap: 10, Scale: 2})
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, len(pageContents(pdfObjects(t, out))), 4)
+}
+
+func TestPDF_FitToPage(t *testing.T) {
+	dd := testDiagram(t)
+	out, err := PDF(dd, PDFOptions{Paper: PaperLetter, Landscape: true, FitToPage: true, Margin: 10, Overlap: 10, Title: "Shop"})
+	assert.NoError(t, err)
+	pages := pageContents(pdfObjects(t, out))
+	assert.Len(t, pages, 1)
+	assert.Contains(t, pages[0], "(Shop) Tj")
+	assert.Contains(t, string(out), "/MediaBox [0 0 792 612]")
+
+	// a single page needs no label without a title
+	out, err = PDF(drawdata.Diagram{}, PDFOptions{FitToPage: true})
+	assert.NoError(t, err)
+	pages = pageContents(pdfObjects(t, out))
+	assert.Len(t, pages, 1)
+	assert.NotContains(t, pages[0], "Tj")
+}
+
+func TestPDF_Errors(t *testing.T) {
+	dd := drawdata.Diagram{}
+	_, err := PDF(dd, PDFOptions{Paper: PaperSize(len(paperSizes))})
+	assert.Error(t, err)
+	_, err = PDF(dd, PDFOptions{Scale: -1})
+	assert.Error(t, err)
+	_, err = PDF(dd, PDFOptions{Margin: 100, Paper: PaperA5})
+	assert.Error(t, err)
+	_, err = PDF(dd, PDFOptions{Overlap: 300})
+	assert.Error(t, err)
+}
+
+func TestPageLabel(t *testing.T) {
+	assert.Equal(t, "Shop", pageLabel("Shop", 0, 0, 1, 1))
+	assert.Equal(t, "B3, page 6 of 6", pageLabel("", 1, 2, 2, 3))
+	assert.Equal(t, "A", rowName(0))
+	assert.Equal(t, "Z", rowName(25))
+	assert.Equal(t, "AA", rowName(26))
+	assert.Equal(t, "BA", rowName(52))
+}
+
+func TestPDFString(t *testing.T) {
+	assert.Equal(t, `(a \(b\) \\ \351 ?)`, pdfString("a (b) \\ é ★"))
+}
@@ -0,0 +1,217 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"Dr.uml/backend/utils/duerror"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// pdfWriter collects the objects of a PDF file, numbered from 1 in the order they are reserved
+type pdfWriter struct {
+	objects [][]byte
+}
+
+// reserve numbers an object, so that others can refer to it before it is set
+func (w *pdfWriter) reserve() int {
+	w.objects = append(w.objects, nil)
+	return len(w.objects)
+}
+
+func (w *pdfWriter) set(id int, body string) {
+	w.objects[id-1] = []byte(body)
+}
+
+// setStream sets a compressed stream, dict is the inside of its dictionary without /Length and /Filter
+func (w *pdfWriter) setStream(id int, dict string, data []byte) {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	// writing to a bytes.Buffer does not fail
+	_, _ = zw.Write(data)
+	_ = zw.Close()
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "<< %s /Filter /FlateDecode /Length %d >>\nstream\n", dict, z.Len())
+	body.Write(z.Bytes())
+	body.WriteString("\nendstream")
+	w.objects[id-1] = body.Bytes()
+}
+
+// bytes lays out the file, with root as its catalog
+func (w *pdfWriter) bytes(root int) []byte {
+	var buf bytes.Buffer
+	// the binary comment tells transfer programs the file is not text
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(w.objects))
+	for i, obj := range w.objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n", i+1)
+		buf.Write(obj)
+		buf.WriteString("\nendobj\n")
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.objects)+1, root, xref)
+	return buf.Bytes()
+}
+
+// pdfFont is a TrueType font file embedded whole, its text written as glyph indices
+type pdfFont struct {
+	file     string
+	resource string // the name content streams select it by, like "/F1"
+	font     *opentype.Font
+	buf      sfnt.Buffer
+	used     map[sfnt.GlyphIndex]rune
+}
+
+func newPDFFont(file, resource string, fnt *opentype.Font) *pdfFont {
+	return &pdfFont{file: file, resource: resource, font: fnt, used: make(map[sfnt.GlyphIndex]rune)}
+}
+
+// encode returns content as a hex string of glyph indices, for an Identity-H font
+func (f *pdfFont) encode(content string) string {
+	var sb strings.Builder
+	sb.WriteByte('<')
+	for _, r := range content {
+		gid, err := f.font.GlyphIndex(&f.buf, r)
+		if err != nil {
+			gid = 0
+		}
+		if _, ok := f.used[gid]; !ok || r < f.used[gid] {
+			f.used[gid] = r
+		}
+		fmt.Fprintf(&sb, "%04X", uint16(gid))
+	}
+	sb.WriteByte('>')
+	return sb.String()
+}
+
+// scaled converts a length of the font to thousandths of its size
+func (f *pdfFont) scaled(v fixed.Int26_6) int {
+	return int(float64(v) / 64 * 1000 / float64(f.font.UnitsPerEm()))
+}
+
+// write sets the objects of the font, id being the Type0 font content streams use
+func (f *pdfFont) write(w *pdfWriter, id int) duerror.DUError {
+	data, err := os.ReadFile(f.file)
+	if err != nil {
+		return duerror.NewFileIOError(err.Error())
+	}
+	upem := fixed.Int26_6(f.font.UnitsPerEm()) << 6
+	bounds, err := f.font.Bounds(&f.buf, upem, font.HintingNone)
+	if err != nil {
+		return duerror.NewFileIOError(err.Error())
+	}
+	metrics, err := f.font.Metrics(&f.buf, upem, font.HintingNone)
+	if err != nil {
+		return duerror.NewFileIOError(err.Error())
+	}
+	name := f.postScriptName()
+
+	fileID, descID, cidID, unicodeID := w.reserve(), w.reserve(), w.reserve(), w.reserve()
+	w.setStream(fileID, fmt.Sprintf("/Length1 %d", len(data)), data)
+	w.set(descID, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] "+
+		"/ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		name, f.scaled(bounds.Min.X), -f.scaled(bounds.Max.Y), f.scaled(bounds.Max.X), -f.scaled(bounds.Min.Y),
+		f.scaled(metrics.Ascent), -f.scaled(metrics.Descent), f.scaled(metrics.CapHeight), fileID))
+
+	gids := make([]sfnt.GlyphIndex, 0, len(f.used))
+	for gid := range f.used {
+		gids = append(gids, gid)
+	}
+	slices.Sort(gids)
+	var widths strings.Builder
+	for _, gid := range gids {
+		advance, err := f.font.GlyphAdvance(&f.buf, gid, upem, font.HintingNone)
+		if err != nil {
+			advance = 0
+		}
+		fmt.Fprintf(&widths, "%d [%d] ", gid, f.scaled(advance))
+	}
+	w.set(cidID, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+		"/FontDescriptor %d 0 R /CIDToGIDMap /Identity /W [%s] >>", name, descID, strings.TrimSpace(widths.String())))
+	w.setStream(unicodeID, "", toUnicode(gids, f.used))
+	w.set(id, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H "+
+		"/DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>", name, cidID, unicodeID))
+	return nil
+}
+
+// postScriptName is the name of the font, with what a PDF name cannot hold left out
+func (f *pdfFont) postScriptName() string {
+	name, err := f.font.Name(&f.buf, sfnt.NameIDPostScript)
+	if err != nil || name == "" {
+		name = strings.TrimSuffix(filepath.Base(f.file), filepath.Ext(f.file))
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || strings.ContainsRune("()<>[]{}/%#", r) {
+			return -1
+		}
+		return r
+	}, name)
+}
+
+// toUnicode is the CMap that lets a reader copy the text of the glyphs back out
+func toUnicode(gids []sfnt.GlyphIndex, runes map[sfnt.GlyphIndex]rune) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	// at most 100 mappings a block
+	for start := 0; start < len(gids); start += 100 {
+		block := gids[start:min(start+100, len(gids))]
+		fmt.Fprintf(&buf, "%d beginbfchar\n", len(block))
+		for _, gid := range block {
+			fmt.Fprintf(&buf, "<%04X> <", uint16(gid))
+			for _, u := range utf16Units(runes[gid]) {
+				fmt.Fprintf(&buf, "%04X", u)
+			}
+			buf.WriteString(">\n")
+		}
+		buf.WriteString("endbfchar\n")
+	}
+	buf.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return buf.Bytes()
+}
+
+func utf16Units(r rune) []uint16 {
+	if r < 0x10000 {
+		return []uint16{uint16(r)}
+	}
+	r -= 0x10000
+	return []uint16{uint16(0xD800 + (r >> 10)), uint16(0xDC00 + (r & 0x3FF))}
+}
+
+// pdfString writes s as a literal string of the standard fonts, in WinAnsiEncoding,
+// which shares its letters with Latin-1; what it cannot show becomes "?"
+func pdfString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('(')
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r >= ' ' && r <= '~':
+			sb.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			fmt.Fprintf(&sb, "\\%03o", r)
+		default:
+			sb.WriteByte('?')
+		}
+	}
+	sb.WriteByte(')')
+	return sb.String()
+}
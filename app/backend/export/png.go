@@ -0,0 +1,296 @@
+package export
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"Dr.uml/backend/component/attribute"
+	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/utils/duerror"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// a diagram pixel is a CSS pixel, 1/96 inch
+const cssDPI = 96
+
+// how far italic text leans, and how far bold text is drawn twice, in diagram pixels
+const (
+	italicSlant = 0.2
+	boldOffset  = 0.6
+)
+
+// the sides of the polygon a round join is drawn with
+const joinSides = 16
+
+// PNGOptions chooses the resolution and the background of a PNG.
+// DPI, when set, takes over Scale, which is in image pixels per diagram pixel, 1 when unset.
+type PNGOptions struct {
+	Scale       float64 `json:"scale"`
+	DPI         float64 `json:"dpi"`
+	Transparent bool    `json:"transparent"`
+}
+
+func (o PNGOptions) scale() (float64, duerror.DUError) {
+	switch {
+	case o.DPI < 0 || o.Scale < 0:
+		return 0, duerror.NewInvalidArgumentError("scale and DPI cannot be negative")
+	case o.DPI > 0:
+		return o.DPI / cssDPI, nil
+	case o.Scale > 0:
+		return o.Scale, nil
+	}
+	return 1, nil
+}
+
+// the largest image PNG renders, in pixels on a side
+const maxPNGSide = 1 << 14
+
+// PNG rasterizes a diagram, anti-aliased, with the fonts its text was measured with
+func PNG(dd drawdata.Diagram, opts PNGOptions) ([]byte, duerror.DUError) {
+	scale, err := opts.scale()
+	if err != nil {
+		return nil, err
+	}
+	img, err := Rasterize(dd, scale, opts.Transparent)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, duerror.NewFileIOError(err.Error())
+	}
+	return buf.Bytes(), nil
+}
+
+// Rasterize draws a diagram into an image, scale image pixels to a diagram pixel,
+// on the diagram background or on a transparent one
+func Rasterize(dd drawdata.Diagram, scale float64, transparent bool) (*image.RGBA, duerror.DUError) {
+	sc, err := newScene(dd)
+	if err != nil {
+		return nil, err
+	}
+	w, h := int(math.Ceil(sc.Width*scale)), int(math.Ceil(sc.Height*scale))
+	if w > maxPNGSide || h > maxPNGSide {
+		return nil, duerror.NewInvalidArgumentError("the image would be too large, lower the scale")
+	}
+	r := rasterizer{
+		img:   image.NewRGBA(image.Rect(0, 0, w, h)),
+		z:     vector.NewRasterizer(w, h),
+		scale: scale,
+		fonts: newFontCache(),
+	}
+	defer r.fonts.close()
+
+	if !transparent {
+		bg, err := parseColor(sc.Background)
+		if err != nil {
+			return nil, err
+		}
+		draw.Draw(r.img, r.img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	}
+	for _, s := range sc.Shapes {
+		switch s := s.(type) {
+		case *pathShape:
+			if err := r.path(s); err != nil {
+				return nil, err
+			}
+		case *textShape:
+			if err := r.text(s); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return r.img, nil
+}
+
+type rasterizer struct {
+	img   *image.RGBA
+	z     *vector.Rasterizer
+	scale float64
+	fonts *fontCache
+}
+
+// fill draws what was added to z in c, and empties z
+func (r *rasterizer) fill(c color.Color) {
+	r.z.Draw(r.img, r.img.Bounds(), image.NewUniform(c), image.Point{})
+	r.z.Reset(r.img.Bounds().Dx(), r.img.Bounds().Dy())
+}
+
+func (r *rasterizer) point(p point) (float32, float32) {
+	return float32(p.X * r.scale), float32(p.Y * r.scale)
+}
+
+// polygon adds the closed polygon through pts to z
+func (r *rasterizer) polygon(pts []point) {
+	r.z.MoveTo(r.point(pts[0]))
+	for _, p := range pts[1:] {
+		r.z.LineTo(r.point(p))
+	}
+	r.z.ClosePath()
+}
+
+func (r *rasterizer) path(p *pathShape) duerror.DUError {
+	if p.Fill != "" && p.Closed {
+		c, err := parseColor(p.Fill)
+		if err != nil {
+			return err
+		}
+		r.polygon(p.Points)
+		r.fill(c)
+	}
+	if p.Stroke == "" || p.Width <= 0 {
+		return nil
+	}
+	c, err := parseColor(p.Stroke)
+	if err != nil {
+		return err
+	}
+	pts := p.Points
+	if p.Closed {
+		pts = append(slices.Clone(pts), pts[0])
+	}
+	runs := [][]point{pts}
+	if p.Dashed {
+		runs = dash(pts, dashOn, dashOff)
+	}
+	for _, run := range runs {
+		r.stroke(run, p.Width/2)
+	}
+	r.fill(c)
+	return nil
+}
+
+// stroke adds the outline of the line through pts, half wide on each side, with round joins.
+// Every polygon winds the same way, so that where they overlap they do not cancel out.
+func (r *rasterizer) stroke(pts []point, half float64) {
+	for i := 1; i < len(pts); i++ {
+		a, b := pts[i-1], pts[i]
+		length := math.Hypot(b.X-a.X, b.Y-a.Y)
+		if length == 0 {
+			continue
+		}
+		n := point{-(b.Y - a.Y) / length * half, (b.X - a.X) / length * half}
+		r.polygon([]point{addPoints(a, n), addPoints(b, n), {b.X - n.X, b.Y - n.Y}, {a.X - n.X, a.Y - n.Y}})
+	}
+	joins := pts[1 : len(pts)-1]
+	if len(pts) > 2 && pts[0] == pts[len(pts)-1] {
+		// a closed line joins at its start too
+		joins = pts[:len(pts)-1]
+	}
+	for _, p := range joins {
+		join := make([]point, joinSides)
+		for i := range join {
+			angle := -2 * math.Pi * float64(i) / joinSides
+			join[i] = point{p.X + half*math.Cos(angle), p.Y + half*math.Sin(angle)}
+		}
+		r.polygon(join)
+	}
+}
+
+// dash cuts the line through pts into the runs drawn by a dash pattern of on, then off
+func dash(pts []point, on, off float64) [][]point {
+	var runs [][]point
+	run := []point{pts[0]}
+	// left is what remains of the current dash, or gap
+	drawing, left := true, on
+	for i := 1; i < len(pts); i++ {
+		a, b := pts[i-1], pts[i]
+		length := math.Hypot(b.X-a.X, b.Y-a.Y)
+		pos := 0.0
+		for length-pos > left {
+			pos += left
+			t := pos / length
+			cut := point{a.X + (b.X-a.X)*t, a.Y + (b.Y-a.Y)*t}
+			if drawing {
+				runs = append(runs, append(run, cut))
+				left = off
+			} else {
+				run = []point{cut}
+				left = on
+			}
+			drawing = !drawing
+		}
+		left -= length - pos
+		if drawing {
+			run = append(run, b)
+		}
+	}
+	if drawing && len(run) > 1 {
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// text draws t into a mask first, then leans the mask when t is italic
+func (r *rasterizer) text(t *textShape) duerror.DUError {
+	// the face at the size and resolution the scene measured with, scaled
+	face, err := r.fonts.face(t.FontFile, t.Size*72/fontDPI, fontDPI*r.scale)
+	if err != nil {
+		return err
+	}
+	bold := t.Style&attribute.Bold != 0
+	italic := t.Style&attribute.Italic != 0
+
+	// the box the text may cover, slant and boldness included
+	extra := 0.0
+	if bold {
+		extra += boldOffset
+	}
+	if italic {
+		extra += italicSlant * t.Ascent
+	}
+	box := image.Rect(
+		int(math.Floor((t.X-1)*r.scale)),
+		int(math.Floor((t.Y-t.Ascent-1)*r.scale)),
+		int(math.Ceil((t.X+t.Width+extra+1)*r.scale)),
+		int(math.Ceil((t.Y+t.Descent+1)*r.scale)),
+	)
+	mask := image.NewAlpha(box)
+	d := font.Drawer{Dst: mask, Src: image.Opaque, Face: face}
+	origin := fixed.Point26_6{X: fixed.Int26_6(t.X * r.scale * 64), Y: fixed.Int26_6(t.Y * r.scale * 64)}
+	d.Dot = origin
+	d.DrawString(t.Content)
+	if bold {
+		d.Dot = origin.Add(fixed.Point26_6{X: fixed.Int26_6(boldOffset * r.scale * 64)})
+		d.DrawString(t.Content)
+	}
+
+	black := image.NewUniform(color.Black)
+	if !italic {
+		draw.DrawMask(r.img, box, black, image.Point{}, mask, box.Min, draw.Over)
+		return nil
+	}
+	// each row leans right in proportion to its height over the baseline
+	baseline := t.Y * r.scale
+	for y := box.Min.Y; y < box.Max.Y; y++ {
+		shift := int(math.Round((baseline - float64(y)) * italicSlant))
+		row := image.Rect(box.Min.X+shift, y, box.Max.X+shift, y+1)
+		draw.DrawMask(r.img, row, black, image.Point{}, mask, image.Point{X: box.Min.X, Y: y}, draw.Over)
+	}
+	return nil
+}
+
+// parseColor reads a color like "#RRGGBB", "#RGB" or "#RRGGBBAA"
+func parseColor(s string) (color.Color, duerror.DUError) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 8 || !strings.HasPrefix(s, "#") {
+		return nil, duerror.NewInvalidArgumentError("invalid color " + strconv.Quote(s))
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
@@ -0,0 +1,107 @@
+package export
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/drawdata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPNG(t *testing.T) {
+	dd := testDiagram(t)
+	sc, err := newScene(dd)
+	assert.NoError(t, err)
+
+	out, err := PNG(dd, PNGOptions{})
+	assert.NoError(t, err)
+	img, decodeErr := png.Decode(bytes.NewReader(out))
+	assert.NoError(t, decodeErr)
+	assert.Equal(t, int(sc.Width+0.5), img.Bounds().Dx())
+	// the corner is background, the diagram white
+	assert.Equal(t, color.RGBA{255, 255, 255, 255}, color.RGBAModel.Convert(img.At(0, 0)))
+
+	// twice the scale is twice the size, and 192 DPI the same as a scale of 2
+	double, err := PNG(dd, PNGOptions{Scale: 2})
+	assert.NoError(t, err)
+	doubleImg, decodeErr := png.Decode(bytes.NewReader(double))
+	assert.NoError(t, decodeErr)
+	assert.InDelta(t, 2*img.Bounds().Dx(), doubleImg.Bounds().Dx(), 1)
+	byDPI, err := PNG(dd, PNGOptions{DPI: 192, Scale: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, double, byDPI)
+
+	_, err = PNG(dd, PNGOptions{Scale: -1})
+	assert.Error(t, err)
+	_, err = PNG(dd, PNGOptions{Scale: 1000})
+	assert.Error(t, err)
+}
+
+func TestRasterize(t *testing.T) {
+	// a gadget with a header and an empty section, at a known place once padded
+	dd := drawdata.Diagram{
+		Color: "#336699",
+		Gadgets: []drawdata.Gadget{{
+			GadgetType: int(component.Class),
+			Width:      100,
+			Height:     60,
+			Color:      "#FF0000",
+			Attributes: [][]drawdata.Attribute{{{Content: "Red", Height: 20, Width: 30, FontSize: 12}}, {}, {}},
+		}},
+	}
+	img, err := Rasterize(dd, 1, false)
+	assert.NoError(t, err)
+	at := func(x, y int) color.RGBA { return img.RGBAAt(x+scenePadding, y+scenePadding) }
+
+	assert.Equal(t, color.RGBA{0x33, 0x66, 0x99, 0xff}, img.RGBAAt(2, 2))
+	// the header on the gadget color, the sections below it white, the border black
+	assert.Equal(t, color.RGBA{0xff, 0, 0, 0xff}, at(90, 10))
+	assert.Equal(t, color.RGBA{0xff, 0xff, 0xff, 0xff}, at(50, 50))
+	assert.Equal(t, color.RGBA{0, 0, 0, 0xff}, at(50, 0))
+	// anti-aliased text: some of the header is neither red nor black
+	partial := false
+	for x := 30; x < 70; x++ {
+		for y := 4; y < 26; y++ {
+			c := at(x, y)
+			partial = partial || (c.R > 0 && c.R < 0xff)
+		}
+	}
+	assert.True(t, partial)
+
+	transparent, err := Rasterize(dd, 1, true)
+	assert.NoError(t, err)
+	assert.Equal(t, color.RGBA{}, transparent.RGBAAt(2, 2))
+	assert.Equal(t, at(90, 10), transparent.RGBAAt(90+scenePadding, 10+scenePadding))
+
+	dd.Color = "blue"
+	_, err = Rasterize(dd, 1, false)
+	assert.Error(t, err)
+}
+
+func TestDash(t *testing.T) {
+	runs := dash([]point{{0, 0}, {15, 0}, {15, 10}}, 6, 4)
+	assert.Equal(t, [][]point{
+		{{0, 0}, {6, 0}},
+		{{10, 0}, {15, 0}, {15, 1}},
+		{{15, 5}, {15, 10}},
+	}, runs)
+}
+
+func TestParseColor(t *testing.T) {
+	c, err := parseColor("#808080")
+	assert.NoError(t, err)
+	assert.Equal(t, color.NRGBA{0x80, 0x80, 0x80, 0xff}, c)
+	c, err = parseColor("#fa0")
+	assert.NoError(t, err)
+	assert.Equal(t, color.NRGBA{0xff, 0xaa, 0, 0xff}, c)
+	c, err = parseColor("#00000080")
+	assert.NoError(t, err)
+	assert.Equal(t, color.NRGBA{0, 0, 0, 0x80}, c)
+	for _, bad := range []string{"", "808080", "#80808", "#gggggg"} {
+		_, err = parseColor(bad)
+		assert.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,433 @@
+package export
+
+import (
+	"math"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/component/attribute"
+	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/utils/duerror"
+	"golang.org/x/image/font"
+)
+
+// the room left around the drawing
+const scenePadding = 20
+
+// the arrowheads, as the canvas draws them
+const (
+	arrowSize     = 10
+	arrowHalfBase = 5
+	triangleHalf  = 6
+	crossArm      = 4
+	dashOn        = 6
+	dashOff       = 4
+)
+
+type point struct {
+	X, Y float64
+}
+
+type rect struct {
+	Min, Max point
+}
+
+// union grows r to hold o, a zero r holds nothing yet
+func (r rect) union(o rect) rect {
+	if r == (rect{}) {
+		return o
+	}
+	return rect{
+		Min: point{math.Min(r.Min.X, o.Min.X), math.Min(r.Min.Y, o.Min.Y)},
+		Max: point{math.Max(r.Max.X, o.Max.X), math.Max(r.Max.Y, o.Max.Y)},
+	}
+}
+
+// shape is what a scene is drawn with, either a *pathShape or a *textShape
+type shape interface {
+	bounds() rect
+}
+
+// pathShape is a line through points, closed into a polygon when Closed.
+// Fill and Stroke are colors like "#808080", empty for none.
+type pathShape struct {
+	Points []point
+	Closed bool
+	Fill   string
+	Stroke string
+	Width  float64
+	Dashed bool
+}
+
+func (p *pathShape) bounds() rect {
+	r := rect{Min: p.Points[0], Max: p.Points[0]}
+	for _, pt := range p.Points[1:] {
+		r = r.union(rect{Min: pt, Max: pt})
+	}
+	return r
+}
+
+// textShape is one line of text, X and Y are the left end of its baseline.
+// Size is in pixels, Ascent and Width too; Style holds attribute.Textstyle flags.
+type textShape struct {
+	Content  string
+	X, Y     float64
+	Size     float64
+	Style    int
+	FontFile string
+	Ascent   float64
+	Descent  float64
+	Width    float64
+}
+
+func (t *textShape) bounds() rect {
+	return rect{Min: point{t.X, t.Y - t.Ascent}, Max: point{t.X + t.Width, t.Y + t.Descent}}
+}
+
+// scene is a diagram laid out as shapes, drawn in order on a Width by Height page
+// with a Background color. The shapes are moved so that the page starts at 0, 0.
+type scene struct {
+	Width      float64
+	Height     float64
+	Background string
+	Shapes     []shape
+	// the font files used, each once, in the order they first appear
+	FontFiles []string
+}
+
+// newScene lays out dd the way the canvas of the frontend draws it, without the selection
+func newScene(dd drawdata.Diagram) (*scene, duerror.DUError) {
+	b := sceneBuilder{dd: dd, fonts: newFontCache(), sc: &scene{Background: dd.Color}}
+	defer b.fonts.close()
+	if b.sc.Background == "" {
+		b.sc.Background = drawdata.DefaultDiagramColor
+	}
+	for _, g := range dd.Gadgets {
+		if err := b.gadget(g); err != nil {
+			return nil, err
+		}
+	}
+	for _, a := range dd.Associations {
+		if err := b.association(a); err != nil {
+			return nil, err
+		}
+	}
+	b.fit()
+	b.sc.FontFiles = b.fonts.files
+	return b.sc, nil
+}
+
+type sceneBuilder struct {
+	dd    drawdata.Diagram
+	sc    *scene
+	fonts *fontCache
+}
+
+func (b *sceneBuilder) add(s shape) {
+	b.sc.Shapes = append(b.sc.Shapes, s)
+}
+
+func (b *sceneBuilder) lineWidth() float64 {
+	if b.dd.LineWidth <= 0 {
+		return drawdata.LineWidth
+	}
+	return float64(b.dd.LineWidth)
+}
+
+func (b *sceneBuilder) margin() float64 {
+	if b.dd.Margin <= 0 {
+		return drawdata.Margin
+	}
+	return float64(b.dd.Margin)
+}
+
+// rectangle adds the rectangle x, y, w, h, filled with fill and stroked when stroke is set
+func (b *sceneBuilder) rectangle(x, y, w, h float64, fill string, stroke bool) {
+	p := &pathShape{
+		Points: []point{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}},
+		Closed: true,
+		Fill:   fill,
+	}
+	if stroke {
+		p.Stroke, p.Width = "#000000", b.lineWidth()
+	}
+	b.add(p)
+}
+
+func (b *sceneBuilder) line(dashed bool, points ...point) {
+	b.add(&pathShape{Points: points, Stroke: "#000000", Width: b.lineWidth(), Dashed: dashed})
+}
+
+// measure makes the text shape of content, not placed yet
+func (b *sceneBuilder) measure(content string, size, style int, fontFile string) (*textShape, duerror.DUError) {
+	if size <= 0 {
+		size = drawdata.DefaultAttributeFontSize
+	}
+	face, err := b.fonts.face(fontFile, float64(size), fontDPI)
+	if err != nil {
+		return nil, err
+	}
+	if fontFile == "" {
+		fontFile = defaultFontFile()
+	}
+	metrics := face.Metrics()
+	return &textShape{
+		Content:  content,
+		Size:     float64(size) * fontDPI / 72,
+		Style:    style,
+		FontFile: fontFile,
+		Ascent:   float64(metrics.Ascent.Round()),
+		Descent:  float64(metrics.Descent.Round()),
+		Width:    float64(font.MeasureString(face, content).Round()),
+	}, nil
+}
+
+// place adds t with its baseline starting at x, y. An underline is a line of its own.
+func (b *sceneBuilder) place(t *textShape, x, y float64) {
+	t.X, t.Y = x, y
+	b.add(t)
+	if t.Style&attribute.Underline != 0 {
+		under := y + t.Descent/2
+		b.add(&pathShape{Points: []point{{x, under}, {x + t.Width, under}}, Stroke: "#000000", Width: 1})
+	}
+}
+
+// text adds content with its top-left at x, y
+func (b *sceneBuilder) text(content string, x, y float64, size, style int, fontFile string) duerror.DUError {
+	t, err := b.measure(content, size, style, fontFile)
+	if err != nil {
+		return err
+	}
+	b.place(t, x, y+t.Ascent)
+	return nil
+}
+
+func (b *sceneBuilder) attribute(att drawdata.Attribute, x, y float64) duerror.DUError {
+	return b.text(att.Content, x, y, att.FontSize, att.FontStyle, att.FontFile)
+}
+
+// section adds the attributes of a gadget section one below the other from y,
+// centered in the width w from x when center is set
+func (b *sceneBuilder) section(atts []drawdata.Attribute, x, y, w float64, center bool) duerror.DUError {
+	m, lw := b.margin(), b.lineWidth()
+	for _, att := range atts {
+		left := x + lw + m
+		if center {
+			left = x + (w-float64(att.Width))/2
+		}
+		if err := b.attribute(att, left, y); err != nil {
+			return err
+		}
+		y += float64(att.Height) + m
+	}
+	return nil
+}
+
+func (b *sceneBuilder) gadget(g drawdata.Gadget) duerror.DUError {
+	x, y, w, h := float64(g.X), float64(g.Y), float64(g.Width), float64(g.Height)
+	m, lw := b.margin(), b.lineWidth()
+	color := g.Color
+	if color == "" {
+		color = drawdata.DefaultGadgetColor
+	}
+
+	switch component.GadgetType(g.GadgetType) {
+	case component.Note:
+		fold := float64(g.FoldSize)
+		b.add(&pathShape{
+			Points: []point{{x, y}, {x + w - fold, y}, {x + w, y + fold}, {x + w, y + h}, {x, y + h}},
+			Closed: true,
+			Fill:   color,
+			Stroke: "#000000",
+			Width:  lw,
+		})
+		b.line(false, point{x + w - fold, y}, point{x + w - fold, y + fold}, point{x + w, y + fold})
+		if len(g.Attributes) == 0 {
+			return nil
+		}
+		return b.section(g.Attributes[0], x, y+lw+m, w, false)
+
+	case component.Package:
+		tabW, tabH := float64(g.TabWidth), float64(g.TabHeight)
+		b.rectangle(x, y, tabW, tabH, color, true)
+		b.rectangle(x, y+tabH, w, h-tabH, "#FFFFFF", true)
+		// the name in the tab, the contents in the body
+		if len(g.Attributes) != 2 {
+			return nil
+		}
+		if err := b.section(g.Attributes[0], x, y+lw+m, tabW, false); err != nil {
+			return err
+		}
+		return b.section(g.Attributes[1], x, y+tabH+m, w, false)
+	}
+
+	// classes, interfaces and enumerations: the header on the gadget color, the other sections below it
+	heights := make([]float64, len(g.Attributes))
+	for i, atts := range g.Attributes {
+		heights[i] = m + lw
+		for _, att := range atts {
+			heights[i] += m + float64(att.Height)
+		}
+	}
+	if g.Stereotype != nil && len(heights) > 0 {
+		heights[0] += m + float64(g.Stereotype.Height)
+	}
+	header := h
+	if len(heights) > 0 {
+		header = heights[0]
+	}
+	b.rectangle(x, y, w, header, color, false)
+	b.rectangle(x, y+header, w, h-header, "#FFFFFF", false)
+	b.rectangle(x, y, w, h, "", true)
+
+	top := y
+	for i, atts := range g.Attributes {
+		if i > 0 {
+			b.line(false, point{x, top}, point{x + w, top})
+		}
+		cursor := top + lw + m
+		if i == 0 && g.Stereotype != nil {
+			if err := b.attribute(*g.Stereotype, x+(w-float64(g.Stereotype.Width))/2, cursor); err != nil {
+				return err
+			}
+			cursor += float64(g.Stereotype.Height) + m
+		}
+		if err := b.section(atts, x, cursor, w, i == 0); err != nil {
+			return err
+		}
+		top += heights[i]
+	}
+	return nil
+}
+
+func (b *sceneBuilder) association(a drawdata.Association) duerror.DUError {
+	start := point{float64(a.StartX), float64(a.StartY)}
+	end := point{float64(a.EndX), float64(a.EndY)}
+	delta := point{float64(a.DeltaX), float64(a.DeltaY)}
+
+	var path []point
+	switch {
+	case len(a.Path) > 1:
+		for _, p := range a.Path {
+			path = append(path, point{float64(p.X), float64(p.Y)})
+		}
+	case delta == point{}:
+		path = []point{start, end}
+	default:
+		// a self association leaves and enters its gadget along the delta
+		path = []point{start, addPoints(start, delta), addPoints(end, delta), end}
+	}
+	b.line(a.Dashed, path...)
+
+	// each head points along the segment it ends, a zero segment along the whole line
+	n := len(path)
+	b.arrowhead(a.StartHead, path[1], path[0], end)
+	b.arrowhead(a.EndHead, path[n-2], path[n-1], start)
+
+	if link := a.ClassLink; link != nil {
+		b.line(true, point{float64(link.StartX), float64(link.StartY)}, point{float64(link.EndX), float64(link.EndY)})
+	}
+
+	for _, att := range a.Attributes {
+		x := start.X + (end.X-start.X)*att.Ratio
+		y := start.Y + (end.Y-start.Y)*att.Ratio
+		t, err := b.measure(att.Content, att.FontSize, att.FontStyle, att.FontFile)
+		if err != nil {
+			return err
+		}
+		// y is the baseline, as on the canvas
+		b.place(t, x+b.margin(), y)
+	}
+
+	for _, label := range a.Labels {
+		if err := b.text(label.Content, float64(label.X), float64(label.Y), label.FontSize, 0, ""); err != nil {
+			return err
+		}
+		b.readingDirection(label, end.X-start.X)
+	}
+	return nil
+}
+
+// readingDirection adds the small triangle after a middle name, pointing to the end it reads toward
+func (b *sceneBuilder) readingDirection(label drawdata.AssLabel, dx float64) {
+	switch component.ReadingDirection(label.ReadingDirection) {
+	case component.ReadingTowardStart:
+		dx = -dx
+	case component.ReadingTowardEnd:
+	default:
+		return
+	}
+	size := float64(label.Height) / 2
+	x := float64(label.X+label.Width) + 4
+	y := float64(label.Y) + float64(label.Height)/2
+	tri := &pathShape{Closed: true, Fill: "#000000"}
+	if dx >= 0 {
+		tri.Points = []point{{x, y - size/2}, {x + size, y}, {x, y + size/2}}
+	} else {
+		tri.Points = []point{{x + size, y - size/2}, {x, y}, {x + size, y + size/2}}
+	}
+	b.add(tri)
+}
+
+// arrowhead adds the head of kind at to, pointing away from from, or from other when they are the same point
+func (b *sceneBuilder) arrowhead(kind int, from, to, other point) {
+	if from == to {
+		from = other
+	}
+	dx, dy := to.X-from.X, to.Y-from.Y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return
+	}
+	ux, uy := dx/length, dy/length
+	base := point{to.X - ux*arrowSize, to.Y - uy*arrowSize}
+	side := func(p point, d float64) point { return point{p.X - uy*d, p.Y + ux*d} }
+
+	head := &pathShape{Stroke: "#000000", Width: b.lineWidth()}
+	switch kind {
+	case drawdata.ArrowheadOpen:
+		head.Points = []point{side(base, arrowHalfBase), to, side(base, -arrowHalfBase)}
+	case drawdata.ArrowheadHollowTriangle:
+		head.Points = []point{to, side(base, triangleHalf), side(base, -triangleHalf)}
+		head.Closed, head.Fill = true, "#FFFFFF"
+	case drawdata.ArrowheadFilledDiamond, drawdata.ArrowheadHollowDiamond:
+		back := point{to.X - ux*arrowSize*2, to.Y - uy*arrowSize*2}
+		head.Points = []point{to, side(base, arrowHalfBase), back, side(base, -arrowHalfBase)}
+		head.Closed, head.Fill = true, "#FFFFFF"
+		if kind == drawdata.ArrowheadFilledDiamond {
+			head.Fill = "#000000"
+		}
+	case drawdata.ArrowheadCross:
+		b.line(false, point{base.X - (ux+uy)*crossArm, base.Y - (uy-ux)*crossArm}, point{base.X + (ux+uy)*crossArm, base.Y + (uy-ux)*crossArm})
+		b.line(false, point{base.X - (ux-uy)*crossArm, base.Y - (uy+ux)*crossArm}, point{base.X + (ux-uy)*crossArm, base.Y + (uy+ux)*crossArm})
+		return
+	default:
+		return
+	}
+	b.add(head)
+}
+
+// fit moves the shapes so that their bounds, padded, start at 0, 0, and sizes the page to them
+func (b *sceneBuilder) fit() {
+	var bounds rect
+	for _, s := range b.sc.Shapes {
+		bounds = bounds.union(s.bounds())
+	}
+	offset := point{scenePadding - bounds.Min.X, scenePadding - bounds.Min.Y}
+	for _, s := range b.sc.Shapes {
+		switch s := s.(type) {
+		case *pathShape:
+			for i := range s.Points {
+				s.Points[i] = addPoints(s.Points[i], offset)
+			}
+		case *textShape:
+			s.X += offset.X
+			s.Y += offset.Y
+		}
+	}
+	b.sc.Width = bounds.Max.X - bounds.Min.X + 2*scenePadding
+	b.sc.Height = bounds.Max.Y - bounds.Min.Y + 2*scenePadding
+}
+
+func addPoints(a, b point) point {
+	return point{a.X + b.X, a.Y + b.Y}
+}
@@ -0,0 +1,99 @@
+package export
+
+import (
+	"testing"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/umldiagram"
+	"Dr.uml/backend/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// testDiagram has a gadget of each type but the abstract class, an implementation
+// and a composition with labels
+func testDiagram(t *testing.T) drawdata.Diagram {
+	d, err := umldiagram.CreateEmptyUMLDiagram("Export.uml", umldiagram.ClassDiagram)
+	assert.NoError(t, err)
+	assert.NoError(t, d.AddGadget(component.Class, utils.Point{X: 100, Y: 200}, 0, drawdata.DefaultGadgetColor, "Order"))
+	assert.NoError(t, d.AddGadget(component.Interface, utils.Point{X: 100, Y: 0}, 0, "#FFCC00", "Priced"))
+	assert.NoError(t, d.AddGadget(component.Class, utils.Point{X: 500, Y: 200}, 0, drawdata.DefaultGadgetColor, "Line"))
+	assert.NoError(t, d.AddGadget(component.Enumeration, utils.Point{X: 500, Y: 0}, 0, drawdata.DefaultGadgetColor, "Status"))
+	assert.NoError(t, d.AddGadget(component.Note, utils.Point{X: 100, Y: 500}, 0, "#FFFFAA", "a & b < c"))
+	assert.NoError(t, d.AddGadget(component.Package, utils.Point{X: 500, Y: 500}, 0, drawdata.DefaultGadgetColor, "shop"))
+
+	assert.NoError(t, d.StartAddAssociation(utils.Point{X: 105, Y: 205}))
+	assert.NoError(t, d.EndAddAssociation(component.Implementation, utils.Point{X: 105, Y: 5}))
+	assert.NoError(t, d.StartAddAssociation(utils.Point{X: 505, Y: 205}))
+	assert.NoError(t, d.EndAddAssociation(component.Composition, utils.Point{X: 110, Y: 205}))
+	assert.NoError(t, d.SelectComponentByID(d.GetDrawData().Associations[1].ID))
+	assert.NoError(t, d.SetLabelAssociation(component.StartMultiplicity, "0..*"))
+	assert.NoError(t, d.SetLabelAssociation(component.MiddleName, "holds"))
+	assert.NoError(t, d.SetReadingDirectionAssociation(component.ReadingTowardEnd))
+	assert.NoError(t, d.UnselectAllComponents())
+	return d.GetDrawData()
+}
+
+func TestNewScene(t *testing.T) {
+	dd := testDiagram(t)
+	sc, err := newScene(dd)
+	assert.NoError(t, err)
+	assert.Equal(t, drawdata.DefaultDiagramColor, sc.Background)
+	assert.Len(t, sc.FontFiles, 1)
+
+	// every shape lies on the page, padded
+	for _, s := range sc.Shapes {
+		r := s.bounds()
+		assert.GreaterOrEqual(t, r.Min.X, float64(scenePadding))
+		assert.GreaterOrEqual(t, r.Min.Y, float64(scenePadding))
+		assert.LessOrEqual(t, r.Max.X, sc.Width-scenePadding)
+		assert.LessOrEqual(t, r.Max.Y, sc.Height-scenePadding)
+	}
+
+	texts := map[string]*textShape{}
+	var dashed, hollow, filled int
+	for _, s := range sc.Shapes {
+		switch s := s.(type) {
+		case *textShape:
+			texts[s.Content] = s
+		case *pathShape:
+			if s.Dashed {
+				dashed++
+			}
+			if s.Closed && s.Fill == "#FFFFFF" && len(s.Points) == 3 {
+				hollow++
+			}
+			if s.Closed && s.Fill == "#000000" && len(s.Points) == 4 {
+				filled++
+			}
+		}
+	}
+	for _, content := range []string{"Order", "Priced", "«interface»", "«enumeration»", "a & b < c", "shop", "0..*", "holds"} {
+		assert.Contains(t, texts, content)
+	}
+	// the implementation is dashed and ends in a hollow triangle, the composition in a filled diamond
+	assert.Equal(t, 1, dashed)
+	assert.Equal(t, 1, hollow)
+	assert.Equal(t, 1, filled)
+	// the stereotype sits above the header, both centered on the gadget
+	assert.Less(t, texts["«interface»"].Y, texts["Priced"].Y)
+	assert.InDelta(t, texts["«interface»"].X+texts["«interface»"].Width/2, texts["Priced"].X+texts["Priced"].Width/2, 1)
+}
+
+func TestNewScene_Empty(t *testing.T) {
+	sc, err := newScene(drawdata.Diagram{})
+	assert.NoError(t, err)
+	assert.Empty(t, sc.Shapes)
+	assert.Equal(t, float64(2*scenePadding), sc.Width)
+}
+
+func TestNewScene_MissingFont(t *testing.T) {
+	dd := drawdata.Diagram{Gadgets: []drawdata.Gadget{{
+		GadgetType: int(component.Note),
+		Width:      50,
+		Height:     50,
+		Attributes: [][]drawdata.Attribute{{{Content: "x", FontSize: 12, FontFile: "/no/such/font.ttf"}}},
+	}}}
+	_, err := newScene(dd)
+	assert.Error(t, err)
+}
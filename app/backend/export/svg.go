@@ -0,0 +1,122 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"Dr.uml/backend/component/attribute"
+	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/utils/duerror"
+)
+
+// SVG renders a diagram as a standalone SVG document, its fonts embedded
+func SVG(dd drawdata.Diagram) ([]byte, duerror.DUError) {
+	sc, err := newScene(dd)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %[1]s %[2]s">`+"\n",
+		num(sc.Width), num(sc.Height))
+
+	families, err := writeFontFaces(&buf, sc.FontFiles)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", sc.Background)
+	for _, s := range sc.Shapes {
+		switch s := s.(type) {
+		case *pathShape:
+			writeSVGPath(&buf, s)
+		case *textShape:
+			writeSVGText(&buf, s, families[s.FontFile])
+		}
+	}
+	buf.WriteString("</svg>\n")
+	return buf.Bytes(), nil
+}
+
+// writeFontFaces embeds each font file under a family of its own, named after the file,
+// and returns the families by file
+func writeFontFaces(buf *bytes.Buffer, files []string) (map[string]string, duerror.DUError) {
+	families := make(map[string]string, len(files))
+	if len(files) == 0 {
+		return families, nil
+	}
+	buf.WriteString("<defs><style>\n")
+	for i, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, duerror.NewFileIOError(err.Error())
+		}
+		family := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		if i > 0 {
+			family += strconv.Itoa(i)
+		}
+		families[file] = family
+		format := "truetype"
+		if strings.EqualFold(filepath.Ext(file), ".otf") {
+			format = "opentype"
+		}
+		fmt.Fprintf(buf, "@font-face { font-family: %q; src: url(data:font/%s;base64,%s) format(%q); }\n",
+			family, strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), "."), base64.StdEncoding.EncodeToString(data), format)
+	}
+	buf.WriteString("</style></defs>\n")
+	return families, nil
+}
+
+func writeSVGPath(buf *bytes.Buffer, p *pathShape) {
+	element := "polyline"
+	if p.Closed {
+		element = "polygon"
+	}
+	points := make([]string, len(p.Points))
+	for i, pt := range p.Points {
+		points[i] = num(pt.X) + "," + num(pt.Y)
+	}
+	fill, stroke := p.Fill, p.Stroke
+	if fill == "" {
+		fill = "none"
+	}
+	if stroke == "" {
+		stroke = "none"
+	}
+	fmt.Fprintf(buf, `<%s points="%s" fill="%s" stroke="%s"`, element, strings.Join(points, " "), fill, stroke)
+	if p.Stroke != "" {
+		fmt.Fprintf(buf, ` stroke-width="%s" stroke-linejoin="round"`, num(p.Width))
+	}
+	if p.Dashed {
+		fmt.Fprintf(buf, ` stroke-dasharray="%d %d"`, dashOn, dashOff)
+	}
+	buf.WriteString("/>\n")
+}
+
+func writeSVGText(buf *bytes.Buffer, t *textShape, family string) {
+	fmt.Fprintf(buf, `<text x="%s" y="%s" font-family="%s, sans-serif" font-size="%s"`, num(t.X), num(t.Y), family, num(t.Size))
+	if t.Style&attribute.Bold != 0 {
+		buf.WriteString(` font-weight="bold"`)
+	}
+	if t.Style&attribute.Italic != 0 {
+		buf.WriteString(` font-style="italic"`)
+	}
+	buf.WriteString(` fill="#000000" xml:space="preserve">`)
+	// xml.EscapeText only fails when writing fails, which a bytes.Buffer does not
+	_ = xml.EscapeText(buf, []byte(t.Content))
+	buf.WriteString("</text>\n")
+}
+
+// num writes v with at most two decimals
+func num(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
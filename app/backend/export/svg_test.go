@@ -0,0 +1,71 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"Dr.uml/backend/drawdata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSVG(t *testing.T) {
+	out, err := SVG(testDiagram(t))
+	assert.NoError(t, err)
+
+	// well-formed, with the texts as they were written
+	var texts []string
+	elements := map[string]int{}
+	dec := xml.NewDecoder(bytes.NewReader(out))
+	inText := false
+	for {
+		tok, decodeErr := dec.Token()
+		if decodeErr == io.EOF {
+			break
+		}
+		assert.NoError(t, decodeErr)
+		if decodeErr != nil {
+			return
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			elements[tok.Name.Local]++
+			inText = tok.Name.Local == "text"
+		case xml.CharData:
+			if inText {
+				texts = append(texts, string(tok))
+			}
+		case xml.EndElement:
+			inText = false
+		}
+	}
+	assert.Contains(t, texts, "a & b < c")
+	assert.Contains(t, texts, "«interface»")
+	assert.Equal(t, 1, elements["svg"])
+	assert.Positive(t, elements["polygon"])
+	assert.Positive(t, elements["polyline"])
+
+	s := string(out)
+	assert.Contains(t, s, `font-family: "Inkfree"; src: url(data:font/ttf;base64,`)
+	assert.Contains(t, s, `stroke-dasharray="6 4"`)
+	assert.Contains(t, s, `fill="#FFCC00"`)
+	assert.True(t, strings.HasPrefix(s, `<svg xmlns="http://www.w3.org/2000/svg"`))
+}
+
+func TestSVG_Empty(t *testing.T) {
+	out, err := SVG(drawdata.Diagram{})
+	assert.NoError(t, err)
+	assert.Equal(t, `<svg xmlns="http://www.w3.org/2000/svg" width="40" height="40" viewBox="0 0 40 40">
+<rect width="100%" height="100%" fill="#FFFFFF"/>
+</svg>
+`, string(out))
+}
+
+func TestNum(t *testing.T) {
+	assert.Equal(t, "10", num(10))
+	assert.Equal(t, "16.67", num(100.0/6))
+	assert.Equal(t, "0", num(-0.001))
+	assert.Equal(t, "0.5", num(0.5))
+}
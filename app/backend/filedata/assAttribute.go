@@ -0,0 +1,9 @@
+package filedata
+
+type AssAttribute struct {
+	Content  string  `json:"content"`
+	Size     int     `json:"size"`
+	Style    int     `json:"style"`
+	FontFile string  `json:"fontFile"` // empty means the default font
+	Ratio    float64 `json:"ratio"`
+}
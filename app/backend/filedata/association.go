@@ -0,0 +1,27 @@
+package filedata
+
+type Association struct {
+	ID           string         `json:"id,omitempty"` // a new one is generated when empty
+	AssType      int            `json:"assType"`
+	Layer        int            `json:"layer"`
+	Order        int            `json:"order,omitempty"` // as Gadget.Order, gadgets and associations count together
+	Parents      [2]int         `json:"parents"`         // indices into Diagram.Gadgets, {start, end}
+	StartRatio   [2]float64     `json:"startRatio"`
+	EndRatio     [2]float64     `json:"endRatio"`
+	Navigability [2]int         `json:"navigability"`        // of {start, end}
+	Waypoints    [][2]int       `json:"waypoints,omitempty"` // {x, y} of each bend, in order from the start
+	Routing      int            `json:"routing,omitempty"`
+	Class        *int           `json:"class,omitempty"` // index of the association class, if any
+	Attributes   []AssAttribute `json:"attributes"`
+	Labels       AssLabels      `json:"labels"`
+}
+
+// AssLabels are the labels at the ends and in the middle of an association, empty when not shown
+type AssLabels struct {
+	StartMultiplicity string `json:"startMultiplicity,omitempty"`
+	StartRole         string `json:"startRole,omitempty"`
+	EndMultiplicity   string `json:"endMultiplicity,omitempty"`
+	EndRole           string `json:"endRole,omitempty"`
+	Name              string `json:"name,omitempty"`
+	ReadingDirection  int    `json:"readingDirection,omitempty"`
+}
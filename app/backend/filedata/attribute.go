@@ -0,0 +1,8 @@
+package filedata
+
+type Attribute struct {
+	Content  string `json:"content"`
+	Size     int    `json:"size"`
+	Style    int    `json:"style"`
+	FontFile string `json:"fontFile"` // empty means the default font
+}
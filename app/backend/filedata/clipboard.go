@@ -0,0 +1,13 @@
+package filedata
+
+const (
+	ClipboardFormat  = "dr.uml/components"
+	ClipboardVersion = 1
+)
+
+// Clipboard is the plain-text form of copied components, it lets another running app paste them.
+type Clipboard struct {
+	Format  string `json:"format"` // tells the payload apart from any other text on the clipboard
+	Version int    `json:"version"`
+	Components
+}
@@ -0,0 +1,7 @@
+package filedata
+
+// Components is a group of gadgets and the associations among them, taken out of a diagram.
+type Components struct {
+	Gadgets      []Gadget      `json:"gadgets"`
+	Associations []Association `json:"associations"` // Parents index into Gadgets
+}
@@ -0,0 +1,16 @@
+package filedata
+
+import "time"
+
+// DiagramFileVersion is the version written to standalone diagram files.
+const DiagramFileVersion = 1
+
+type Diagram struct {
+	Version         int           `json:"version,omitempty"` // only set in standalone diagram files
+	Name            string        `json:"name"`
+	DiagramType     int           `json:"diagramType"`
+	BackgroundColor string        `json:"backgroundColor"`
+	LastModified    time.Time     `json:"lastModified"`
+	Gadgets         []Gadget      `json:"gadgets"`
+	Associations    []Association `json:"associations"`
+}
@@ -0,0 +1,12 @@
+package filedata
+
+type Gadget struct {
+	ID         string        `json:"id,omitempty"` // a new one is generated when empty
+	GadgetType int           `json:"gadgetType"`
+	X          int           `json:"x"`
+	Y          int           `json:"y"`
+	Layer      int           `json:"layer"`
+	Order      int           `json:"order,omitempty"` // place in the diagram from the bottom, starting at 1, 0 puts it on top
+	Color      string        `json:"color"`
+	Attributes [][]Attribute `json:"attributes"`
+}
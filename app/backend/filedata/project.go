@@ -0,0 +1,14 @@
+package filedata
+
+import "time"
+
+// ProjectFileVersion is bumped whenever the on-disk layout changes in a way
+// older readers cannot understand.
+const ProjectFileVersion = 1
+
+type Project struct {
+	Version      int       `json:"version"`
+	Name         string    `json:"name"`
+	LastModified time.Time `json:"lastModified"`
+	Diagrams     []Diagram `json:"diagrams"`
+}
@@ -0,0 +1,14 @@
+package filedata
+
+import "time"
+
+// SubmoduleFileVersion is the version written to submodule files.
+const SubmoduleFileVersion = 1
+
+// Submodule is a named group of components saved on its own, to be imported into any diagram.
+type Submodule struct {
+	Version      int       `json:"version"`
+	Name         string    `json:"name"`
+	LastModified time.Time `json:"lastModified"`
+	Components
+}
@@ -0,0 +1,597 @@
+package gocode
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/component/attribute"
+	"Dr.uml/backend/filedata"
+	"Dr.uml/backend/umldiagram"
+	"Dr.uml/backend/utils/duerror"
+)
+
+// File is a generated Go source file, Name is relative to the output directory
+type File struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// umlTypes maps the types UML and other languages commonly use to Go types
+var umlTypes = map[string]string{
+	"String":   "string",
+	"Integer":  "int",
+	"Int":      "int",
+	"Long":     "int64",
+	"long":     "int64",
+	"Short":    "int16",
+	"short":    "int16",
+	"Boolean":  "bool",
+	"boolean":  "bool",
+	"Double":   "float64",
+	"double":   "float64",
+	"Float":    "float32",
+	"float":    "float32",
+	"Real":     "float64",
+	"Char":     "rune",
+	"char":     "rune",
+	"Byte":     "byte",
+	"Object":   "any",
+	"Date":     "time.Time",
+	"DateTime": "time.Time",
+}
+
+// importPaths gives the import path of the standard packages a type may be qualified with, by name.
+// A qualifier that is not one of them cannot be imported, the package it stands for is unknown.
+var importPaths = map[string]string{
+	"ast":       "go/ast",
+	"atomic":    "sync/atomic",
+	"big":       "math/big",
+	"bufio":     "bufio",
+	"bytes":     "bytes",
+	"color":     "image/color",
+	"constant":  "go/constant",
+	"context":   "context",
+	"csv":       "encoding/csv",
+	"ecdsa":     "crypto/ecdsa",
+	"ed25519":   "crypto/ed25519",
+	"errors":    "errors",
+	"exec":      "os/exec",
+	"filepath":  "path/filepath",
+	"fmt":       "fmt",
+	"fs":        "io/fs",
+	"hash":      "hash",
+	"heap":      "container/heap",
+	"http":      "net/http",
+	"image":     "image",
+	"io":        "io",
+	"iter":      "iter",
+	"json":      "encoding/json",
+	"list":      "container/list",
+	"log":       "log",
+	"multipart": "mime/multipart",
+	"net":       "net",
+	"netip":     "net/netip",
+	"os":        "os",
+	"reflect":   "reflect",
+	"regexp":    "regexp",
+	"rand":      "math/rand",
+	"rsa":       "crypto/rsa",
+	"scanner":   "text/scanner",
+	"signal":    "os/signal",
+	"slog":      "log/slog",
+	"sql":       "database/sql",
+	"strings":   "strings",
+	"sync":      "sync",
+	"syscall":   "syscall",
+	"tabwriter": "text/tabwriter",
+	"template":  "text/template",
+	"time":      "time",
+	"tls":       "crypto/tls",
+	"token":     "go/token",
+	"types":     "go/types",
+	"unicode":   "unicode",
+	"unsafe":    "unsafe",
+	"url":       "net/url",
+	"utf8":      "unicode/utf8",
+	"x509":      "crypto/x509",
+	"xml":       "encoding/xml",
+}
+
+var identifier = regexp.MustCompile(`[\p{L}_][\p{L}\p{N}_]*`)
+
+// Generate writes the skeleton of a Go package called pkgName from a class diagram, one file per type.
+// Classes become structs with their fields and methods, interfaces become interfaces with their method
+// sets and enumerations become constants. An Extension embeds its end, a Composition gives the whole a
+// field of its part, a slice when the multiplicity at the part allows more than one, and an
+// Implementation becomes a compile-time assertion. Every file is gofmt-clean. Types may be qualified with
+// standard packages only, there is no telling which module another one comes from.
+func Generate(fd filedata.Diagram, pkgName string) ([]File, duerror.DUError) {
+	if umldiagram.DiagramType(fd.DiagramType) != umldiagram.ClassDiagram {
+		return nil, duerror.NewInvalidArgumentError("only class diagrams generate code")
+	}
+	if !token.IsIdentifier(pkgName) {
+		return nil, duerror.NewInvalidArgumentError("package name " + strconv.Quote(pkgName) + " is not an identifier")
+	}
+
+	decls := make(map[int]*goDecl)
+	names := make(map[string]bool)
+	var order []int
+	for i, g := range fd.Gadgets {
+		switch component.GadgetType(g.GadgetType) {
+		case component.Note, component.Package:
+			continue
+		}
+		d, err := newGoDecl(g)
+		if err != nil {
+			return nil, err
+		}
+		if names[d.name] {
+			return nil, duerror.NewInvalidArgumentError("more than one type called " + d.name)
+		}
+		names[d.name] = true
+		decls[i] = d
+		order = append(order, i)
+	}
+
+	for _, a := range fd.Associations {
+		st, en := decls[a.Parents[0]], decls[a.Parents[1]]
+		if st == nil || en == nil || st == en {
+			continue
+		}
+		switch component.AssociationType(a.AssType) {
+		case component.Extension:
+			if st.gadgetType != component.Interface || en.gadgetType == component.Interface {
+				st.embeds = append(st.embeds, en)
+			}
+		case component.Implementation:
+			if en.gadgetType == component.Interface {
+				st.implements = append(st.implements, en)
+			}
+		case component.Composition:
+			en.parts = append(en.parts, part{decl: st, role: a.Labels.StartRole, many: allowsMany(a.Labels.StartMultiplicity)})
+		}
+	}
+
+	files := make([]File, 0, len(order))
+	for _, i := range order {
+		f, err := decls[i].file(pkgName)
+		if err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(files, func(other File) bool { return other.Name == f.Name }) {
+			return nil, duerror.NewInvalidArgumentError("more than one type would be written to " + f.Name)
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// WriteFiles writes files into dir, creating it if needed. It never overwrites a file.
+func WriteFiles(dir string, files []File) duerror.DUError {
+	for _, f := range files {
+		if _, err := os.Stat(filepath.Join(dir, f.Name)); err == nil {
+			return duerror.NewFileIOError(f.Name + " already exists in " + dir)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return duerror.NewFileIOError(err.Error())
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f.Name), []byte(f.Content), 0644); err != nil {
+			return duerror.NewFileIOError(err.Error())
+		}
+	}
+	return nil
+}
+
+// goDecl is the Go type a gadget becomes
+type goDecl struct {
+	gadgetType component.GadgetType
+	name       string
+	typeParams *ast.FieldList // nil unless the header has type parameters
+	header     string         // name and type parameters, as written in a type declaration
+	fields     []attribute.Member
+	methods    []attribute.Member
+	embeds     []*goDecl
+	implements []*goDecl
+	parts      []part
+	imports    map[string]bool
+}
+
+// part is what a Composition adds to the whole
+type part struct {
+	decl *goDecl
+	role string
+	many bool
+}
+
+func newGoDecl(g filedata.Gadget) (*goDecl, duerror.DUError) {
+	if len(g.Attributes) == 0 || len(g.Attributes[0]) == 0 {
+		return nil, duerror.NewInvalidArgumentError("a gadget has no name")
+	}
+	header := strings.TrimSpace(g.Attributes[0][0].Content)
+	// the header is checked the way Go reads it, type parameters included
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package p\ntype "+header+" struct{}", 0)
+	if err != nil || len(f.Decls) != 1 || len(f.Decls[0].(*ast.GenDecl).Specs) != 1 {
+		return nil, duerror.NewInvalidArgumentError(strconv.Quote(header) + " is not a Go type name")
+	}
+	spec := f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec)
+	d := &goDecl{
+		gadgetType: component.GadgetType(g.GadgetType),
+		name:       spec.Name.Name,
+		typeParams: spec.TypeParams,
+		header:     header,
+		imports:    make(map[string]bool),
+	}
+
+	for section := 1; section < len(g.Attributes); section++ {
+		for line, att := range g.Attributes[section] {
+			m, err := attribute.ParseMember(att.Content)
+			if err != nil {
+				return nil, duerror.NewInvalidArgumentError(fmt.Sprintf("%s, section %d, %v", d.name, section, withLine(err, line)))
+			}
+			m.Static = m.Static || attribute.Textstyle(att.Style)&attribute.Underline != 0
+			if err := d.translate(&m); err != nil {
+				return nil, duerror.NewInvalidArgumentError(fmt.Sprintf("%s.%s: %v", d.name, m.Name, err))
+			}
+			if m.Kind == attribute.Operation {
+				d.methods = append(d.methods, m)
+			} else {
+				d.fields = append(d.fields, m)
+			}
+		}
+	}
+	return d, nil
+}
+
+func withLine(err duerror.DUError, line int) duerror.DUError {
+	e := *err.(*attribute.MemberError)
+	e.Line = line
+	return &e
+}
+
+// translate turns the name and types of m into Go, checking every type parses
+func (d *goDecl) translate(m *attribute.Member) duerror.DUError {
+	m.Name = goName(m.Name, m.Visibility)
+	var err duerror.DUError
+	if m.Kind == attribute.Field {
+		m.Type, err = d.goType(m.Type)
+		return err
+	}
+	for i := range m.Parameters {
+		// the last parameter may be variadic, the dots are not part of its type
+		typ, variadic := strings.CutPrefix(m.Parameters[i].Type, "...")
+		if variadic && i != len(m.Parameters)-1 {
+			return duerror.NewInvalidArgumentError("only the last parameter can be variadic")
+		}
+		if typ, err = d.goType(typ); err != nil {
+			return err
+		}
+		if variadic {
+			typ = "..." + typ
+		}
+		m.Parameters[i].Type = typ
+	}
+	m.Type, err = d.goResults(m.Type)
+	return err
+}
+
+// goType translates a type, an empty one being any
+func (d *goDecl) goType(typ string) (string, duerror.DUError) {
+	if typ == "" {
+		return "any", nil
+	}
+	// generics written with angle brackets, leaving the arrows of channels alone
+	var sb strings.Builder
+	for i, r := range typ {
+		switch {
+		case r == '<' && !strings.HasPrefix(typ[i:], "<-"):
+			sb.WriteRune('[')
+		case r == '>':
+			sb.WriteRune(']')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	typ = sb.String()
+	// replace the known names that are not qualified, nor a qualifier
+	locs := identifier.FindAllStringIndex(typ, -1)
+	for i := len(locs) - 1; i >= 0; i-- {
+		start, end := locs[i][0], locs[i][1]
+		goTyp, ok := umlTypes[typ[start:end]]
+		if !ok || (start > 0 && typ[start-1] == '.') || (end < len(typ) && typ[end] == '.') {
+			continue
+		}
+		typ = typ[:start] + goTyp + typ[end:]
+	}
+
+	expr, err := parser.ParseExpr(typ)
+	if err != nil {
+		return "", duerror.NewInvalidArgumentError(strconv.Quote(typ) + " is not a Go type")
+	}
+	if err := d.addImports(expr); err != nil {
+		return "", err
+	}
+	return typ, nil
+}
+
+// goResults translates a return type, which may be a list of results in parentheses
+func (d *goDecl) goResults(typ string) (string, duerror.DUError) {
+	if typ == "" || typ == "void" {
+		return "", nil
+	}
+	if !strings.HasPrefix(typ, "(") {
+		return d.goType(typ)
+	}
+	expr, err := parser.ParseExpr("func() " + typ)
+	if err != nil {
+		return "", duerror.NewInvalidArgumentError(strconv.Quote(typ) + " are not Go results")
+	}
+	if err := d.addImports(expr); err != nil {
+		return "", err
+	}
+	return typ, nil
+}
+
+// addImports records the packages the types in expr are qualified with
+func (d *goDecl) addImports(expr ast.Expr) duerror.DUError {
+	var err duerror.DUError
+	ast.Inspect(expr, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok || err != nil {
+			return err == nil
+		}
+		if x, ok := sel.X.(*ast.Ident); ok {
+			if _, known := importPaths[x.Name]; !known {
+				err = duerror.NewInvalidArgumentError("the package of " + x.Name + "." + sel.Sel.Name + " is not a standard one, it cannot be imported")
+				return false
+			}
+			d.imports[x.Name] = true
+		}
+		return true
+	})
+	return err
+}
+
+// goName exports public names and unexports the others, names without a visibility stay as they are
+func goName(name string, v attribute.Visibility) string {
+	r, size := utf8.DecodeRuneInString(name)
+	switch v {
+	case attribute.VisibilityNone:
+		return name
+	case attribute.Public:
+		return string(unicode.ToUpper(r)) + name[size:]
+	}
+	return string(unicode.ToLower(r)) + name[size:]
+}
+
+// allowsMany tells whether a multiplicity lets the part be there more than once
+func allowsMany(multiplicity string) bool {
+	upper := multiplicity
+	if i := strings.Index(multiplicity, ".."); i >= 0 {
+		upper = multiplicity[i+2:]
+	}
+	if upper == "*" {
+		return true
+	}
+	n, err := strconv.Atoi(upper)
+	return err == nil && n > 1
+}
+
+// typeArgs is the name of d as its methods' receivers write it, as in "Stack[T]"
+func (d *goDecl) typeArgs() string {
+	if d.typeParams == nil {
+		return d.name
+	}
+	var names []string
+	for _, f := range d.typeParams.List {
+		for _, n := range f.Names {
+			names = append(names, n.Name)
+		}
+	}
+	return d.name + "[" + strings.Join(names, ", ") + "]"
+}
+
+// file writes the declaration of d into its own file, named after it
+func (d *goDecl) file(pkgName string) (File, duerror.DUError) {
+	var body strings.Builder
+	switch d.gadgetType {
+	case component.Interface:
+		d.writeInterface(&body)
+	case component.Enumeration:
+		d.writeEnumeration(&body)
+	default:
+		d.writeStruct(&body)
+	}
+
+	var src strings.Builder
+	fmt.Fprintf(&src, "package %s\n\n", pkgName)
+	if len(d.imports) > 0 {
+		paths := make([]string, 0, len(d.imports))
+		for name := range d.imports {
+			paths = append(paths, importPaths[name])
+		}
+		slices.Sort(paths)
+		src.WriteString("import (\n")
+		for _, p := range paths {
+			fmt.Fprintf(&src, "\t%q\n", p)
+		}
+		src.WriteString(")\n\n")
+	}
+	src.WriteString(body.String())
+
+	content, err := format.Source([]byte(src.String()))
+	if err != nil {
+		return File{}, duerror.NewInvalidArgumentError(d.name + ": " + err.Error())
+	}
+	return File{Name: fileName(d.name), Content: string(content)}, nil
+}
+
+func (d *goDecl) writeStruct(sb *strings.Builder) {
+	fieldNames := make(map[string]bool)
+	fmt.Fprintf(sb, "type %s struct {\n", d.header)
+	for _, e := range d.embeds {
+		fmt.Fprintf(sb, "%s\n", e.name)
+		fieldNames[e.name] = true
+	}
+	var statics []attribute.Member
+	for _, f := range d.fields {
+		if f.Static {
+			statics = append(statics, f)
+			continue
+		}
+		fmt.Fprintf(sb, "%s %s\n", f.Name, f.Type)
+		fieldNames[f.Name] = true
+	}
+	for _, p := range d.parts {
+		// a generic part cannot be instantiated without knowing its arguments
+		if p.decl.typeParams != nil || d.holds(p.decl.name) {
+			continue
+		}
+		name := p.role
+		if !token.IsIdentifier(name) {
+			name = goName(p.decl.name, attribute.PackagePrivate)
+		}
+		for i := 2; fieldNames[name]; i++ {
+			name = fmt.Sprintf("%s%d", strings.TrimRight(name, "0123456789"), i)
+		}
+		fieldNames[name] = true
+		typ := p.decl.name
+		if p.many {
+			typ = "[]" + typ
+		}
+		fmt.Fprintf(sb, "%s %s\n", name, typ)
+	}
+	sb.WriteString("}\n")
+
+	for _, f := range statics {
+		fmt.Fprintf(sb, "\nvar %s %s\n", d.staticName(f.Name), f.Type)
+	}
+	for _, i := range d.implements {
+		if d.typeParams == nil && i.typeParams == nil {
+			fmt.Fprintf(sb, "\nvar _ %s = (*%s)(nil)\n", i.name, d.name)
+		}
+	}
+	first, _ := utf8.DecodeRuneInString(d.name)
+	receiver := string(unicode.ToLower(first))
+	for _, m := range d.methods {
+		if m.Static {
+			fmt.Fprintf(sb, "\nfunc %s(%s) %s {\n\tpanic(\"not implemented\")\n}\n", d.staticName(m.Name), params(m), m.Type)
+			continue
+		}
+		r := receiver
+		if slices.ContainsFunc(m.Parameters, func(p attribute.Parameter) bool { return p.Name == r }) {
+			r = "recv"
+		}
+		fmt.Fprintf(sb, "\nfunc (%s *%s) %s(%s) %s {\n\tpanic(\"not implemented\")\n}\n", r, d.typeArgs(), m.Name, params(m), m.Type)
+	}
+}
+
+// staticName is the package level name of a static member of d, as in "CircleCount" for "Count",
+// exported if the member is
+func (d *goDecl) staticName(name string) string {
+	v := attribute.PackagePrivate
+	if token.IsExported(name) {
+		v = attribute.Public
+	}
+	return goName(d.name+goName(name, attribute.Public), v)
+}
+
+// holds tells whether a field of d already mentions the type called name
+func (d *goDecl) holds(name string) bool {
+	for _, f := range d.fields {
+		if slices.Contains(identifier.FindAllString(f.Type, -1), name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *goDecl) writeInterface(sb *strings.Builder) {
+	fmt.Fprintf(sb, "type %s interface {\n", d.header)
+	for _, e := range d.embeds {
+		fmt.Fprintf(sb, "%s\n", e.name)
+	}
+	for _, m := range d.methods {
+		fmt.Fprintf(sb, "%s(%s) %s\n", m.Name, params(m), m.Type)
+	}
+	sb.WriteString("}\n")
+	// the constants of an interface belong to its package in Go
+	for _, f := range d.fields {
+		if f.Default != "" {
+			fmt.Fprintf(sb, "\nconst %s %s = %s\n", f.Name, f.Type, f.Default)
+		} else {
+			fmt.Fprintf(sb, "\nvar %s %s\n", f.Name, f.Type)
+		}
+	}
+}
+
+func (d *goDecl) writeEnumeration(sb *strings.Builder) {
+	fmt.Fprintf(sb, "type %s int\n", d.name)
+	if len(d.fields) == 0 {
+		return
+	}
+	sb.WriteString("\nconst (\n")
+	for i, f := range d.fields {
+		if i == 0 {
+			fmt.Fprintf(sb, "%s %s = iota\n", f.Name, d.name)
+		} else {
+			fmt.Fprintf(sb, "%s\n", f.Name)
+		}
+	}
+	sb.WriteString(")\n")
+}
+
+func params(m attribute.Member) string {
+	list := make([]string, 0, len(m.Parameters))
+	for _, p := range m.Parameters {
+		list = append(list, p.Name+" "+p.Type)
+	}
+	return strings.Join(list, ", ")
+}
+
+// fileName turns a type name into a file name, as in "HTTPServer" to "http_server.go"
+func fileName(name string) string {
+	runes := []rune(name)
+	var sb strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 &&
+			(unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+			sb.WriteRune('_')
+		}
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	// a name ending in _test would make a test file, and one ending in a GOOS or a GOARCH, as in
+	// _windows or _amd64, a file built there only. go/build does not look at the first part.
+	parts := strings.Split(sb.String(), "_")
+	if last := parts[len(parts)-1]; len(parts) > 1 && (last == "test" || knownOS[last] || knownArch[last]) {
+		sb.WriteString("_type")
+	}
+	return sb.String() + ".go"
+}
+
+// knownOS and knownArch are the GOOS and GOARCH values go/build takes from file names
+var knownOS = map[string]bool{
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true, "hurd": true,
+	"illumos": true, "ios": true, "js": true, "linux": true, "nacl": true, "netbsd": true, "openbsd": true,
+	"plan9": true, "solaris": true, "wasip1": true, "windows": true, "zos": true,
+}
+
+var knownArch = map[string]bool{
+	"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true, "arm64be": true,
+	"loong64": true, "mips": true, "mipsle": true, "mips64": true, "mips64le": true, "mips64p32": true,
+	"mips64p32le": true, "ppc": true, "ppc64": true, "ppc64le": true, "riscv": true, "riscv64": true,
+	"s390": true, "s390x": true, "sparc": true, "sparc64": true, "wasm": true,
+}
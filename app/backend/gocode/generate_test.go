@@ -0,0 +1,235 @@
+package gocode
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/filedata"
+	"Dr.uml/backend/umldiagram"
+	"github.com/stretchr/testify/assert"
+)
+
+// gadget makes the saved form of a gadget with sections of attribute contents
+func gadget(gadgetType component.GadgetType, sections ...[]string) filedata.Gadget {
+	g := filedata.Gadget{GadgetType: int(gadgetType)}
+	for _, section := range sections {
+		atts := []filedata.Attribute{}
+		for _, content := range section {
+			atts = append(atts, filedata.Attribute{Content: content, Size: 12})
+		}
+		g.Attributes = append(g.Attributes, atts)
+	}
+	return g
+}
+
+// typeCheck checks the generated files compile together, against the standard library
+func typeCheck(t *testing.T, files []File) {
+	fset := token.NewFileSet()
+	var parsed []*ast.File
+	for _, f := range files {
+		file, err := parser.ParseFile(fset, f.Name, f.Content, 0)
+		assert.NoError(t, err)
+		parsed = append(parsed, file)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err := conf.Check("generated", fset, parsed, nil)
+	assert.NoError(t, err)
+}
+
+func contentOf(files []File, name string) string {
+	for _, f := range files {
+		if f.Name == name {
+			return f.Content
+		}
+	}
+	return ""
+}
+
+func TestGenerate(t *testing.T) {
+	fd := filedata.Diagram{
+		DiagramType: int(umldiagram.ClassDiagram),
+		Gadgets: []filedata.Gadget{
+			gadget(component.Class, []string{"Order"},
+				[]string{"- id: String", "+ placed: Date", "{static} + count: int"},
+				[]string{"+ total(): double", "+ add(item: Item, qty: int = 1): void", "- split(): (Order, error)"}),
+			gadget(component.Class, []string{"Item"}, []string{"+ Name: string"}, []string{}),
+			gadget(component.Interface, []string{"Priced"}, []string{"+ Currency: string = \"EUR\""}, []string{"+ total(): double"}),
+			gadget(component.Enumeration, []string{"Status"}, []string{"Open", "Closed"}),
+			gadget(component.Class, []string{"HTTPOrder"}, []string{}, []string{}),
+			gadget(component.Note, []string{"ignored"}),
+			gadget(component.Class, []string{"List[T any]"}, []string{"- items: []T"}, []string{"+ Get(t: int): T"}),
+		},
+		Associations: []filedata.Association{
+			{AssType: int(component.Composition), Parents: [2]int{1, 0}, Labels: filedata.AssLabels{StartMultiplicity: "0..*", StartRole: "lines"}},
+			{AssType: int(component.Implementation), Parents: [2]int{0, 2}},
+			{AssType: int(component.Extension), Parents: [2]int{4, 0}},
+			{AssType: int(component.Composition), Parents: [2]int{3, 4}},
+			{AssType: int(component.Dependency), Parents: [2]int{0, 5}},
+		},
+	}
+	files, err := Generate(fd, "shop")
+	assert.NoError(t, err)
+	assert.Len(t, files, 6)
+	assert.Equal(t, "order.go", files[0].Name)
+	assert.Equal(t, `package shop
+
+import (
+	"time"
+)
+
+type Order struct {
+	id     string
+	Placed time.Time
+	lines  []Item
+}
+
+var OrderCount int
+
+var _ Priced = (*Order)(nil)
+
+func (o *Order) Total() float64 {
+	panic("not implemented")
+}
+
+func (o *Order) Add(item Item, qty int) {
+	panic("not implemented")
+}
+
+func (o *Order) split() (Order, error) {
+	panic("not implemented")
+}
+`, files[0].Content)
+	assert.Contains(t, contentOf(files, "priced.go"), "type Priced interface {\n\tTotal() float64\n}")
+	assert.Contains(t, contentOf(files, "priced.go"), "const Currency string = \"EUR\"")
+	assert.Contains(t, contentOf(files, "status.go"), "Open Status = iota\n\tClosed\n")
+	assert.Contains(t, contentOf(files, "http_order.go"), "type HTTPOrder struct {\n\tOrder\n\tstatus Status\n}")
+	assert.Contains(t, contentOf(files, "list.go"), "func (l *List[T]) Get(t int) T {")
+	typeCheck(t, files)
+
+	// the preview is what gets written
+	dir := filepath.Join(t.TempDir(), "shop")
+	assert.NoError(t, WriteFiles(dir, files))
+	content, readErr := os.ReadFile(filepath.Join(dir, "order.go"))
+	assert.NoError(t, readErr)
+	assert.Equal(t, files[0].Content, string(content))
+	assert.Error(t, WriteFiles(dir, files[:1]))
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	class := func(header string, fields ...string) filedata.Diagram {
+		return filedata.Diagram{
+			DiagramType: int(umldiagram.ClassDiagram),
+			Gadgets:     []filedata.Gadget{gadget(component.Class, []string{header}, fields, []string{})},
+		}
+	}
+	_, err := Generate(class("A"), "not a name")
+	assert.Error(t, err)
+	_, err = Generate(class("Two words"), "p")
+	assert.Error(t, err)
+	_, err = Generate(class("A", "x: int", "broken ("), "p")
+	assert.ErrorContains(t, err, "line 2")
+	_, err = Generate(class("A", "x: map[int"), "p")
+	assert.Error(t, err)
+	twice := class("A")
+	twice.Gadgets = append(twice.Gadgets, twice.Gadgets[0])
+	_, err = Generate(twice, "p")
+	assert.Error(t, err)
+	other := class("A")
+	other.DiagramType = int(umldiagram.UseCaseDiagram)
+	_, err = Generate(other, "p")
+	assert.Error(t, err)
+	// no path is known for the package
+	_, err = Generate(class("A", "x: widgets.Button"), "p")
+	assert.ErrorContains(t, err, "widgets.Button")
+	withMethod := class("A")
+	withMethod.Gadgets[0].Attributes[2] = []filedata.Attribute{{Content: "Log(args: ...any, level: int)", Size: 12}}
+	_, err = Generate(withMethod, "p")
+	assert.ErrorContains(t, err, "variadic")
+}
+
+func TestGenerate_RoundTrip(t *testing.T) {
+	dir := writePackage(t, map[string]string{"shapes.go": shapesSource})
+	diagram, err := ReverseEngineer(dir, "shapes")
+	assert.NoError(t, err)
+	fd, err := diagram.GetFileData()
+	assert.NoError(t, err)
+	files, err := Generate(fd, "shapes")
+	assert.NoError(t, err)
+	assert.Len(t, files, 8)
+	typeCheck(t, files)
+
+	// and reading the generated package back finds the same relations
+	out := filepath.Join(t.TempDir(), "shapes")
+	assert.NoError(t, WriteFiles(out, files))
+	again, err := ReverseEngineer(out, "shapes")
+	assert.NoError(t, err)
+	againFd, err := again.GetFileData()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, relations(fd), relations(againFd))
+}
+
+// variadic parameters and types of go/ast and go/token come back as they were
+func TestGenerate_RoundTripVariadic(t *testing.T) {
+	dir := writePackage(t, map[string]string{"visit.go": `package visit
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+type Visitor struct {
+	Pos   token.Pos
+	nodes []ast.Node
+}
+
+func (v *Visitor) Logf(format string, args ...any) {}
+
+func (v *Visitor) Visit(nodes ...ast.Node) int { return 0 }
+`})
+	diagram, err := ReverseEngineer(dir, "visit")
+	assert.NoError(t, err)
+	fd, err := diagram.GetFileData()
+	assert.NoError(t, err)
+	files, err := Generate(fd, "visit")
+	assert.NoError(t, err)
+	typeCheck(t, files)
+	assert.Equal(t, `package visit
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+type Visitor struct {
+	Pos   token.Pos
+	nodes []ast.Node
+}
+
+func (v *Visitor) Logf(format string, args ...any) {
+	panic("not implemented")
+}
+
+func (v *Visitor) Visit(nodes ...ast.Node) int {
+	panic("not implemented")
+}
+`, contentOf(files, "visitor.go"))
+}
+
+func TestFileName(t *testing.T) {
+	assert.Equal(t, "http_server.go", fileName("HTTPServer"))
+	assert.Equal(t, "order_line.go", fileName("orderLine"))
+	assert.Equal(t, "unit_test_type.go", fileName("UnitTest"))
+	// not built on other systems
+	assert.Equal(t, "config_windows_type.go", fileName("ConfigWindows"))
+	assert.Equal(t, "port_amd64_type.go", fileName("PortAmd64"))
+	assert.Equal(t, "server_linux_arm64_type.go", fileName("ServerLinuxArm64"))
+	assert.Equal(t, "windows.go", fileName("Windows"))
+	assert.Equal(t, "client_js_type.go", fileName("ClientJs"))
+}
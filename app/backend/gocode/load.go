@@ -0,0 +1,168 @@
+package gocode
+
+import (
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"Dr.uml/backend/utils/duerror"
+)
+
+// goPackage is a parsed and type checked Go package
+type goPackage struct {
+	name  string
+	files []*ast.File
+	types *types.Package
+	info  *types.Info
+	decls []*typeDecl // in the order they are declared
+}
+
+// loadPackage parses the Go files of dir built on this platform, test files aside, and type checks them.
+// Imported packages are never loaded, see stubImporter, so that it works offline on any package.
+// Type errors do not stop it, what cannot be resolved is left out of the relations.
+func loadPackage(dir string) (*goPackage, duerror.DUError) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, duerror.NewFileIOError(err.Error())
+	}
+	fset := token.NewFileSet()
+	pkg := &goPackage{}
+	for _, e := range entries {
+		if e.IsDir() || strings.HasSuffix(e.Name(), "_test.go") {
+			continue
+		}
+		// the files the build constraints leave out for this platform, and what is not Go
+		if ok, err := build.Default.MatchFile(dir, e.Name()); err != nil || !ok || filepath.Ext(e.Name()) != ".go" {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, e.Name()), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, duerror.NewInvalidArgumentError(err.Error())
+		}
+		if pkg.name != "" && f.Name.Name != pkg.name {
+			return nil, duerror.NewInvalidArgumentError("more than one package in " + dir)
+		}
+		pkg.name = f.Name.Name
+		pkg.files = append(pkg.files, f)
+	}
+	if len(pkg.files) == 0 {
+		return nil, duerror.NewInvalidArgumentError("no Go files in " + dir)
+	}
+
+	pkg.info = &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	conf := types.Config{
+		Importer:         newStubImporter(pkg.files),
+		IgnoreFuncBodies: true,
+		FakeImportC:      true,
+		Error:            func(error) {},
+	}
+	// the errors were reported to conf.Error, and ignored
+	pkg.types, _ = conf.Check(pkg.name, fset, pkg.files, pkg.info)
+	pkg.collectDecls()
+	return pkg, nil
+}
+
+// collectDecls finds the structs and interfaces, and the methods declared on them
+func (pkg *goPackage) collectDecls() {
+	for _, f := range pkg.files {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				switch ts.Type.(type) {
+				case *ast.StructType, *ast.InterfaceType:
+					if !ts.Assign.IsValid() {
+						pkg.decls = append(pkg.decls, &typeDecl{name: ts.Name.Name, spec: ts})
+					}
+				}
+			}
+		}
+	}
+	for _, f := range pkg.files {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || len(fn.Recv.List) == 0 {
+				continue
+			}
+			if d := pkg.decl(pkg.localName(fn.Recv.List[0].Type)); d != nil {
+				d.methods = append(d.methods, fn)
+			}
+		}
+	}
+}
+
+// majorVersion matches the last element of an import path like "github.com/x/y/v2"
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// stubImporter stands in for every imported package. Each name the files use from it is declared as
+// a distinct empty struct type, so that signatures still tell io.Reader from io.Writer.
+type stubImporter struct {
+	names    map[string]map[string]bool // by import path
+	packages map[string]*types.Package
+}
+
+func newStubImporter(files []*ast.File) *stubImporter {
+	imp := &stubImporter{names: make(map[string]map[string]bool), packages: make(map[string]*types.Package)}
+	for _, f := range files {
+		// the name each import is known by in this file
+		local := make(map[string]string)
+		for _, spec := range f.Imports {
+			p, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			name := importName(p)
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			local[name] = p
+			if imp.names[p] == nil {
+				imp.names[p] = make(map[string]bool)
+			}
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if x, ok := sel.X.(*ast.Ident); ok && local[x.Name] != "" {
+					imp.names[local[x.Name]][sel.Sel.Name] = true
+				}
+			}
+			return true
+		})
+	}
+	return imp
+}
+
+func (imp *stubImporter) Import(p string) (*types.Package, error) {
+	if pkg, ok := imp.packages[p]; ok {
+		return pkg, nil
+	}
+	pkg := types.NewPackage(p, importName(p))
+	for name := range imp.names[p] {
+		obj := types.NewTypeName(token.NoPos, pkg, name, nil)
+		types.NewNamed(obj, types.NewStruct(nil, nil), nil)
+		pkg.Scope().Insert(obj)
+	}
+	pkg.MarkComplete()
+	imp.packages[p] = pkg
+	return pkg, nil
+}
+
+// importName guesses the name of a package from its import path
+func importName(p string) string {
+	name := path.Base(p)
+	if majorVersion.MatchString(name) && path.Dir(p) != "." {
+		name = path.Base(path.Dir(p))
+	}
+	return strings.ReplaceAll(name, "-", "_")
+}
@@ -0,0 +1,364 @@
+package gocode
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+	"time"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/component/attribute"
+	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/filedata"
+	"Dr.uml/backend/layout"
+	"Dr.uml/backend/umldiagram"
+	"Dr.uml/backend/utils"
+	"Dr.uml/backend/utils/duerror"
+)
+
+// relationRanks orders the association types, only the first one found from one type to another is kept
+var relationRanks = []component.AssociationType{
+	component.Extension,
+	component.Implementation,
+	component.Composition,
+	component.Dependency,
+}
+
+// ReverseEngineer reads the Go package in dir, as built on this platform and test files aside,
+// into a class diagram called name. Structs become classes and interfaces become interfaces,
+// with their fields and methods as members.
+// Embedding a type of the package becomes an Extension, satisfying one of its interfaces an
+// Implementation, and a field holding one of its types a Composition, or a Dependency when it only
+// refers to it through a pointer, map, channel or function.
+func ReverseEngineer(dir string, name string) (*umldiagram.UMLDiagram, duerror.DUError) {
+	pkg, err := loadPackage(dir)
+	if err != nil {
+		return nil, err
+	}
+	gadgets, err := pkg.gadgets()
+	if err != nil {
+		return nil, err
+	}
+	relations := pkg.relations()
+
+	fd := filedata.Diagram{
+		Name:            name,
+		DiagramType:     int(umldiagram.ClassDiagram),
+		BackgroundColor: drawdata.DefaultDiagramColor,
+		LastModified:    time.Now(),
+		Gadgets:         make([]filedata.Gadget, 0, len(gadgets)),
+		Associations:    make([]filedata.Association, 0, len(relations)),
+	}
+	index := make(map[string]int, len(gadgets))
+	for i, d := range pkg.decls {
+		index[d.name] = i
+		fd.Gadgets = append(fd.Gadgets, gadgets[i])
+	}
+	for _, r := range relations {
+		fd.Associations = append(fd.Associations, filedata.Association{
+			AssType:    int(r.assType),
+			Parents:    [2]int{index[r.from], index[r.to]},
+			Attributes: []filedata.AssAttribute{},
+		})
+	}
+	if err := layout.Layout(&fd, nil); err != nil {
+		return nil, err
+	}
+	return umldiagram.LoadUMLDiagramFromFileData(fd)
+}
+
+// typeDecl is a struct or interface declared in the package, with the methods declared on it
+type typeDecl struct {
+	name    string
+	spec    *ast.TypeSpec
+	methods []*ast.FuncDecl
+}
+
+func (d *typeDecl) isInterface() bool {
+	_, ok := d.spec.Type.(*ast.InterfaceType)
+	return ok
+}
+
+// relation is an association to draw from the type called from to the one called to
+type relation struct {
+	assType  component.AssociationType
+	from, to string
+}
+
+// gadgets builds the gadget of every declaration, at the origin, in the order of pkg.decls
+func (pkg *goPackage) gadgets() ([]filedata.Gadget, duerror.DUError) {
+	gadgets := make([]filedata.Gadget, 0, len(pkg.decls))
+	for _, d := range pkg.decls {
+		gadgetType, section := component.Class, 1
+		if d.isInterface() {
+			// interfaces keep their methods in the last section, after the constants
+			gadgetType, section = component.Interface, 2
+		}
+		g, err := component.NewGadget(gadgetType, utils.Point{}, 0, drawdata.DefaultGadgetColor, typeHeader(d.spec))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range pkg.members(d) {
+			if m.Kind == attribute.Operation && gadgetType == component.Class {
+				section = 2
+			}
+			if err := g.AddAttribute(section, m.String()); err != nil {
+				return nil, err
+			}
+		}
+		gadgets = append(gadgets, g.GetFileData())
+	}
+	return gadgets, nil
+}
+
+// members lists the fields then the methods of d, embedded types of the package are left to the relations
+func (pkg *goPackage) members(d *typeDecl) []attribute.Member {
+	var members []attribute.Member
+	switch t := d.spec.Type.(type) {
+	case *ast.StructType:
+		for _, f := range t.Fields.List {
+			typ := types.ExprString(f.Type)
+			if len(f.Names) == 0 {
+				if pkg.localName(f.Type) != "" {
+					continue
+				}
+				// an embedded field is named after its type
+				name := embeddedName(f.Type)
+				members = append(members, attribute.Member{Kind: attribute.Field, Visibility: visibility(name), Name: name, Type: typ})
+				continue
+			}
+			for _, n := range f.Names {
+				members = append(members, attribute.Member{Kind: attribute.Field, Visibility: visibility(n.Name), Name: n.Name, Type: typ})
+			}
+		}
+		for _, fn := range d.methods {
+			members = append(members, funcMember(fn.Name.Name, fn.Type))
+		}
+	case *ast.InterfaceType:
+		for _, f := range t.Methods.List {
+			if ft, ok := f.Type.(*ast.FuncType); ok && len(f.Names) > 0 {
+				members = append(members, funcMember(f.Names[0].Name, ft))
+			}
+		}
+	}
+	return members
+}
+
+// relations finds the associations among the declarations, at most one from a type to another
+func (pkg *goPackage) relations() []relation {
+	found := make(map[[2]string]component.AssociationType)
+	var order [][2]string
+	add := func(assType component.AssociationType, from, to string) {
+		key := [2]string{from, to}
+		old, ok := found[key]
+		if !ok {
+			order = append(order, key)
+		} else if slices.Index(relationRanks, old) <= slices.Index(relationRanks, assType) {
+			return
+		}
+		found[key] = assType
+	}
+
+	for _, d := range pkg.decls {
+		switch t := d.spec.Type.(type) {
+		case *ast.StructType:
+			for _, f := range t.Fields.List {
+				if embedded := pkg.localName(f.Type); len(f.Names) == 0 && embedded != "" {
+					add(component.Extension, d.name, embedded)
+					continue
+				}
+				if tv, ok := pkg.info.Types[f.Type]; ok {
+					pkg.walkType(tv.Type, true, func(name string, value bool) {
+						if value {
+							add(component.Composition, d.name, name)
+						} else {
+							add(component.Dependency, d.name, name)
+						}
+					})
+				}
+			}
+		case *ast.InterfaceType:
+			for _, f := range t.Methods.List {
+				if embedded := pkg.localName(f.Type); len(f.Names) == 0 && embedded != "" {
+					add(component.Extension, d.name, embedded)
+				}
+			}
+		}
+	}
+
+	for _, d := range pkg.decls {
+		named := pkg.named(d.name)
+		if d.isInterface() || named == nil || named.TypeParams().Len() > 0 {
+			continue
+		}
+		for _, i := range pkg.decls {
+			iNamed := pkg.named(i.name)
+			if !i.isInterface() || iNamed == nil || iNamed.TypeParams().Len() > 0 {
+				continue
+			}
+			iface, ok := iNamed.Underlying().(*types.Interface)
+			// everything satisfies an empty interface, that says nothing
+			if !ok || iface.NumMethods() == 0 {
+				continue
+			}
+			if types.Implements(named, iface) || types.Implements(types.NewPointer(named), iface) {
+				add(component.Implementation, d.name, i.name)
+			}
+		}
+	}
+
+	relations := make([]relation, 0, len(order))
+	for _, key := range order {
+		r := relation{assType: found[key], from: key[0], to: key[1]}
+		if r.assType == component.Composition {
+			// the diamond is at the end, on the whole
+			r.from, r.to = r.to, r.from
+		}
+		relations = append(relations, r)
+	}
+	return relations
+}
+
+// walkType calls visit for every declaration of the package t refers to. value tells whether t holds it
+// by value, directly or in a slice or an array, rather than through a pointer, map, channel or function.
+func (pkg *goPackage) walkType(t types.Type, value bool, visit func(name string, value bool)) {
+	switch t := types.Unalias(t).(type) {
+	case *types.Named:
+		if obj := t.Obj(); obj.Pkg() == pkg.types && pkg.decl(obj.Name()) != nil {
+			visit(obj.Name(), value)
+		}
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			pkg.walkType(t.TypeArgs().At(i), false, visit)
+		}
+	case *types.Pointer:
+		pkg.walkType(t.Elem(), false, visit)
+	case *types.Slice:
+		pkg.walkType(t.Elem(), value, visit)
+	case *types.Array:
+		pkg.walkType(t.Elem(), value, visit)
+	case *types.Map:
+		pkg.walkType(t.Key(), false, visit)
+		pkg.walkType(t.Elem(), false, visit)
+	case *types.Chan:
+		pkg.walkType(t.Elem(), false, visit)
+	case *types.Signature:
+		for _, tuple := range []*types.Tuple{t.Params(), t.Results()} {
+			for i := 0; i < tuple.Len(); i++ {
+				pkg.walkType(tuple.At(i).Type(), false, visit)
+			}
+		}
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			pkg.walkType(t.Field(i).Type(), value, visit)
+		}
+	}
+}
+
+// localName gives the declaration of the package expr names, through pointers and type arguments, or ""
+func (pkg *goPackage) localName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return pkg.localName(e.X)
+	case *ast.IndexExpr:
+		return pkg.localName(e.X)
+	case *ast.IndexListExpr:
+		return pkg.localName(e.X)
+	case *ast.Ident:
+		if pkg.decl(e.Name) != nil {
+			return e.Name
+		}
+	}
+	return ""
+}
+
+func (pkg *goPackage) decl(name string) *typeDecl {
+	i := slices.IndexFunc(pkg.decls, func(d *typeDecl) bool { return d.name == name })
+	if i < 0 {
+		return nil
+	}
+	return pkg.decls[i]
+}
+
+func (pkg *goPackage) named(name string) *types.Named {
+	obj, ok := pkg.types.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	named, _ := obj.Type().(*types.Named)
+	return named
+}
+
+// typeHeader is the name of the type, with its type parameters if it has any
+func typeHeader(spec *ast.TypeSpec) string {
+	if spec.TypeParams == nil || len(spec.TypeParams.List) == 0 {
+		return spec.Name.Name
+	}
+	return spec.Name.Name + "[" + fieldList(spec.TypeParams) + "]"
+}
+
+// embeddedName is the name of the field an embedded type gives
+func embeddedName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(e.X)
+	case *ast.IndexExpr:
+		return embeddedName(e.X)
+	case *ast.IndexListExpr:
+		return embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.Ident:
+		return e.Name
+	}
+	return types.ExprString(expr)
+}
+
+// visibility maps Go's exported names to public and the others to package private
+func visibility(name string) attribute.Visibility {
+	if token.IsExported(name) {
+		return attribute.Public
+	}
+	return attribute.PackagePrivate
+}
+
+// funcMember is the operation of a method, unnamed parameters are called _
+func funcMember(name string, ft *ast.FuncType) attribute.Member {
+	m := attribute.Member{Kind: attribute.Operation, Visibility: visibility(name), Name: name, Parameters: []attribute.Parameter{}}
+	for _, f := range ft.Params.List {
+		typ := types.ExprString(f.Type)
+		if len(f.Names) == 0 {
+			m.Parameters = append(m.Parameters, attribute.Parameter{Name: "_", Type: typ})
+		}
+		for _, n := range f.Names {
+			m.Parameters = append(m.Parameters, attribute.Parameter{Name: n.Name, Type: typ})
+		}
+	}
+	if ft.Results != nil && len(ft.Results.List) > 0 {
+		if len(ft.Results.List) == 1 && len(ft.Results.List[0].Names) == 0 {
+			m.Type = types.ExprString(ft.Results.List[0].Type)
+		} else {
+			m.Type = "(" + fieldList(ft.Results) + ")"
+		}
+	}
+	return m
+}
+
+// fieldList writes fields the way they are declared, as in "a, b int, err error"
+func fieldList(fl *ast.FieldList) string {
+	parts := make([]string, 0, len(fl.List))
+	for _, f := range fl.List {
+		typ := types.ExprString(f.Type)
+		if len(f.Names) == 0 {
+			parts = append(parts, typ)
+			continue
+		}
+		names := make([]string, 0, len(f.Names))
+		for _, n := range f.Names {
+			names = append(names, n.Name)
+		}
+		parts = append(parts, strings.Join(names, ", ")+" "+typ)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,178 @@
+package gocode
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/filedata"
+	"github.com/stretchr/testify/assert"
+)
+
+const shapesSource = `package shapes
+
+import (
+	"fmt"
+	"io"
+)
+
+// Shape is drawn on a canvas
+type Shape interface {
+	Area() float64
+	Draw(w io.Writer) error
+}
+
+type Named interface {
+	Shape
+	Name() string
+}
+
+type Point struct{ X, Y int }
+
+type Base struct {
+	id   string
+	Tags map[string]string
+}
+
+type Circle struct {
+	Base
+	Center Point
+	Radius float64
+	next   *Circle
+	io.Writer
+}
+
+func (c *Circle) Area() float64 { return 0 }
+
+func (c Circle) Draw(w io.Writer) error {
+	fmt.Fprintln(w, "circle")
+	return nil
+}
+
+func (c *Circle) Name() string { return "circle" }
+
+type Square struct {
+	Corners [4]Point
+	Style   *Style
+}
+
+func (s Square) Area() float64 { return 0 }
+
+// Draw reads, so a square is not a Shape
+func (s Square) Draw(r io.Reader) error { return nil }
+
+type Style struct{ Color string }
+
+type Stack[T any] struct{ items []T }
+
+func (s *Stack[T]) Push(v T) {}
+
+func (s *Stack[T]) Pop() (v T, ok bool) { return }
+
+type ID int
+`
+
+// writePackage writes files, by name, into a new directory
+func writePackage(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+// contents lists the contents of a section of the gadget with header
+func contents(fd filedata.Diagram, header string, section int) []string {
+	for _, g := range fd.Gadgets {
+		if g.Attributes[0][0].Content == header {
+			list := []string{}
+			for _, att := range g.Attributes[section] {
+				list = append(list, att.Content)
+			}
+			return list
+		}
+	}
+	return nil
+}
+
+// relations writes each association as "start type end"
+func relations(fd filedata.Diagram) []string {
+	names := map[component.AssociationType]string{
+		component.Extension:      "extends",
+		component.Implementation: "implements",
+		component.Composition:    "composes",
+		component.Dependency:     "depends on",
+	}
+	list := []string{}
+	for _, a := range fd.Associations {
+		st := fd.Gadgets[a.Parents[0]].Attributes[0][0].Content
+		en := fd.Gadgets[a.Parents[1]].Attributes[0][0].Content
+		list = append(list, fmt.Sprintf("%s %s %s", st, names[component.AssociationType(a.AssType)], en))
+	}
+	return list
+}
+
+func TestReverseEngineer(t *testing.T) {
+	dir := writePackage(t, map[string]string{
+		"shapes.go":      shapesSource,
+		"shapes_test.go": "package shapes_test\n",
+	})
+	diagram, err := ReverseEngineer(dir, "shapes")
+	assert.NoError(t, err)
+	assert.Equal(t, "shapes", diagram.GetName())
+	fd, err := diagram.GetFileData()
+	assert.NoError(t, err)
+	assert.Len(t, fd.Gadgets, 8)
+
+	assert.Equal(t, int(component.Interface), fd.Gadgets[0].GadgetType)
+	assert.Equal(t, []string{"+ Area(): float64", "+ Draw(w: io.Writer): error"}, contents(fd, "Shape", 2))
+	assert.Equal(t, []string{"+ Name(): string"}, contents(fd, "Named", 2))
+	assert.Equal(t, []string{"+ X: int", "+ Y: int"}, contents(fd, "Point", 1))
+	assert.Equal(t, []string{"+ Center: Point", "+ Radius: float64", "~ next: *Circle", "+ Writer: io.Writer"}, contents(fd, "Circle", 1))
+	assert.Equal(t, []string{"+ Area(): float64", "+ Draw(w: io.Writer): error", "+ Name(): string"}, contents(fd, "Circle", 2))
+	assert.Equal(t, []string{"~ items: []T"}, contents(fd, "Stack[T any]", 1))
+	assert.Equal(t, []string{"+ Push(v: T)", "+ Pop(): (v T, ok bool)"}, contents(fd, "Stack[T any]", 2))
+
+	assert.ElementsMatch(t, []string{
+		"Named extends Shape",
+		"Circle extends Base",
+		"Point composes Circle",
+		"Circle depends on Circle",
+		"Point composes Square",
+		"Square depends on Style",
+		"Circle implements Shape",
+		"Circle implements Named",
+	}, relations(fd))
+
+	// the ones a type extends or implements are above it
+	y := func(header string) int {
+		for _, g := range fd.Gadgets {
+			if g.Attributes[0][0].Content == header {
+				return g.Y
+			}
+		}
+		return -1
+	}
+	assert.Less(t, y("Shape"), y("Named"))
+	assert.Less(t, y("Named"), y("Circle"))
+	assert.Less(t, y("Base"), y("Circle"))
+	assert.Equal(t, y("Shape"), y("Style"))
+}
+
+func TestReverseEngineer_Errors(t *testing.T) {
+	_, err := ReverseEngineer(filepath.Join(t.TempDir(), "missing"), "missing")
+	assert.Error(t, err)
+	_, err = ReverseEngineer(writePackage(t, map[string]string{"README.md": "# empty"}), "empty")
+	assert.Error(t, err)
+	_, err = ReverseEngineer(writePackage(t, map[string]string{"a.go": "package a\n", "b.go": "package b\n"}), "ab")
+	assert.Error(t, err)
+	_, err = ReverseEngineer(writePackage(t, map[string]string{"a.go": "package a\nfunc {"}), "broken")
+	assert.Error(t, err)
+
+	// unresolved types do not stop it
+	diagram, err := ReverseEngineer(writePackage(t, map[string]string{"a.go": "package a\ntype A struct{ b Missing }\n"}), "a")
+	assert.NoError(t, err)
+	assert.Len(t, diagram.GetDrawData().Gadgets, 1)
+}
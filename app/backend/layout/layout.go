@@ -0,0 +1,118 @@
+package layout
+
+import (
+	"maps"
+	"slices"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/filedata"
+	"Dr.uml/backend/utils"
+	"Dr.uml/backend/utils/duerror"
+)
+
+// gaps between the gadgets, rows are further apart to leave room for the associations
+const (
+	GapX = 60
+	GapY = 100
+)
+
+// Layout places the gadgets of a diagram that has no positions yet, such as one read from source
+// or from a text format. Gadgets go in rows, every gadget below the ones it extends or implements,
+// and the ends of every association go on the sides of its gadgets that face each other.
+// Only the gadgets not placed are moved, placed may be nil when none are. An association between
+// placed gadgets keeps its ends, unless they are left at zero.
+func Layout(fd *filedata.Diagram, placed []bool) duerror.DUError {
+	sizes := make([]utils.Point, len(fd.Gadgets))
+	for i, gfd := range fd.Gadgets {
+		g, err := component.NewGadgetFromFileData(gfd)
+		if err != nil {
+			return err
+		}
+		bounds := g.GetBounds()
+		sizes[i] = utils.SubPoints(bounds.Max, bounds.Min)
+	}
+	isPlaced := func(i int) bool { return i < len(placed) && placed[i] }
+
+	parents := make(map[int][]int)
+	for _, a := range fd.Associations {
+		st, en := a.Parents[0], a.Parents[1]
+		assType := component.AssociationType(a.AssType)
+		if (assType == component.Extension || assType == component.Implementation) && st != en {
+			parents[st] = append(parents[st], en)
+		}
+	}
+	ranks := make(map[int]int)
+	var rank func(i int, visiting map[int]bool) int
+	rank = func(i int, visiting map[int]bool) int {
+		if r, ok := ranks[i]; ok {
+			return r
+		}
+		// a diagram can go round in circles
+		if visiting[i] {
+			return 0
+		}
+		visiting[i] = true
+		r := 0
+		for _, p := range parents[i] {
+			r = max(r, rank(p, visiting)+1)
+		}
+		delete(visiting, i)
+		ranks[i] = r
+		return r
+	}
+
+	// the rows start under the gadgets already placed
+	top := 0
+	for i, gfd := range fd.Gadgets {
+		if isPlaced(i) {
+			top = max(top, gfd.Y+sizes[i].Y+GapY)
+		}
+	}
+	rows := make(map[int][]int)
+	for i := range fd.Gadgets {
+		if !isPlaced(i) {
+			r := rank(i, make(map[int]bool))
+			rows[r] = append(rows[r], i)
+		}
+	}
+	// ranks can be missing when their gadgets are all placed
+	y := top
+	for _, r := range slices.Sorted(maps.Keys(rows)) {
+		x, height := 0, 0
+		for _, i := range rows[r] {
+			fd.Gadgets[i].X, fd.Gadgets[i].Y = x, y
+			x += sizes[i].X + GapX
+			height = max(height, sizes[i].Y)
+		}
+		y += height + GapY
+	}
+
+	for i := range fd.Associations {
+		a := &fd.Associations[i]
+		st, en := a.Parents[0], a.Parents[1]
+		if isPlaced(st) && isPlaced(en) && (a.StartRatio != [2]float64{} || a.EndRatio != [2]float64{}) {
+			continue
+		}
+		a.StartRatio, a.EndRatio = facingRatios(fd.Gadgets[st], sizes[st], fd.Gadgets[en], sizes[en], st == en)
+	}
+	return nil
+}
+
+// facingRatios puts the ends of an association on the sides of st and en that face each other
+func facingRatios(st filedata.Gadget, stSize utils.Point, en filedata.Gadget, enSize utils.Point, self bool) ([2]float64, [2]float64) {
+	if self {
+		return [2]float64{1, 0.5}, [2]float64{0.5, 1}
+	}
+	dx := (en.X + enSize.X/2) - (st.X + stSize.X/2)
+	dy := (en.Y + enSize.Y/2) - (st.Y + stSize.Y/2)
+	if utils.AbsInt(dy) >= utils.AbsInt(dx) {
+		if dy > 0 {
+			return [2]float64{0.5, 1}, [2]float64{0.5, 0}
+		}
+		return [2]float64{0.5, 0}, [2]float64{0.5, 1}
+	}
+	if dx > 0 {
+		return [2]float64{1, 0.5}, [2]float64{0, 0.5}
+	}
+	return [2]float64{0, 0.5}, [2]float64{1, 0.5}
+}
@@ -0,0 +1,90 @@
+package layout
+
+import (
+	"testing"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/filedata"
+	"github.com/stretchr/testify/assert"
+)
+
+func class(header string) filedata.Gadget {
+	return filedata.Gadget{
+		GadgetType: int(component.Class),
+		Attributes: [][]filedata.Attribute{{{Content: header, Size: 12}}, {}, {}},
+	}
+}
+
+func TestLayout(t *testing.T) {
+	fd := filedata.Diagram{
+		Gadgets: []filedata.Gadget{class("Dog"), class("Animal"), class("Bone"), class("Living")},
+		Associations: []filedata.Association{
+			{AssType: int(component.Extension), Parents: [2]int{0, 1}},
+			{AssType: int(component.Implementation), Parents: [2]int{1, 3}},
+			{AssType: int(component.Composition), Parents: [2]int{2, 0}},
+			{AssType: int(component.Dependency), Parents: [2]int{2, 2}},
+		},
+	}
+	assert.NoError(t, Layout(&fd, nil))
+
+	// parents above their children, unrelated gadgets on the top row side by side
+	assert.Equal(t, 0, fd.Gadgets[3].Y)
+	assert.Equal(t, 0, fd.Gadgets[2].Y)
+	assert.Greater(t, fd.Gadgets[3].X, fd.Gadgets[2].X)
+	assert.Greater(t, fd.Gadgets[1].Y, fd.Gadgets[3].Y)
+	assert.Greater(t, fd.Gadgets[0].Y, fd.Gadgets[1].Y)
+
+	// the child leaves from its top to the bottom of its parent
+	assert.Equal(t, [2]float64{0.5, 0}, fd.Associations[0].StartRatio)
+	assert.Equal(t, [2]float64{0.5, 1}, fd.Associations[0].EndRatio)
+	assert.Equal(t, [2]float64{1, 0.5}, fd.Associations[3].StartRatio)
+	assert.Equal(t, [2]float64{0.5, 1}, fd.Associations[3].EndRatio)
+}
+
+func TestLayout_Placed(t *testing.T) {
+	placedGadget := class("Placed")
+	placedGadget.X, placedGadget.Y = 20, 40
+	fd := filedata.Diagram{
+		Gadgets: []filedata.Gadget{placedGadget, class("New")},
+		Associations: []filedata.Association{
+			{AssType: int(component.Extension), Parents: [2]int{1, 0}},
+		},
+	}
+	assert.NoError(t, Layout(&fd, []bool{true}))
+	assert.Equal(t, 20, fd.Gadgets[0].X)
+	assert.Equal(t, 40, fd.Gadgets[0].Y)
+	// the new gadget goes below the placed ones
+	assert.Greater(t, fd.Gadgets[1].Y, 40+GapY)
+	assert.Equal(t, [2]float64{0.5, 0}, fd.Associations[0].StartRatio)
+
+	// between placed gadgets, ends that are set stay and the others face each other
+	other := class("Other")
+	other.X, other.Y = 400, 40
+	fd = filedata.Diagram{
+		Gadgets: []filedata.Gadget{placedGadget, other},
+		Associations: []filedata.Association{
+			{AssType: int(component.Dependency), Parents: [2]int{0, 1}, StartRatio: [2]float64{0.5, 1}, EndRatio: [2]float64{0.5, 1}},
+			{AssType: int(component.Dependency), Parents: [2]int{0, 1}},
+		},
+	}
+	assert.NoError(t, Layout(&fd, []bool{true, true}))
+	assert.Equal(t, [2]float64{0.5, 1}, fd.Associations[0].StartRatio)
+	assert.Equal(t, [2]float64{1, 0.5}, fd.Associations[1].StartRatio)
+	assert.Equal(t, [2]float64{0, 0.5}, fd.Associations[1].EndRatio)
+}
+
+func TestLayout_Cycle(t *testing.T) {
+	fd := filedata.Diagram{
+		Gadgets: []filedata.Gadget{class("A"), class("B")},
+		Associations: []filedata.Association{
+			{AssType: int(component.Extension), Parents: [2]int{0, 1}},
+			{AssType: int(component.Extension), Parents: [2]int{1, 0}},
+		},
+	}
+	assert.NoError(t, Layout(&fd, nil))
+}
+
+func TestLayout_InvalidGadget(t *testing.T) {
+	fd := filedata.Diagram{Gadgets: []filedata.Gadget{{GadgetType: int(component.Class)}}}
+	assert.Error(t, Layout(&fd, nil))
+}
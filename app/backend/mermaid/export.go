@@ -0,0 +1,282 @@
+package mermaid
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/component/attribute"
+	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/filedata"
+	"Dr.uml/backend/umldiagram"
+	"Dr.uml/backend/utils/duerror"
+)
+
+// arrows is the Mermaid arrow of each association type, drawn from its start to its end
+var arrows = map[component.AssociationType]string{
+	component.Extension:           "--|>",
+	component.Implementation:      "..|>",
+	component.Composition:         "--*",
+	component.Aggregation:         "--o",
+	component.Dependency:          "..>",
+	component.DirectedAssociation: "-->",
+	component.PlainAssociation:    "--",
+	component.AssociationClass:    "--",
+}
+
+// Export writes a class diagram as a Mermaid classDiagram. Mermaid places the gadgets itself, where
+// they are is kept in comments for Import, along with the rest of the associations Mermaid cannot show.
+// The warnings tell what is left out for good.
+func Export(fd filedata.Diagram) (string, []Warning, duerror.DUError) {
+	if umldiagram.DiagramType(fd.DiagramType) != umldiagram.ClassDiagram {
+		return "", nil, duerror.NewInvalidArgumentError("only class diagrams can be exported to Mermaid")
+	}
+	ex := exporter{warnings: []Warning{}, used: make(map[string]bool)}
+	if fd.Name != "" {
+		title, _ := json.Marshal(fd.Name)
+		fmt.Fprintf(&ex.sb, "---\ntitle: %s\n---\n", title)
+	}
+	ex.sb.WriteString("classDiagram\n")
+	if fd.BackgroundColor != "" && !strings.EqualFold(fd.BackgroundColor, drawdata.DefaultDiagramColor) {
+		ex.extra(diagramExtra{BackgroundColor: fd.BackgroundColor})
+	}
+
+	ids := make([]string, len(fd.Gadgets))
+	headers := make([]string, len(fd.Gadgets))
+	for i, g := range fd.Gadgets {
+		if len(g.Attributes) > 0 && len(g.Attributes[0]) > 0 {
+			headers[i] = g.Attributes[0][0].Content
+		}
+		ids[i] = ex.gadget(g, headers[i], i)
+	}
+	if len(fd.Associations) > 0 {
+		ex.sb.WriteString("\n")
+	}
+	for _, a := range fd.Associations {
+		if err := ex.association(a, ids, headers); err != nil {
+			return "", nil, err
+		}
+	}
+	return ex.sb.String(), ex.warnings, nil
+}
+
+type exporter struct {
+	sb       strings.Builder
+	used     map[string]bool // the names given so far
+	warnings []Warning
+}
+
+func (ex *exporter) warn(format string, args ...any) {
+	ex.warnings = append(ex.warnings, Warning{Message: fmt.Sprintf(format, args...)})
+}
+
+// extra writes the comment holding v, unless there is nothing in it
+func (ex *exporter) extra(v any) {
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "{}" {
+		return
+	}
+	ex.sb.WriteString(extraPrefix + string(data) + "\n")
+}
+
+// gadget writes the i-th gadget and returns the name relations refer to it by,
+// which is empty for notes and packages, Mermaid cannot relate them
+func (ex *exporter) gadget(g filedata.Gadget, header string, i int) string {
+	gadgetType := component.GadgetType(g.GadgetType)
+	position := gadgetExtra{X: g.X, Y: g.Y, Layer: g.Layer}
+	color := ""
+	if g.Color != "" && !strings.EqualFold(g.Color, drawdata.DefaultGadgetColor) {
+		color = g.Color
+	}
+
+	switch gadgetType {
+	case component.Note:
+		lines := []string{}
+		for _, att := range g.Attributes[0] {
+			lines = append(lines, strings.ReplaceAll(att.Content, `"`, "'"))
+		}
+		fmt.Fprintf(&ex.sb, "note \"%s\"\n", strings.Join(lines, `\n`))
+		position.Color = color
+		ex.extra(position)
+		return ""
+	case component.Package:
+		ex.warn("package %s is left out along with its contents, Mermaid has no packages", header)
+		return ""
+	}
+	if len(g.Attributes) > 0 && len(g.Attributes[0]) > 1 {
+		ex.warn("the header of %s has more than one line, the others are left out", header)
+	}
+
+	id, name := ex.name(header, i)
+	var lines []string
+	if annotation, ok := annotations[gadgetType]; ok {
+		lines = append(lines, "<<"+annotation+">>")
+	}
+	for section, atts := range g.Attributes[1:] {
+		for _, att := range atts {
+			if gadgetType == component.Enumeration {
+				lines = append(lines, att.Content)
+				continue
+			}
+			content, kind := member(att)
+			switch {
+			case section == 0 && kind == attribute.Operation:
+				ex.warn("%q in the fields of %s is read back as a method", att.Content, header)
+			case section == 1 && kind == attribute.Field:
+				ex.warn("%q in the methods of %s is read back as a field", att.Content, header)
+			}
+			lines = append(lines, content)
+		}
+	}
+	if len(lines) == 0 {
+		fmt.Fprintf(&ex.sb, "class %s\n", name)
+	} else {
+		fmt.Fprintf(&ex.sb, "class %s {\n", name)
+		for _, line := range lines {
+			fmt.Fprintf(&ex.sb, "    %s\n", line)
+		}
+		ex.sb.WriteString("}\n")
+	}
+	ex.extra(position)
+	if color != "" {
+		fmt.Fprintf(&ex.sb, "style %s fill:%s\n", id, color)
+	}
+	return id
+}
+
+// name is how a gadget with header is declared, and the name relations refer to it by.
+// Headers Mermaid cannot take as they are, or taken already, are given a name and shown as a label.
+func (ex *exporter) name(header string, i int) (id, declared string) {
+	if plainName.MatchString(header) {
+		id, _, _ = strings.Cut(header, "<")
+		if !ex.used[id] {
+			ex.used[id] = true
+			return id, genericsOf(header)
+		}
+	}
+	id = fmt.Sprintf("G%d", i+1)
+	for ex.used[id] {
+		id += "_"
+	}
+	ex.used[id] = true
+	return id, fmt.Sprintf(`%s["%s"]`, id, strings.ReplaceAll(header, `"`, "'"))
+}
+
+// member writes an attribute of a member section the way Mermaid shows members, as "+count: int$"
+// or "+Area() float64*", with the classifiers its style stands for. What cannot be parsed is written
+// as it is, Mermaid takes it for a method when it has parentheses.
+func member(att filedata.Attribute) (string, attribute.MemberKind) {
+	m, err := attribute.ParseMember(att.Content)
+	if err != nil {
+		if strings.Contains(att.Content, "(") {
+			return att.Content, attribute.Operation
+		}
+		return att.Content, attribute.Field
+	}
+	static := m.Static || att.Style&attribute.Underline != 0
+	abstract := m.Kind == attribute.Operation && (m.Abstract || att.Style&attribute.Italic != 0)
+	m.Static, m.Abstract = false, false
+	result := ""
+	if m.Kind == attribute.Operation {
+		result, m.Type = m.Type, ""
+	}
+	content := m.String()
+	if m.Visibility != attribute.VisibilityNone {
+		// Mermaid reads the visibility from the first character
+		content = content[:1] + strings.TrimPrefix(content[1:], " ")
+	}
+	if result != "" {
+		content += " " + result
+	}
+	content = genericsOf(content)
+	if abstract {
+		content += "*"
+	}
+	if static {
+		content += "$"
+	}
+	return content, m.Kind
+}
+
+// association writes a relation between gadgets named ids, and the comment with what its arrow and labels do not tell
+func (ex *exporter) association(a filedata.Association, ids, headers []string) duerror.DUError {
+	assType := component.AssociationType(a.AssType)
+	arrow, ok := arrows[assType]
+	if !ok {
+		return duerror.NewInvalidArgumentError("unsupported association type")
+	}
+	for _, p := range a.Parents {
+		if p < 0 || p >= len(ids) {
+			return duerror.NewInvalidArgumentError("association parent out of range")
+		}
+	}
+	if a.Class != nil && (*a.Class < 0 || *a.Class >= len(ids)) {
+		return duerror.NewInvalidArgumentError("association class out of range")
+	}
+	start, end := ids[a.Parents[0]], ids[a.Parents[1]]
+	if start == "" || end == "" {
+		ex.warn("the association from %s to %s is left out, Mermaid relates classes only", headers[a.Parents[0]], headers[a.Parents[1]])
+		return nil
+	}
+
+	if component.Navigability(a.Navigability[0]) == component.Navigable {
+		arrow = "<" + arrow
+	}
+	if component.Navigability(a.Navigability[1]) == component.Navigable && !strings.HasSuffix(arrow, ">") {
+		arrow += ">"
+	}
+	extra := relationExtra{
+		Layer:            a.Layer,
+		Waypoints:        a.Waypoints,
+		Routing:          a.Routing,
+		StartRole:        a.Labels.StartRole,
+		EndRole:          a.Labels.EndRole,
+		ReadingDirection: a.Labels.ReadingDirection,
+		Attributes:       a.Attributes,
+	}
+	// Mermaid has no crosses, and some arrows are read back as another association
+	m := arrowLine.FindStringSubmatch(arrow)
+	r := arrowRelation(m[1], m[3], m[2] == "..", func(string, ...any) {})
+	if r.assType != assType || r.parents != [2]int{0, 1} ||
+		[2]int{int(r.navigability[0]), int(r.navigability[1])} != a.Navigability {
+		extra.AssType, extra.Navigability = a.AssType, &a.Navigability
+	}
+	if a.StartRatio != [2]float64{} || a.EndRatio != [2]float64{} {
+		extra.StartRatio, extra.EndRatio = &a.StartRatio, &a.EndRatio
+	}
+	if a.Class != nil {
+		extra.Class = ids[*a.Class]
+	}
+
+	line := start
+	if label := cardinality(a.Labels.StartMultiplicity, a.Labels.StartRole, a.Attributes, true); label != "" {
+		line += ` "` + label + `"`
+	}
+	line += " " + arrow
+	if label := cardinality(a.Labels.EndMultiplicity, a.Labels.EndRole, a.Attributes, false); label != "" {
+		line += ` "` + label + `"`
+	}
+	line += " " + end
+	if a.Labels.Name != "" {
+		line += " : " + a.Labels.Name
+	}
+	ex.sb.WriteString(line + "\n")
+	ex.extra(extra)
+	return nil
+}
+
+// cardinality is the label Mermaid shows at an end of a relation. It is the multiplicity of the end,
+// or its role when it has none, or else the first text on the half of the association by the end.
+func cardinality(multiplicity, role string, atts []filedata.AssAttribute, atStart bool) string {
+	label := multiplicity
+	if label == "" {
+		label = role
+	}
+	for _, att := range atts {
+		if label == "" && (att.Ratio < 0.5) == atStart {
+			label = att.Content
+		}
+	}
+	return strings.ReplaceAll(label, `"`, "'")
+}
@@ -0,0 +1,189 @@
+package mermaid
+
+import (
+	"testing"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/component/attribute"
+	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/filedata"
+	"Dr.uml/backend/umldiagram"
+	"github.com/stretchr/testify/assert"
+)
+
+// gadget builds a gadget of gadgetType whose sections hold the given contents
+func gadget(gadgetType component.GadgetType, sections ...[]string) filedata.Gadget {
+	g := filedata.Gadget{GadgetType: int(gadgetType), Color: drawdata.DefaultGadgetColor}
+	for _, contents := range sections {
+		atts := []filedata.Attribute{}
+		for _, content := range contents {
+			atts = append(atts, filedata.Attribute{Content: content, Size: 12})
+		}
+		g.Attributes = append(g.Attributes, atts)
+	}
+	return g
+}
+
+func TestExport(t *testing.T) {
+	stack := gadget(component.Class, []string{"Stack<T>"}, nil, []string{"+ Push(v: T)", "Pop(): T"})
+	stack.Color = "#FFCC00"
+	stack.X, stack.Y = 300, 40
+	shape := gadget(component.AbstractClass, []string{"Shape"}, []string{"count: int", "- ids: List<int>"}, []string{"+ Area(scale: int): float64"})
+	shape.Attributes[1][0].Style = attribute.Underline
+	shape.Attributes[2][0].Style = attribute.Italic
+	note := gadget(component.Note, []string{"round", "things"})
+	note.Color, note.Layer = "#FFFFAA", 2
+	class := 5
+	fd := filedata.Diagram{
+		Name:            "Shapes",
+		DiagramType:     int(umldiagram.ClassDiagram),
+		BackgroundColor: "#EEEEEE",
+		Gadgets: []filedata.Gadget{
+			shape,
+			gadget(component.Class, []string{"Circle"}, []string{"radius: float64"}, nil),
+			gadget(component.Interface, []string{"Drawable"}, nil, []string{"Draw()"}),
+			gadget(component.Enumeration, []string{"Color"}, []string{"Red", "Green = 2"}),
+			stack,
+			gadget(component.Class, []string{"Circle"}, nil, nil),
+			note,
+		},
+		Associations: []filedata.Association{
+			{AssType: component.Extension, Parents: [2]int{1, 0}, StartRatio: [2]float64{0.5, 0}, EndRatio: [2]float64{0.5, 1}},
+			{AssType: component.Implementation, Parents: [2]int{0, 2}},
+			{AssType: component.Composition, Parents: [2]int{3, 1}, Navigability: [2]int{int(component.Navigable), 0},
+				Labels: filedata.AssLabels{StartMultiplicity: "1", EndMultiplicity: "0..*", Name: "fills", ReadingDirection: int(component.ReadingTowardStart)}},
+			{AssType: component.Dependency, Parents: [2]int{4, 3}, Attributes: []filedata.AssAttribute{{Content: "uses", Size: 12, Ratio: 0.3}}},
+			{AssType: component.AssociationClass, Parents: [2]int{1, 4}, Class: &class,
+				Labels: filedata.AssLabels{StartRole: "shape", EndRole: "store"}},
+			{AssType: component.PlainAssociation, Parents: [2]int{1, 2}, Navigability: [2]int{int(component.NonNavigable), int(component.Navigable)}},
+			{AssType: component.Aggregation, Parents: [2]int{2, 4}},
+			{AssType: component.DirectedAssociation, Parents: [2]int{5, 1}},
+		},
+	}
+	got, warnings, err := Export(fd)
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.Equal(t, `---
+title: "Shapes"
+---
+classDiagram
+%% dr.uml {"backgroundColor":"#EEEEEE"}
+class Shape {
+    <<abstract>>
+    count: int$
+    -ids: List~int~
+    +Area(scale: int) float64*
+}
+%% dr.uml {"x":0,"y":0}
+class Circle {
+    radius: float64
+}
+%% dr.uml {"x":0,"y":0}
+class Drawable {
+    <<interface>>
+    Draw()
+}
+%% dr.uml {"x":0,"y":0}
+class Color {
+    <<enumeration>>
+    Red
+    Green = 2
+}
+%% dr.uml {"x":0,"y":0}
+class Stack~T~ {
+    +Push(v: T)
+    Pop() T
+}
+%% dr.uml {"x":300,"y":40}
+style Stack fill:#FFCC00
+class G6["Circle"]
+%% dr.uml {"x":0,"y":0}
+note "round\nthings"
+%% dr.uml {"x":0,"y":0,"layer":2,"color":"#FFFFAA"}
+
+Circle --|> Shape
+%% dr.uml {"startRatio":[0.5,0],"endRatio":[0.5,1]}
+Shape ..|> Drawable
+Color "1" <--* "0..*" Circle : fills
+%% dr.uml {"readingDirection":2}
+Stack "uses" ..> Color
+%% dr.uml {"attributes":[{"content":"uses","size":12,"style":0,"fontFile":"","ratio":0.3}]}
+Circle "shape" -- "store" Stack
+%% dr.uml {"assType":128,"navigability":[0,0],"class":"G6","startRole":"shape","endRole":"store"}
+Circle --> Drawable
+%% dr.uml {"assType":16,"navigability":[2,1]}
+Drawable --o Stack
+G6 --> Circle
+`, got)
+}
+
+func TestExport_Warnings(t *testing.T) {
+	fd := filedata.Diagram{
+		DiagramType: int(umldiagram.ClassDiagram),
+		Gadgets: []filedata.Gadget{
+			gadget(component.Class, []string{"A", "second"}, []string{"broken(: int"}, []string{"size"}),
+			gadget(component.Package, []string{"pkg"}, []string{"A and B"}),
+			gadget(component.Note, []string{"remember"}),
+		},
+		Associations: []filedata.Association{
+			{AssType: component.PlainAssociation, Parents: [2]int{0, 1}},
+			{AssType: component.Dependency, Parents: [2]int{2, 0}},
+		},
+	}
+	got, warnings, err := Export(fd)
+	assert.NoError(t, err)
+	assert.Equal(t, "classDiagram\nclass A {\n    broken(: int\n    size\n}\n%% dr.uml {\"x\":0,\"y\":0}\n"+
+		"note \"remember\"\n%% dr.uml {\"x\":0,\"y\":0}\n\n", got)
+	assert.Equal(t, []Warning{
+		{Message: "the header of A has more than one line, the others are left out"},
+		{Message: `"broken(: int" in the fields of A is read back as a method`},
+		{Message: `"size" in the methods of A is read back as a field`},
+		{Message: "package pkg is left out along with its contents, Mermaid has no packages"},
+		{Message: "the association from A to pkg is left out, Mermaid relates classes only"},
+		{Message: "the association from remember to A is left out, Mermaid relates classes only"},
+	}, warnings)
+}
+
+func TestExport_Errors(t *testing.T) {
+	_, _, err := Export(filedata.Diagram{DiagramType: 0})
+	assert.Error(t, err)
+	_, _, err = Export(filedata.Diagram{
+		DiagramType:  int(umldiagram.ClassDiagram),
+		Gadgets:      []filedata.Gadget{gadget(component.Class, []string{"A"}, nil, nil)},
+		Associations: []filedata.Association{{AssType: component.Extension, Parents: [2]int{0, 1}}},
+	})
+	assert.Error(t, err)
+}
+
+// what Mermaid has no syntax for comes back from the comments
+func TestExport_RoundTrip(t *testing.T) {
+	d, _, err := Import(shopSource, "Shop")
+	assert.NoError(t, err)
+	fd, err := d.GetFileData()
+	assert.NoError(t, err)
+	// moved by hand, and a label only a comment keeps
+	fd.Gadgets[0].X, fd.Gadgets[0].Y = 700, 300
+	fd.Associations[0].Labels.StartRole = "items"
+	fd.Associations[0].Attributes = []filedata.AssAttribute{{Content: "owned", Size: 14, Style: attribute.Bold, Ratio: 0.5}}
+	src, warnings, err := Export(fd)
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	again, warnings, err := Import(src, "Shop")
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+	fdAgain, err := again.GetFileData()
+	assert.NoError(t, err)
+
+	strip := func(fd filedata.Diagram) filedata.Diagram {
+		for i := range fd.Gadgets {
+			fd.Gadgets[i].ID = ""
+		}
+		for i := range fd.Associations {
+			fd.Associations[i].ID = ""
+		}
+		fd.LastModified = fdAgain.LastModified
+		return fd
+	}
+	assert.Equal(t, strip(fd), strip(fdAgain))
+}
@@ -0,0 +1,788 @@
+package mermaid
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/component/attribute"
+	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/filedata"
+	"Dr.uml/backend/layout"
+	"Dr.uml/backend/umldiagram"
+	"Dr.uml/backend/utils"
+	"Dr.uml/backend/utils/duerror"
+)
+
+// a name as statements refer to it, in backticks or not, with generics that are left out,
+// and an arrow, with its heads and line
+const (
+	nameToken  = "`[^`]+`|[\\p{L}\\p{N}_]+(?:-[\\p{L}\\p{N}_]+)*"
+	arrowToken = `(<\||\*|o|<|\(\))?(--|\.\.)(\|>|\*|o|>|\(\))?`
+	generics   = `(?:~[^~\s]*~)?`
+)
+
+var (
+	fenceLine      = regexp.MustCompile("(?i)^```\\s*mermaid\\s*$")
+	headerLine     = regexp.MustCompile(`^(classDiagram(?:-v2)?)\s*$`)
+	classLine      = regexp.MustCompile(`^class\s+(` + nameToken + `)(~[^\[{:"]*~)?(?:\s*\["([^"]*)"\])?\s*(:::\s*[\w-]+)?\s*(\{.*)?$`)
+	annotationLine = regexp.MustCompile(`^<<\s*([^<>]+?)\s*>>\s*(` + nameToken + `)?$`)
+	relationLine   = regexp.MustCompile(`^(` + nameToken + `)` + generics + `\s*(?:"([^"]*)"\s*)?` + arrowToken + `\s*(?:"([^"]*)"\s*)?(` + nameToken + `)` + generics + `\s*(?::\s*(.*))?$`)
+	memberLine     = regexp.MustCompile(`^(` + nameToken + `)` + generics + `\s*:\s*(.+)$`)
+	noteLine       = regexp.MustCompile(`^note\s+(?:for\s+(` + nameToken + `)\s+)?"(.*)"$`)
+	namespaceLine  = regexp.MustCompile(`^namespace\s+(\S+)\s*\{$`)
+	styleLine      = regexp.MustCompile(`^style\s+(` + nameToken + `)\s+(.+)$`)
+	directiveLine  = regexp.MustCompile(`^(direction|classDef|cssClass|click|callback|link|accTitle|accDescr|title)\b`)
+	arrowLine      = regexp.MustCompile(`^` + arrowToken + `$`)
+	hexColor       = regexp.MustCompile(`^#([0-9a-fA-F]{6}|[0-9a-fA-F]{3})$`)
+	// members with the type in front, as Mermaid writes them, "List<int> ids" and "Area(int scale) float64"
+	typedFirst = regexp.MustCompile(`^(.+?)\s+([\p{L}_][\p{L}\p{N}_]*)$`)
+	operation  = regexp.MustCompile(`^([\p{L}_][\p{L}\p{N}_]*)\((.*)\)\s*(.*)$`)
+)
+
+// decl is a gadget being read, its members are kept as written until its type is known
+type decl struct {
+	gadgetType component.GadgetType
+	header     string
+	color      string
+	note       []string
+	members    []written
+	declared   bool // false while only relations have named it
+	extra      *gadgetExtra
+}
+
+// written is a member as it was written, on line
+type written struct {
+	text string
+	line int
+}
+
+// relation is an association being read, parents index into the decls
+type relation struct {
+	assType       component.AssociationType
+	parents       [2]int
+	sides         [2]int // the decls on the left and the right of the arrow
+	navigability  [2]component.Navigability
+	cardinalities [2]string // at the start and the end
+	labels        filedata.AssLabels
+	class         int // the association class, -1 when there is none
+	extra         *relationExtra
+}
+
+// what the last statement was, a comment of extras goes with it, with the diagram when there was none
+const (
+	lastNone = iota
+	lastGadget
+	lastRelation
+	lastOther
+)
+
+type importer struct {
+	lines      []string
+	pos        int // of the next line
+	decls      []*decl
+	ids        map[string]int // indices into decls, by name
+	relations  []relation
+	namespaces int // how deep the current line is in namespaces
+	last       int
+	lastIndex  int // of the decl or relation last read
+	background string
+	warnings   []Warning
+	warned     map[string]bool
+}
+
+// Import reads a Mermaid classDiagram into a diagram called name. It may be fenced as in Markdown,
+// the diagram in the first fence marked mermaid is read then. Classes become gadgets of the type their
+// annotation tells, with their members, and notes become notes. Relations become associations, with
+// the cardinalities that are multiplicities as such and the others as text on the association.
+// Where the comments written by Export place the gadgets, they stay, layout.Layout places the others.
+// Whatever else the diagram holds is left out, or changed, with a warning.
+func Import(src string, name string) (*umldiagram.UMLDiagram, []Warning, duerror.DUError) {
+	im := importer{
+		lines:    strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n"),
+		ids:      make(map[string]int),
+		warnings: []Warning{},
+		warned:   make(map[string]bool),
+	}
+	for i := range im.lines {
+		im.lines[i] = strings.TrimSpace(im.lines[i])
+	}
+	if err := im.read(); err != nil {
+		return nil, nil, err
+	}
+	fd, placed, err := im.diagram(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := layout.Layout(&fd, placed); err != nil {
+		return nil, nil, err
+	}
+	d, err := umldiagram.LoadUMLDiagramFromFileData(fd)
+	if err != nil {
+		return nil, nil, err
+	}
+	// members are read last, their warnings go where their lines are
+	sort.SliceStable(im.warnings, func(i, j int) bool { return im.warnings[i].Line < im.warnings[j].Line })
+	return d, im.warnings, nil
+}
+
+func (im *importer) warn(line int, format string, args ...any) {
+	im.warnings = append(im.warnings, Warning{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// warnOnce warns about what may happen on many lines only the first time, key tells them apart
+func (im *importer) warnOnce(key string, line int, format string, args ...any) {
+	if im.warned[key] {
+		return
+	}
+	im.warned[key] = true
+	im.warn(line, format, args...)
+}
+
+// next returns the next line that is no comment and its number, ok is false past the last one.
+// The comments of extras are returned, the others are skipped.
+func (im *importer) next() (text string, line int, ok bool) {
+	for im.pos < len(im.lines) {
+		im.pos++
+		text = im.lines[im.pos-1]
+		if !strings.HasPrefix(text, "%%") || strings.HasPrefix(text, extraPrefix) {
+			return text, im.pos, true
+		}
+	}
+	return "", 0, false
+}
+
+// read finds the classDiagram and goes through its statements
+func (im *importer) read() duerror.DUError {
+	for i, text := range im.lines {
+		if fenceLine.MatchString(text) {
+			im.pos = i + 1
+			break
+		}
+	}
+	text, ok := im.nonEmpty()
+	if text == "---" {
+		// the front matter, with the title of the diagram, which is named on import
+		for text, _, ok = im.next(); ok && text != "---"; {
+			text, _, ok = im.next()
+		}
+		text, ok = im.nonEmpty()
+	}
+	if !ok {
+		return duerror.NewInvalidArgumentError("there is no classDiagram to import")
+	}
+	if !headerLine.MatchString(text) {
+		kind, _, _ := strings.Cut(text, " ")
+		return duerror.NewInvalidArgumentError("only class diagrams can be imported, not " + kind)
+	}
+
+	for {
+		text, line, ok := im.next()
+		if !ok {
+			return nil
+		}
+		if strings.HasPrefix(text, "```") {
+			im.rest()
+			return nil
+		}
+		im.statement(text, line)
+	}
+}
+
+// nonEmpty returns the next line that is not empty
+func (im *importer) nonEmpty() (string, bool) {
+	for {
+		text, _, ok := im.next()
+		if !ok || text != "" {
+			return text, ok
+		}
+	}
+}
+
+// rest warns when there is more after the fence closing the diagram
+func (im *importer) rest() {
+	for {
+		text, line, ok := im.next()
+		if !ok {
+			return
+		}
+		if fenceLine.MatchString(text) {
+			im.warn(line, "only the first diagram is imported, the rest is left out")
+			return
+		}
+	}
+}
+
+func (im *importer) statement(text string, line int) {
+	if text == "" {
+		return
+	}
+	if strings.HasPrefix(text, extraPrefix) {
+		im.extra(strings.TrimPrefix(text, extraPrefix), line)
+		return
+	}
+	// annotations, styles and members leave the comment of extras to the statement before them
+	last := lastOther
+	defer func() { im.last = last }()
+	switch {
+	case text == "}":
+		if im.namespaces == 0 {
+			im.warn(line, "unexpected \"}\" is left out")
+			return
+		}
+		im.namespaces--
+	case namespaceLine.MatchString(text):
+		im.namespaces++
+		im.warn(line, "namespace %s is left out, its contents are imported", namespaceLine.FindStringSubmatch(text)[1])
+	case noteLine.MatchString(text):
+		m := noteLine.FindStringSubmatch(text)
+		if m[1] != "" {
+			im.warn(line, "the note is not attached to %s, it stands on its own", unquote(m[1]))
+		}
+		d := &decl{gadgetType: component.Note, declared: true}
+		for _, content := range strings.Split(m[2], `\n`) {
+			d.note = append(d.note, im.printable(strings.TrimSpace(content), line))
+		}
+		im.decls = append(im.decls, d)
+		last, im.lastIndex = lastGadget, len(im.decls)-1
+	case classLine.MatchString(text):
+		last, im.lastIndex = lastGadget, im.class(classLine.FindStringSubmatch(text), line)
+	case annotationLine.MatchString(text):
+		m := annotationLine.FindStringSubmatch(text)
+		if m[2] == "" {
+			im.warn(line, "%q is not understood, it is left out", text)
+			return
+		}
+		im.annotate(im.decls[im.gadget(m[2], line)], m[1], line)
+		last = im.last
+	case styleLine.MatchString(text):
+		m := styleLine.FindStringSubmatch(text)
+		im.style(im.decls[im.gadget(m[1], line)], m[2], line)
+		last = im.last
+	case relationLine.MatchString(text):
+		if im.relation(relationLine.FindStringSubmatch(text), line) {
+			last, im.lastIndex = lastRelation, len(im.relations)-1
+		}
+	case directiveLine.MatchString(text):
+		keyword := directiveLine.FindStringSubmatch(text)[1]
+		im.warnOnce(keyword, line, "%s is not supported, it is left out", keyword)
+		if strings.HasSuffix(text, "{") {
+			im.skipBlock()
+		}
+	case memberLine.MatchString(text):
+		m := memberLine.FindStringSubmatch(text)
+		d := im.decls[im.gadget(m[1], line)]
+		d.members = append(d.members, written{m[2], line})
+		last = im.last
+	default:
+		im.warn(line, "%q is not understood, it is left out", text)
+	}
+}
+
+// skipBlock skips the lines up to the "}" closing a block already opened
+func (im *importer) skipBlock() {
+	for {
+		text, _, ok := im.next()
+		if !ok || strings.HasPrefix(text, "}") {
+			return
+		}
+	}
+}
+
+// gadget returns the index of the decl called name, declaring a class when there is none
+func (im *importer) gadget(name string, line int) int {
+	name = unquote(name)
+	if i, ok := im.ids[name]; ok {
+		return i
+	}
+	im.decls = append(im.decls, &decl{gadgetType: component.Class, header: im.printable(name, line)})
+	im.ids[name] = len(im.decls) - 1
+	return len(im.decls) - 1
+}
+
+// class reads the match of classLine, "class Name~T~["label"]:::style {", and the body it opens
+func (im *importer) class(m []string, line int) int {
+	i := im.gadget(m[1], line)
+	d := im.decls[i]
+	if !d.declared {
+		d.declared = true
+		switch {
+		case m[3] != "":
+			d.header = im.printable(m[3], line)
+		case m[2] != "":
+			d.header = im.printable(angled(unquote(m[1])+m[2]), line)
+		}
+	}
+	if m[4] != "" {
+		im.warnOnce(":::", line, "css classes are left out")
+	}
+	if m[5] == "" {
+		return i
+	}
+
+	if inner, _, closed := strings.Cut(m[5][1:], "}"); closed {
+		if inner = strings.TrimSpace(inner); inner != "" {
+			d.members = append(d.members, written{inner, line})
+		}
+		return i
+	}
+	for {
+		text, n, ok := im.next()
+		if !ok {
+			im.warn(line, "the body of %s is not closed", d.header)
+			return i
+		}
+		switch {
+		case strings.HasPrefix(text, "}"):
+			return i
+		case text == "", strings.HasPrefix(text, "%%"):
+		case annotationLine.MatchString(text):
+			im.annotate(d, annotationLine.FindStringSubmatch(text)[1], n)
+		default:
+			d.members = append(d.members, written{text, n})
+		}
+	}
+}
+
+// annotate reads the annotation of d, the ones of the gadget types that have one change its type
+func (im *importer) annotate(d *decl, annotation string, line int) {
+	var gadgetType component.GadgetType
+	switch strings.ToLower(annotation) {
+	case "interface":
+		gadgetType = component.Interface
+	case "abstract":
+		gadgetType = component.AbstractClass
+	case "enumeration", "enum":
+		gadgetType = component.Enumeration
+	default:
+		im.warn(line, "annotation <<%s>> of %s is left out", annotation, d.header)
+		return
+	}
+	if d.gadgetType == component.Note {
+		im.warn(line, "annotation <<%s>> of a note is left out", annotation)
+		return
+	}
+	d.gadgetType = gadgetType
+}
+
+// style reads the fill of "style Name fill:#f9f,stroke:#333", the other properties are left out
+func (im *importer) style(d *decl, properties string, line int) {
+	for _, property := range strings.Split(properties, ",") {
+		key, value, _ := strings.Cut(property, ":")
+		if strings.TrimSpace(key) != "fill" {
+			im.warnOnce("style", line, "style properties other than fill are left out")
+			continue
+		}
+		d.color = im.color(strings.TrimSpace(value), line)
+	}
+}
+
+// relation reads the match of relationLine, it returns false when it is left out
+func (im *importer) relation(m []string, line int) bool {
+	a, b := im.gadget(m[1], line), im.gadget(m[7], line)
+	if im.decls[a].gadgetType == component.Note || im.decls[b].gadgetType == component.Note {
+		im.warn(line, "links to notes are left out")
+		return false
+	}
+	leftHead, rightHead := m[3], m[5]
+	for _, head := range []*string{&leftHead, &rightHead} {
+		if *head == "()" {
+			im.warn(line, "lollipop interfaces are not supported, the lollipop is left out")
+			*head = ""
+		}
+	}
+	r := arrowRelation(leftHead, rightHead, m[4] == "..", func(format string, args ...any) { im.warn(line, format, args...) })
+	r.sides = [2]int{a, b}
+	cardinalities := [2]string{m[2], m[6]}
+	if r.parents[0] == 1 {
+		r.parents = [2]int{b, a}
+		cardinalities = [2]string{m[6], m[2]}
+	} else {
+		r.parents = [2]int{a, b}
+	}
+	for end, text := range cardinalities {
+		text = im.printable(strings.TrimSpace(text), line)
+		if component.ValidateMultiplicity(text) != nil {
+			r.cardinalities[end] = text
+			continue
+		}
+		if end == 0 {
+			r.labels.StartMultiplicity = text
+		} else {
+			r.labels.EndMultiplicity = text
+		}
+	}
+	r.labels.Name = im.printable(strings.TrimSpace(m[8]), line)
+	im.relations = append(im.relations, r)
+	return true
+}
+
+// arrowRelation tells the association a Mermaid arrow stands for from its heads, on the left then the right.
+// The parents of the relation returned are the sides of the arrow, 0 for the left, in the order of the association.
+func arrowRelation(leftHead, rightHead string, dotted bool, warn func(format string, args ...any)) relation {
+	r := relation{parents: [2]int{0, 1}, class: -1}
+	heads := [2]string{leftHead, strings.NewReplacer("|>", "<|", ">", "<").Replace(rightHead)}
+	structural := func(h string) bool { return h == "<|" || h == "*" || h == "o" }
+	if structural(heads[0]) && structural(heads[1]) {
+		warn("an arrow keeps one of its two heads, the right one")
+		heads[0] = ""
+	}
+
+	// a triangle or diamond is at the end of the association
+	for _, end := range []int{1, 0} {
+		if !structural(heads[end]) {
+			continue
+		}
+		r.parents = [2]int{1 - end, end}
+		switch heads[end] {
+		case "<|":
+			r.assType = component.Extension
+			if dotted {
+				r.assType = component.Implementation
+			}
+		case "*":
+			r.assType = component.Composition
+		case "o":
+			r.assType = component.Aggregation
+		}
+		if dotted && r.assType != component.Implementation {
+			warn("a dotted line with a diamond is read as a solid one")
+		}
+		if heads[1-end] != "" {
+			if r.assType == component.Extension || r.assType == component.Implementation {
+				warn("the arrowhead at the other end of a generalization is left out")
+			} else {
+				r.navigability[0] = component.Navigable
+			}
+		}
+		return r
+	}
+
+	if dotted {
+		r.assType = component.Dependency
+		switch {
+		case heads[0] != "" && heads[1] != "":
+			warn("a dependency has one arrowhead, the left one is left out")
+		case heads[0] != "":
+			r.parents = [2]int{1, 0}
+		case heads[1] == "":
+			warn("a dotted line without an arrowhead is read as a plain association")
+			r.assType = component.PlainAssociation
+		}
+		return r
+	}
+
+	// a solid line with one open arrowhead is directed, from its other end
+	switch {
+	case heads[1] != "" && heads[0] == "":
+		r.assType = component.DirectedAssociation
+	case heads[0] != "" && heads[1] == "":
+		r.assType = component.DirectedAssociation
+		r.parents = [2]int{1, 0}
+	case heads[0] != "":
+		r.assType = component.PlainAssociation
+		r.navigability = [2]component.Navigability{component.Navigable, component.Navigable}
+	default:
+		r.assType = component.PlainAssociation
+	}
+	return r
+}
+
+// extra reads the comment of extras into the statement before it
+func (im *importer) extra(data string, line int) {
+	var err error
+	switch im.last {
+	case lastNone:
+		var extra diagramExtra
+		if err = json.Unmarshal([]byte(data), &extra); err == nil && extra.BackgroundColor != "" {
+			im.background = im.color(extra.BackgroundColor, line)
+		}
+	case lastGadget:
+		var extra gadgetExtra
+		if err = json.Unmarshal([]byte(data), &extra); err == nil {
+			im.decls[im.lastIndex].extra = &extra
+		}
+	case lastRelation:
+		var extra relationExtra
+		if err = json.Unmarshal([]byte(data), &extra); err == nil {
+			im.relationExtra(&im.relations[im.lastIndex], &extra, line)
+		}
+	default:
+		im.warn(line, "the dr.uml comment follows no class or relation, it is left out")
+		return
+	}
+	if err != nil {
+		im.warn(line, "the dr.uml comment is not understood, it is left out: %s", err.Error())
+	}
+}
+
+// relationExtra puts what the comment after a relation holds into it
+func (im *importer) relationExtra(r *relation, extra *relationExtra, line int) {
+	r.extra = extra
+	if extra.AssType != 0 {
+		if _, ok := arrows[component.AssociationType(extra.AssType)]; !ok {
+			im.warn(line, "association type %d is not supported, the arrow tells the type", extra.AssType)
+		} else {
+			// the association starts on the left of the arrow then
+			if r.parents != r.sides {
+				r.parents = r.sides
+				r.labels.StartMultiplicity, r.labels.EndMultiplicity = r.labels.EndMultiplicity, r.labels.StartMultiplicity
+				r.cardinalities = [2]string{r.cardinalities[1], r.cardinalities[0]}
+			}
+			r.assType = component.AssociationType(extra.AssType)
+			r.navigability = [2]component.Navigability{}
+			if extra.Navigability != nil {
+				r.navigability = [2]component.Navigability{component.Navigability(extra.Navigability[0]), component.Navigability(extra.Navigability[1])}
+			}
+		}
+	}
+	r.labels.StartRole, r.labels.EndRole = extra.StartRole, extra.EndRole
+	r.labels.ReadingDirection = extra.ReadingDirection
+
+	if extra.Class == "" {
+		if r.assType == component.AssociationClass {
+			r.assType = component.PlainAssociation
+		}
+		return
+	}
+	class, ok := im.ids[extra.Class]
+	switch {
+	case !ok:
+		im.warn(line, "there is no class %s for the association, it is left out", extra.Class)
+	case im.decls[class].gadgetType != component.Class:
+		im.warn(line, "%s cannot be an association class, only a class can, the link is left out", im.decls[class].header)
+	case class == r.parents[0] || class == r.parents[1]:
+		im.warn(line, "%s cannot be the class of its own association, the link is left out", im.decls[class].header)
+	case r.assType != component.PlainAssociation && r.assType != component.AssociationClass:
+		im.warn(line, "only a plain association can have a class, the link to %s is left out", im.decls[class].header)
+	default:
+		r.assType, r.class = component.AssociationClass, class
+		return
+	}
+	if r.assType == component.AssociationClass {
+		r.assType = component.PlainAssociation
+	}
+}
+
+// member turns a Mermaid member into the canonical form of a member. Mermaid writes the classifiers
+// after members, "$" for static and "*" for abstract, types with tildes and often in front of names,
+// as "+List~int~ ids". What cannot be read is kept as written, a field unless it has parentheses.
+func (im *importer) member(text string, line int) (string, attribute.MemberKind) {
+	static, abstract := false, false
+	content := text
+	for {
+		if strings.HasSuffix(content, "$") {
+			static = true
+		} else if strings.HasSuffix(content, "*") && strings.Contains(content, "(") {
+			abstract = true
+		} else {
+			break
+		}
+		content = strings.TrimSpace(content[:len(content)-1])
+	}
+	visibility := ""
+	if content != "" && strings.ContainsRune("+-#~", rune(content[0])) {
+		visibility, content = content[:1], strings.TrimSpace(content[1:])
+	}
+	content = angled(content)
+
+	m, err := attribute.ParseMember(visibility + content)
+	if err != nil {
+		m, err = attribute.ParseMember(visibility + typedLast(content))
+	}
+	if err != nil {
+		im.warn(line, "member %q is kept as written, it cannot be read: %s", text, err.Error())
+		if strings.Contains(text, "(") {
+			return text, attribute.Operation
+		}
+		return text, attribute.Field
+	}
+	m.Static = m.Static || static
+	m.Abstract = m.Abstract || abstract
+	return m.String(), m.Kind
+}
+
+// typedLast rewrites a member with types in front of names, or after the parameters without a colon,
+// in the form of a UML one, "List<int> ids" as "ids: List<int>" and "Area(int scale) float64" as "Area(scale: int): float64"
+func typedLast(text string) string {
+	m := operation.FindStringSubmatch(text)
+	if m == nil {
+		if t := typedFirst.FindStringSubmatch(text); t != nil && !strings.Contains(text, ":") {
+			return t[2] + ": " + t[1]
+		}
+		return text
+	}
+	params := splitTopLevel(m[2])
+	for i, p := range params {
+		p = strings.TrimSpace(p)
+		if t := typedFirst.FindStringSubmatch(p); t != nil && !strings.Contains(p, ":") {
+			p = t[2] + ": " + t[1]
+		}
+		params[i] = p
+	}
+	result := strings.TrimSpace(strings.TrimPrefix(m[3], ":"))
+	if result != "" {
+		result = ": " + result
+	}
+	return m[1] + "(" + strings.Join(params, ", ") + ")" + result
+}
+
+// splitTopLevel splits s at the commas outside of brackets
+func splitTopLevel(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(', '[', '{', '<':
+			depth++
+		case ')', ']', '}', '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// color reads a hex color, the default one is kept for the others
+func (im *importer) color(color string, line int) string {
+	m := hexColor.FindStringSubmatch(color)
+	if m == nil {
+		im.warn(line, "color %s is not a hex color, the default one is kept", color)
+		return ""
+	}
+	hex := strings.ToUpper(m[1])
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	return "#" + hex
+}
+
+// diagram builds the file data of what was read, and tells which gadgets the comments placed
+func (im *importer) diagram(name string) (filedata.Diagram, []bool, duerror.DUError) {
+	fd := filedata.Diagram{
+		Name:            name,
+		DiagramType:     int(umldiagram.ClassDiagram),
+		BackgroundColor: drawdata.DefaultDiagramColor,
+		LastModified:    time.Now(),
+		Gadgets:         make([]filedata.Gadget, 0, len(im.decls)),
+		Associations:    make([]filedata.Association, 0, len(im.relations)),
+	}
+	if im.background != "" {
+		fd.BackgroundColor = im.background
+	}
+	placed := make([]bool, len(im.decls))
+	for i, d := range im.decls {
+		color := drawdata.DefaultGadgetColor
+		if d.color != "" {
+			color = d.color
+		}
+		var position utils.Point
+		layer := 0
+		if d.extra != nil {
+			position, layer, placed[i] = utils.Point{X: d.extra.X, Y: d.extra.Y}, d.extra.Layer, true
+			if d.gadgetType == component.Note && d.extra.Color != "" {
+				color = im.color(d.extra.Color, 0)
+				if color == "" {
+					color = drawdata.DefaultGadgetColor
+				}
+			}
+		}
+		g, err := component.NewGadget(d.gadgetType, position, layer, color, d.header)
+		if err != nil {
+			return filedata.Diagram{}, nil, err
+		}
+		var sections [3][]string
+		if d.gadgetType == component.Note {
+			sections[0] = d.note
+		}
+		for _, w := range d.members {
+			if d.gadgetType == component.Enumeration {
+				// an enum has its literals only
+				sections[1] = append(sections[1], im.printable(w.text, w.line))
+				continue
+			}
+			content, kind := im.member(w.text, w.line)
+			section := 1
+			if kind == attribute.Operation {
+				section = 2
+			}
+			sections[section] = append(sections[section], im.printable(content, w.line))
+		}
+		for section, contents := range sections {
+			for _, content := range contents {
+				if err := g.AddAttribute(section, content); err != nil {
+					return filedata.Diagram{}, nil, err
+				}
+			}
+		}
+		fd.Gadgets = append(fd.Gadgets, g.GetFileData())
+	}
+
+	for _, r := range im.relations {
+		a := filedata.Association{
+			AssType:      int(r.assType),
+			Parents:      r.parents,
+			Navigability: [2]int{int(r.navigability[0]), int(r.navigability[1])},
+			Attributes:   []filedata.AssAttribute{},
+			Labels:       r.labels,
+		}
+		if r.class >= 0 {
+			a.Class = &r.class
+		}
+		if e := r.extra; e != nil {
+			a.Layer, a.Waypoints, a.Routing = e.Layer, e.Waypoints, e.Routing
+			if e.StartRatio != nil {
+				a.StartRatio = *e.StartRatio
+			}
+			if e.EndRatio != nil {
+				a.EndRatio = *e.EndRatio
+			}
+			if e.Attributes != nil {
+				a.Attributes = e.Attributes
+			}
+		} else {
+			// cardinalities that are no multiplicities are text by their end
+			for end, ratio := range []float64{0.15, 0.85} {
+				if content := r.cardinalities[end]; content != "" {
+					a.Attributes = append(a.Attributes, filedata.AssAttribute{Content: content, Size: drawdata.DefaultAttributeFontSize, Ratio: ratio})
+				}
+			}
+		}
+		fd.Associations = append(fd.Associations, a)
+	}
+	return fd, placed, nil
+}
+
+// printable leaves out of text the characters the font has no glyph for, text could not be measured with them
+func (im *importer) printable(text string, line int) string {
+	if _, _, err := utils.GetTextSize(text, drawdata.DefaultAttributeFontSize, ""); err == nil {
+		return text
+	}
+	if _, _, err := utils.GetTextSize("", drawdata.DefaultAttributeFontSize, ""); err != nil {
+		// without the font there is nothing to measure with, loading the diagram tells why
+		return text
+	}
+	kept := strings.Map(func(r rune) rune {
+		if _, _, err := utils.GetTextSize(string(r), drawdata.DefaultAttributeFontSize, ""); err != nil {
+			return -1
+		}
+		return r
+	}, text)
+	im.warn(line, "characters the font has no glyph for are left out of %q", text)
+	return kept
+}
+
+func unquote(name string) string {
+	return strings.Trim(name, "`")
+}
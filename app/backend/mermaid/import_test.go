@@ -0,0 +1,246 @@
+package mermaid
+
+import (
+	"testing"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/filedata"
+	"github.com/stretchr/testify/assert"
+)
+
+const shopSource = "# The shop\n\n```mermaid\n" + `---
+title: Shop
+---
+classDiagram
+    %% the shop
+    class Product {
+        <<abstract>>
+        -String id
+        +int count$
+        +Price() float64
+        +Describe(bool verbose) String*
+    }
+    Product <|-- Book
+    Priced <|.. Book
+    Book : String isbn
+    class Priced {
+        <<interface>>
+        +Price(): float64
+    }
+    class Status {
+        <<enumeration>>
+        Open
+        Closed = 2
+    }
+    class Line["Order Line"]
+    class Stack~T~
+    style Product fill:#fc0,stroke:#333
+    Order "1" *-- "1..*" Line : holds
+    Customer "buyer" --> "0..*" Order
+    Order ..> Status
+    Shelf o-- Book
+    Shelf <--> Customer
+    note for Order "Prices are\nin euros"
+` + "```\n"
+
+func TestImport(t *testing.T) {
+	d, warnings, err := Import(shopSource, "Shop")
+	assert.NoError(t, err)
+	assert.Equal(t, []Warning{
+		{Line: 30, Message: "style properties other than fill are left out"},
+		{Line: 36, Message: "the note is not attached to Order, it stands on its own"},
+	}, warnings)
+	fd, err := d.GetFileData()
+	assert.NoError(t, err)
+	assert.Equal(t, "Shop", fd.Name)
+
+	index := make(map[string]int)
+	for i, g := range fd.Gadgets {
+		if len(g.Attributes[0]) > 0 {
+			index[g.Attributes[0][0].Content] = i
+		}
+	}
+	contents := func(header string, section int) []string {
+		list := []string{}
+		for _, att := range fd.Gadgets[index[header]].Attributes[section] {
+			list = append(list, att.Content)
+		}
+		return list
+	}
+	gadgetType := func(header string) component.GadgetType {
+		return component.GadgetType(fd.Gadgets[index[header]].GadgetType)
+	}
+
+	assert.Len(t, fd.Gadgets, 10)
+	assert.Equal(t, component.AbstractClass, gadgetType("Product"))
+	assert.Equal(t, "#FFCC00", fd.Gadgets[index["Product"]].Color)
+	assert.Equal(t, []string{"- id: String", "{static} + count: int"}, contents("Product", 1))
+	assert.Equal(t, []string{"+ Price(): float64", "{abstract} + Describe(verbose: bool): String"}, contents("Product", 2))
+	assert.Equal(t, []string{"isbn: String"}, contents("Book", 1))
+	assert.Equal(t, component.Interface, gadgetType("Priced"))
+	assert.Equal(t, []string{"+ Price(): float64"}, contents("Priced", 2))
+	assert.Equal(t, component.Enumeration, gadgetType("Status"))
+	assert.Equal(t, []string{"Open", "Closed = 2"}, contents("Status", 1))
+	assert.Equal(t, component.Class, gadgetType("Order Line"))
+	assert.Equal(t, component.Class, gadgetType("Stack<T>"))
+	// named by relations only
+	assert.Equal(t, component.Class, gadgetType("Order"))
+	assert.Equal(t, component.Class, gadgetType("Shelf"))
+
+	var note filedata.Gadget
+	for _, g := range fd.Gadgets {
+		if component.GadgetType(g.GadgetType) == component.Note {
+			note = g
+		}
+	}
+	assert.Len(t, note.Attributes[0], 2)
+	assert.Equal(t, "Prices are", note.Attributes[0][0].Content)
+	assert.Equal(t, "in euros", note.Attributes[0][1].Content)
+
+	type assoc struct {
+		assType      component.AssociationType
+		start, end   string
+		navigability [2]int
+		labels       filedata.AssLabels
+		attributes   []string
+	}
+	var got []assoc
+	for _, a := range fd.Associations {
+		g := assoc{
+			assType:      component.AssociationType(a.AssType),
+			start:        fd.Gadgets[a.Parents[0]].Attributes[0][0].Content,
+			end:          fd.Gadgets[a.Parents[1]].Attributes[0][0].Content,
+			navigability: a.Navigability,
+			labels:       a.Labels,
+		}
+		for _, att := range a.Attributes {
+			g.attributes = append(g.attributes, att.Content)
+		}
+		got = append(got, g)
+	}
+	assert.ElementsMatch(t, []assoc{
+		{assType: component.Extension, start: "Book", end: "Product"},
+		{assType: component.Implementation, start: "Book", end: "Priced"},
+		{assType: component.Composition, start: "Order Line", end: "Order", labels: filedata.AssLabels{
+			StartMultiplicity: "1..*", EndMultiplicity: "1", Name: "holds",
+		}},
+		{assType: component.DirectedAssociation, start: "Customer", end: "Order", labels: filedata.AssLabels{
+			EndMultiplicity: "0..*",
+		}, attributes: []string{"buyer"}},
+		{assType: component.Dependency, start: "Order", end: "Status"},
+		{assType: component.Aggregation, start: "Book", end: "Shelf"},
+		{assType: component.PlainAssociation, start: "Shelf", end: "Customer",
+			navigability: [2]int{int(component.Navigable), int(component.Navigable)}},
+	}, got)
+
+	// laid out, parents above their children
+	assert.Less(t, fd.Gadgets[index["Product"]].Y, fd.Gadgets[index["Book"]].Y)
+}
+
+func TestImport_Warnings(t *testing.T) {
+	src := `classDiagram
+direction LR
+namespace shop {
+    class Cart:::heavy {
+        <<entity>>
+        +Add(Item item)
+        +Total() List~Item~]
+    }
+}
+%% dr.uml {"x":1}
+style Cart fill:pink,stroke-width:2px
+Cart ()-- Point
+Cart .. Point
+Cart --> Point
+%% dr.uml {"assType":99}
+Cart -- Point
+%% dr.uml {"class":"Cart"}
+Cart -- Point
+%% dr.uml not json
+Point : +int x
+cssClass "Cart" heavy
+Point ==> Cart
+`
+	d, warnings, err := Import(src, "Shop")
+	assert.NoError(t, err)
+	assert.Equal(t, []Warning{
+		{Line: 2, Message: "direction is not supported, it is left out"},
+		{Line: 3, Message: "namespace shop is left out, its contents are imported"},
+		{Line: 4, Message: "css classes are left out"},
+		{Line: 5, Message: "annotation <<entity>> of Cart is left out"},
+		{Line: 7, Message: `member "+Total() List~Item~]" is kept as written, it cannot be read: line 1, column 21: unbalanced ']'`},
+		{Line: 10, Message: "the dr.uml comment follows no class or relation, it is left out"},
+		{Line: 11, Message: "color pink is not a hex color, the default one is kept"},
+		{Line: 11, Message: "style properties other than fill are left out"},
+		{Line: 12, Message: "lollipop interfaces are not supported, the lollipop is left out"},
+		{Line: 13, Message: "a dotted line without an arrowhead is read as a plain association"},
+		{Line: 15, Message: "association type 99 is not supported, the arrow tells the type"},
+		{Line: 17, Message: "Cart cannot be the class of its own association, the link is left out"},
+		{Line: 19, Message: "the dr.uml comment is not understood, it is left out: invalid character 'o' in literal null (expecting 'u')"},
+		{Line: 21, Message: "cssClass is not supported, it is left out"},
+		{Line: 22, Message: `"Point ==> Cart" is not understood, it is left out`},
+	}, warnings)
+
+	fd, err := d.GetFileData()
+	assert.NoError(t, err)
+	assert.Len(t, fd.Gadgets, 2)
+	cart := fd.Gadgets[0]
+	assert.Equal(t, "+ Add(item: Item)", cart.Attributes[2][0].Content)
+	assert.Equal(t, "+Total() List~Item~]", cart.Attributes[2][1].Content)
+	assert.Equal(t, "+ x: int", fd.Gadgets[1].Attributes[1][0].Content)
+	assert.Len(t, fd.Associations, 5)
+	assert.Equal(t, int(component.PlainAssociation), fd.Associations[4].AssType)
+	assert.Nil(t, fd.Associations[4].Class)
+}
+
+func TestImport_Errors(t *testing.T) {
+	_, _, err := Import("sequenceDiagram\nAlice->>Bob: Hello\n", "Hello")
+	assert.Error(t, err)
+	_, _, err = Import("# Nothing to see\n", "Empty")
+	assert.Error(t, err)
+}
+
+func TestArrowRelation(t *testing.T) {
+	tests := []struct {
+		left, right  string
+		dotted       bool
+		assType      component.AssociationType
+		parents      [2]int
+		navigability [2]component.Navigability
+		warns        int
+	}{
+		{"<|", "", false, component.Extension, [2]int{1, 0}, [2]component.Navigability{}, 0},
+		{"", "|>", true, component.Implementation, [2]int{0, 1}, [2]component.Navigability{}, 0},
+		{"*", ">", false, component.Composition, [2]int{1, 0}, [2]component.Navigability{component.Navigable, 0}, 0},
+		{"", "o", true, component.Aggregation, [2]int{0, 1}, [2]component.Navigability{}, 1},
+		{"*", "|>", false, component.Extension, [2]int{0, 1}, [2]component.Navigability{}, 1},
+		{"<", "", true, component.Dependency, [2]int{1, 0}, [2]component.Navigability{}, 0},
+		{"", "", true, component.PlainAssociation, [2]int{0, 1}, [2]component.Navigability{}, 1},
+		{"", ">", false, component.DirectedAssociation, [2]int{0, 1}, [2]component.Navigability{}, 0},
+		{"<", ">", false, component.PlainAssociation, [2]int{0, 1}, [2]component.Navigability{component.Navigable, component.Navigable}, 0},
+		{"", "", false, component.PlainAssociation, [2]int{0, 1}, [2]component.Navigability{}, 0},
+	}
+	for i, tt := range tests {
+		warns := 0
+		r := arrowRelation(tt.left, tt.right, tt.dotted, func(string, ...any) { warns++ })
+		assert.Equal(t, tt.assType, r.assType, i)
+		assert.Equal(t, tt.parents, r.parents, i)
+		assert.Equal(t, tt.navigability, r.navigability, i)
+		assert.Equal(t, tt.warns, warns, i)
+	}
+}
+
+func TestAngled(t *testing.T) {
+	tests := map[string]string{
+		"List~int~":         "List<int>",
+		"List~int~ ids":     "List<int> ids",
+		"Map~K, List~V~~":   "Map<K, List<V>>",
+		"f(a: List~T~) T":   "f(a: List<T>) T",
+		"no generics":       "no generics",
+		"Stack~T~ {":        "Stack<T> {",
+		"Pair~A, B~, other": "Pair<A, B>, other",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, angled(in), in)
+	}
+}
@@ -0,0 +1,95 @@
+package mermaid
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/filedata"
+)
+
+// Warning is something of a diagram that could not be carried over, and was left out or changed.
+// Line counts from 1 in the Mermaid text, it is 0 for warnings about no line in particular.
+type Warning struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	if w.Line == 0 {
+		return w.Message
+	}
+	return fmt.Sprintf("line %d: %s", w.Line, w.Message)
+}
+
+// extraPrefix starts the comments holding what Mermaid has no syntax for,
+// each one follows the statement it belongs to
+const extraPrefix = "%% dr.uml "
+
+// diagramExtra is what the comment after "classDiagram" holds
+type diagramExtra struct {
+	BackgroundColor string `json:"backgroundColor,omitempty"`
+}
+
+// gadgetExtra is what the comment after a class or a note holds
+type gadgetExtra struct {
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Layer int    `json:"layer,omitempty"`
+	Color string `json:"color,omitempty"` // of notes, the color of classes is styled
+}
+
+// relationExtra is what the comment after a relation holds. When there is one, the roles and
+// the text on the association are the ones it lists, the cardinalities give the multiplicities only.
+type relationExtra struct {
+	AssType          int                     `json:"assType,omitempty"`      // when the arrow tells another one
+	Navigability     *[2]int                 `json:"navigability,omitempty"` // along with the association type
+	Layer            int                     `json:"layer,omitempty"`
+	StartRatio       *[2]float64             `json:"startRatio,omitempty"`
+	EndRatio         *[2]float64             `json:"endRatio,omitempty"`
+	Waypoints        [][2]int                `json:"waypoints,omitempty"`
+	Routing          int                     `json:"routing,omitempty"`
+	Class            string                  `json:"class,omitempty"` // the name of the association class
+	StartRole        string                  `json:"startRole,omitempty"`
+	EndRole          string                  `json:"endRole,omitempty"`
+	ReadingDirection int                     `json:"readingDirection,omitempty"`
+	Attributes       []filedata.AssAttribute `json:"attributes,omitempty"`
+}
+
+// annotations are the Mermaid annotations of the gadget types that have one, a class has none
+var annotations = map[component.GadgetType]string{
+	component.AbstractClass: "abstract",
+	component.Interface:     "interface",
+	component.Enumeration:   "enumeration",
+}
+
+// a name Mermaid takes as it is, with generics that have no commas or nesting
+var plainName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(<[^<>~,"]+>)?$`)
+
+// genericsOf writes the angle brackets of generics the way Mermaid does, as tildes
+func genericsOf(s string) string {
+	return strings.NewReplacer("<", "~", ">", "~").Replace(s)
+}
+
+// angled reads the tildes of Mermaid generics back into angle brackets. A tilde right after a name
+// and before more of the type opens, the others close, so "Map~K, List~V~~" is "Map<K, List<V>>".
+func angled(s string) string {
+	runes := []rune(s)
+	for i, r := range runes {
+		if r != '~' {
+			continue
+		}
+		opens := i > 0 && isNameRune(runes[i-1]) && i+1 < len(runes) && !strings.ContainsRune(" ~,)", runes[i+1])
+		if opens {
+			runes[i] = '<'
+		} else {
+			runes[i] = '>'
+		}
+	}
+	return string(runes)
+}
+
+func isNameRune(r rune) bool {
+	return r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 0x7f
+}
@@ -50,6 +50,35 @@ func (mr *MockComponentMockRecorder) Cover(p interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cover", reflect.TypeOf((*MockComponent)(nil).Cover), p)
 }
 
+// CoverRect mocks base method.
+func (m *MockComponent) CoverRect(r utils.Rect) (bool, duerror.DUError) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CoverRect", r)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(duerror.DUError)
+	return ret0, ret1
+}
+
+// CoverRect indicates an expected call of CoverRect.
+func (mr *MockComponentMockRecorder) CoverRect(r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CoverRect", reflect.TypeOf((*MockComponent)(nil).CoverRect), r)
+}
+
+// GetBounds mocks base method.
+func (m *MockComponent) GetBounds() utils.Rect {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBounds")
+	ret0, _ := ret[0].(utils.Rect)
+	return ret0
+}
+
+// GetBounds indicates an expected call of GetBounds.
+func (mr *MockComponentMockRecorder) GetBounds() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBounds", reflect.TypeOf((*MockComponent)(nil).GetBounds))
+}
+
 // GetDrawData mocks base method.
 func (m *MockComponent) GetDrawData() any {
 	m.ctrl.T.Helper()
@@ -64,6 +93,34 @@ func (mr *MockComponentMockRecorder) GetDrawData() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDrawData", reflect.TypeOf((*MockComponent)(nil).GetDrawData))
 }
 
+// GetID mocks base method.
+func (m *MockComponent) GetID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetID indicates an expected call of GetID.
+func (mr *MockComponentMockRecorder) GetID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetID", reflect.TypeOf((*MockComponent)(nil).GetID))
+}
+
+// GetIsSelected mocks base method.
+func (m *MockComponent) GetIsSelected() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIsSelected")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// GetIsSelected indicates an expected call of GetIsSelected.
+func (mr *MockComponentMockRecorder) GetIsSelected() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIsSelected", reflect.TypeOf((*MockComponent)(nil).GetIsSelected))
+}
+
 // GetLayer mocks base method.
 func (m *MockComponent) GetLayer() int {
 	m.ctrl.T.Helper()
@@ -92,6 +149,20 @@ func (mr *MockComponentMockRecorder) RegisterUpdateParentDraw(update interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterUpdateParentDraw", reflect.TypeOf((*MockComponent)(nil).RegisterUpdateParentDraw), update)
 }
 
+// SetIsSelected mocks base method.
+func (m *MockComponent) SetIsSelected(isSelected bool) duerror.DUError {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetIsSelected", isSelected)
+	ret0, _ := ret[0].(duerror.DUError)
+	return ret0
+}
+
+// SetIsSelected indicates an expected call of SetIsSelected.
+func (mr *MockComponentMockRecorder) SetIsSelected(isSelected interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIsSelected", reflect.TypeOf((*MockComponent)(nil).SetIsSelected), isSelected)
+}
+
 // SetLayer mocks base method.
 func (m *MockComponent) SetLayer(layer int) duerror.DUError {
 	m.ctrl.T.Helper()
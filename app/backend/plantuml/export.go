@@ -0,0 +1,236 @@
+package plantuml
+
+import (
+	"fmt"
+	"strings"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/component/attribute"
+	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/filedata"
+	"Dr.uml/backend/umldiagram"
+	"Dr.uml/backend/utils/duerror"
+)
+
+// arrows is the PlantUML arrow of each association type, drawn from its start to its end
+var arrows = map[component.AssociationType]string{
+	component.Extension:           "--|>",
+	component.Implementation:      "..|>",
+	component.Composition:         "--*",
+	component.Aggregation:         "--o",
+	component.Dependency:          "..>",
+	component.DirectedAssociation: "-->",
+	component.PlainAssociation:    "--",
+	component.AssociationClass:    "--",
+}
+
+// Export writes a class diagram as PlantUML. Where the gadgets are is left to PlantUML,
+// the warnings tell what else PlantUML cannot show and is left out.
+func Export(fd filedata.Diagram) (string, []Warning, duerror.DUError) {
+	if umldiagram.DiagramType(fd.DiagramType) != umldiagram.ClassDiagram {
+		return "", nil, duerror.NewInvalidArgumentError("only class diagrams can be exported to PlantUML")
+	}
+	ex := exporter{warnings: []Warning{}, used: make(map[string]bool)}
+	ex.sb.WriteString("@startuml")
+	if plainName.MatchString(fd.Name) {
+		ex.sb.WriteString(" " + fd.Name)
+	}
+	ex.sb.WriteString("\n")
+
+	ids := make([]string, len(fd.Gadgets))
+	for i, g := range fd.Gadgets {
+		id, err := ex.gadget(g, i)
+		if err != nil {
+			return "", nil, err
+		}
+		ids[i] = id
+	}
+	if len(fd.Associations) > 0 {
+		ex.sb.WriteString("\n")
+	}
+	for _, a := range fd.Associations {
+		if err := ex.association(a, ids); err != nil {
+			return "", nil, err
+		}
+	}
+	ex.sb.WriteString("@enduml\n")
+	return ex.sb.String(), ex.warnings, nil
+}
+
+type exporter struct {
+	sb       strings.Builder
+	used     map[string]bool // the names given so far
+	warnings []Warning
+}
+
+func (ex *exporter) warn(format string, args ...any) {
+	ex.warnings = append(ex.warnings, Warning{Message: fmt.Sprintf(format, args...)})
+}
+
+// gadget writes the declaration of the i-th gadget and returns the name relations refer to it by
+func (ex *exporter) gadget(g filedata.Gadget, i int) (string, duerror.DUError) {
+	if len(g.Attributes) == 0 {
+		return "", duerror.NewInvalidArgumentError("gadget without attribute sections")
+	}
+	gadgetType := component.GadgetType(g.GadgetType)
+	header := ""
+	if len(g.Attributes[0]) > 0 {
+		header = g.Attributes[0][0].Content
+	}
+	color := ""
+	if g.Color != "" && !strings.EqualFold(g.Color, drawdata.DefaultGadgetColor) {
+		color = " " + g.Color
+	}
+
+	if gadgetType == component.Note {
+		id := ex.alias("N", i)
+		fmt.Fprintf(&ex.sb, "note as %s%s\n", id, color)
+		for _, att := range g.Attributes[0] {
+			fmt.Fprintf(&ex.sb, "    %s\n", att.Content)
+		}
+		ex.sb.WriteString("end note\n")
+		return id, nil
+	}
+	if len(g.Attributes[0]) > 1 {
+		ex.warn("the header of %s has more than one line, the others are left out", header)
+	}
+	id, name := ex.name(header, i)
+	if gadgetType == component.Package {
+		fmt.Fprintf(&ex.sb, "package %s%s {\n}\n", name, color)
+		if len(g.Attributes) > 1 && len(g.Attributes[1]) > 0 {
+			ex.warn("the contents of package %s are left out", header)
+		}
+		return id, nil
+	}
+
+	fmt.Fprintf(&ex.sb, "%s %s%s", gadgetKeywords[gadgetType], name, color)
+	var members []string
+	for section, atts := range g.Attributes[1:] {
+		for _, att := range atts {
+			members = append(members, member(att, gadgetType == component.Enumeration || section == 0))
+		}
+	}
+	if len(members) == 0 {
+		ex.sb.WriteString("\n")
+		return id, nil
+	}
+	ex.sb.WriteString(" {\n")
+	for _, m := range members {
+		fmt.Fprintf(&ex.sb, "    %s\n", m)
+	}
+	ex.sb.WriteString("}\n")
+	return id, nil
+}
+
+// name is how a gadget with header is declared, and the name relations refer to it by.
+// Headers PlantUML cannot take as they are, or taken already, are quoted and given an alias.
+func (ex *exporter) name(header string, i int) (id, declared string) {
+	if plainName.MatchString(header) {
+		id, _, _ = strings.Cut(header, "<")
+		if !ex.used[id] {
+			ex.used[id] = true
+			return id, header
+		}
+	}
+	id = ex.alias("G", i)
+	return id, fmt.Sprintf(`"%s" as %s`, strings.ReplaceAll(header, `"`, "'"), id)
+}
+
+// alias names the i-th gadget after prefix, as G3 or N3
+func (ex *exporter) alias(prefix string, i int) string {
+	id := fmt.Sprintf("%s%d", prefix, i+1)
+	for ex.used[id] {
+		id += "_"
+	}
+	ex.used[id] = true
+	return id
+}
+
+// member writes an attribute of a member section, with the modifiers its style stands for.
+// PlantUML tells fields from methods by their parentheses, the ones it would take wrong say which they are.
+func member(att filedata.Attribute, field bool) string {
+	content := att.Content
+	if m, err := attribute.ParseMember(content); err == nil {
+		m.Static = m.Static || att.Style&attribute.Underline != 0
+		m.Abstract = m.Abstract || (m.Kind == attribute.Operation && att.Style&attribute.Italic != 0)
+		content = m.String()
+	}
+	switch parens := strings.Contains(content, "("); {
+	case field && parens:
+		return "{field} " + content
+	case !field && !parens:
+		return "{method} " + content
+	}
+	return content
+}
+
+// association writes a relation between gadgets named ids, and the link to its association class
+func (ex *exporter) association(a filedata.Association, ids []string) duerror.DUError {
+	assType := component.AssociationType(a.AssType)
+	arrow, ok := arrows[assType]
+	if !ok {
+		return duerror.NewInvalidArgumentError("unsupported association type")
+	}
+	for _, p := range a.Parents {
+		if p < 0 || p >= len(ids) {
+			return duerror.NewInvalidArgumentError("association parent out of range")
+		}
+	}
+	start, end := ids[a.Parents[0]], ids[a.Parents[1]]
+
+	switch component.Navigability(a.Navigability[0]) {
+	case component.Navigable:
+		arrow = "<" + arrow
+	case component.NonNavigable:
+		arrow = "x" + arrow
+	}
+	switch component.Navigability(a.Navigability[1]) {
+	case component.Navigable:
+		arrow += ">"
+	case component.NonNavigable:
+		arrow += "x"
+	}
+
+	l := a.Labels
+	line := start
+	if label := ex.endLabel(l.StartMultiplicity, l.StartRole, start, end); label != "" {
+		line += ` "` + label + `"`
+	}
+	line += " " + arrow
+	if label := ex.endLabel(l.EndMultiplicity, l.EndRole, start, end); label != "" {
+		line += ` "` + label + `"`
+	}
+	line += " " + end
+	if l.Name != "" {
+		line += " : " + l.Name
+		switch component.ReadingDirection(l.ReadingDirection) {
+		case component.ReadingTowardEnd:
+			line += " >"
+		case component.ReadingTowardStart:
+			line += " <"
+		}
+	}
+	ex.sb.WriteString(line + "\n")
+
+	if a.Class != nil {
+		if *a.Class < 0 || *a.Class >= len(ids) {
+			return duerror.NewInvalidArgumentError("association class out of range")
+		}
+		fmt.Fprintf(&ex.sb, "(%s, %s) .. %s\n", start, end, ids[*a.Class])
+	}
+	if len(a.Attributes) > 0 {
+		ex.warn("the text on the association from %s to %s is left out", start, end)
+	}
+	return nil
+}
+
+// endLabel is the label at an end of a relation, PlantUML has room for one of multiplicity and role only
+func (ex *exporter) endLabel(multiplicity, role, start, end string) string {
+	if multiplicity != "" && role != "" {
+		ex.warn("the role %s on the association from %s to %s is left out, its multiplicity is kept", role, start, end)
+	}
+	if multiplicity != "" {
+		return multiplicity
+	}
+	return strings.ReplaceAll(role, `"`, "'")
+}
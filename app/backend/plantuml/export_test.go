@@ -0,0 +1,175 @@
+package plantuml
+
+import (
+	"testing"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/component/attribute"
+	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/filedata"
+	"Dr.uml/backend/umldiagram"
+	"github.com/stretchr/testify/assert"
+)
+
+// gadget builds a gadget of gadgetType whose sections hold the given contents
+func gadget(gadgetType component.GadgetType, sections ...[]string) filedata.Gadget {
+	g := filedata.Gadget{GadgetType: int(gadgetType), Color: drawdata.DefaultGadgetColor}
+	for _, contents := range sections {
+		atts := []filedata.Attribute{}
+		for _, content := range contents {
+			atts = append(atts, filedata.Attribute{Content: content, Size: 12})
+		}
+		g.Attributes = append(g.Attributes, atts)
+	}
+	return g
+}
+
+func TestExport(t *testing.T) {
+	stack := gadget(component.Class, []string{"Stack[T any]"}, nil, []string{"+ Push(v: T)", "Pop() T", "size"})
+	stack.Color = "#FFCC00"
+	shape := gadget(component.AbstractClass, []string{"Shape"}, []string{"count: int", "broken(: int"}, []string{"+ Area(): float64"})
+	shape.Attributes[1][0].Style = attribute.Underline
+	shape.Attributes[2][0].Style = attribute.Italic
+	class := 5
+	fd := filedata.Diagram{
+		Name:        "Shapes",
+		DiagramType: int(umldiagram.ClassDiagram),
+		Gadgets: []filedata.Gadget{
+			shape,
+			gadget(component.Class, []string{"Circle"}, []string{"radius: float64"}, nil),
+			gadget(component.Interface, []string{"Drawable"}, nil, []string{"Draw()"}),
+			gadget(component.Enumeration, []string{"Color"}, []string{"Red", "Green"}),
+			stack,
+			gadget(component.Class, []string{"Circle"}, nil, nil),
+			gadget(component.Note, []string{"round", "things"}),
+		},
+		Associations: []filedata.Association{
+			{AssType: component.Extension, Parents: [2]int{1, 0}},
+			{AssType: component.Implementation, Parents: [2]int{0, 2}},
+			{AssType: component.Composition, Parents: [2]int{3, 1}, Navigability: [2]int{int(component.Navigable), 0},
+				Labels: filedata.AssLabels{StartMultiplicity: "1", EndMultiplicity: "0..*", Name: "fills", ReadingDirection: int(component.ReadingTowardStart)}},
+			{AssType: component.Dependency, Parents: [2]int{4, 3}},
+			{AssType: component.AssociationClass, Parents: [2]int{1, 4}, Class: &class,
+				Labels: filedata.AssLabels{StartRole: "shape", EndRole: "store"}},
+			{AssType: component.PlainAssociation, Parents: [2]int{1, 2}, Navigability: [2]int{int(component.NonNavigable), int(component.Navigable)}},
+			{AssType: component.Aggregation, Parents: [2]int{2, 4}},
+			{AssType: component.DirectedAssociation, Parents: [2]int{5, 1}},
+		},
+	}
+	got, warnings, err := Export(fd)
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.Equal(t, `@startuml Shapes
+abstract class Shape {
+    {static} count: int
+    {field} broken(: int
+    {abstract} + Area(): float64
+}
+class Circle {
+    radius: float64
+}
+interface Drawable {
+    Draw()
+}
+enum Color {
+    Red
+    Green
+}
+class "Stack[T any]" as G5 #FFCC00 {
+    + Push(v: T)
+    Pop() T
+    {method} size
+}
+class "Circle" as G6
+note as N7
+    round
+    things
+end note
+
+Circle --|> Shape
+Shape ..|> Drawable
+Color "1" <--* "0..*" Circle : fills <
+G5 ..> Color
+Circle "shape" -- "store" G5
+(Circle, G5) .. G6
+Circle x--> Drawable
+Drawable --o G5
+G6 --> Circle
+@enduml
+`, got)
+}
+
+func TestExport_Warnings(t *testing.T) {
+	fd := filedata.Diagram{
+		Name:        "Left out",
+		DiagramType: int(umldiagram.ClassDiagram),
+		Gadgets: []filedata.Gadget{
+			gadget(component.Class, []string{"A", "second"}, nil, nil),
+			gadget(component.Package, []string{"pkg"}, []string{"A and B"}),
+		},
+		Associations: []filedata.Association{
+			{AssType: component.PlainAssociation, Parents: [2]int{0, 1},
+				Labels:     filedata.AssLabels{StartMultiplicity: "1", StartRole: "owner"},
+				Attributes: []filedata.AssAttribute{{Content: "note", Size: 12, Ratio: 0.5}}},
+		},
+	}
+	got, warnings, err := Export(fd)
+	assert.NoError(t, err)
+	assert.Equal(t, "@startuml\nclass A\npackage pkg {\n}\n\nA \"1\" -- pkg\n@enduml\n", got)
+	assert.Equal(t, []Warning{
+		{Message: "the header of A has more than one line, the others are left out"},
+		{Message: "the contents of package pkg are left out"},
+		{Message: "the role owner on the association from A to pkg is left out, its multiplicity is kept"},
+		{Message: "the text on the association from A to pkg is left out"},
+	}, warnings)
+}
+
+func TestExport_Errors(t *testing.T) {
+	_, _, err := Export(filedata.Diagram{DiagramType: 0})
+	assert.Error(t, err)
+	_, _, err = Export(filedata.Diagram{
+		DiagramType:  int(umldiagram.ClassDiagram),
+		Gadgets:      []filedata.Gadget{gadget(component.Class, []string{"A"}, nil, nil)},
+		Associations: []filedata.Association{{AssType: component.Extension, Parents: [2]int{0, 1}}},
+	})
+	assert.Error(t, err)
+
+	// gadgets without attribute sections are refused, not indexed
+	for _, gadgetType := range []component.GadgetType{component.Class, component.Note} {
+		_, _, err = Export(filedata.Diagram{
+			DiagramType: int(umldiagram.ClassDiagram),
+			Gadgets:     []filedata.Gadget{{GadgetType: int(gadgetType)}},
+		})
+		assert.Error(t, err)
+	}
+}
+
+func TestExport_RoundTrip(t *testing.T) {
+	d, _, err := Import(shopSource, "Shop")
+	assert.NoError(t, err)
+	fd, err := d.GetFileData()
+	assert.NoError(t, err)
+	src, warnings, err := Export(fd)
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	again, warnings, err := Import(src, "Shop")
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+	fdAgain, err := again.GetFileData()
+	assert.NoError(t, err)
+
+	// the same gadgets and associations, wherever they are
+	strip := func(fd filedata.Diagram) filedata.Diagram {
+		for i := range fd.Gadgets {
+			fd.Gadgets[i].ID, fd.Gadgets[i].X, fd.Gadgets[i].Y = "", 0, 0
+		}
+		for i := range fd.Associations {
+			fd.Associations[i].ID = ""
+			fd.Associations[i].StartRatio, fd.Associations[i].EndRatio = [2]float64{}, [2]float64{}
+		}
+		fd.LastModified = fdAgain.LastModified
+		return fd
+	}
+	assert.Equal(t, strip(fd), strip(fdAgain))
+}
@@ -0,0 +1,895 @@
+package plantuml
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/component/attribute"
+	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/filedata"
+	"Dr.uml/backend/layout"
+	"Dr.uml/backend/umldiagram"
+	"Dr.uml/backend/utils"
+	"Dr.uml/backend/utils/duerror"
+)
+
+// a name as relations refer to it, quoted or not, and an arrow, with its heads, options and direction
+const (
+	nameToken  = `"[^"]+"|[\p{L}_$][\p{L}\p{N}_$]*(?:\.[\p{L}_$][\p{L}\p{N}_$]*)*`
+	arrowToken = `[<>|*ox#{}+^()0]*[-.](?:[^\s"]*[-.])?[<>|*ox#{}+^()0]*`
+)
+
+var (
+	startLine       = regexp.MustCompile(`(?i)^@start(\w+)`)
+	sequenceLine    = regexp.MustCompile(`(?i)^(participant|actor|boundary|control|collections|queue|autonumber|activate|deactivate)\b`)
+	directiveLine   = regexp.MustCompile(`(?i)^(skinparam|hide|show|remove|restrict|title|header|footer|caption|legend|scale|set|left to right direction|top to bottom direction|allowmixing|newpage|mainframe|!\w+)\b\s*(.*)$`)
+	declarationLine = regexp.MustCompile(`(?i)^(abstract\s+class|abstract|class|interface|enum|struct|object|entity|exception|record|dataclass|metaclass|annotation|stereotype|protocol)\s+(.+)$`)
+	packageLine     = regexp.MustCompile(`(?i)^(package|namespace|together|rectangle|frame|folder|node|cloud|database)\b\s*(.*?)\s*\{$`)
+	floatingNote    = regexp.MustCompile(`(?i)^note\s+"([^"]*)"\s+as\s+(` + nameToken + `)\s*(#\S+)?$`)
+	namedNote       = regexp.MustCompile(`(?i)^note\s+as\s+(` + nameToken + `)\s*(#\S+)?$`)
+	attachedNote    = regexp.MustCompile(`(?i)^note(?:\s+(?:left|right|top|bottom))?(?:\s+of\s+(` + nameToken + `)|\s+(on\s+link))?\s*(#\S+)?\s*(?::\s*(.*))?$`)
+	relationLine    = regexp.MustCompile(`^(` + nameToken + `)\s*(?:"([^"]*)"\s*)?(` + arrowToken + `)\s*(?:"([^"]*)"\s*)?(` + nameToken + `)\s*(?::\s*(.*))?$`)
+	classLinkLine   = regexp.MustCompile(`^\(\s*(` + nameToken + `)\s*,\s*(` + nameToken + `)\s*\)\s*[-.]+\s*(` + nameToken + `)$`)
+	memberLine      = regexp.MustCompile(`^(` + nameToken + `)\s*:\s*(.+)$`)
+
+	declaredName  = regexp.MustCompile(`^("[^"]+"|[^\s"<{#]+(?:<[^<>]*>)?)`)
+	aliasName     = regexp.MustCompile(`^\s+as\s+("[^"]+"|[^\s"<{#]+)`)
+	inheritance   = regexp.MustCompile(`(?i)^(extends|implements)\s+((?:` + nameToken + `)(?:\s*,\s*(?:` + nameToken + `))*)`)
+	hexColor      = regexp.MustCompile(`^#([0-9a-fA-F]{6}|[0-9a-fA-F]{3})$`)
+	separatorLine = regexp.MustCompile(`^(--|\.\.|==|__)`)
+	markup        = regexp.MustCompile(`(?i)</?(font|color|b|i|u|s|strike|size|back|sub|sup|w|code)\b[^<>]*>`)
+	forcedKind    = regexp.MustCompile(`\{(field|method)\}\s*`)
+	// Go style members, "name Type" for a field and "name Type" for a parameter
+	goTyped     = regexp.MustCompile(`^((?:\{\w+\}\s*)*[+\-#~]?\s*(?:\{\w+\}\s*)*)([\p{L}_][\p{L}\p{N}_]*)\s+([^\s:=(].*)$`)
+	goOpening   = regexp.MustCompile(`^((?:\{\w+\}\s*)*[+\-#~]?\s*(?:\{\w+\}\s*)*[\p{L}_][\p{L}\p{N}_]*)\(`)
+	arrowOption = regexp.MustCompile(`\[[^\]]*\]`)
+	arrowLine   = regexp.MustCompile(`(?i)^[-.]+(?:(?:up|down|left|right|le|ri|do|u|d|l|r)[-.]+)?$`)
+)
+
+// lookAlikes are the other kinds of class PlantUML has, each read as the gadget type closest to it
+var lookAlikes = map[string]component.GadgetType{
+	"struct":     component.Class,
+	"object":     component.Class,
+	"entity":     component.Class,
+	"exception":  component.Class,
+	"record":     component.Class,
+	"dataclass":  component.Class,
+	"metaclass":  component.Class,
+	"annotation": component.Class,
+	"stereotype": component.Class,
+	"protocol":   component.Interface,
+}
+
+// decl is a gadget being read, its sections after the header listed as text
+type decl struct {
+	gadgetType component.GadgetType
+	header     string
+	color      string
+	sections   [3][]string // the note text goes in the first one, members in the others
+	declared   bool        // false while only relations have named it
+}
+
+// relation is an association being read, parents index into the decls
+type relation struct {
+	assType      component.AssociationType
+	parents      [2]int
+	navigability [2]component.Navigability
+	labels       filedata.AssLabels
+	class        int // the association class, -1 when there is none
+}
+
+type importer struct {
+	lines     []string
+	pos       int // of the next line
+	decls     []*decl
+	ids       map[string]int // indices into decls, by name or alias
+	relations []relation
+	packages  []string // the packages the current line is in
+	notes     int
+	warnings  []Warning
+	warned    map[string]bool
+}
+
+// Import reads a PlantUML class diagram into a diagram called name, laid out by layout.Layout.
+// It reads the first diagram of src, between @startuml and @enduml, which may be left out.
+// Classes, abstract classes, interfaces and enums become gadgets with their members, the other
+// kinds of class the ones closest to them, and notes become notes. Relations become associations,
+// with their multiplicities, roles and name. Whatever else the diagram holds is left out, or
+// changed, with a warning.
+func Import(src string, name string) (*umldiagram.UMLDiagram, []Warning, duerror.DUError) {
+	im := importer{
+		lines:    preprocess(src),
+		ids:      make(map[string]int),
+		warnings: []Warning{},
+		warned:   make(map[string]bool),
+	}
+	if err := im.read(); err != nil {
+		return nil, nil, err
+	}
+	fd, err := im.diagram(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := layout.Layout(&fd, nil); err != nil {
+		return nil, nil, err
+	}
+	d, err := umldiagram.LoadUMLDiagramFromFileData(fd)
+	if err != nil {
+		return nil, nil, err
+	}
+	return d, im.warnings, nil
+}
+
+// preprocess splits src into trimmed lines, blanking out comments but keeping the line numbers
+func preprocess(src string) []string {
+	var sb strings.Builder
+	for {
+		start := strings.Index(src, "/'")
+		if start < 0 {
+			sb.WriteString(src)
+			break
+		}
+		sb.WriteString(src[:start])
+		end := strings.Index(src[start+2:], "'/")
+		if end < 0 {
+			// an unclosed comment runs to the end
+			sb.WriteString(strings.Repeat("\n", strings.Count(src[start:], "\n")))
+			break
+		}
+		sb.WriteString(strings.Repeat("\n", strings.Count(src[start:start+2+end], "\n")))
+		src = src[start+2+end+2:]
+	}
+	lines := strings.Split(strings.ReplaceAll(sb.String(), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "'") {
+			line = ""
+		}
+		lines[i] = line
+	}
+	return lines
+}
+
+func (im *importer) warn(line int, format string, args ...any) {
+	im.warnings = append(im.warnings, Warning{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// warnOnce warns about what may happen on many lines only the first time, key tells them apart
+func (im *importer) warnOnce(key string, line int, format string, args ...any) {
+	if im.warned[key] {
+		return
+	}
+	im.warned[key] = true
+	im.warn(line, format, args...)
+}
+
+// next returns the next line and its number, ok is false past the last one
+func (im *importer) next() (text string, line int, ok bool) {
+	if im.pos >= len(im.lines) {
+		return "", 0, false
+	}
+	im.pos++
+	return im.lines[im.pos-1], im.pos, true
+}
+
+// read goes through the statements of the first diagram
+func (im *importer) read() duerror.DUError {
+	// the diagram starts after the first @startuml, or at the top when there is none
+	for i, text := range im.lines {
+		m := startLine.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		if !strings.EqualFold(m[1], "uml") {
+			return duerror.NewInvalidArgumentError("only class diagrams can be imported, not @start" + m[1])
+		}
+		im.pos = i + 1
+		break
+	}
+	for {
+		text, line, ok := im.next()
+		if !ok {
+			return nil
+		}
+		if strings.HasPrefix(strings.ToLower(text), "@enduml") {
+			im.rest()
+			return nil
+		}
+		if err := im.statement(text, line); err != nil {
+			return err
+		}
+	}
+}
+
+// rest warns when there are more diagrams after the first
+func (im *importer) rest() {
+	for {
+		text, line, ok := im.next()
+		if !ok {
+			return
+		}
+		if text != "" {
+			im.warn(line, "only the first diagram is imported, the rest is left out")
+			return
+		}
+	}
+}
+
+func (im *importer) statement(text string, line int) duerror.DUError {
+	if text == "" {
+		return nil
+	}
+	if strings.HasPrefix(text, "}") {
+		if len(im.packages) == 0 {
+			im.warn(line, "unexpected \"}\" is left out")
+			return nil
+		}
+		im.packages = im.packages[:len(im.packages)-1]
+		return nil
+	}
+	if strings.HasPrefix(text, "@") {
+		im.warn(line, "%s is left out", text)
+		return nil
+	}
+	if m := directiveLine.FindStringSubmatch(text); m != nil {
+		im.directive(strings.ToLower(m[1]), m[2], line)
+		return nil
+	}
+	if m := packageLine.FindStringSubmatch(text); m != nil {
+		im.packages = append(im.packages, m[2])
+		if m[2] == "" {
+			im.warn(line, "%s is left out, its contents are imported", strings.ToLower(m[1]))
+		} else {
+			im.warn(line, "%s %s is left out, its contents are imported", strings.ToLower(m[1]), m[2])
+		}
+		return nil
+	}
+	if strings.HasPrefix(strings.ToLower(text), "note") {
+		if im.note(text, line) {
+			return nil
+		}
+	}
+	if m := declarationLine.FindStringSubmatch(text); m != nil {
+		im.declaration(strings.Join(strings.Fields(strings.ToLower(m[1])), " "), m[2], line)
+		return nil
+	}
+	if m := classLinkLine.FindStringSubmatch(text); m != nil {
+		im.classLink(m[1], m[2], m[3], line)
+		return nil
+	}
+	if m := relationLine.FindStringSubmatch(text); m != nil {
+		if im.relation(m, line) {
+			return nil
+		}
+	}
+	if m := memberLine.FindStringSubmatch(text); m != nil {
+		d := im.decls[im.gadget(m[1], line)]
+		im.addMember(d, m[2], line)
+		return nil
+	}
+	if sequenceLine.MatchString(text) {
+		return duerror.NewInvalidArgumentError(fmt.Sprintf("line %d: only class diagrams can be imported, this looks like a sequence diagram", line))
+	}
+	im.warn(line, "%q is not understood, it is left out", text)
+	return nil
+}
+
+// directive skips the settings of how PlantUML draws, and the blocks some of them open
+func (im *importer) directive(keyword, rest string, line int) {
+	im.warnOnce(keyword, line, "%s is not supported, it is left out", keyword)
+	switch {
+	case strings.HasSuffix(rest, "{"):
+		im.skipBlock()
+	case keyword == "legend",
+		(keyword == "title" || keyword == "header" || keyword == "footer" || keyword == "caption") &&
+			strings.Trim(strings.ToLower(rest), "leftrightcenterbottop ") == "":
+		im.skipUntil("end" + keyword)
+	}
+}
+
+// skipBlock skips the lines up to the "}" closing a block already opened
+func (im *importer) skipBlock() {
+	depth := 1
+	for depth > 0 {
+		text, _, ok := im.next()
+		if !ok {
+			return
+		}
+		if strings.HasSuffix(text, "{") {
+			depth++
+		}
+		if strings.HasPrefix(text, "}") {
+			depth--
+		}
+	}
+}
+
+// skipUntil skips the lines up to end, which may be written with a space, as "end note"
+func (im *importer) skipUntil(end string) []string {
+	var skipped []string
+	for {
+		text, _, ok := im.next()
+		if !ok {
+			return skipped
+		}
+		if strings.EqualFold(strings.ReplaceAll(text, " ", ""), end) {
+			return skipped
+		}
+		skipped = append(skipped, text)
+	}
+}
+
+// note reads a note into a gadget of its own, it returns false when text is no note after all
+func (im *importer) note(text string, line int) bool {
+	var id, color string
+	var content []string
+	if m := floatingNote.FindStringSubmatch(text); m != nil {
+		id, color, content = m[2], m[3], strings.Split(m[1], `\n`)
+	} else if m := namedNote.FindStringSubmatch(text); m != nil {
+		id, color = m[1], m[2]
+		content = im.skipUntil("endnote")
+	} else if m := attachedNote.FindStringSubmatch(text); m != nil {
+		color = m[3]
+		switch {
+		case m[1] != "":
+			im.warn(line, "the note is not attached to %s, it stands on its own", unquote(m[1]))
+		case m[2] != "":
+			im.warn(line, "the note is not attached to its link, it stands on its own")
+		}
+		if strings.Contains(text, ":") {
+			content = strings.Split(m[4], `\n`)
+		} else {
+			content = im.skipUntil("endnote")
+		}
+	} else {
+		return false
+	}
+
+	im.notes++
+	d := &decl{gadgetType: component.Note, declared: true, color: im.color(color, line)}
+	for _, c := range content {
+		d.sections[0] = append(d.sections[0], im.printable(strings.TrimSpace(c), line))
+	}
+	im.decls = append(im.decls, d)
+	if id == "" {
+		// notes without an alias cannot be referred to, but are named all the same
+		id = fmt.Sprintf("note %d", im.notes)
+	}
+	im.ids[unquote(id)] = len(im.decls) - 1
+	return true
+}
+
+// gadget returns the index of the decl called name, declaring a class when there is none
+func (im *importer) gadget(name string, line int) int {
+	name = unquote(name)
+	if i, ok := im.ids[name]; ok {
+		return i
+	}
+	im.decls = append(im.decls, &decl{gadgetType: component.Class, header: im.printable(name, line)})
+	im.ids[name] = len(im.decls) - 1
+	return len(im.decls) - 1
+}
+
+// declaration reads the rest of "class Name<T> as Alias <<stereotype>> #color extends A implements B {"
+func (im *importer) declaration(keyword, rest string, line int) {
+	gadgetType := component.Class
+	switch keyword {
+	case "abstract class", "abstract":
+		gadgetType = component.AbstractClass
+	case "interface":
+		gadgetType = component.Interface
+	case "enum":
+		gadgetType = component.Enumeration
+	case "class":
+	default:
+		gadgetType = lookAlikes[keyword]
+		im.warn(line, "%s is read as %s", keyword, articled(gadgetKeywords[gadgetType]))
+	}
+
+	m := declaredName.FindStringSubmatch(rest)
+	if m == nil {
+		im.warn(line, "%q is not understood, it is left out", keyword+" "+rest)
+		return
+	}
+	id, header := m[1], m[1]
+	rest = rest[len(m[0]):]
+	if m := aliasName.FindStringSubmatch(rest); m != nil {
+		if strings.HasPrefix(m[1], `"`) {
+			header = m[1]
+		} else {
+			id = m[1]
+		}
+		rest = rest[len(m[0]):]
+	}
+	header = unquote(header)
+	if !strings.HasPrefix(id, `"`) {
+		// generics are part of the header, not of the name relations use
+		id, _, _ = strings.Cut(id, "<")
+	}
+
+	i := im.gadget(id, line)
+	d := im.decls[i]
+	switch {
+	case !d.declared:
+		d.gadgetType, d.header, d.declared = gadgetType, im.printable(header, line), true
+	case d.gadgetType != gadgetType:
+		im.warn(line, "%s is declared again as %s, it stays %s", header, articled(keyword), articled(gadgetKeywords[d.gadgetType]))
+	}
+
+	for {
+		rest = strings.TrimSpace(rest)
+		switch {
+		case rest == "":
+			return
+		case strings.HasPrefix(rest, "<<"):
+			end := strings.Index(rest, ">>")
+			if end < 0 {
+				end = len(rest) - 2
+			}
+			im.warn(line, "stereotype %s of %s is left out", rest[:end+2], header)
+			rest = rest[end+2:]
+		case strings.HasPrefix(rest, "#"):
+			color, after, _ := strings.Cut(rest, " ")
+			d.color = im.color(color, line)
+			rest = after
+		case inheritance.MatchString(rest):
+			m := inheritance.FindStringSubmatch(rest)
+			var assType component.AssociationType = component.Extension
+			if strings.EqualFold(m[1], "implements") {
+				assType = component.Implementation
+			}
+			for _, parent := range strings.Split(m[2], ",") {
+				im.relations = append(im.relations, relation{assType: assType, parents: [2]int{i, im.gadget(strings.TrimSpace(parent), line)}, class: -1})
+			}
+			rest = rest[len(m[0]):]
+		case strings.HasPrefix(rest, "{"):
+			im.body(d, rest[1:], line)
+			return
+		default:
+			im.warn(line, "%q in the declaration of %s is not understood, it is left out", rest, header)
+			return
+		}
+	}
+}
+
+// body reads the members between braces, after the "{" on line
+func (im *importer) body(d *decl, rest string, line int) {
+	if inner, _, closed := strings.Cut(rest, "}"); closed {
+		if inner = strings.TrimSpace(inner); inner != "" {
+			im.addMember(d, inner, line)
+		}
+		return
+	}
+	for {
+		text, n, ok := im.next()
+		if !ok {
+			im.warn(line, "the body of %s is not closed", d.header)
+			return
+		}
+		switch {
+		case strings.HasPrefix(text, "}"):
+			return
+		case text == "":
+		case separatorLine.MatchString(text):
+			im.warnOnce("separator", n, "separators in class bodies are left out")
+		default:
+			im.addMember(d, text, n)
+		}
+	}
+}
+
+// addMember puts the member text into the section of d its kind goes in
+func (im *importer) addMember(d *decl, text string, line int) {
+	content, kind := im.member(text, line)
+	section := 1
+	if kind == attribute.Operation && d.gadgetType != component.Enumeration {
+		section = 2
+	}
+	d.sections[section] = append(d.sections[section], im.printable(content, line))
+}
+
+// member turns text into the canonical form of a member, reading Go style members too.
+// What cannot be read is kept as written, a field unless it has parentheses.
+func (im *importer) member(text string, line int) (string, attribute.MemberKind) {
+	if cleaned := markup.ReplaceAllString(text, ""); cleaned != text {
+		im.warnOnce("markup", line, "text markup like <font> is left out of members")
+		text = cleaned
+	}
+	kind, forced := attribute.Field, false
+	if m := forcedKind.FindStringSubmatch(text); m != nil {
+		forced = true
+		if m[1] == "method" {
+			kind = attribute.Operation
+		}
+		text = strings.TrimSpace(forcedKind.ReplaceAllString(text, ""))
+	}
+
+	m, err := attribute.ParseMember(text)
+	if err != nil {
+		m, err = attribute.ParseMember(goMember(text))
+	}
+	if err != nil {
+		im.warn(line, "member %q is kept as written, it cannot be read: %s", text, err.Error())
+		if !forced && strings.Contains(text, "(") {
+			kind = attribute.Operation
+		}
+		return text, kind
+	}
+	if !forced {
+		kind = m.Kind
+	}
+	return m.String(), kind
+}
+
+// goMember rewrites a Go style member, as "name Type" or "Name(a int) (int, error)", in the form of a UML one
+func goMember(text string) string {
+	m := goOpening.FindStringSubmatch(text)
+	if m == nil {
+		if t := goTyped.FindStringSubmatch(text); t != nil {
+			return t[1] + t[2] + ": " + t[3]
+		}
+		return text
+	}
+	// the parameters end at the parenthesis matching the opening one
+	depth, end := 0, -1
+	for i := len(m[0]) - 1; i < len(text) && end < 0; i++ {
+		switch text[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		}
+	}
+	if end < 0 {
+		return text
+	}
+	params := splitTopLevel(text[len(m[0]):end])
+	for i, p := range params {
+		p = strings.TrimSpace(p)
+		if t := goTyped.FindStringSubmatch(p); t != nil && t[1] == "" {
+			p = t[2] + ": " + t[3]
+		}
+		params[i] = p
+	}
+	result := strings.TrimSpace(text[end+1:])
+	if result != "" && !strings.HasPrefix(result, ":") {
+		result = ": " + result
+	}
+	return m[1] + "(" + strings.Join(params, ", ") + ")" + result
+}
+
+// splitTopLevel splits s at the commas outside of brackets
+func splitTopLevel(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(', '[', '{', '<':
+			depth++
+		case ')', ']', '}', '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// the kinds of arrowhead, as far as associations tell them apart
+const (
+	headNone = iota
+	headTriangle
+	headDiamond
+	headHollowDiamond
+	headOpen
+	headCross
+	headUnsupported
+)
+
+var leftHeads = map[string]int{"": headNone, "<|": headTriangle, "^": headTriangle, "*": headDiamond, "o": headHollowDiamond, "<": headOpen, "x": headCross}
+var rightHeads = map[string]int{"": headNone, "|>": headTriangle, "^": headTriangle, "*": headDiamond, "o": headHollowDiamond, ">": headOpen, "x": headCross}
+
+// relation reads the match of relationLine, it returns false when its arrow is no arrow after all
+func (im *importer) relation(m []string, line int) bool {
+	arrow := m[3]
+	hidden := false
+	for _, option := range arrowOption.FindAllString(arrow, -1) {
+		hidden = hidden || strings.Contains(option, "hidden")
+		arrow = strings.Replace(arrow, option, "", 1)
+	}
+	first, last := strings.IndexAny(arrow, "-."), strings.LastIndexAny(arrow, "-.")
+	if first < 0 || !arrowLine.MatchString(arrow[first:last+1]) ||
+		strings.Trim(arrow[:first]+arrow[last+1:], "<>|*ox#{}+^()0") != "" {
+		return false
+	}
+	if hidden {
+		im.warn(line, "hidden links are left out")
+		return true
+	}
+	leftHead, rightHead := arrow[:first], arrow[last+1:]
+	dotted := strings.Contains(arrow[first:last+1], ".")
+
+	a, b := im.gadget(m[1], line), im.gadget(m[5], line)
+	if im.decls[a].gadgetType == component.Note || im.decls[b].gadgetType == component.Note {
+		im.warn(line, "links to notes are left out")
+		return true
+	}
+	heads := [2]int{headUnsupported, headUnsupported}
+	if h, ok := leftHeads[leftHead]; ok {
+		heads[0] = h
+	}
+	if h, ok := rightHeads[rightHead]; ok {
+		heads[1] = h
+	}
+	for i, head := range []string{leftHead, rightHead} {
+		if heads[i] == headUnsupported {
+			im.warn(line, "arrowhead %q is not supported, it is left out", head)
+			heads[i] = headNone
+		}
+	}
+
+	r := arrowRelation(heads, dotted, func(format string, args ...any) { im.warn(line, format, args...) })
+	// start is the side of the arrow the relation starts at, 0 for the left
+	start := 0
+	if r.parents[0] == 1 {
+		start = 1
+	}
+	r.parents = [2]int{a, b}
+	if start == 1 {
+		r.parents = [2]int{b, a}
+	}
+	for side, text := range []string{m[2], m[4]} {
+		im.endLabel(&r.labels, text, side == start, line)
+	}
+	if m[6] != "" {
+		name := strings.TrimSpace(strings.ReplaceAll(m[6], `\n`, " "))
+		toward := -1
+		switch {
+		case strings.HasSuffix(name, ">") || strings.HasPrefix(name, ">"):
+			toward = 1
+		case strings.HasSuffix(name, "<") || strings.HasPrefix(name, "<"):
+			toward = 0
+		}
+		if toward >= 0 {
+			name = strings.TrimSpace(strings.Trim(name, "<>"))
+			r.labels.ReadingDirection = int(component.ReadingTowardEnd)
+			if toward == start {
+				r.labels.ReadingDirection = int(component.ReadingTowardStart)
+			}
+		}
+		r.labels.Name = im.printable(name, line)
+	}
+	im.relations = append(im.relations, r)
+	return true
+}
+
+// arrowRelation tells the association an arrow stands for from its heads, on the left then the right.
+// The parents of the relation returned are the sides of the arrow, 0 for the left, in the order of the association.
+func arrowRelation(heads [2]int, dotted bool, warn func(format string, args ...any)) relation {
+	r := relation{parents: [2]int{0, 1}, class: -1}
+	structural := func(h int) bool { return h == headTriangle || h == headDiamond || h == headHollowDiamond }
+	navigability := func(h int) component.Navigability {
+		switch h {
+		case headOpen:
+			return component.Navigable
+		case headCross:
+			return component.NonNavigable
+		}
+		return component.NavigabilityUnspecified
+	}
+	if structural(heads[0]) && structural(heads[1]) {
+		warn("an arrow keeps one of its two heads, the right one")
+		heads[0] = headNone
+	}
+
+	// a triangle or diamond is at the end of the association
+	for _, end := range []int{1, 0} {
+		if !structural(heads[end]) {
+			continue
+		}
+		r.parents = [2]int{1 - end, end}
+		switch heads[end] {
+		case headTriangle:
+			r.assType = component.Extension
+			if dotted {
+				r.assType = component.Implementation
+			}
+		case headDiamond:
+			r.assType = component.Composition
+		case headHollowDiamond:
+			r.assType = component.Aggregation
+		}
+		if dotted && r.assType != component.Implementation {
+			warn("a dotted line with a diamond is read as a solid one")
+		}
+		if other := heads[1-end]; other != headNone {
+			if r.assType == component.Extension || r.assType == component.Implementation {
+				warn("the arrowhead at the other end of a generalization is left out")
+			} else {
+				r.navigability[0] = navigability(other)
+			}
+		}
+		return r
+	}
+
+	if dotted {
+		r.assType = component.Dependency
+		switch {
+		case heads[0] == headOpen && heads[1] == headOpen:
+			warn("a dependency has one arrowhead, the left one is left out")
+		case heads[0] == headOpen:
+			r.parents = [2]int{1, 0}
+		case heads[1] != headOpen:
+			warn("a dotted line without an arrowhead is read as a plain association")
+			r.assType = component.PlainAssociation
+		}
+		if heads[0] == headCross || heads[1] == headCross {
+			warn("a cross on a dotted line is left out")
+		}
+		return r
+	}
+
+	// a solid line with one open arrowhead is directed, from its other end
+	switch {
+	case heads[1] == headOpen && heads[0] != headOpen:
+		r.assType = component.DirectedAssociation
+		r.navigability[0] = navigability(heads[0])
+	case heads[0] == headOpen && heads[1] != headOpen:
+		r.assType = component.DirectedAssociation
+		r.parents = [2]int{1, 0}
+		r.navigability[0] = navigability(heads[1])
+	default:
+		r.assType = component.PlainAssociation
+		r.navigability = [2]component.Navigability{navigability(heads[0]), navigability(heads[1])}
+	}
+	return r
+}
+
+// endLabel puts the quoted text at an end of a relation into its multiplicity, or its role when it is no multiplicity
+func (im *importer) endLabel(labels *filedata.AssLabels, text string, atStart bool, line int) {
+	text = im.printable(strings.TrimSpace(strings.ReplaceAll(text, `\n`, " ")), line)
+	if text == "" {
+		return
+	}
+	isMultiplicity := component.ValidateMultiplicity(text) == nil
+	switch {
+	case atStart && isMultiplicity:
+		labels.StartMultiplicity = text
+	case atStart:
+		labels.StartRole = text
+	case isMultiplicity:
+		labels.EndMultiplicity = text
+	default:
+		labels.EndRole = text
+	}
+}
+
+// classLink reads "(A, B) .. C", making the association between A and B an association class.
+// The first plain association between them is the one, there is a new one when there is none.
+func (im *importer) classLink(a, b, class string, line int) {
+	ia, ib, ic := im.gadget(a, line), im.gadget(b, line), im.gadget(class, line)
+	if im.decls[ic].gadgetType != component.Class {
+		im.warn(line, "%s cannot be an association class, only a class can, the link is left out", im.decls[ic].header)
+		return
+	}
+	if ic == ia || ic == ib {
+		im.warn(line, "%s cannot be the class of its own association, the link is left out", im.decls[ic].header)
+		return
+	}
+	for i, r := range im.relations {
+		if r.assType == component.PlainAssociation && (r.parents == [2]int{ia, ib} || r.parents == [2]int{ib, ia}) {
+			im.relations[i].assType, im.relations[i].class = component.AssociationClass, ic
+			return
+		}
+	}
+	im.relations = append(im.relations, relation{assType: component.AssociationClass, parents: [2]int{ia, ib}, class: ic})
+}
+
+// color reads a hex color, the default one is kept for the others
+func (im *importer) color(color string, line int) string {
+	if color == "" {
+		return drawdata.DefaultGadgetColor
+	}
+	m := hexColor.FindStringSubmatch(color)
+	if m == nil {
+		im.warn(line, "color %s is not a hex color, the default one is kept", color)
+		return drawdata.DefaultGadgetColor
+	}
+	hex := strings.ToUpper(m[1])
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	return "#" + hex
+}
+
+// diagram builds the file data of what was read, at the origin
+func (im *importer) diagram(name string) (filedata.Diagram, duerror.DUError) {
+	fd := filedata.Diagram{
+		Name:            name,
+		DiagramType:     int(umldiagram.ClassDiagram),
+		BackgroundColor: drawdata.DefaultDiagramColor,
+		LastModified:    time.Now(),
+		Gadgets:         make([]filedata.Gadget, 0, len(im.decls)),
+		Associations:    make([]filedata.Association, 0, len(im.relations)),
+	}
+	for _, d := range im.decls {
+		if d.color == "" {
+			d.color = drawdata.DefaultGadgetColor
+		}
+		g, err := component.NewGadget(d.gadgetType, utils.Point{}, 0, d.color, d.header)
+		if err != nil {
+			return filedata.Diagram{}, err
+		}
+		sections := d.sections
+		if d.gadgetType == component.Enumeration {
+			// an enum has its literals only, whatever was read as an operation goes with them
+			sections[1] = append(sections[1], sections[2]...)
+			sections[2] = nil
+		}
+		for section, contents := range sections {
+			for _, content := range contents {
+				if err := g.AddAttribute(section, content); err != nil {
+					return filedata.Diagram{}, err
+				}
+			}
+		}
+		fd.Gadgets = append(fd.Gadgets, g.GetFileData())
+	}
+	for _, r := range im.relations {
+		a := filedata.Association{
+			AssType:      int(r.assType),
+			Parents:      r.parents,
+			Navigability: [2]int{int(r.navigability[0]), int(r.navigability[1])},
+			Attributes:   []filedata.AssAttribute{},
+			Labels:       r.labels,
+		}
+		if r.class >= 0 {
+			a.Class = &r.class
+		}
+		fd.Associations = append(fd.Associations, a)
+	}
+	return fd, nil
+}
+
+// printable leaves out of text the characters the font has no glyph for, text could not be measured with them
+func (im *importer) printable(text string, line int) string {
+	if _, _, err := utils.GetTextSize(text, drawdata.DefaultAttributeFontSize, ""); err == nil {
+		return text
+	}
+	if _, _, err := utils.GetTextSize("", drawdata.DefaultAttributeFontSize, ""); err != nil {
+		// without the font there is nothing to measure with, loading the diagram tells why
+		return text
+	}
+	kept := strings.Map(func(r rune) rune {
+		if _, _, err := utils.GetTextSize(string(r), drawdata.DefaultAttributeFontSize, ""); err != nil {
+			return -1
+		}
+		return r
+	}, text)
+	im.warn(line, "characters the font has no glyph for are left out of %q", text)
+	return kept
+}
+
+func unquote(name string) string {
+	return strings.Trim(name, `"`)
+}
+
+// articled puts "a" or "an" before a keyword
+func articled(keyword string) string {
+	if strings.ContainsRune("aeiou", rune(keyword[0])) {
+		return "an " + keyword
+	}
+	return "a " + keyword
+}
@@ -0,0 +1,265 @@
+package plantuml
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/filedata"
+	"github.com/stretchr/testify/assert"
+)
+
+const shopSource = `@startuml Shop
+' the shop
+abstract class Product #FFCC00 {
+    - id: String
+    {static} + count: int
+    + Price() float64
+    {abstract} + Describe(verbose bool): String
+}
+class Book extends Product implements Priced {
+    isbn string
+}
+interface Priced {
+    + Price(): float64
+}
+enum Status {
+    Open
+    Closed = 2
+}
+class "Order Line" as Line
+class Stack<T>
+/' a block comment
+   over two lines '/
+Order "1" *-- "1..*" Line : holds >
+Customer "buyer" --> "0..*" Order
+Order ..> Status
+Shelf o-- Book
+Customer <-x Line
+Shelf -- Customer
+Loan -- Book
+(Shelf, Customer) .. Loan
+note "Prices are\nin euros" as N1
+@enduml
+`
+
+func TestImport(t *testing.T) {
+	d, warnings, err := Import(shopSource, "Shop")
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+	fd, err := d.GetFileData()
+	assert.NoError(t, err)
+	assert.Equal(t, "Shop", fd.Name)
+
+	index := make(map[string]int)
+	for i, g := range fd.Gadgets {
+		if len(g.Attributes[0]) > 0 {
+			index[g.Attributes[0][0].Content] = i
+		}
+	}
+	contents := func(header string, section int) []string {
+		list := []string{}
+		for _, att := range fd.Gadgets[index[header]].Attributes[section] {
+			list = append(list, att.Content)
+		}
+		return list
+	}
+	gadgetType := func(header string) component.GadgetType {
+		return component.GadgetType(fd.Gadgets[index[header]].GadgetType)
+	}
+
+	assert.Len(t, fd.Gadgets, 11)
+	assert.Equal(t, component.AbstractClass, gadgetType("Product"))
+	assert.Equal(t, "#FFCC00", fd.Gadgets[index["Product"]].Color)
+	assert.Equal(t, []string{"- id: String", "{static} + count: int"}, contents("Product", 1))
+	assert.Equal(t, []string{"+ Price(): float64", "{abstract} + Describe(verbose: bool): String"}, contents("Product", 2))
+	assert.Equal(t, []string{"isbn: string"}, contents("Book", 1))
+	assert.Equal(t, component.Interface, gadgetType("Priced"))
+	assert.Equal(t, []string{"+ Price(): float64"}, contents("Priced", 2))
+	assert.Equal(t, component.Enumeration, gadgetType("Status"))
+	assert.Equal(t, []string{"Open", "Closed = 2"}, contents("Status", 1))
+	assert.Equal(t, component.Class, gadgetType("Order Line"))
+	assert.Equal(t, component.Class, gadgetType("Stack<T>"))
+	// named by relations only
+	assert.Equal(t, component.Class, gadgetType("Order"))
+	assert.Equal(t, component.Class, gadgetType("Loan"))
+
+	var note filedata.Gadget
+	for _, g := range fd.Gadgets {
+		if component.GadgetType(g.GadgetType) == component.Note {
+			note = g
+		}
+	}
+	assert.Len(t, note.Attributes[0], 2)
+	assert.Equal(t, "Prices are", note.Attributes[0][0].Content)
+	assert.Equal(t, "in euros", note.Attributes[0][1].Content)
+
+	type assoc struct {
+		assType      component.AssociationType
+		start, end   string
+		navigability [2]int
+		labels       filedata.AssLabels
+		class        string
+	}
+	var got []assoc
+	for _, a := range fd.Associations {
+		g := assoc{
+			assType:      component.AssociationType(a.AssType),
+			start:        fd.Gadgets[a.Parents[0]].Attributes[0][0].Content,
+			end:          fd.Gadgets[a.Parents[1]].Attributes[0][0].Content,
+			navigability: a.Navigability,
+			labels:       a.Labels,
+		}
+		if a.Class != nil {
+			g.class = fd.Gadgets[*a.Class].Attributes[0][0].Content
+		}
+		got = append(got, g)
+	}
+	assert.ElementsMatch(t, []assoc{
+		{assType: component.Extension, start: "Book", end: "Product"},
+		{assType: component.Implementation, start: "Book", end: "Priced"},
+		{assType: component.Composition, start: "Order Line", end: "Order", labels: filedata.AssLabels{
+			StartMultiplicity: "1..*", EndMultiplicity: "1", Name: "holds", ReadingDirection: int(component.ReadingTowardStart),
+		}},
+		{assType: component.DirectedAssociation, start: "Customer", end: "Order", labels: filedata.AssLabels{
+			StartRole: "buyer", EndMultiplicity: "0..*",
+		}},
+		{assType: component.Dependency, start: "Order", end: "Status"},
+		{assType: component.Aggregation, start: "Book", end: "Shelf"},
+		{assType: component.DirectedAssociation, start: "Order Line", end: "Customer",
+			navigability: [2]int{int(component.NonNavigable), 0}},
+		{assType: component.AssociationClass, start: "Shelf", end: "Customer", class: "Loan"},
+		{assType: component.PlainAssociation, start: "Loan", end: "Book"},
+	}, got)
+
+	// laid out, parents above their children
+	assert.Less(t, fd.Gadgets[index["Product"]].Y, fd.Gadgets[index["Book"]].Y)
+}
+
+func TestImport_Warnings(t *testing.T) {
+	src := `@startuml
+skinparam class {
+    BackgroundColor White
+}
+hide empty members
+package shop {
+    class Cart <<entity>> #pink {
+        -- items --
+        + Add(<b>item</b> Item)
+        + Total(): List<*Item, int>]
+    }
+}
+struct Point
+Cart -[hidden]- Point
+Cart --> Point : "moves"
+Cart #-- Point
+Cart <--> Point
+Cart .. Point
+note left of Cart : heavy
+Cart --> N1
+circle Dot
+@enduml
+@startuml
+class Other
+@enduml
+`
+	d, warnings, err := Import(src, "Shop")
+	assert.NoError(t, err)
+	assert.Equal(t, []Warning{
+		{Line: 2, Message: "skinparam is not supported, it is left out"},
+		{Line: 5, Message: "hide is not supported, it is left out"},
+		{Line: 6, Message: "package shop is left out, its contents are imported"},
+		{Line: 7, Message: "stereotype <<entity>> of Cart is left out"},
+		{Line: 7, Message: "color #pink is not a hex color, the default one is kept"},
+		{Line: 8, Message: "separators in class bodies are left out"},
+		{Line: 9, Message: "text markup like <font> is left out of members"},
+		{Line: 10, Message: `member "+ Total(): List<*Item, int>]" is kept as written, it cannot be read: line 1, column 28: unbalanced ']'`},
+		{Line: 13, Message: "struct is read as a class"},
+		{Line: 14, Message: "hidden links are left out"},
+		{Line: 16, Message: `arrowhead "#" is not supported, it is left out`},
+		{Line: 18, Message: "a dotted line without an arrowhead is read as a plain association"},
+		{Line: 19, Message: "the note is not attached to Cart, it stands on its own"},
+		{Line: 21, Message: `"circle Dot" is not understood, it is left out`},
+		{Line: 23, Message: "only the first diagram is imported, the rest is left out"},
+	}, warnings)
+
+	fd, err := d.GetFileData()
+	assert.NoError(t, err)
+	// Cart, Point, the note and N1, which the note was not called
+	assert.Len(t, fd.Gadgets, 4)
+	cart := fd.Gadgets[0]
+	assert.Equal(t, "+ Add(item: Item)", cart.Attributes[2][0].Content)
+	assert.Equal(t, "+ Total(): List<*Item, int>]", cart.Attributes[2][1].Content)
+	assert.Len(t, fd.Associations, 5)
+	assert.Equal(t, "\"moves\"", fd.Associations[0].Labels.Name)
+	assert.Equal(t, [2]int{int(component.Navigable), int(component.Navigable)}, fd.Associations[2].Navigability)
+}
+
+func TestImport_Errors(t *testing.T) {
+	_, _, err := Import("@startmindmap\n* root\n@endmindmap\n", "Map")
+	assert.Error(t, err)
+	_, _, err = Import("@startuml\nactor User\nUser -> System : log in\n@enduml\n", "Login")
+	assert.Error(t, err)
+}
+
+// the design documents of this repository are PlantUML
+func TestImport_Docs(t *testing.T) {
+	for name, gadgets := range map[string]int{"dcd.iuml": 26, "idcd.puml": 16} {
+		src, err := os.ReadFile(filepath.Join("..", "..", "..", "docs", "graphs", name))
+		assert.NoError(t, err)
+		d, _, duErr := Import(string(src), name)
+		if !assert.NoError(t, duErr, name) {
+			continue
+		}
+		fd, duErr := d.GetFileData()
+		assert.NoError(t, duErr)
+		assert.Len(t, fd.Gadgets, gadgets, name)
+	}
+}
+
+func TestArrowRelation(t *testing.T) {
+	tests := []struct {
+		heads        [2]int
+		dotted       bool
+		assType      component.AssociationType
+		parents      [2]int
+		navigability [2]component.Navigability
+		warns        int
+	}{
+		{[2]int{headTriangle, headNone}, false, component.Extension, [2]int{1, 0}, [2]component.Navigability{}, 0},
+		{[2]int{headNone, headTriangle}, true, component.Implementation, [2]int{0, 1}, [2]component.Navigability{}, 0},
+		{[2]int{headDiamond, headOpen}, false, component.Composition, [2]int{1, 0}, [2]component.Navigability{component.Navigable, 0}, 0},
+		{[2]int{headNone, headHollowDiamond}, true, component.Aggregation, [2]int{0, 1}, [2]component.Navigability{}, 1},
+		{[2]int{headDiamond, headTriangle}, false, component.Extension, [2]int{0, 1}, [2]component.Navigability{}, 1},
+		{[2]int{headOpen, headNone}, true, component.Dependency, [2]int{1, 0}, [2]component.Navigability{}, 0},
+		{[2]int{headNone, headNone}, true, component.PlainAssociation, [2]int{0, 1}, [2]component.Navigability{}, 1},
+		{[2]int{headCross, headOpen}, false, component.DirectedAssociation, [2]int{0, 1}, [2]component.Navigability{component.NonNavigable, 0}, 0},
+		{[2]int{headOpen, headOpen}, false, component.PlainAssociation, [2]int{0, 1}, [2]component.Navigability{component.Navigable, component.Navigable}, 0},
+		{[2]int{headNone, headNone}, false, component.PlainAssociation, [2]int{0, 1}, [2]component.Navigability{}, 0},
+	}
+	for i, tt := range tests {
+		warns := 0
+		r := arrowRelation(tt.heads, tt.dotted, func(string, ...any) { warns++ })
+		assert.Equal(t, tt.assType, r.assType, i)
+		assert.Equal(t, tt.parents, r.parents, i)
+		assert.Equal(t, tt.navigability, r.navigability, i)
+		assert.Equal(t, tt.warns, warns, i)
+	}
+}
+
+func TestGoMember(t *testing.T) {
+	tests := map[string]string{
+		"- ratio float64":                      "- ratio: float64",
+		"+ SetRatio(ratio float64) DUError":    "+ SetRatio(ratio: float64): DUError",
+		"+ Pop() (T, bool)":                    "+ Pop(): (T, bool)",
+		"update func(a, b int) error":          "update: func(a, b int) error",
+		"+ Each(f func(k string, v int)) bool": "+ Each(f: func(k string, v int)): bool",
+		"+ Done()":                             "+ Done()",
+		"count: int":                           "count: int",
+		"closing":                              "closing",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, goMember(in), in)
+	}
+}
@@ -0,0 +1,33 @@
+package plantuml
+
+import (
+	"fmt"
+	"regexp"
+
+	"Dr.uml/backend/component"
+)
+
+// Warning is something of a diagram that could not be carried over, and was left out or changed.
+// Line counts from 1 in the PlantUML text, it is 0 for warnings about no line in particular.
+type Warning struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	if w.Line == 0 {
+		return w.Message
+	}
+	return fmt.Sprintf("line %d: %s", w.Line, w.Message)
+}
+
+// gadgetKeywords are the PlantUML keywords declaring each gadget type
+var gadgetKeywords = map[component.GadgetType]string{
+	component.Class:         "class",
+	component.AbstractClass: "abstract class",
+	component.Interface:     "interface",
+	component.Enumeration:   "enum",
+}
+
+// a name PlantUML takes unquoted, with generics
+var plainName = regexp.MustCompile(`^[\p{L}_$][\p{L}\p{N}_$]*(?:\.[\p{L}_$][\p{L}\p{N}_$]*)*(<[^<>"]*>)?$`)
@@ -0,0 +1,173 @@
+package umldiagram
+
+import (
+	"slices"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/filedata"
+	"Dr.uml/backend/utils"
+	"Dr.uml/backend/utils/duerror"
+)
+
+// how far a duplicate is placed from its original
+const duplicateOffset = 20
+
+// CopySelectedComponents returns a deep copy of the selected gadgets and of every association between two of them.
+// Selected associations with an unselected end are left out.
+func (ud *UMLDiagram) CopySelectedComponents() (filedata.Components, duerror.DUError) {
+	gadgets := make([]*component.Gadget, 0, len(ud.componentsSelected))
+	for c := range ud.componentsSelected {
+		if g, ok := c.(*component.Gadget); ok {
+			gadgets = append(gadgets, g)
+		}
+	}
+	if len(gadgets) == 0 {
+		return filedata.Components{}, duerror.NewInvalidArgumentError("no gadget selected")
+	}
+	// a stable order, so that copying the same selection twice gives the same payload
+	slices.SortFunc(gadgets, func(a, b *component.Gadget) int {
+		if a.GetLayer() != b.GetLayer() {
+			return a.GetLayer() - b.GetLayer()
+		}
+		if a.GetPoint().Y != b.GetPoint().Y {
+			return a.GetPoint().Y - b.GetPoint().Y
+		}
+		return a.GetPoint().X - b.GetPoint().X
+	})
+	return ud.getComponentsFileData(gadgets)
+}
+
+// CutSelectedComponents copies the selection, then removes it as one undoable step
+func (ud *UMLDiagram) CutSelectedComponents() (filedata.Components, duerror.DUError) {
+	components, err := ud.CopySelectedComponents()
+	if err != nil {
+		return filedata.Components{}, err
+	}
+	if err = ud.RemoveSelectedComponents(); err != nil {
+		return filedata.Components{}, err
+	}
+	return components, nil
+}
+
+// PasteComponents inserts a copy of components with fresh ids, its top-left corner at point,
+// as one undoable step. The pasted components become the selection.
+func (ud *UMLDiagram) PasteComponents(components filedata.Components, point utils.Point) duerror.DUError {
+	if err := ud.validatePoint(point); err != nil {
+		return err
+	}
+	if len(components.Gadgets) == 0 {
+		return duerror.NewInvalidArgumentError("nothing to paste")
+	}
+	return ud.insertComponents(components, utils.SubPoints(point, getTopLeft(components)), 0)
+}
+
+// DuplicateSelectedComponents pastes a copy of the selection slightly below and right of it
+func (ud *UMLDiagram) DuplicateSelectedComponents() duerror.DUError {
+	components, err := ud.CopySelectedComponents()
+	if err != nil {
+		return err
+	}
+	return ud.insertComponents(components, utils.Point{X: duplicateOffset, Y: duplicateOffset}, 0)
+}
+
+// getTopLeft returns the top-left corner of the gadgets in components, which must not be empty
+func getTopLeft(components filedata.Components) utils.Point {
+	topLeft := utils.Point{X: components.Gadgets[0].X, Y: components.Gadgets[0].Y}
+	for _, gFd := range components.Gadgets[1:] {
+		topLeft.X = min(topLeft.X, gFd.X)
+		topLeft.Y = min(topLeft.Y, gFd.Y)
+	}
+	return topLeft
+}
+
+// getComponentsFileData returns the saved form of gadgets and of the associations among them
+func (ud *UMLDiagram) getComponentsFileData(gadgets []*component.Gadget) (filedata.Components, duerror.DUError) {
+	fd := filedata.Components{
+		Gadgets:      make([]filedata.Gadget, 0, len(gadgets)),
+		Associations: make([]filedata.Association, 0),
+	}
+	gadgetIndex := make(map[*component.Gadget]int, len(gadgets))
+	for _, g := range gadgets {
+		gadgetIndex[g] = len(fd.Gadgets)
+		fd.Gadgets = append(fd.Gadgets, g.GetFileData())
+	}
+	// every association starts at exactly one gadget, so this visits each one once
+	for _, g := range gadgets {
+		for _, a := range ud.associations[g][0] {
+			if _, ok := gadgetIndex[a.GetParentEnd()]; !ok {
+				continue
+			}
+			aFd, err := a.GetFileData(gadgetIndex)
+			if err != nil {
+				return filedata.Components{}, err
+			}
+			fd.Associations = append(fd.Associations, aFd)
+		}
+	}
+	return fd, nil
+}
+
+// insertComponents builds the components moved by delta and raised by layerDelta, with fresh ids,
+// and adds them in one transaction. They replace the selection.
+func (ud *UMLDiagram) insertComponents(components filedata.Components, delta utils.Point, layerDelta int) duerror.DUError {
+	gadgets := make([]*component.Gadget, 0, len(components.Gadgets))
+	for _, gFd := range components.Gadgets {
+		gFd.ID = ""
+		gFd.X += delta.X
+		gFd.Y += delta.Y
+		gFd.Layer += layerDelta
+		if err := ud.validatePoint(utils.Point{X: gFd.X, Y: gFd.Y}); err != nil {
+			return err
+		}
+		g, err := component.NewGadgetFromFileData(gFd)
+		if err != nil {
+			return err
+		}
+		gadgets = append(gadgets, g)
+	}
+	asses := make([]*component.Association, 0, len(components.Associations))
+	for _, aFd := range components.Associations {
+		aFd.ID = ""
+		aFd.Layer += layerDelta
+		aFd.Waypoints = slices.Clone(aFd.Waypoints)
+		for i, wp := range aFd.Waypoints {
+			aFd.Waypoints[i] = [2]int{wp[0] + delta.X, wp[1] + delta.Y}
+			if err := ud.validatePoint(utils.Point{X: aFd.Waypoints[i][0], Y: aFd.Waypoints[i][1]}); err != nil {
+				return err
+			}
+		}
+		a, err := newAssociationFromFileData(aFd, gadgets)
+		if err != nil {
+			return err
+		}
+		asses = append(asses, a)
+	}
+
+	if err := ud.transact(func() duerror.DUError {
+		for _, g := range gadgets {
+			if err := ud.execute(&addGadgetCommand{diagram: ud, gadget: g}); err != nil {
+				return err
+			}
+		}
+		for _, a := range asses {
+			if err := ud.execute(&addAssociationCommand{diagram: ud, association: a}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := ud.UnselectAllComponents(); err != nil {
+		return err
+	}
+	inserted := make([]component.Component, 0, len(gadgets)+len(asses))
+	for _, g := range gadgets {
+		inserted = append(inserted, g)
+	}
+	for _, a := range asses {
+		inserted = append(inserted, a)
+	}
+	return ud.selectComponents(inserted)
+}
@@ -0,0 +1,211 @@
+package umldiagram
+
+import (
+	"testing"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// three gadgets, A-B linked twice, B-C linked once, A has a self association
+func newClipboardTestDiagram(t *testing.T) *UMLDiagram {
+	diagram, _ := CreateEmptyUMLDiagram("Clipboard.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 100, Y: 100}, 0, "#111111", "A"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 300, Y: 100}, 1, "#222222", "B"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 300, Y: 300}, 0, "#333333", "C"))
+	_ = diagram.StartAddAssociation(utils.Point{X: 105, Y: 110})
+	assert.NoError(t, diagram.EndAddAssociation(component.Dependency, utils.Point{X: 305, Y: 110}))
+	_ = diagram.StartAddAssociation(utils.Point{X: 305, Y: 130})
+	assert.NoError(t, diagram.EndAddAssociation(component.Extension, utils.Point{X: 105, Y: 130}))
+	_ = diagram.StartAddAssociation(utils.Point{X: 310, Y: 105})
+	assert.NoError(t, diagram.EndAddAssociation(component.Composition, utils.Point{X: 310, Y: 305}))
+	_ = diagram.StartAddAssociation(utils.Point{X: 100, Y: 120})
+	assert.NoError(t, diagram.EndAddAssociation(component.Implementation, utils.Point{X: 110, Y: 100}))
+	assert.NoError(t, diagram.AddAttributeToGadgetByID(gadgetIDByHeader(diagram, "A"), 1, "field: int"))
+	assert.NoError(t, diagram.SetAttrStyleGadgetByID(gadgetIDByHeader(diagram, "A"), 1, 0, 3))
+	return diagram
+}
+
+func gadgetIDByHeader(diagram *UMLDiagram, header string) string {
+	for _, g := range diagram.GetDrawData().Gadgets {
+		if g.Attributes[0][0].Content == header {
+			return g.ID
+		}
+	}
+	return ""
+}
+
+func gadgetsByHeader(diagram *UMLDiagram, header string) []drawdata.Gadget {
+	found := make([]drawdata.Gadget, 0)
+	for _, g := range diagram.GetDrawData().Gadgets {
+		if g.Attributes[0][0].Content == header {
+			found = append(found, g)
+		}
+	}
+	return found
+}
+
+func TestUMLDiagram_CopySelectedComponents(t *testing.T) {
+	diagram := newClipboardTestDiagram(t)
+
+	// nothing selected
+	_, err := diagram.CopySelectedComponents()
+	assert.Error(t, err)
+
+	// A and B with the two associations between them and the self one, not the one to C
+	assert.NoError(t, diagram.SelectComponentByID(gadgetIDByHeader(diagram, "A")))
+	assert.NoError(t, diagram.SelectComponentByID(gadgetIDByHeader(diagram, "B")))
+	components, err := diagram.CopySelectedComponents()
+	assert.NoError(t, err)
+	assert.Len(t, components.Gadgets, 2)
+	assert.Len(t, components.Associations, 3)
+	assert.Equal(t, "A", components.Gadgets[0].Attributes[0][0].Content)
+	assert.Equal(t, "field: int", components.Gadgets[0].Attributes[1][0].Content)
+	assert.Equal(t, 3, components.Gadgets[0].Attributes[1][0].Style)
+	for _, a := range components.Associations {
+		assert.NotEqual(t, int(component.Composition), a.AssType)
+	}
+
+	// the same selection copies the same way
+	again, err := diagram.CopySelectedComponents()
+	assert.NoError(t, err)
+	assert.Equal(t, components, again)
+
+	// a selected association with an unselected end is left out
+	assert.NoError(t, diagram.SelectAssociationsByType(component.Composition))
+	again, err = diagram.CopySelectedComponents()
+	assert.NoError(t, err)
+	assert.Equal(t, components, again)
+
+	// copying changes nothing
+	assert.Len(t, diagram.GetDrawData().Gadgets, 3)
+	assert.Len(t, diagram.GetDrawData().Associations, 4)
+}
+
+func TestUMLDiagram_PasteComponents(t *testing.T) {
+	diagram := newClipboardTestDiagram(t)
+	assert.NoError(t, diagram.SelectComponentByID(gadgetIDByHeader(diagram, "A")))
+	assert.NoError(t, diagram.SelectComponentByID(gadgetIDByHeader(diagram, "B")))
+	components, err := diagram.CopySelectedComponents()
+	assert.NoError(t, err)
+	original := gadgetsByHeader(diagram, "A")[0]
+
+	assert.Error(t, diagram.PasteComponents(components, utils.Point{X: -1, Y: 0}))
+	assert.NoError(t, diagram.PasteComponents(components, utils.Point{X: 500, Y: 600}))
+	assert.Len(t, diagram.GetDrawData().Gadgets, 5)
+	assert.Len(t, diagram.GetDrawData().Associations, 7)
+
+	// the copy of A is a deep copy with a fresh id, at the target point
+	as := gadgetsByHeader(diagram, "A")
+	assert.Len(t, as, 2)
+	pasted := as[0]
+	if pasted.ID == original.ID {
+		pasted = as[1]
+	}
+	assert.NotEqual(t, original.ID, pasted.ID)
+	assert.Equal(t, 500, pasted.X)
+	assert.Equal(t, 600, pasted.Y)
+	assert.Equal(t, original.Attributes, pasted.Attributes)
+	assert.Equal(t, original.Color, pasted.Color)
+	bXs := []int{gadgetsByHeader(diagram, "B")[0].X, gadgetsByHeader(diagram, "B")[1].X}
+	assert.ElementsMatch(t, []int{300, 700}, bXs)
+
+	// only the pasted components are selected, and the associations are wired to the copies
+	assert.Len(t, diagram.componentsSelected, 5)
+	for c := range diagram.componentsSelected {
+		if a, ok := c.(*component.Association); ok {
+			assert.True(t, a.GetParentStart().GetPoint().X >= 500)
+			assert.True(t, a.GetParentEnd().GetPoint().X >= 500)
+			assert.Contains(t, diagram.associations[a.GetParentStart()][0], a)
+			assert.Contains(t, diagram.associations[a.GetParentEnd()][1], a)
+		}
+	}
+
+	// editing the copy leaves the original alone
+	assert.NoError(t, diagram.SetAttrContentGadgetByID(pasted.ID, 1, 0, "changed"))
+	fields := make([]string, 0, 2)
+	for _, g := range gadgetsByHeader(diagram, "A") {
+		fields = append(fields, g.Attributes[1][0].Content)
+	}
+	assert.ElementsMatch(t, []string{"field: int", "changed"}, fields)
+
+	// a paste undoes as one step
+	assert.NoError(t, diagram.Undo())
+	assert.NoError(t, diagram.Undo())
+	assert.Len(t, diagram.GetDrawData().Gadgets, 3)
+	assert.Len(t, diagram.GetDrawData().Associations, 4)
+	assert.Len(t, diagram.componentsSelected, 0)
+
+	// pasting the same components twice gives two independent copies
+	assert.NoError(t, diagram.PasteComponents(components, utils.Point{X: 0, Y: 0}))
+	assert.NoError(t, diagram.PasteComponents(components, utils.Point{X: 0, Y: 300}))
+	assert.Len(t, diagram.GetDrawData().Gadgets, 7)
+	ids := make(map[string]bool)
+	for _, g := range diagram.GetDrawData().Gadgets {
+		ids[g.ID] = true
+	}
+	assert.Len(t, ids, 7)
+}
+
+func TestUMLDiagram_PasteAcrossDiagrams(t *testing.T) {
+	diagram := newClipboardTestDiagram(t)
+	assert.NoError(t, diagram.SelectAllGadgets())
+	components, err := diagram.CopySelectedComponents()
+	assert.NoError(t, err)
+
+	other, _ := CreateEmptyUMLDiagram("Other.uml", ClassDiagram)
+	assert.NoError(t, other.PasteComponents(components, utils.Point{X: 10, Y: 10}))
+	assert.Len(t, other.GetDrawData().Gadgets, 3)
+	assert.Len(t, other.GetDrawData().Associations, 4)
+	assert.Equal(t, 10, gadgetsByHeader(other, "A")[0].X)
+	assert.Equal(t, 210, gadgetsByHeader(other, "C")[0].Y)
+
+	// dragging a pasted gadget re-routes its associations
+	assert.NoError(t, other.UnselectAllComponents())
+	assert.NoError(t, other.SelectComponentByID(gadgetIDByHeader(other, "C")))
+	before := other.GetDrawData().Associations
+	assert.NoError(t, other.MoveSelectedComponent(utils.Point{X: 0, Y: 50}))
+	assert.NotEqual(t, before, other.GetDrawData().Associations)
+
+	// broken payloads are refused without changing anything
+	components.Associations[0].Parents[1] = 9
+	assert.Error(t, other.PasteComponents(components, utils.Point{X: 10, Y: 10}))
+	components.Associations[0].Parents[1] = 0
+	components.Gadgets[0].GadgetType = 0
+	assert.Error(t, other.PasteComponents(components, utils.Point{X: 10, Y: 10}))
+	assert.Len(t, other.GetDrawData().Gadgets, 3)
+}
+
+func TestUMLDiagram_CutAndDuplicate(t *testing.T) {
+	diagram := newClipboardTestDiagram(t)
+	assert.NoError(t, diagram.SelectComponentByID(gadgetIDByHeader(diagram, "B")))
+
+	// duplicate places the copy next to the original and selects it
+	assert.NoError(t, diagram.DuplicateSelectedComponents())
+	bs := gadgetsByHeader(diagram, "B")
+	assert.Len(t, bs, 2)
+	assert.Equal(t, duplicateOffset, utils.AbsInt(bs[0].X-bs[1].X))
+	assert.Equal(t, duplicateOffset, utils.AbsInt(bs[0].Y-bs[1].Y))
+	assert.Len(t, diagram.componentsSelected, 1)
+	assert.NoError(t, diagram.Undo())
+	assert.Len(t, gadgetsByHeader(diagram, "B"), 1)
+
+	// cut removes B and every association attached to it
+	assert.NoError(t, diagram.SelectComponentByID(gadgetIDByHeader(diagram, "B")))
+	components, err := diagram.CutSelectedComponents()
+	assert.NoError(t, err)
+	assert.Len(t, components.Gadgets, 1)
+	assert.Len(t, components.Associations, 0)
+	assert.Len(t, diagram.GetDrawData().Gadgets, 2)
+	assert.Len(t, diagram.GetDrawData().Associations, 1)
+	assert.NoError(t, diagram.PasteComponents(components, utils.Point{X: 300, Y: 100}))
+	assert.Len(t, gadgetsByHeader(diagram, "B"), 1)
+
+	// nothing selected
+	assert.NoError(t, diagram.UnselectAllComponents())
+	_, err = diagram.CutSelectedComponents()
+	assert.Error(t, err)
+	assert.Error(t, diagram.DuplicateSelectedComponents())
+}
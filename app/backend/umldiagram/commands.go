@@ -0,0 +1,93 @@
+package umldiagram
+
+import (
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/utils/duerror"
+)
+
+// addGadgetCommand inserts a gadget that has already been constructed
+type addGadgetCommand struct {
+	diagram *UMLDiagram
+	gadget  *component.Gadget
+}
+
+func (c *addGadgetCommand) Execute() duerror.DUError {
+	if err := c.diagram.insertGadget(c.gadget, 0); err != nil {
+		return err
+	}
+	return c.diagram.updateDrawData()
+}
+
+func (c *addGadgetCommand) Unexecute() duerror.DUError {
+	if err := c.diagram.removeGadget(c.gadget); err != nil {
+		return err
+	}
+	return c.diagram.updateDrawData()
+}
+
+// addAssociationCommand inserts an association that has already been constructed
+type addAssociationCommand struct {
+	diagram     *UMLDiagram
+	association *component.Association
+}
+
+func (c *addAssociationCommand) Execute() duerror.DUError {
+	if err := c.diagram.insertAssociation(c.association, 0); err != nil {
+		return err
+	}
+	return c.diagram.updateDrawData()
+}
+
+func (c *addAssociationCommand) Unexecute() duerror.DUError {
+	if err := c.diagram.removeAssociation(c.association); err != nil {
+		return err
+	}
+	return c.diagram.updateDrawData()
+}
+
+// removeGadgetCommand removes a gadget that no association is attached to anymore,
+// the attached ones are removed by their own commands in the same transaction
+type removeGadgetCommand struct {
+	diagram *UMLDiagram
+	gadget  *component.Gadget
+	seq     int // where it was among the components on its layer, undo puts it back there
+}
+
+func (c *removeGadgetCommand) Execute() duerror.DUError {
+	if len(c.diagram.getAttachedAssociations(c.gadget)) != 0 {
+		return duerror.NewInvalidArgumentError("gadget still has associations attached")
+	}
+	c.seq = c.diagram.componentsContainer.GetSequence(c.gadget)
+	if err := c.diagram.removeGadget(c.gadget); err != nil {
+		return err
+	}
+	return c.diagram.updateDrawData()
+}
+
+func (c *removeGadgetCommand) Unexecute() duerror.DUError {
+	if err := c.diagram.insertGadget(c.gadget, c.seq); err != nil {
+		return err
+	}
+	return c.diagram.updateDrawData()
+}
+
+type removeAssociationCommand struct {
+	diagram     *UMLDiagram
+	association *component.Association
+	seq         int
+}
+
+func (c *removeAssociationCommand) Execute() duerror.DUError {
+	c.seq = c.diagram.componentsContainer.GetSequence(c.association)
+	if err := c.diagram.removeAssociation(c.association); err != nil {
+		return err
+	}
+	return c.diagram.updateDrawData()
+}
+
+func (c *removeAssociationCommand) Unexecute() duerror.DUError {
+	if err := c.diagram.insertAssociation(c.association, c.seq); err != nil {
+		return err
+	}
+	return c.diagram.updateDrawData()
+}
@@ -0,0 +1,87 @@
+package umldiagram
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"Dr.uml/backend/filedata"
+	"Dr.uml/backend/utils"
+	"Dr.uml/backend/utils/duerror"
+)
+
+// ExportSubmodule writes the selected gadgets, and the associations among them, to target as a submodule called name.
+func (ud *UMLDiagram) ExportSubmodule(target string, name string) duerror.DUError {
+	if err := utils.ValidateFilePath(target); err != nil {
+		return err
+	}
+	if name == "" {
+		return duerror.NewInvalidArgumentError("submodule name is empty")
+	}
+	components, err := ud.CopySelectedComponents()
+	if err != nil {
+		return err
+	}
+	fd := filedata.Submodule{
+		Version:      filedata.SubmoduleFileVersion,
+		Name:         name,
+		LastModified: time.Now(),
+		Components:   components,
+	}
+	content, jsonErr := json.MarshalIndent(fd, "", "  ")
+	if jsonErr != nil {
+		return duerror.NewFileIOError(jsonErr.Error())
+	}
+	if err := os.WriteFile(target, content, 0644); err != nil {
+		return duerror.NewFileIOError(err.Error())
+	}
+	return nil
+}
+
+// ImportSubmodule inserts the submodule saved in source with fresh ids, its top-left corner at point,
+// as one undoable step. Its layers are rebased so that it lies above everything already in the diagram.
+func (ud *UMLDiagram) ImportSubmodule(source string, point utils.Point) duerror.DUError {
+	if err := ud.validatePoint(point); err != nil {
+		return err
+	}
+	fd, err := loadSubmodule(source)
+	if err != nil {
+		return err
+	}
+	if len(fd.Gadgets) == 0 {
+		return duerror.NewInvalidArgumentError("submodule is empty")
+	}
+
+	bottom := fd.Gadgets[0].Layer
+	for _, gFd := range fd.Gadgets {
+		bottom = min(bottom, gFd.Layer)
+	}
+	for _, aFd := range fd.Associations {
+		bottom = min(bottom, aFd.Layer)
+	}
+	base := 0
+	if all := ud.componentsContainer.GetAll(); len(all) > 0 {
+		_, top := getLayerRange(all)
+		base = top + 1
+	}
+	return ud.insertComponents(fd.Components, utils.SubPoints(point, getTopLeft(fd.Components)), base-bottom)
+}
+
+// loadSubmodule reads a submodule file written by ExportSubmodule
+func loadSubmodule(source string) (filedata.Submodule, duerror.DUError) {
+	if err := utils.ValidateFilePath(source); err != nil {
+		return filedata.Submodule{}, err
+	}
+	content, err := os.ReadFile(source)
+	if err != nil {
+		return filedata.Submodule{}, duerror.NewFileIOError(err.Error())
+	}
+	var fd filedata.Submodule
+	if err = json.Unmarshal(content, &fd); err != nil {
+		return filedata.Submodule{}, duerror.NewFileIOError(err.Error())
+	}
+	if fd.Version <= 0 || fd.Version > filedata.SubmoduleFileVersion {
+		return filedata.Submodule{}, duerror.NewFileIOError("unsupported submodule file version")
+	}
+	return fd, nil
+}
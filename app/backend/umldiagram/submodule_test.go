@@ -0,0 +1,106 @@
+package umldiagram
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUMLDiagram_ExportSubmodule(t *testing.T) {
+	diagram := newClipboardTestDiagram(t)
+	target := filepath.Join(t.TempDir(), "pattern.json")
+
+	// nothing selected
+	assert.Error(t, diagram.ExportSubmodule(target, "Pattern"))
+	_, err := os.Stat(target)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NoError(t, diagram.SelectComponentByID(gadgetIDByHeader(diagram, "A")))
+	assert.NoError(t, diagram.SelectComponentByID(gadgetIDByHeader(diagram, "B")))
+	assert.Error(t, diagram.ExportSubmodule(target, ""))
+	assert.Error(t, diagram.ExportSubmodule("", "Pattern"))
+	assert.NoError(t, diagram.ExportSubmodule(target, "Pattern"))
+
+	fd, err := loadSubmodule(target)
+	assert.NoError(t, err)
+	assert.Equal(t, "Pattern", fd.Name)
+	assert.Len(t, fd.Gadgets, 2)
+	assert.Len(t, fd.Associations, 3)
+
+	// exporting changes nothing
+	assert.Len(t, diagram.GetDrawData().Gadgets, 3)
+	assert.Len(t, diagram.GetDrawData().Associations, 4)
+}
+
+func TestUMLDiagram_ImportSubmodule(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "pattern.json")
+	diagram := newClipboardTestDiagram(t)
+	assert.NoError(t, diagram.SelectAllGadgets())
+	assert.NoError(t, diagram.ExportSubmodule(source, "Pattern"))
+
+	// into an empty diagram the lowest layer becomes 0
+	empty, _ := CreateEmptyUMLDiagram("Empty.uml", ClassDiagram)
+	assert.NoError(t, empty.ImportSubmodule(source, utils.Point{X: 0, Y: 0}))
+	assert.Len(t, empty.GetDrawData().Gadgets, 3)
+	assert.Len(t, empty.GetDrawData().Associations, 4)
+	assert.Equal(t, 0, gadgetsByHeader(empty, "A")[0].Layer)
+	assert.Equal(t, 1, gadgetsByHeader(empty, "B")[0].Layer)
+	assert.Equal(t, 0, gadgetsByHeader(empty, "A")[0].X)
+
+	// into a used diagram it lies above the existing content
+	target, _ := CreateEmptyUMLDiagram("Target.uml", ClassDiagram)
+	assert.NoError(t, target.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 5, "#000000", "Existing"))
+	assert.NoError(t, target.AddGadget(component.Class, utils.Point{X: 0, Y: 100}, -2, "#000000", "Below"))
+	assert.NoError(t, target.ImportSubmodule(source, utils.Point{X: 400, Y: 50}))
+	a := gadgetsByHeader(target, "A")[0]
+	assert.Equal(t, 6, a.Layer)
+	assert.Equal(t, 7, gadgetsByHeader(target, "B")[0].Layer)
+	assert.Equal(t, 6, gadgetsByHeader(target, "C")[0].Layer)
+	for _, ass := range target.GetDrawData().Associations {
+		assert.GreaterOrEqual(t, ass.Layer, 6)
+	}
+	assert.Equal(t, 400, a.X)
+	assert.Equal(t, 50, a.Y)
+	assert.Len(t, target.componentsSelected, 7)
+
+	// a second import gets fresh ids and goes above the first one
+	assert.NoError(t, target.ImportSubmodule(source, utils.Point{X: 400, Y: 400}))
+	as := gadgetsByHeader(target, "A")
+	assert.Len(t, as, 2)
+	assert.NotEqual(t, as[0].ID, as[1].ID)
+	assert.ElementsMatch(t, []int{6, 8}, []int{as[0].Layer, as[1].Layer})
+
+	// an import undoes as one step
+	assert.NoError(t, target.Undo())
+	assert.NoError(t, target.Undo())
+	assert.Len(t, target.GetDrawData().Gadgets, 2)
+	assert.Len(t, target.GetDrawData().Associations, 0)
+}
+
+func TestUMLDiagram_ImportSubmoduleInvalid(t *testing.T) {
+	dir := t.TempDir()
+	diagram, _ := CreateEmptyUMLDiagram("Diagram.uml", ClassDiagram)
+
+	assert.Error(t, diagram.ImportSubmodule(filepath.Join(dir, "missing.json"), utils.Point{X: 0, Y: 0}))
+
+	cases := map[string]string{
+		"notjson.json": "not json",
+		"version.json": `{"version":99,"name":"Future","gadgets":[]}`,
+		"empty.json":   `{"version":1,"name":"Empty","gadgets":[],"associations":[]}`,
+		"parents.json": `{"version":1,"name":"Broken","gadgets":[{"gadgetType":1,"attributes":[[],[],[]]}],` +
+			`"associations":[{"assType":1,"parents":[0,3]}]}`,
+	}
+	for name, content := range cases {
+		source := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(source, []byte(content), 0644))
+		assert.Error(t, diagram.ImportSubmodule(source, utils.Point{X: 0, Y: 0}), name)
+	}
+	assert.Len(t, diagram.GetDrawData().Gadgets, 0)
+
+	source := filepath.Join(dir, "empty.json")
+	assert.Error(t, diagram.ImportSubmodule(source, utils.Point{X: -1, Y: 0}))
+}
@@ -1,12 +1,17 @@
 package umldiagram
 
 import (
+	"encoding/json"
+	"os"
 	"slices"
 	"time"
 
+	"Dr.uml/backend/command"
 	"Dr.uml/backend/component"
+	"Dr.uml/backend/component/attribute"
 	"Dr.uml/backend/components"
 	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/filedata"
 	"Dr.uml/backend/utils"
 	"Dr.uml/backend/utils/duerror"
 )
@@ -27,6 +32,10 @@ var AllDiagramTypes = []struct {
 	{ClassDiagram, "ClassDiagram"},
 }
 
+// how far around a gadget that appeared, moved or went away orthogonal routes are looked for,
+// wider than the margin they keep around gadgets
+const rerouteZone = 32
+
 // Other methods
 func validateDiagramType(input DiagramType) duerror.DUError {
 	if !(input&supportedType == input && input != 0) {
@@ -42,16 +51,36 @@ type UMLDiagram struct {
 	startPoint      utils.Point // for dragging and linking ass
 	backgroundColor string
 
+	dragging  bool
+	dragPoint utils.Point // where the selection has been dragged to so far
+
 	componentsContainer components.Container
+	growToGrid          bool // the container is the default map, to be swapped for a grid at gridDiagramSize
 	componentsSelected  map[component.Component]bool
 	associations        map[*component.Gadget]([2][]*component.Association)
+	associationClasses  map[*component.Gadget][]*component.Association // the associations a class is linked to
+	gadgetBounds        map[*component.Gadget]utils.Rect               // as of the last update, to reroute around where a gadget was
+
+	cmdManager *command.Manager // undo / redo history of this diagram
 
 	updateParentDraw func() duerror.DUError
 	drawData         drawdata.Diagram
+	redrawHeld       int // batchRedraw calls running, the draw data is rebuilt when the last one ends
 }
 
 // Constructor
 func CreateEmptyUMLDiagram(name string, dt DiagramType) (*UMLDiagram, duerror.DUError) {
+	ud, err := CreateEmptyUMLDiagramWithContainer(name, dt, components.NewContainerMap())
+	if err != nil {
+		return nil, err
+	}
+	ud.growToGrid = true
+	return ud, nil
+}
+
+// CreateEmptyUMLDiagramWithContainer is CreateEmptyUMLDiagram with the container that keeps its components,
+// e.g. components.NewContainerGrid() for large diagrams. The container must be empty.
+func CreateEmptyUMLDiagramWithContainer(name string, dt DiagramType, container components.Container) (*UMLDiagram, duerror.DUError) {
 	// TODO: also check the file is exist or not
 	if err := utils.ValidateFilePath(name); err != nil {
 		return nil, err
@@ -59,15 +88,26 @@ func CreateEmptyUMLDiagram(name string, dt DiagramType) (*UMLDiagram, duerror.DU
 	if err := validateDiagramType(dt); err != nil {
 		return nil, err
 	}
+	if container == nil {
+		return nil, duerror.NewInvalidArgumentError("container is nil")
+	}
+	if l, err := container.Len(); err != nil {
+		return nil, err
+	} else if l != 0 {
+		return nil, duerror.NewInvalidArgumentError("container is not empty")
+	}
 	return &UMLDiagram{
 		name:                name,
 		diagramType:         dt,
 		lastModified:        time.Now(),
 		startPoint:          utils.Point{X: 0, Y: 0},
 		backgroundColor:     drawdata.DefaultDiagramColor, // Default white background
-		componentsContainer: components.NewContainerMap(),
+		componentsContainer: container,
 		associations:        make(map[*component.Gadget][2][]*component.Association),
+		associationClasses:  make(map[*component.Gadget][]*component.Association),
+		gadgetBounds:        make(map[*component.Gadget]utils.Rect),
 		componentsSelected:  make(map[component.Component]bool),
+		cmdManager:          command.NewManager(),
 		drawData: drawdata.Diagram{
 			Margin:    drawdata.Margin,
 			LineWidth: drawdata.LineWidth,
@@ -76,9 +116,94 @@ func CreateEmptyUMLDiagram(name string, dt DiagramType) (*UMLDiagram, duerror.DU
 	}, nil
 }
 
+// LoadExistUMLDiagram reads a standalone diagram file, the file path becomes the diagram name.
 func LoadExistUMLDiagram(name string) (*UMLDiagram, duerror.DUError) {
-	// TODO
-	return CreateEmptyUMLDiagram(name, ClassDiagram)
+	if err := utils.ValidateFilePath(name); err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return nil, duerror.NewFileIOError(err.Error())
+	}
+	var fd filedata.Diagram
+	if err = json.Unmarshal(content, &fd); err != nil {
+		return nil, duerror.NewFileIOError(err.Error())
+	}
+	if fd.Version <= 0 || fd.Version > filedata.DiagramFileVersion {
+		return nil, duerror.NewFileIOError("unsupported diagram file version")
+	}
+	fd.Name = name
+	return LoadUMLDiagramFromFileData(fd)
+}
+
+// gridDiagramSize is the number of components from which a diagram keeps them in a grid,
+// it searches them an order of magnitude faster than the map from about there on
+const gridDiagramSize = 100
+
+// LoadUMLDiagramFromFileData rebuilds a diagram, with its gadgets, associations and
+// the associations index, from its saved form. Large diagrams keep their components in a grid.
+func LoadUMLDiagramFromFileData(fd filedata.Diagram) (*UMLDiagram, duerror.DUError) {
+	if len(fd.Gadgets)+len(fd.Associations) >= gridDiagramSize {
+		return LoadUMLDiagramFromFileDataWithContainer(fd, components.NewContainerGrid())
+	}
+	ud, err := LoadUMLDiagramFromFileDataWithContainer(fd, components.NewContainerMap())
+	if err != nil {
+		return nil, err
+	}
+	ud.growToGrid = true
+	return ud, nil
+}
+
+// LoadUMLDiagramFromFileDataWithContainer is LoadUMLDiagramFromFileData with the container that keeps
+// the components, as for CreateEmptyUMLDiagramWithContainer.
+func LoadUMLDiagramFromFileDataWithContainer(fd filedata.Diagram, container components.Container) (*UMLDiagram, duerror.DUError) {
+	ud, err := CreateEmptyUMLDiagramWithContainer(fd.Name, DiagramType(fd.DiagramType), container)
+	if err != nil {
+		return nil, err
+	}
+	ud.lastModified = fd.LastModified
+	ud.backgroundColor = fd.BackgroundColor
+	ud.drawData.Color = fd.BackgroundColor
+
+	ids := make(map[string]bool)
+	checkID := func(c component.Component) duerror.DUError {
+		if ids[c.GetID()] {
+			return duerror.NewInvalidArgumentError("duplicate component id " + c.GetID())
+		}
+		ids[c.GetID()] = true
+		return nil
+	}
+
+	gadgets := make([]*component.Gadget, 0, len(fd.Gadgets))
+	for _, gFd := range fd.Gadgets {
+		g, err := component.NewGadgetFromFileData(gFd)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkID(g); err != nil {
+			return nil, err
+		}
+		if err = ud.insertGadget(g, gFd.Order); err != nil {
+			return nil, err
+		}
+		gadgets = append(gadgets, g)
+	}
+	for _, aFd := range fd.Associations {
+		a, err := newAssociationFromFileData(aFd, gadgets)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkID(a); err != nil {
+			return nil, err
+		}
+		if err = ud.insertAssociation(a, aFd.Order); err != nil {
+			return nil, err
+		}
+	}
+	if err = ud.updateDrawData(); err != nil {
+		return nil, err
+	}
+	return ud, nil
 }
 
 // Getters
@@ -94,103 +219,417 @@ func (ud *UMLDiagram) GetLastModified() time.Time {
 	return ud.lastModified
 }
 
+func (ud *UMLDiagram) GetBackgroundColor() string {
+	return ud.backgroundColor
+}
+
+// GetFileData returns the persistent form of the diagram.
+// Gadgets are saved first so that associations can refer to them by index,
+// the order of each component keeps where it was drawn among all of them.
+func (ud *UMLDiagram) GetFileData() (filedata.Diagram, duerror.DUError) {
+	fd := filedata.Diagram{
+		Name:            ud.name,
+		DiagramType:     int(ud.diagramType),
+		BackgroundColor: ud.backgroundColor,
+		LastModified:    ud.lastModified,
+		Gadgets:         make([]filedata.Gadget, 0),
+		Associations:    make([]filedata.Association, 0),
+	}
+	all := ud.componentsContainer.GetAll()
+
+	gadgetIndex := make(map[*component.Gadget]int)
+	for i, c := range all {
+		if g, ok := c.(*component.Gadget); ok {
+			gFd := g.GetFileData()
+			gFd.Order = i + 1
+			gadgetIndex[g] = len(fd.Gadgets)
+			fd.Gadgets = append(fd.Gadgets, gFd)
+		}
+	}
+	for i, c := range all {
+		if a, ok := c.(*component.Association); ok {
+			aFd, err := a.GetFileData(gadgetIndex)
+			if err != nil {
+				return filedata.Diagram{}, err
+			}
+			aFd.Order = i + 1
+			fd.Associations = append(fd.Associations, aFd)
+		}
+	}
+	return fd, nil
+}
+
+// SaveToFile writes the diagram as a standalone file that LoadExistUMLDiagram can read.
+func (ud *UMLDiagram) SaveToFile(fileName string) duerror.DUError {
+	if err := utils.ValidateFilePath(fileName); err != nil {
+		return err
+	}
+	fd, err := ud.GetFileData()
+	if err != nil {
+		return err
+	}
+	fd.Version = filedata.DiagramFileVersion
+	content, jsonErr := json.MarshalIndent(fd, "", "  ")
+	if jsonErr != nil {
+		return duerror.NewFileIOError(jsonErr.Error())
+	}
+	if err := os.WriteFile(fileName, content, 0644); err != nil {
+		return duerror.NewFileIOError(err.Error())
+	}
+	return nil
+}
+
+// GetMembersGadget parses a section of the selected gadget into members, with the syntax errors per line
+func (ud *UMLDiagram) GetMembersGadget(section int) (attribute.MemberSection, duerror.DUError) {
+	g, err := ud.getSelectedGadget()
+	if err != nil {
+		return attribute.MemberSection{}, err
+	}
+	return g.GetMembers(section)
+}
+
+func (ud *UMLDiagram) GetMembersGadgetByID(id string, section int) (attribute.MemberSection, duerror.DUError) {
+	g, err := ud.getGadgetByID(id)
+	if err != nil {
+		return attribute.MemberSection{}, err
+	}
+	return g.GetMembers(section)
+}
+
 // Setters
 func (ud *UMLDiagram) SetPointGadget(point utils.Point) duerror.DUError {
-	c, err := ud.getSelectedComponent()
+	g, err := ud.getSelectedGadget()
 	if err != nil {
 		return err
 	}
+	return ud.setPointGadget(g, point)
+}
 
-	switch g := c.(type) {
-	case *component.Gadget:
-		return g.SetPoint(point)
-	default:
-		return duerror.NewInvalidArgumentError("selected component is not a gadget")
+func (ud *UMLDiagram) SetSetLayerGadget(layer int) duerror.DUError {
+	g, err := ud.getSelectedGadget()
+	if err != nil {
+		return err
 	}
+	return ud.setLayerGadget(g, layer)
 }
 
-func (ud *UMLDiagram) SetSetLayerGadget(layer int) duerror.DUError {
-	c, err := ud.getSelectedComponent()
+func (ud *UMLDiagram) SetColorGadget(colorHexStr string) duerror.DUError {
+	g, err := ud.getSelectedGadget()
 	if err != nil {
 		return err
 	}
+	return ud.setColorGadget(g, colorHexStr)
+}
 
-	switch g := c.(type) {
-	case *component.Gadget:
-		return g.SetLayer(layer)
-	default:
-		return duerror.NewInvalidArgumentError("selected component is not a gadget")
+func (ud *UMLDiagram) SetAttrContentGadget(section int, index int, content string) duerror.DUError {
+	g, err := ud.getSelectedGadget()
+	if err != nil {
+		return err
 	}
+	return ud.setAttrContentGadget(g, section, index, content)
 }
 
-func (ud *UMLDiagram) SetColorGadget(colorHexStr string) duerror.DUError {
-	c, err := ud.getSelectedComponent()
+func (ud *UMLDiagram) SetAttrSizeGadget(section int, index int, size int) duerror.DUError {
+	g, err := ud.getSelectedGadget()
 	if err != nil {
 		return err
 	}
+	return ud.setAttrSizeGadget(g, section, index, size)
+}
 
-	switch g := c.(type) {
-	case *component.Gadget:
-		return g.SetColor(colorHexStr)
-	default:
-		return duerror.NewInvalidArgumentError("selected component is not a gadget")
+func (ud *UMLDiagram) SetAttrStyleGadget(section int, index int, style int) duerror.DUError {
+	g, err := ud.getSelectedGadget()
+	if err != nil {
+		return err
 	}
+	return ud.setAttrStyleGadget(g, section, index, style)
 }
 
-func (ud *UMLDiagram) SetAttrContentGadget(section int, index int, content string) duerror.DUError {
-	c, err := ud.getSelectedComponent()
+// FormatMembersGadget rewrites the members of a section of the selected gadget canonically as one undoable step,
+// lines with a syntax error are left as they are
+func (ud *UMLDiagram) FormatMembersGadget(section int) duerror.DUError {
+	g, err := ud.getSelectedGadget()
+	if err != nil {
+		return err
+	}
+	if _, err := g.GetMembers(section); err != nil {
+		return err
+	}
+	return ud.transact(func() duerror.DUError {
+		for i := range g.GetAttributesLen()[section] {
+			att, err := g.GetAttribute(section, i)
+			if err != nil {
+				return err
+			}
+			// from the content alone, the text style already shows what it shows
+			m, err := attribute.ParseMember(att.GetContent())
+			if err != nil || m.String() == att.GetContent() {
+				continue
+			}
+			if err := ud.setAttrContentGadget(g, section, i, m.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Setters addressing a gadget by id, whether it is selected or not
+func (ud *UMLDiagram) SetPointGadgetByID(id string, point utils.Point) duerror.DUError {
+	g, err := ud.getGadgetByID(id)
 	if err != nil {
 		return err
 	}
+	return ud.setPointGadget(g, point)
+}
 
-	switch g := c.(type) {
-	case *component.Gadget:
-		return g.SetAttrContent(section, index, content)
-	default:
-		return duerror.NewInvalidArgumentError("selected component is not a gadget")
+func (ud *UMLDiagram) SetLayerGadgetByID(id string, layer int) duerror.DUError {
+	g, err := ud.getGadgetByID(id)
+	if err != nil {
+		return err
 	}
+	return ud.setLayerGadget(g, layer)
 }
-func (ud *UMLDiagram) SetAttrSizeGadget(section int, index int, size int) duerror.DUError {
-	c, err := ud.getSelectedComponent()
+
+func (ud *UMLDiagram) SetColorGadgetByID(id string, colorHexStr string) duerror.DUError {
+	g, err := ud.getGadgetByID(id)
 	if err != nil {
 		return err
 	}
+	return ud.setColorGadget(g, colorHexStr)
+}
 
-	switch g := c.(type) {
-	case *component.Gadget:
-		return g.SetAttrSize(section, index, size)
-	default:
-		return duerror.NewInvalidArgumentError("selected component is not a gadget")
+func (ud *UMLDiagram) SetAttrContentGadgetByID(id string, section int, index int, content string) duerror.DUError {
+	g, err := ud.getGadgetByID(id)
+	if err != nil {
+		return err
 	}
+	return ud.setAttrContentGadget(g, section, index, content)
 }
-func (ud *UMLDiagram) SetAttrStyleGadget(section int, index int, style int) duerror.DUError {
-	c, err := ud.getSelectedComponent()
+
+func (ud *UMLDiagram) SetAttrSizeGadgetByID(id string, section int, index int, size int) duerror.DUError {
+	g, err := ud.getGadgetByID(id)
 	if err != nil {
 		return err
 	}
+	return ud.setAttrSizeGadget(g, section, index, size)
+}
 
-	switch g := c.(type) {
-	case *component.Gadget:
-		return g.SetAttrStyle(section, index, style)
-	default:
-		return duerror.NewInvalidArgumentError("selected component is not a gadget")
+func (ud *UMLDiagram) SetAttrStyleGadgetByID(id string, section int, index int, style int) duerror.DUError {
+	g, err := ud.getGadgetByID(id)
+	if err != nil {
+		return err
 	}
+	return ud.setAttrStyleGadget(g, section, index, style)
 }
 
-// Methods
-func (ud *UMLDiagram) AddGadget(gadgetType component.GadgetType, point utils.Point, layer int, colorHexStr string, header string) duerror.DUError {
-	g, err := component.NewGadget(gadgetType, point, layer, colorHexStr, header)
+// Setters of the selected association
+func (ud *UMLDiagram) SetAssTypeAssociation(assType component.AssociationType) duerror.DUError {
+	a, err := ud.getSelectedAssociation()
+	if err != nil {
+		return err
+	}
+	old := a.GetAssType()
+	// the navigability the new type cannot show is cleared, undo brings it back
+	oldStart, oldEnd := a.GetStartNavigability(), a.GetEndNavigability()
+	return ud.executeFunc(
+		func() duerror.DUError { return a.SetAssType(assType) },
+		func() duerror.DUError {
+			if err := a.SetAssType(old); err != nil {
+				return err
+			}
+			if err := a.SetStartNavigability(oldStart); err != nil {
+				return err
+			}
+			return a.SetEndNavigability(oldEnd)
+		},
+	)
+}
+
+func (ud *UMLDiagram) SetStartNavigabilityAssociation(navigability component.Navigability) duerror.DUError {
+	a, err := ud.getSelectedAssociation()
 	if err != nil {
 		return err
 	}
-	if err = g.RegisterUpdateParentDraw(ud.updateDrawData); err != nil {
+	old := a.GetStartNavigability()
+	return ud.executeFunc(
+		func() duerror.DUError { return a.SetStartNavigability(navigability) },
+		func() duerror.DUError { return a.SetStartNavigability(old) },
+	)
+}
+
+func (ud *UMLDiagram) SetEndNavigabilityAssociation(navigability component.Navigability) duerror.DUError {
+	a, err := ud.getSelectedAssociation()
+	if err != nil {
 		return err
 	}
-	if err = ud.componentsContainer.Insert(g); err != nil {
+	old := a.GetEndNavigability()
+	return ud.executeFunc(
+		func() duerror.DUError { return a.SetEndNavigability(navigability) },
+		func() duerror.DUError { return a.SetEndNavigability(old) },
+	)
+}
+
+// SetRoutingAssociation switches the selected association between its waypoints and an orthogonal route
+func (ud *UMLDiagram) SetRoutingAssociation(routing component.Routing) duerror.DUError {
+	a, err := ud.getSelectedAssociation()
+	if err != nil {
 		return err
 	}
-	ud.associations[g] = [2][]*component.Association{{}, {}}
-	return ud.updateDrawData()
+	old := a.GetRouting()
+	return ud.executeFunc(
+		func() duerror.DUError { return a.SetRouting(routing) },
+		func() duerror.DUError { return a.SetRouting(old) },
+	)
+}
+
+// AddWaypointAssociation inserts a bend at point into the selected association, before the waypoint at index
+func (ud *UMLDiagram) AddWaypointAssociation(index int, point utils.Point) duerror.DUError {
+	a, err := ud.getSelectedAssociation()
+	if err != nil {
+		return err
+	}
+	if err = ud.validatePoint(point); err != nil {
+		return err
+	}
+	waypoints := a.GetWaypoints()
+	if index < 0 || index > len(waypoints) {
+		return duerror.NewInvalidArgumentError("index out of range")
+	}
+	return ud.setWaypoints(a, slices.Insert(waypoints, index, point))
+}
+
+func (ud *UMLDiagram) MoveWaypointAssociation(index int, point utils.Point) duerror.DUError {
+	a, err := ud.getSelectedAssociation()
+	if err != nil {
+		return err
+	}
+	if err = ud.validatePoint(point); err != nil {
+		return err
+	}
+	waypoints := a.GetWaypoints()
+	if index < 0 || index >= len(waypoints) {
+		return duerror.NewInvalidArgumentError("index out of range")
+	}
+	waypoints[index] = point
+	return ud.setWaypoints(a, waypoints)
+}
+
+func (ud *UMLDiagram) RemoveWaypointAssociation(index int) duerror.DUError {
+	a, err := ud.getSelectedAssociation()
+	if err != nil {
+		return err
+	}
+	waypoints := a.GetWaypoints()
+	if index < 0 || index >= len(waypoints) {
+		return duerror.NewInvalidArgumentError("index out of range")
+	}
+	return ud.setWaypoints(a, slices.Delete(waypoints, index, index+1))
+}
+
+// SetLabelAssociation shows content as the label of kind on the selected association, empty hides it
+func (ud *UMLDiagram) SetLabelAssociation(kind component.LabelKind, content string) duerror.DUError {
+	a, err := ud.getSelectedAssociation()
+	if err != nil {
+		return err
+	}
+	old, err := a.GetLabel(kind)
+	if err != nil {
+		return err
+	}
+	return ud.executeFunc(
+		func() duerror.DUError { return a.SetLabel(kind, content) },
+		func() duerror.DUError { return a.SetLabel(kind, old) },
+	)
+}
+
+func (ud *UMLDiagram) SetReadingDirectionAssociation(direction component.ReadingDirection) duerror.DUError {
+	a, err := ud.getSelectedAssociation()
+	if err != nil {
+		return err
+	}
+	old := a.GetReadingDirection()
+	return ud.executeFunc(
+		func() duerror.DUError { return a.SetReadingDirection(direction) },
+		func() duerror.DUError { return a.SetReadingDirection(old) },
+	)
+}
+
+// SetClassAssociation links the selected association class to the class gadget with classID,
+// an empty classID unlinks it
+func (ud *UMLDiagram) SetClassAssociation(classID string) duerror.DUError {
+	a, err := ud.getSelectedAssociation()
+	if err != nil {
+		return err
+	}
+	var class *component.Gadget
+	if classID != "" {
+		if class, err = ud.getGadgetByID(classID); err != nil {
+			return err
+		}
+	}
+	old := a.GetAssociationClass()
+	return ud.executeFunc(
+		func() duerror.DUError { return ud.setAssociationClass(a, class) },
+		func() duerror.DUError { return ud.setAssociationClass(a, old) },
+	)
+}
+
+func (ud *UMLDiagram) SetLayerAssociation(layer int) duerror.DUError {
+	a, err := ud.getSelectedAssociation()
+	if err != nil {
+		return err
+	}
+	old := a.GetLayer()
+	return ud.executeFunc(
+		func() duerror.DUError { return a.SetLayer(layer) },
+		func() duerror.DUError { return a.SetLayer(old) },
+	)
+}
+
+// SetStartPointAssociation moves the start of the selected association along its start gadget
+func (ud *UMLDiagram) SetStartPointAssociation(point utils.Point) duerror.DUError {
+	a, err := ud.getSelectedAssociation()
+	if err != nil {
+		return err
+	}
+	old := a.GetStartRatio()
+	return ud.executeFunc(
+		func() duerror.DUError { return a.SetStartPoint(point) },
+		func() duerror.DUError { return a.SetStartRatio(old) },
+	)
+}
+
+// SetEndPointAssociation moves the end of the selected association along its end gadget
+func (ud *UMLDiagram) SetEndPointAssociation(point utils.Point) duerror.DUError {
+	a, err := ud.getSelectedAssociation()
+	if err != nil {
+		return err
+	}
+	old := a.GetEndRatio()
+	return ud.executeFunc(
+		func() duerror.DUError { return a.SetEndPoint(point) },
+		func() duerror.DUError { return a.SetEndRatio(old) },
+	)
+}
+
+// ReconnectStartAssociation drags the start of the selected association onto the gadget under point
+func (ud *UMLDiagram) ReconnectStartAssociation(point utils.Point) duerror.DUError {
+	return ud.reconnectAssociation(0, point)
+}
+
+// ReconnectEndAssociation drags the end of the selected association onto the gadget under point
+func (ud *UMLDiagram) ReconnectEndAssociation(point utils.Point) duerror.DUError {
+	return ud.reconnectAssociation(1, point)
+}
+
+// Methods
+func (ud *UMLDiagram) AddGadget(gadgetType component.GadgetType, point utils.Point, layer int, colorHexStr string, header string) duerror.DUError {
+	g, err := component.NewGadget(gadgetType, point, layer, colorHexStr, header)
+	if err != nil {
+		return err
+	}
+	return ud.execute(&addGadgetCommand{diagram: ud, gadget: g})
 }
 
 func (ud *UMLDiagram) StartAddAssociation(point utils.Point) duerror.DUError {
@@ -230,41 +669,28 @@ func (ud *UMLDiagram) EndAddAssociation(assType component.AssociationType, endPo
 	if err != nil {
 		return err
 	}
-	if err = a.RegisterUpdateParentDraw(ud.updateDrawData); err != nil {
-		return err
-	}
-	if err = ud.componentsContainer.Insert(a); err != nil {
-		return err
-	}
-
-	// record it, cant modify the slice, being a value of the map, directly
-	tmp := ud.associations[stGad]
-	tmp[0] = append(tmp[0], a)
-	ud.associations[stGad] = tmp
-
-	tmp = ud.associations[enGad]
-	tmp[1] = append(tmp[1], a)
-	ud.associations[enGad] = tmp
-
-	return ud.updateDrawData()
+	return ud.execute(&addAssociationCommand{diagram: ud, association: a})
 }
 
+// RemoveSelectedComponents removes the selection and every association attached to it as one undoable step
 func (ud *UMLDiagram) RemoveSelectedComponents() duerror.DUError {
+	selected := make([]component.Component, 0, len(ud.componentsSelected))
 	for c := range ud.componentsSelected {
-		switch c := c.(type) {
-		case *component.Gadget:
-			if err := ud.removeGadget(c); err != nil {
-				return err
-			}
-		case *component.Association:
-			if err := ud.removeAssociation(c); err != nil {
-				return err
-			}
-		}
+		selected = append(selected, c)
 	}
-	return ud.updateDrawData()
+	return ud.removeComponents(selected)
+}
+
+// RemoveComponentByID removes the component, and every association attached to it, as one undoable step
+func (ud *UMLDiagram) RemoveComponentByID(id string) duerror.DUError {
+	c, err := ud.getComponentByID(id)
+	if err != nil {
+		return err
+	}
+	return ud.removeComponents([]component.Component{c})
 }
 
+// SelectComponent toggles the selection of the topmost gadget or association at point
 func (ud *UMLDiagram) SelectComponent(point utils.Point) duerror.DUError {
 	c, err := ud.componentsContainer.Search(point)
 	if err != nil {
@@ -274,16 +700,10 @@ func (ud *UMLDiagram) SelectComponent(point utils.Point) duerror.DUError {
 		return nil
 	}
 	// if is in componentsSelected remove it, else add it
-	if _, ok := ud.componentsSelected[c]; ok {
-		gadget := c.(*component.Gadget)
-		gadget.SetIsSelected(false)
-		delete(ud.componentsSelected, c)
-	} else {
-		gadget := c.(*component.Gadget)
-		gadget.SetIsSelected(true)
-		ud.componentsSelected[c] = true
+	_, selected := ud.componentsSelected[c]
+	if err = ud.setComponentSelected(c, !selected); err != nil {
+		return err
 	}
-	//ud.componentsSelected[c] = true
 	return ud.updateDrawData()
 }
 
@@ -295,41 +715,265 @@ func (ud *UMLDiagram) UnselectComponent(point utils.Point) duerror.DUError {
 	if c == nil {
 		return nil
 	}
-	delete(ud.componentsSelected, c)
+	if err = ud.setComponentSelected(c, false); err != nil {
+		return err
+	}
 	return ud.updateDrawData()
 }
 
 func (ud *UMLDiagram) UnselectAllComponents() duerror.DUError {
-	ud.componentsSelected = make(map[component.Component]bool)
-	return nil
+	for c := range ud.componentsSelected {
+		if err := ud.setComponentSelected(c, false); err != nil {
+			return err
+		}
+	}
+	return ud.updateDrawData()
 }
 
-func (ud *UMLDiagram) AddAttributeToGadget(section int, content string) duerror.DUError {
-	c, err := ud.getSelectedComponent()
-	if err != nil {
+// StartDragging begins moving the selection from point, everything until StopDragging undoes as one step
+func (ud *UMLDiagram) StartDragging(point utils.Point) duerror.DUError {
+	if ud.dragging {
+		return duerror.NewInvalidArgumentError("already dragging")
+	}
+	if err := ud.validatePoint(point); err != nil {
 		return err
 	}
+	ud.cmdManager.Begin()
+	ud.dragging = true
+	ud.dragPoint = point
+	return nil
+}
 
-	switch g := c.(type) {
-	case *component.Gadget:
-		return g.AddAttribute(section, content)
-	default:
-		return duerror.NewInvalidArgumentError("selected component is not a gadget")
+// StopDragging moves the selection the rest of the way to point and ends the drag,
+// if that move fails the whole drag is reverted
+func (ud *UMLDiagram) StopDragging(point utils.Point) duerror.DUError {
+	if !ud.dragging {
+		return duerror.NewInvalidArgumentError("not dragging")
 	}
+	ud.dragging = false
+	if err := ud.batchRedraw(func() duerror.DUError {
+		return ud.moveSelectedGadgets(utils.SubPoints(point, ud.dragPoint))
+	}); err != nil {
+		if rollbackErr := ud.RollbackTransaction(); rollbackErr != nil {
+			return rollbackErr
+		}
+		return err
+	}
+	return ud.cmdManager.Commit()
+}
+
+// MoveSelectedComponent moves every selected gadget by delta, the associations attached to them follow.
+// During a drag it moves the selection along, otherwise it is an undoable step of its own.
+func (ud *UMLDiagram) MoveSelectedComponent(delta utils.Point) duerror.DUError {
+	if err := ud.batchRedraw(func() duerror.DUError {
+		return ud.transact(func() duerror.DUError {
+			return ud.moveSelectedGadgets(delta)
+		})
+	}); err != nil {
+		return err
+	}
+	if ud.dragging {
+		ud.dragPoint = utils.AddPoints(ud.dragPoint, delta)
+	}
+	return nil
+}
+
+// SelectComponentsInRect adds every component fully or partly inside the rectangle spanned by p1 and p2 to the selection
+func (ud *UMLDiagram) SelectComponentsInRect(p1 utils.Point, p2 utils.Point) duerror.DUError {
+	cs, err := ud.componentsContainer.SearchRange(utils.NewRect(p1, p2))
+	if err != nil {
+		return err
+	}
+	return ud.selectComponents(cs)
+}
+
+func (ud *UMLDiagram) SelectAllComponents() duerror.DUError {
+	return ud.selectComponents(ud.componentsContainer.GetAll())
+}
+
+// InvertSelection selects every unselected component and unselects the rest
+func (ud *UMLDiagram) InvertSelection() duerror.DUError {
+	for _, c := range ud.componentsContainer.GetAll() {
+		_, selected := ud.componentsSelected[c]
+		if err := ud.setComponentSelected(c, !selected); err != nil {
+			return err
+		}
+	}
+	return ud.updateDrawData()
+}
+
+// SelectAllGadgets adds every gadget to the selection
+func (ud *UMLDiagram) SelectAllGadgets() duerror.DUError {
+	gadgets := make([]component.Component, 0)
+	for _, c := range ud.componentsContainer.GetAll() {
+		if _, ok := c.(*component.Gadget); ok {
+			gadgets = append(gadgets, c)
+		}
+	}
+	return ud.selectComponents(gadgets)
+}
+
+// SelectAssociationsByType adds every association of assType to the selection
+func (ud *UMLDiagram) SelectAssociationsByType(assType component.AssociationType) duerror.DUError {
+	asses := make([]component.Component, 0)
+	for _, c := range ud.componentsContainer.GetAll() {
+		if a, ok := c.(*component.Association); ok && a.GetAssType() == assType {
+			asses = append(asses, c)
+		}
+	}
+	return ud.selectComponents(asses)
+}
+
+// SelectComponentByID adds the component to the selection, unlike SelectComponent it does not toggle
+func (ud *UMLDiagram) SelectComponentByID(id string) duerror.DUError {
+	c, err := ud.getComponentByID(id)
+	if err != nil {
+		return err
+	}
+	if err = ud.setComponentSelected(c, true); err != nil {
+		return err
+	}
+	return ud.updateDrawData()
+}
+
+func (ud *UMLDiagram) UnselectComponentByID(id string) duerror.DUError {
+	c, err := ud.getComponentByID(id)
+	if err != nil {
+		return err
+	}
+	if err = ud.setComponentSelected(c, false); err != nil {
+		return err
+	}
+	return ud.updateDrawData()
+}
+
+func (ud *UMLDiagram) AddAttributeToGadget(section int, content string) duerror.DUError {
+	g, err := ud.getSelectedGadget()
+	if err != nil {
+		return err
+	}
+	return ud.addAttributeToGadget(g, section, content)
 }
 
 func (ud *UMLDiagram) RemoveAttributeFromGadget(section int, index int) duerror.DUError {
-	c, err := ud.getSelectedComponent()
+	g, err := ud.getSelectedGadget()
+	if err != nil {
+		return err
+	}
+	return ud.removeAttributeFromGadget(g, section, index)
+}
+
+func (ud *UMLDiagram) AddAttributeToGadgetByID(id string, section int, content string) duerror.DUError {
+	g, err := ud.getGadgetByID(id)
+	if err != nil {
+		return err
+	}
+	return ud.addAttributeToGadget(g, section, content)
+}
+
+func (ud *UMLDiagram) RemoveAttributeFromGadgetByID(id string, section int, index int) duerror.DUError {
+	g, err := ud.getGadgetByID(id)
+	if err != nil {
+		return err
+	}
+	return ud.removeAttributeFromGadget(g, section, index)
+}
+
+// AddAttributeToAssociation adds a label at ratio, 0 being the start and 1 the end of the selected association
+func (ud *UMLDiagram) AddAttributeToAssociation(ratio float64, content string) duerror.DUError {
+	a, err := ud.getSelectedAssociation()
+	if err != nil {
+		return err
+	}
+	att, err := attribute.NewAssAttribute(ratio)
 	if err != nil {
 		return err
 	}
+	if err = att.SetContent(content); err != nil {
+		return err
+	}
+	index := a.GetAttributesLen() // the new attribute is appended
+	return ud.executeFunc(
+		func() duerror.DUError { return a.InsertAttribute(index, att) },
+		func() duerror.DUError { return a.RemoveAttribute(index) },
+	)
+}
+
+func (ud *UMLDiagram) MoveAttributeAssociation(index int, ratio float64) duerror.DUError {
+	a, err := ud.getSelectedAssociation()
+	if err != nil {
+		return err
+	}
+	att, err := ud.getAssociationAttribute(a, index)
+	if err != nil {
+		return err
+	}
+	old := att.GetRatio()
+	return ud.executeFunc(
+		func() duerror.DUError { return a.MoveAttribute(index, ratio) },
+		func() duerror.DUError { return a.MoveAttribute(index, old) },
+	)
+}
 
-	switch g := c.(type) {
-	case *component.Gadget:
-		return g.RemoveAttribute(section, index)
-	default:
-		return duerror.NewInvalidArgumentError("selected component is not a gadget")
+func (ud *UMLDiagram) RemoveAttributeFromAssociation(index int) duerror.DUError {
+	a, err := ud.getSelectedAssociation()
+	if err != nil {
+		return err
+	}
+	att, err := ud.getAssociationAttribute(a, index)
+	if err != nil {
+		return err
 	}
+	return ud.executeFunc(
+		func() duerror.DUError { return a.RemoveAttribute(index) },
+		func() duerror.DUError { return a.InsertAttribute(index, att) },
+	)
+}
+
+// BeginTransaction groups every following edit, until CommitTransaction, into one undoable step,
+// e.g. the stream of SetPointGadget calls of a drag. Transactions can be nested.
+func (ud *UMLDiagram) BeginTransaction() duerror.DUError {
+	ud.cmdManager.Begin()
+	return nil
+}
+
+func (ud *UMLDiagram) CommitTransaction() duerror.DUError {
+	return ud.cmdManager.Commit()
+}
+
+// RollbackTransaction reverts every edit since the matching BeginTransaction
+func (ud *UMLDiagram) RollbackTransaction() duerror.DUError {
+	if err := ud.cmdManager.Rollback(); err != nil {
+		return err
+	}
+	ud.lastModified = time.Now()
+	return ud.updateDrawData()
+}
+
+// Undo reverts the latest edit of this diagram
+func (ud *UMLDiagram) Undo() duerror.DUError {
+	if err := ud.cmdManager.Undo(); err != nil {
+		return err
+	}
+	ud.lastModified = time.Now()
+	return ud.updateDrawData()
+}
+
+// Redo re-applies the latest undone edit of this diagram
+func (ud *UMLDiagram) Redo() duerror.DUError {
+	if err := ud.cmdManager.Redo(); err != nil {
+		return err
+	}
+	ud.lastModified = time.Now()
+	return ud.updateDrawData()
+}
+
+func (ud *UMLDiagram) CanUndo() bool {
+	return ud.cmdManager.CanUndo()
+}
+
+func (ud *UMLDiagram) CanRedo() bool {
+	return ud.cmdManager.CanRedo()
 }
 
 // Private methods
@@ -343,22 +987,511 @@ func (ud *UMLDiagram) getSelectedComponent() (component.Component, duerror.DUErr
 	return nil, duerror.NewInvalidArgumentError("no component selected")
 }
 
-func (ud *UMLDiagram) removeGadget(gad *component.Gadget) duerror.DUError {
-	if _, ok := ud.associations[gad]; ok {
-		for _, a := range ud.associations[gad][0] {
-			if err := ud.removeAssociation(a); err != nil {
+func (ud *UMLDiagram) getSelectedGadget() (*component.Gadget, duerror.DUError) {
+	c, err := ud.getSelectedComponent()
+	if err != nil {
+		return nil, err
+	}
+	g, ok := c.(*component.Gadget)
+	if !ok {
+		return nil, duerror.NewInvalidArgumentError("selected component is not a gadget")
+	}
+	return g, nil
+}
+
+func (ud *UMLDiagram) getSelectedAssociation() (*component.Association, duerror.DUError) {
+	c, err := ud.getSelectedComponent()
+	if err != nil {
+		return nil, err
+	}
+	a, ok := c.(*component.Association)
+	if !ok {
+		return nil, duerror.NewInvalidArgumentError("selected component is not an association")
+	}
+	return a, nil
+}
+
+func (ud *UMLDiagram) getAssociationAttribute(a *component.Association, index int) (*attribute.AssAttribute, duerror.DUError) {
+	atts, err := a.GetAttributes()
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(atts) {
+		return nil, duerror.NewInvalidArgumentError("index out of range")
+	}
+	return atts[index], nil
+}
+
+func (ud *UMLDiagram) getComponentByID(id string) (component.Component, duerror.DUError) {
+	c, err := ud.componentsContainer.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, duerror.NewInvalidArgumentError("component not found")
+	}
+	return c, nil
+}
+
+func (ud *UMLDiagram) getGadgetByID(id string) (*component.Gadget, duerror.DUError) {
+	c, err := ud.getComponentByID(id)
+	if err != nil {
+		return nil, err
+	}
+	g, ok := c.(*component.Gadget)
+	if !ok {
+		return nil, duerror.NewInvalidArgumentError("component is not a gadget")
+	}
+	return g, nil
+}
+
+func (ud *UMLDiagram) setPointGadget(g *component.Gadget, point utils.Point) duerror.DUError {
+	old := g.GetPoint()
+	return ud.executeFunc(
+		func() duerror.DUError { return g.SetPoint(point) },
+		func() duerror.DUError { return g.SetPoint(old) },
+	)
+}
+
+func (ud *UMLDiagram) setLayerGadget(g *component.Gadget, layer int) duerror.DUError {
+	old := g.GetLayer()
+	return ud.executeFunc(
+		func() duerror.DUError { return g.SetLayer(layer) },
+		func() duerror.DUError { return g.SetLayer(old) },
+	)
+}
+
+func (ud *UMLDiagram) setColorGadget(g *component.Gadget, colorHexStr string) duerror.DUError {
+	old := g.GetColor()
+	return ud.executeFunc(
+		func() duerror.DUError { return g.SetColor(colorHexStr) },
+		func() duerror.DUError { return g.SetColor(old) },
+	)
+}
+
+func (ud *UMLDiagram) setAttrContentGadget(g *component.Gadget, section int, index int, content string) duerror.DUError {
+	att, err := g.GetAttribute(section, index)
+	if err != nil {
+		return err
+	}
+	old := att.GetContent()
+	return ud.executeFunc(
+		func() duerror.DUError { return g.SetAttrContent(section, index, content) },
+		func() duerror.DUError { return g.SetAttrContent(section, index, old) },
+	)
+}
+
+func (ud *UMLDiagram) setAttrSizeGadget(g *component.Gadget, section int, index int, size int) duerror.DUError {
+	att, err := g.GetAttribute(section, index)
+	if err != nil {
+		return err
+	}
+	old := att.GetSize()
+	return ud.executeFunc(
+		func() duerror.DUError { return g.SetAttrSize(section, index, size) },
+		func() duerror.DUError { return g.SetAttrSize(section, index, old) },
+	)
+}
+
+func (ud *UMLDiagram) setAttrStyleGadget(g *component.Gadget, section int, index int, style int) duerror.DUError {
+	att, err := g.GetAttribute(section, index)
+	if err != nil {
+		return err
+	}
+	old := int(att.GetStyle())
+	return ud.executeFunc(
+		func() duerror.DUError { return g.SetAttrStyle(section, index, style) },
+		func() duerror.DUError { return g.SetAttrStyle(section, index, old) },
+	)
+}
+
+func (ud *UMLDiagram) addAttributeToGadget(g *component.Gadget, section int, content string) duerror.DUError {
+	lens := g.GetAttributesLen()
+	if section < 0 || section >= len(lens) {
+		return duerror.NewInvalidArgumentError("section out of range")
+	}
+	index := lens[section] // the new attribute is appended
+	return ud.executeFunc(
+		func() duerror.DUError { return g.AddAttribute(section, content) },
+		func() duerror.DUError { return g.RemoveAttribute(section, index) },
+	)
+}
+
+func (ud *UMLDiagram) removeAttributeFromGadget(g *component.Gadget, section int, index int) duerror.DUError {
+	att, err := g.GetAttribute(section, index)
+	if err != nil {
+		return err
+	}
+	return ud.executeFunc(
+		func() duerror.DUError { return g.RemoveAttribute(section, index) },
+		func() duerror.DUError { return g.InsertAttribute(section, index, att) },
+	)
+}
+
+// every mutation goes through here so that it can be undone
+func (ud *UMLDiagram) execute(c command.Command) duerror.DUError {
+	if err := ud.cmdManager.Execute(c); err != nil {
+		return err
+	}
+	ud.lastModified = time.Now()
+	return nil
+}
+
+// transact runs edit inside a transaction, so that it undoes as one step
+// and leaves nothing half done when one of its commands fails
+func (ud *UMLDiagram) transact(edit func() duerror.DUError) duerror.DUError {
+	ud.cmdManager.Begin()
+	if err := edit(); err != nil {
+		if rollbackErr := ud.RollbackTransaction(); rollbackErr != nil {
+			return rollbackErr
+		}
+		return err
+	}
+	return ud.cmdManager.Commit()
+}
+
+// removeComponents removes cs, and the associations attached to the gadgets among them, in one transaction
+func (ud *UMLDiagram) removeComponents(cs []component.Component) duerror.DUError {
+	if err := ud.transact(func() duerror.DUError {
+		gadgets := make([]*component.Gadget, 0, len(cs))
+		for _, c := range cs {
+			switch c := c.(type) {
+			case *component.Gadget:
+				gadgets = append(gadgets, c)
+			case *component.Association:
+				if err := ud.execute(&removeAssociationCommand{diagram: ud, association: c}); err != nil {
+					return err
+				}
+			}
+		}
+		for _, g := range gadgets {
+			if err := ud.removeGadgetCascade(g); err != nil {
 				return err
 			}
 		}
-		for _, a := range ud.associations[gad][1] {
+		return nil
+	}); err != nil {
+		return err
+	}
+	return ud.updateDrawData()
+}
+
+// removeGadgetCascade removes the associations attached to g, unlinks the ones g is the class of, then removes g itself
+func (ud *UMLDiagram) removeGadgetCascade(g *component.Gadget) duerror.DUError {
+	return ud.transact(func() duerror.DUError {
+		for _, a := range slices.Clone(ud.associationClasses[g]) {
+			if err := ud.executeFunc(
+				func() duerror.DUError { return ud.setAssociationClass(a, nil) },
+				func() duerror.DUError { return ud.setAssociationClass(a, g) },
+			); err != nil {
+				return err
+			}
+		}
+		for _, a := range ud.getAttachedAssociations(g) {
+			if err := ud.execute(&removeAssociationCommand{diagram: ud, association: a}); err != nil {
+				return err
+			}
+		}
+		return ud.execute(&removeGadgetCommand{diagram: ud, gadget: g})
+	})
+}
+
+// moveSelectedGadgets moves nothing at all if one of the gadgets would leave the canvas
+func (ud *UMLDiagram) moveSelectedGadgets(delta utils.Point) duerror.DUError {
+	if utils.EqualPoints(delta, utils.Point{}) {
+		return nil
+	}
+	gadgets := make([]*component.Gadget, 0, len(ud.componentsSelected))
+	moved := make(map[*component.Gadget]bool)
+	for c := range ud.componentsSelected {
+		if g, ok := c.(*component.Gadget); ok {
+			if err := ud.validatePoint(utils.AddPoints(g.GetPoint(), delta)); err != nil {
+				return err
+			}
+			gadgets = append(gadgets, g)
+			moved[g] = true
+		}
+	}
+	// the waypoints of an association move along when both its ends do
+	asses := make([]*component.Association, 0)
+	for _, g := range gadgets {
+		for _, a := range ud.associations[g][0] {
+			if !moved[a.GetParentEnd()] || len(a.GetWaypoints()) == 0 {
+				continue
+			}
+			for _, wp := range a.GetWaypoints() {
+				if err := ud.validatePoint(utils.AddPoints(wp, delta)); err != nil {
+					return err
+				}
+			}
+			asses = append(asses, a)
+		}
+	}
+	for _, g := range gadgets {
+		if err := ud.setPointGadget(g, utils.AddPoints(g.GetPoint(), delta)); err != nil {
+			return err
+		}
+	}
+	for _, a := range asses {
+		waypoints := a.GetWaypoints()
+		for i := range waypoints {
+			waypoints[i] = utils.AddPoints(waypoints[i], delta)
+		}
+		if err := ud.setWaypoints(a, waypoints); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ud *UMLDiagram) selectComponents(cs []component.Component) duerror.DUError {
+	for _, c := range cs {
+		if err := ud.setComponentSelected(c, true); err != nil {
+			return err
+		}
+	}
+	return ud.updateDrawData()
+}
+
+func (ud *UMLDiagram) setComponentSelected(c component.Component, selected bool) duerror.DUError {
+	if selected {
+		ud.componentsSelected[c] = true
+	} else {
+		delete(ud.componentsSelected, c)
+	}
+	return c.SetIsSelected(selected)
+}
+
+func (ud *UMLDiagram) executeFunc(execute func() duerror.DUError, unexecute func() duerror.DUError) duerror.DUError {
+	c, err := command.NewFuncCommand(execute, unexecute)
+	if err != nil {
+		return err
+	}
+	return ud.execute(c)
+}
+
+// getAttachedAssociations lists every association starting or ending at g, self associations only once
+func (ud *UMLDiagram) getAttachedAssociations(g *component.Gadget) []*component.Association {
+	list := ud.associations[g]
+	attached := slices.Clone(list[0])
+	for _, a := range list[1] {
+		if !slices.Contains(attached, a) {
+			attached = append(attached, a)
+		}
+	}
+	return attached
+}
+
+func (ud *UMLDiagram) setWaypoints(a *component.Association, waypoints []utils.Point) duerror.DUError {
+	old := a.GetWaypoints()
+	return ud.executeFunc(
+		func() duerror.DUError { return a.SetWaypoints(waypoints) },
+		func() duerror.DUError { return a.SetWaypoints(old) },
+	)
+}
+
+func (ud *UMLDiagram) reconnectAssociation(end int, point utils.Point) duerror.DUError {
+	a, err := ud.getSelectedAssociation()
+	if err != nil {
+		return err
+	}
+	if err := ud.validatePoint(point); err != nil {
+		return err
+	}
+	g, err := ud.componentsContainer.SearchGadget(point)
+	if err != nil {
+		return err
+	}
+	if g == nil {
+		return duerror.NewInvalidArgumentError("point does not contain a gadget")
+	}
+	old := [2]*component.Gadget{a.GetParentStart(), a.GetParentEnd()}[end]
+	oldRatio := [2][2]float64{a.GetStartRatio(), a.GetEndRatio()}[end]
+	return ud.executeFunc(
+		func() duerror.DUError { return ud.setAssociationParent(a, end, g, point) },
+		func() duerror.DUError {
+			// the point only picks the gadget, the ratio puts the end back exactly
+			bounds := old.GetBounds()
+			back := utils.Point{
+				X: bounds.Min.X + int(float64(bounds.Max.X-bounds.Min.X)*oldRatio[0]),
+				Y: bounds.Min.Y + int(float64(bounds.Max.Y-bounds.Min.Y)*oldRatio[1]),
+			}
+			if err := ud.setAssociationParent(a, end, old, back); err != nil {
+				return err
+			}
+			if end == 0 {
+				return a.SetStartRatio(oldRatio)
+			}
+			return a.SetEndRatio(oldRatio)
+		},
+	)
+}
+
+// setAssociationParent moves one end of a onto g, end being 0 for the start and 1 for the end,
+// and moves a between the gadgets' lists of the association index
+func (ud *UMLDiagram) setAssociationParent(a *component.Association, end int, g *component.Gadget, point utils.Point) duerror.DUError {
+	if _, ok := ud.associations[g]; !ok {
+		return duerror.NewInvalidArgumentError("gadget is not in the diagram")
+	}
+	old := a.GetParentStart()
+	set := a.SetParentStart
+	if end == 1 {
+		old = a.GetParentEnd()
+		set = a.SetParentEnd
+	}
+	if err := set(g, point); err != nil {
+		return err
+	}
+	if old == g {
+		return nil
+	}
+
+	// record it, cant modify the slice, being a value of the map, directly
+	tmp := ud.associations[old]
+	tmp[end] = slices.DeleteFunc(tmp[end], func(other *component.Association) bool {
+		return other == a
+	})
+	ud.associations[old] = tmp
+
+	tmp = ud.associations[g]
+	tmp[end] = append(tmp[end], a)
+	ud.associations[g] = tmp
+	return nil
+}
+
+// setAssociationClass links a to class, or unlinks it for nil, and keeps the class index up to date
+func (ud *UMLDiagram) setAssociationClass(a *component.Association, class *component.Gadget) duerror.DUError {
+	old := a.GetAssociationClass()
+	if err := a.SetAssociationClass(class); err != nil {
+		return err
+	}
+	ud.unlinkAssociationClass(a, old)
+	if class != nil {
+		ud.associationClasses[class] = append(ud.associationClasses[class], a)
+	}
+	return nil
+}
+
+func (ud *UMLDiagram) unlinkAssociationClass(a *component.Association, class *component.Gadget) {
+	if class == nil {
+		return
+	}
+	list := slices.DeleteFunc(ud.associationClasses[class], func(other *component.Association) bool {
+		return other == a
+	})
+	if len(list) == 0 {
+		delete(ud.associationClasses, class)
+		return
+	}
+	ud.associationClasses[class] = list
+}
+
+// insertGadget puts g where seq places it among the components on its layer, or on top of them for 0
+func (ud *UMLDiagram) insertGadget(g *component.Gadget, seq int) duerror.DUError {
+	update := func() duerror.DUError {
+		return ud.updateGadgetDrawData(g)
+	}
+	if err := g.RegisterUpdateParentDraw(update); err != nil {
+		return err
+	}
+	if err := ud.insertComponent(g, seq); err != nil {
+		return err
+	}
+	ud.associations[g] = [2][]*component.Association{{}, {}}
+	ud.gadgetBounds[g] = g.GetBounds()
+	return ud.rerouteAround(g.GetBounds())
+}
+
+// insertAssociation puts a where seq places it among the components on its layer, or on top of them for 0
+func (ud *UMLDiagram) insertAssociation(a *component.Association, seq int) duerror.DUError {
+	update := func() duerror.DUError {
+		return ud.updateAssociationDrawData(a)
+	}
+	if err := a.RegisterUpdateParentDraw(update); err != nil {
+		return err
+	}
+	if err := a.RegisterObstacleSearch(ud.searchGadgetBounds); err != nil {
+		return err
+	}
+	if err := ud.insertComponent(a, seq); err != nil {
+		return err
+	}
+
+	// record it, cant modify the slice, being a value of the map, directly
+	st, en := a.GetParentStart(), a.GetParentEnd()
+	tmp := ud.associations[st]
+	tmp[0] = append(tmp[0], a)
+	ud.associations[st] = tmp
+
+	tmp = ud.associations[en]
+	tmp[1] = append(tmp[1], a)
+	ud.associations[en] = tmp
+
+	if class := a.GetAssociationClass(); class != nil {
+		ud.associationClasses[class] = append(ud.associationClasses[class], a)
+	}
+	if a.GetRouting() == component.RoutingOrthogonal {
+		// it was routed around its parents only, until it could search the diagram
+		return a.UpdateDrawData()
+	}
+	return nil
+}
+
+func (ud *UMLDiagram) insertComponent(c component.Component, seq int) duerror.DUError {
+	var err duerror.DUError
+	if seq == 0 {
+		err = ud.componentsContainer.Insert(c)
+	} else {
+		err = ud.componentsContainer.InsertWithSequence(c, seq)
+	}
+	if err != nil {
+		return err
+	}
+	return ud.growContainer()
+}
+
+// growContainer moves the components of a diagram grown to gridDiagramSize, by pasting, importing or
+// adding, from the default map into a grid, keeping their stacking order
+func (ud *UMLDiagram) growContainer() duerror.DUError {
+	if !ud.growToGrid {
+		return nil
+	}
+	if l, err := ud.componentsContainer.Len(); err != nil {
+		return err
+	} else if l < gridDiagramSize {
+		return nil
+	}
+	grid := components.NewContainerGrid()
+	for _, c := range ud.componentsContainer.GetAll() {
+		if err := grid.InsertWithSequence(c, ud.componentsContainer.GetSequence(c)); err != nil {
+			return err
+		}
+	}
+	ud.componentsContainer = grid
+	ud.growToGrid = false
+	return nil
+}
+
+func (ud *UMLDiagram) removeGadget(gad *component.Gadget) duerror.DUError {
+	if _, ok := ud.associations[gad]; ok {
+		// removeAssociation shrinks the lists in place, so iterate over a copy
+		for _, a := range ud.getAttachedAssociations(gad) {
 			if err := ud.removeAssociation(a); err != nil {
 				return err
 			}
 		}
 		delete(ud.associations, gad)
 	}
-	delete(ud.componentsSelected, gad)
-	return ud.componentsContainer.Remove(gad)
+	if _, ok := ud.componentsSelected[gad]; ok {
+		if err := ud.setComponentSelected(gad, false); err != nil {
+			return err
+		}
+	}
+	if err := ud.componentsContainer.Remove(gad); err != nil {
+		return err
+	}
+	delete(ud.gadgetBounds, gad)
+	return ud.rerouteAround(gad.GetBounds())
 }
 
 func (ud *UMLDiagram) removeAssociation(a *component.Association) duerror.DUError {
@@ -380,10 +1513,39 @@ func (ud *UMLDiagram) removeAssociation(a *component.Association) duerror.DUErro
 		}
 		ud.associations[en] = [2][]*component.Association{ud.associations[en][0], enList}
 	}
-	delete(ud.componentsSelected, a)
+	ud.unlinkAssociationClass(a, a.GetAssociationClass())
+	if _, ok := ud.componentsSelected[a]; ok {
+		if err := ud.setComponentSelected(a, false); err != nil {
+			return err
+		}
+	}
 	return ud.componentsContainer.Remove(a)
 }
 
+// newAssociationFromFileData rebuilds a saved association, its parent and class indices resolved against gadgets
+func newAssociationFromFileData(aFd filedata.Association, gadgets []*component.Gadget) (*component.Association, duerror.DUError) {
+	var parents [2]*component.Gadget
+	for i, index := range aFd.Parents {
+		if index < 0 || index >= len(gadgets) {
+			return nil, duerror.NewInvalidArgumentError("association parent index out of range")
+		}
+		parents[i] = gadgets[index]
+	}
+	a, err := component.NewAssociationFromFileData(aFd, parents)
+	if err != nil {
+		return nil, err
+	}
+	if aFd.Class != nil {
+		if *aFd.Class < 0 || *aFd.Class >= len(gadgets) {
+			return nil, duerror.NewInvalidArgumentError("association class index out of range")
+		}
+		if err = a.SetAssociationClass(gadgets[*aFd.Class]); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
 func (ud *UMLDiagram) validatePoint(point utils.Point) duerror.DUError {
 	if point.X < 0 || point.Y < 0 {
 		return duerror.NewInvalidArgumentError("point coordinates must be non-negative")
@@ -405,7 +1567,92 @@ func (ud *UMLDiagram) RegisterUpdateParentDraw(update func() duerror.DUError) du
 
 }
 
+// a gadget moved or resized, so the associations attached to it must follow
+func (ud *UMLDiagram) updateGadgetDrawData(g *component.Gadget) duerror.DUError {
+	return ud.batchRedraw(func() duerror.DUError {
+		if err := ud.componentsContainer.Update(g); err != nil {
+			return err
+		}
+		for _, list := range ud.associations[g] {
+			for _, a := range list {
+				if err := a.UpdateDrawData(); err != nil {
+					return err
+				}
+			}
+		}
+		for _, a := range ud.associationClasses[g] {
+			if err := a.UpdateDrawData(); err != nil {
+				return err
+			}
+		}
+		if old, ok := ud.gadgetBounds[g]; ok {
+			ud.gadgetBounds[g] = g.GetBounds()
+			if err := ud.rerouteAround(old); err != nil {
+				return err
+			}
+		}
+		return ud.rerouteAround(g.GetBounds())
+	})
+}
+
+// batchRedraw runs f, rebuilding the draw data once at the end rather than for every component f changes
+func (ud *UMLDiagram) batchRedraw(f func() duerror.DUError) duerror.DUError {
+	ud.redrawHeld++
+	err := f()
+	ud.redrawHeld--
+	if redrawErr := ud.updateDrawData(); err == nil {
+		err = redrawErr
+	}
+	return err
+}
+
+// rerouteAround routes the orthogonal associations near r again, a gadget appeared, moved or went away there
+func (ud *UMLDiagram) rerouteAround(r utils.Rect) duerror.DUError {
+	zone := utils.Rect{
+		Min: utils.Point{X: r.Min.X - rerouteZone, Y: r.Min.Y - rerouteZone},
+		Max: utils.Point{X: r.Max.X + rerouteZone, Y: r.Max.Y + rerouteZone},
+	}
+	cs, err := ud.componentsContainer.SearchRange(zone)
+	if err != nil {
+		return err
+	}
+	for _, c := range cs {
+		if a, ok := c.(*component.Association); ok && a.GetRouting() == component.RoutingOrthogonal {
+			if err = a.UpdateDrawData(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// searchGadgetBounds returns the bounds of the gadgets in area, for orthogonal routes to go around
+func (ud *UMLDiagram) searchGadgetBounds(area utils.Rect) []utils.Rect {
+	cs, err := ud.componentsContainer.SearchRange(area)
+	if err != nil {
+		return nil
+	}
+	bounds := make([]utils.Rect, 0, len(cs))
+	for _, c := range cs {
+		if g, ok := c.(*component.Gadget); ok {
+			bounds = append(bounds, g.GetBounds())
+		}
+	}
+	return bounds
+}
+
+// the line of an association may have moved
+func (ud *UMLDiagram) updateAssociationDrawData(a *component.Association) duerror.DUError {
+	if err := ud.componentsContainer.Update(a); err != nil {
+		return err
+	}
+	return ud.updateDrawData()
+}
+
 func (ud *UMLDiagram) updateDrawData() duerror.DUError {
+	if ud.redrawHeld > 0 {
+		return nil
+	}
 	gs := make([]drawdata.Gadget, 0, len(ud.componentsSelected))
 	as := make([]drawdata.Association, 0, len(ud.componentsSelected))
 	for _, c := range ud.componentsContainer.GetAll() {
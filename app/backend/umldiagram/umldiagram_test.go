@@ -3,10 +3,16 @@
 package umldiagram
 
 import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"Dr.uml/backend/component"
+	"Dr.uml/backend/components"
 	"Dr.uml/backend/drawdata"
 	"Dr.uml/backend/utils"
 	"Dr.uml/backend/utils/duerror"
@@ -88,11 +94,34 @@ func TestCreateEmptyDiagram(t *testing.T) {
 }
 
 func TestLoadExistUMLDiagram(t *testing.T) {
-	diagram, err := LoadExistUMLDiagram("existing.uml")
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "existing.uml")
+
+	// file does not exist
+	diagram, err := LoadExistUMLDiagram(fileName)
+	assert.Error(t, err)
+	assert.Nil(t, diagram)
+
+	// save and load
+	saved, err := CreateEmptyUMLDiagram("existing.uml", ClassDiagram)
+	assert.NoError(t, err)
+	err = saved.AddGadget(component.Class, utils.Point{X: 10, Y: 10}, 2, "#123456", "header")
+	assert.NoError(t, err)
+	err = saved.SaveToFile(fileName)
+	assert.NoError(t, err)
+
+	diagram, err = LoadExistUMLDiagram(fileName)
 	assert.NoError(t, err)
 	assert.NotNil(t, diagram)
-	assert.Equal(t, "existing.uml", diagram.GetName())
+	assert.Equal(t, fileName, diagram.GetName())
 	assert.Equal(t, DiagramType(ClassDiagram), diagram.GetDiagramType())
+	assert.Equal(t, saved.GetDrawData().Gadgets, diagram.GetDrawData().Gadgets)
+
+	// missing version
+	noVersion := filepath.Join(dir, "noversion.uml")
+	assert.NoError(t, os.WriteFile(noVersion, []byte(`{"name": "x", "diagramType": 1}`), 0644))
+	_, err = LoadExistUMLDiagram(noVersion)
+	assert.Error(t, err)
 }
 
 func TestCheckDiagramType(t *testing.T) {
@@ -267,7 +296,69 @@ func TestUMLDiagram_EndAddAssociation_InvalidPoint(t *testing.T) {
 }
 
 func TestUMLDiagram_LoadExistUMLDiagram(t *testing.T) {
-	// TODO
+	fileName := filepath.Join(t.TempDir(), "drag.uml")
+	saved, _ := CreateEmptyUMLDiagram("drag.uml", ClassDiagram)
+	_ = saved.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A")
+	_ = saved.AddGadget(component.Class, utils.Point{X: 100, Y: 0}, 0, drawdata.DefaultGadgetColor, "B")
+	_ = saved.StartAddAssociation(utils.Point{X: 5, Y: 5})
+	assert.NoError(t, saved.EndAddAssociation(component.Dependency, utils.Point{X: 105, Y: 5}))
+	assert.NoError(t, saved.SaveToFile(fileName))
+
+	diagram, err := LoadExistUMLDiagram(fileName)
+	assert.NoError(t, err)
+	redrawn := false
+	assert.NoError(t, diagram.RegisterUpdateParentDraw(func() duerror.DUError {
+		redrawn = true
+		return nil
+	}))
+
+	// drag the end gadget, the association follows it
+	assert.NoError(t, diagram.SelectComponent(utils.Point{X: 105, Y: 5}))
+	assert.NoError(t, diagram.SetPointGadget(utils.Point{X: 100, Y: 200}))
+	assert.True(t, redrawn)
+	ass := diagram.GetDrawData().Associations
+	assert.Len(t, ass, 1)
+	assert.GreaterOrEqual(t, ass[0].EndY, 200)
+}
+
+func TestUMLDiagram_FileData(t *testing.T) {
+	diagram, err := CreateEmptyUMLDiagram("FileData.uml", ClassDiagram)
+	assert.NoError(t, err)
+	err = diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A")
+	assert.NoError(t, err)
+	err = diagram.AddGadget(component.Class, utils.Point{X: 100, Y: 100}, 1, drawdata.DefaultGadgetColor, "B")
+	assert.NoError(t, err)
+	err = diagram.StartAddAssociation(utils.Point{X: 5, Y: 5})
+	assert.NoError(t, err)
+	err = diagram.EndAddAssociation(component.Extension, utils.Point{X: 105, Y: 105})
+	assert.NoError(t, err)
+
+	fd, err := diagram.GetFileData()
+	assert.NoError(t, err)
+	assert.Equal(t, "FileData.uml", fd.Name)
+	assert.Len(t, fd.Gadgets, 2)
+	assert.Len(t, fd.Associations, 1)
+
+	loaded, err := LoadUMLDiagramFromFileData(fd)
+	assert.NoError(t, err)
+	assert.Equal(t, diagram.GetDiagramType(), loaded.GetDiagramType())
+	assert.Equal(t, diagram.GetBackgroundColor(), loaded.GetBackgroundColor())
+	assert.ElementsMatch(t, diagram.GetDrawData().Gadgets, loaded.GetDrawData().Gadgets)
+	assert.ElementsMatch(t, diagram.GetDrawData().Associations, loaded.GetDrawData().Associations)
+
+	// the associations index is rebuilt
+	for g, asses := range loaded.associations {
+		if g.GetLayer() == 0 {
+			assert.Len(t, asses[0], 1)
+		} else {
+			assert.Len(t, asses[1], 1)
+		}
+	}
+
+	// parent index out of range
+	fd.Associations[0].Parents = [2]int{0, 2}
+	_, err = LoadUMLDiagramFromFileData(fd)
+	assert.Error(t, err)
 }
 
 func TestValidatePoint(t *testing.T) {
@@ -452,3 +543,1047 @@ func (m *mockContainer) Len() (int, duerror.DUError) {
 	}
 	return 0, nil
 }
+
+func TestUMLDiagram_UndoRedoAddAndRemove(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("UndoRedo.uml", ClassDiagram)
+	assert.False(t, diagram.CanUndo())
+	assert.Error(t, diagram.Undo())
+
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 200, Y: 0}, 0, drawdata.DefaultGadgetColor, "B"))
+	_ = diagram.StartAddAssociation(utils.Point{X: 5, Y: 5})
+	assert.NoError(t, diagram.EndAddAssociation(component.Dependency, utils.Point{X: 205, Y: 5}))
+	_ = diagram.StartAddAssociation(utils.Point{X: 205, Y: 10})
+	assert.NoError(t, diagram.EndAddAssociation(component.Extension, utils.Point{X: 5, Y: 10}))
+	assert.Len(t, diagram.GetDrawData().Associations, 2)
+
+	// undo / redo adding an association
+	assert.NoError(t, diagram.Undo())
+	assert.Len(t, diagram.GetDrawData().Associations, 1)
+	assert.True(t, diagram.CanRedo())
+	assert.NoError(t, diagram.Redo())
+	assert.Len(t, diagram.GetDrawData().Associations, 2)
+	_ = diagram.StartAddAssociation(utils.Point{X: 5, Y: 15})
+	assert.NoError(t, diagram.EndAddAssociation(component.Composition, utils.Point{X: 205, Y: 15}))
+
+	// removing a gadget with several associations removes them too, and undo restores all
+	assert.NoError(t, diagram.SelectComponent(utils.Point{X: 5, Y: 20}))
+	assert.NoError(t, diagram.RemoveSelectedComponents())
+	assert.Len(t, diagram.GetDrawData().Gadgets, 1)
+	assert.Len(t, diagram.GetDrawData().Associations, 0)
+	assert.NoError(t, diagram.Undo())
+	assert.Len(t, diagram.GetDrawData().Gadgets, 2)
+	assert.Len(t, diagram.GetDrawData().Associations, 3)
+	starts, ends := 0, 0
+	for _, asses := range diagram.associations {
+		starts += len(asses[0])
+		ends += len(asses[1])
+	}
+	assert.Equal(t, 3, starts)
+	assert.Equal(t, 3, ends)
+	assert.NoError(t, diagram.Redo())
+	assert.Len(t, diagram.GetDrawData().Gadgets, 1)
+	assert.Len(t, diagram.GetDrawData().Associations, 0)
+
+	// undo everything
+	for diagram.CanUndo() {
+		assert.NoError(t, diagram.Undo())
+	}
+	assert.Len(t, diagram.componentsContainer.GetAll(), 0)
+	assert.True(t, diagram.CanRedo())
+}
+
+func TestUMLDiagram_UndoRedoSetters(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("UndoRedoSetters.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.SelectComponent(utils.Point{X: 5, Y: 5}))
+	original := diagram.GetDrawData().Gadgets[0]
+
+	assert.NoError(t, diagram.SetPointGadget(utils.Point{X: 50, Y: 60}))
+	assert.NoError(t, diagram.SetColorGadget("#000000"))
+	assert.NoError(t, diagram.SetSetLayerGadget(3))
+	assert.NoError(t, diagram.AddAttributeToGadget(1, "field: int"))
+	assert.NoError(t, diagram.SetAttrContentGadget(1, 0, "field: string"))
+	assert.NoError(t, diagram.SetAttrSizeGadget(1, 0, 20))
+	assert.NoError(t, diagram.SetAttrStyleGadget(1, 0, 1))
+	assert.NoError(t, diagram.SetAttrStyleGadget(0, 0, 2))
+	assert.NoError(t, diagram.RemoveAttributeFromGadget(0, 0))
+	edited := diagram.GetDrawData().Gadgets[0]
+	assert.Equal(t, 50, edited.X)
+	assert.Equal(t, "#000000", edited.Color)
+	assert.Len(t, edited.Attributes[0], 0)
+	assert.Equal(t, "field: string", edited.Attributes[1][0].Content)
+
+	// failed edits are not recorded
+	assert.Error(t, diagram.SetAttrContentGadget(1, 5, "nope"))
+	assert.Error(t, diagram.AddAttributeToGadget(7, "nope"))
+
+	// removed attribute comes back with its style
+	assert.NoError(t, diagram.Undo())
+	assert.Equal(t, 2, diagram.GetDrawData().Gadgets[0].Attributes[0][0].FontStyle)
+
+	for diagram.CanUndo() {
+		assert.NoError(t, diagram.Undo())
+	}
+	assert.Len(t, diagram.GetDrawData().Gadgets, 0)
+	assert.NoError(t, diagram.Redo())
+	restored := diagram.GetDrawData().Gadgets[0]
+	restored.IsSelected = original.IsSelected
+	assert.Equal(t, original, restored)
+
+	for diagram.CanRedo() {
+		assert.NoError(t, diagram.Redo())
+	}
+	redone := diagram.GetDrawData().Gadgets[0]
+	redone.IsSelected = edited.IsSelected
+	assert.Equal(t, edited, redone)
+}
+
+func TestUMLDiagram_Transaction(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("Transaction.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.SelectComponent(utils.Point{X: 5, Y: 5}))
+
+	// a drag is one step
+	assert.NoError(t, diagram.BeginTransaction())
+	for i := 1; i <= 10; i++ {
+		assert.NoError(t, diagram.SetPointGadget(utils.Point{X: i * 10, Y: i * 5}))
+	}
+	assert.NoError(t, diagram.CommitTransaction())
+	assert.Equal(t, 100, diagram.GetDrawData().Gadgets[0].X)
+	assert.NoError(t, diagram.Undo())
+	assert.Equal(t, 0, diagram.GetDrawData().Gadgets[0].X)
+	assert.NoError(t, diagram.Undo())
+	assert.False(t, diagram.CanUndo())
+	assert.NoError(t, diagram.Redo())
+	assert.NoError(t, diagram.SelectComponent(utils.Point{X: 5, Y: 5}))
+
+	// rollback puts everything back
+	assert.NoError(t, diagram.BeginTransaction())
+	assert.NoError(t, diagram.SetPointGadget(utils.Point{X: 30, Y: 30}))
+	assert.NoError(t, diagram.SetColorGadget("#000000"))
+	assert.NoError(t, diagram.RollbackTransaction())
+	assert.Equal(t, 0, diagram.GetDrawData().Gadgets[0].X)
+	assert.Equal(t, drawdata.DefaultGadgetColor, diagram.GetDrawData().Gadgets[0].Color)
+
+	assert.Error(t, diagram.CommitTransaction())
+	assert.Error(t, diagram.RollbackTransaction())
+}
+
+func TestUMLDiagram_TransactionFailureRollsBack(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("TransactionFailure.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 200, Y: 0}, 0, drawdata.DefaultGadgetColor, "B"))
+	_ = diagram.StartAddAssociation(utils.Point{X: 5, Y: 5})
+	assert.NoError(t, diagram.EndAddAssociation(component.Dependency, utils.Point{X: 205, Y: 5}))
+	gadget, err := diagram.componentsContainer.SearchGadget(utils.Point{X: 5, Y: 5})
+	assert.NoError(t, err)
+
+	// the cascade succeeds, then a later step fails: nothing may be left half removed
+	err = diagram.transact(func() duerror.DUError {
+		if err := diagram.removeGadgetCascade(gadget); err != nil {
+			return err
+		}
+		return diagram.SetPointGadget(utils.Point{X: 1, Y: 1}) // nothing selected
+	})
+	assert.Error(t, err)
+	assert.Len(t, diagram.GetDrawData().Gadgets, 2)
+	assert.Len(t, diagram.GetDrawData().Associations, 1)
+	assert.Len(t, diagram.associations[gadget][0], 1)
+	// and the failed group is not in the history
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, diagram.Undo())
+	}
+	assert.False(t, diagram.CanUndo())
+}
+
+func TestUMLDiagram_ComponentByID(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("ByID.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "Below"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 10, Y: 10}, 1, drawdata.DefaultGadgetColor, "Above"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 300, Y: 0}, 0, drawdata.DefaultGadgetColor, "Other"))
+	_ = diagram.StartAddAssociation(utils.Point{X: 305, Y: 5})
+	assert.NoError(t, diagram.EndAddAssociation(component.Dependency, utils.Point{X: 3, Y: 3}))
+
+	ids := make(map[string]drawdata.Gadget)
+	for _, g := range diagram.GetDrawData().Gadgets {
+		assert.NotEmpty(t, g.ID)
+		ids[g.Attributes[0][0].Content] = g
+	}
+	below := ids["Below"].ID
+	assID := diagram.GetDrawData().Associations[0].ID
+	assert.NotEmpty(t, assID)
+
+	// the gadget hidden below another one can be edited without selecting it
+	assert.NoError(t, diagram.SetColorGadgetByID(below, "#000000"))
+	assert.NoError(t, diagram.SetLayerGadgetByID(below, 2))
+	assert.NoError(t, diagram.AddAttributeToGadgetByID(below, 1, "field: int"))
+	assert.NoError(t, diagram.SetAttrContentGadgetByID(below, 1, 0, "field: string"))
+	assert.NoError(t, diagram.SetAttrSizeGadgetByID(below, 1, 0, 20))
+	assert.NoError(t, diagram.SetAttrStyleGadgetByID(below, 1, 0, 1))
+	assert.NoError(t, diagram.RemoveAttributeFromGadgetByID(below, 0, 0))
+	assert.NoError(t, diagram.SetPointGadgetByID(below, utils.Point{X: 0, Y: 200}))
+	g, err := diagram.getGadgetByID(below)
+	assert.NoError(t, err)
+	assert.Equal(t, "#000000", g.GetColor())
+	assert.Equal(t, 2, g.GetLayer())
+	assert.Equal(t, []int{0, 1, 0}, g.GetAttributesLen())
+	assert.Equal(t, 200, g.GetPoint().Y)
+	assert.NoError(t, diagram.Undo())
+	assert.Equal(t, 0, g.GetPoint().Y)
+
+	// unknown ids and ids of the wrong kind
+	assert.Error(t, diagram.SetColorGadgetByID("missing", "#000000"))
+	assert.Error(t, diagram.SetColorGadgetByID(assID, "#000000"))
+	assert.Error(t, diagram.SelectComponentByID(""))
+	assert.Error(t, diagram.RemoveComponentByID("missing"))
+
+	// select and unselect
+	assert.NoError(t, diagram.SelectComponentByID(below))
+	assert.NoError(t, diagram.SelectComponentByID(below))
+	assert.Len(t, diagram.componentsSelected, 1)
+	assert.True(t, g.GetIsSelected())
+	assert.NoError(t, diagram.SetColorGadget("#FFFFFF"))
+	assert.Equal(t, "#FFFFFF", g.GetColor())
+	assert.NoError(t, diagram.UnselectComponentByID(below))
+	assert.Len(t, diagram.componentsSelected, 0)
+	assert.False(t, g.GetIsSelected())
+
+	// removing by id cascades to the attached association, and undoes as one step
+	assert.NoError(t, diagram.RemoveComponentByID(below))
+	assert.Len(t, diagram.GetDrawData().Gadgets, 2)
+	assert.Len(t, diagram.GetDrawData().Associations, 0)
+	assert.NoError(t, diagram.Undo())
+	assert.Len(t, diagram.GetDrawData().Gadgets, 3)
+	assert.Len(t, diagram.GetDrawData().Associations, 1)
+	assert.NoError(t, diagram.RemoveComponentByID(assID))
+	assert.Len(t, diagram.GetDrawData().Gadgets, 3)
+	assert.Len(t, diagram.GetDrawData().Associations, 0)
+}
+
+func TestUMLDiagram_IDsPersist(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("IDs.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 200, Y: 0}, 0, drawdata.DefaultGadgetColor, "B"))
+	_ = diagram.StartAddAssociation(utils.Point{X: 5, Y: 5})
+	assert.NoError(t, diagram.EndAddAssociation(component.Dependency, utils.Point{X: 205, Y: 5}))
+
+	fd, err := diagram.GetFileData()
+	assert.NoError(t, err)
+	loaded, err := LoadUMLDiagramFromFileData(fd)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, diagram.GetDrawData().Gadgets, loaded.GetDrawData().Gadgets)
+	assert.Equal(t, diagram.GetDrawData().Associations[0].ID, loaded.GetDrawData().Associations[0].ID)
+
+	// two components with the same id
+	fd.Gadgets[1].ID = fd.Gadgets[0].ID
+	_, err = LoadUMLDiagramFromFileData(fd)
+	assert.Error(t, err)
+}
+
+func TestUMLDiagram_SelectAssociation(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("SelectAssociation.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 200, Y: 0}, 0, drawdata.DefaultGadgetColor, "B"))
+	_ = diagram.StartAddAssociation(utils.Point{X: 5, Y: 10})
+	assert.NoError(t, diagram.EndAddAssociation(component.Dependency, utils.Point{X: 205, Y: 10}))
+	ass := diagram.GetDrawData().Associations[0]
+	mid := utils.Point{X: (ass.StartX + ass.EndX) / 2, Y: (ass.StartY + ass.EndY) / 2}
+
+	// clicking the line selects the association instead of panicking
+	assert.NoError(t, diagram.SelectComponent(mid))
+	assert.Len(t, diagram.componentsSelected, 1)
+	assert.True(t, diagram.GetDrawData().Associations[0].IsSelected)
+	assert.Error(t, diagram.SetColorGadget("#000000"))
+
+	// and clicking it again toggles it off
+	assert.NoError(t, diagram.SelectComponent(mid))
+	assert.Len(t, diagram.componentsSelected, 0)
+	assert.False(t, diagram.GetDrawData().Associations[0].IsSelected)
+
+	// unselect all resets the flags too
+	assert.NoError(t, diagram.SelectComponent(mid))
+	assert.NoError(t, diagram.SelectComponent(utils.Point{X: 205, Y: 30}))
+	assert.NoError(t, diagram.UnselectAllComponents())
+	assert.Len(t, diagram.componentsSelected, 0)
+	for _, g := range diagram.GetDrawData().Gadgets {
+		assert.False(t, g.IsSelected)
+	}
+	assert.False(t, diagram.GetDrawData().Associations[0].IsSelected)
+}
+
+func TestUMLDiagram_EditSelectedAssociation(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("EditAssociation.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 200, Y: 0}, 0, drawdata.DefaultGadgetColor, "B"))
+	_ = diagram.StartAddAssociation(utils.Point{X: 5, Y: 10})
+	assert.NoError(t, diagram.EndAddAssociation(component.Dependency, utils.Point{X: 205, Y: 10}))
+
+	// nothing selected, or a gadget selected
+	assert.Error(t, diagram.SetAssTypeAssociation(component.Extension))
+	assert.NoError(t, diagram.SelectComponent(utils.Point{X: 205, Y: 30}))
+	assert.Error(t, diagram.SetLayerAssociation(1))
+	assert.NoError(t, diagram.UnselectAllComponents())
+
+	assert.NoError(t, diagram.SelectComponentByID(diagram.GetDrawData().Associations[0].ID))
+	original := diagram.GetDrawData().Associations[0]
+	assert.NoError(t, diagram.SetAssTypeAssociation(component.Composition))
+	assert.NoError(t, diagram.SetLayerAssociation(3))
+	assert.NoError(t, diagram.SetStartPointAssociation(utils.Point{X: 0, Y: 5}))
+	assert.NoError(t, diagram.SetEndPointAssociation(utils.Point{X: 210, Y: 0}))
+	assert.NoError(t, diagram.AddAttributeToAssociation(0.2, "owner"))
+	assert.NoError(t, diagram.AddAttributeToAssociation(0.8, "0..*"))
+	assert.NoError(t, diagram.MoveAttributeAssociation(1, 0.9))
+	assert.NoError(t, diagram.RemoveAttributeFromAssociation(0))
+	edited := diagram.GetDrawData().Associations[0]
+	assert.Equal(t, int(component.Composition), edited.AssType)
+	assert.Equal(t, 3, edited.Layer)
+	assert.Equal(t, 0, edited.StartX)
+	assert.Equal(t, 0, edited.EndY)
+	assert.Len(t, edited.Attributes, 1)
+	assert.Equal(t, "0..*", edited.Attributes[0].Content)
+	assert.Equal(t, 0.9, edited.Attributes[0].Ratio)
+
+	// invalid edits are refused and not recorded
+	assert.Error(t, diagram.SetAssTypeAssociation(0))
+	assert.Error(t, diagram.SetStartPointAssociation(utils.Point{X: 500, Y: 500}))
+	assert.Error(t, diagram.AddAttributeToAssociation(1.5, "x"))
+	assert.Error(t, diagram.MoveAttributeAssociation(4, 0.5))
+	assert.Error(t, diagram.MoveAttributeAssociation(0, 2))
+	assert.Error(t, diagram.RemoveAttributeFromAssociation(1))
+
+	// every edit undoes exactly
+	assert.NoError(t, diagram.Undo())
+	assert.Len(t, diagram.GetDrawData().Associations[0].Attributes, 2)
+	assert.Equal(t, "owner", diagram.GetDrawData().Associations[0].Attributes[0].Content)
+	for i := 0; i < 7; i++ {
+		assert.NoError(t, diagram.Undo())
+	}
+	assert.Equal(t, original, diagram.GetDrawData().Associations[0])
+}
+
+func TestUMLDiagram_SelectMany(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("SelectMany.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 200, Y: 0}, 0, drawdata.DefaultGadgetColor, "B"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 200, Y: 200}, 0, drawdata.DefaultGadgetColor, "C"))
+	_ = diagram.StartAddAssociation(utils.Point{X: 5, Y: 10})
+	assert.NoError(t, diagram.EndAddAssociation(component.Dependency, utils.Point{X: 205, Y: 10}))
+	_ = diagram.StartAddAssociation(utils.Point{X: 205, Y: 30})
+	assert.NoError(t, diagram.EndAddAssociation(component.Extension, utils.Point{X: 205, Y: 205}))
+	_ = diagram.StartAddAssociation(utils.Point{X: 210, Y: 205})
+	assert.NoError(t, diagram.EndAddAssociation(component.Dependency, utils.Point{X: 10, Y: 30}))
+	countSelected := func() (gadgets int, asses int) {
+		for _, g := range diagram.GetDrawData().Gadgets {
+			if g.IsSelected {
+				gadgets++
+			}
+		}
+		for _, a := range diagram.GetDrawData().Associations {
+			if a.IsSelected {
+				asses++
+			}
+		}
+		assert.Equal(t, gadgets+asses, len(diagram.componentsSelected))
+		return
+	}
+
+	// a rectangle around the top row catches A, B and the line between them, and the lines leaving them
+	assert.NoError(t, diagram.SelectComponentsInRect(utils.Point{X: 250, Y: 60}, utils.Point{X: -1, Y: -1}))
+	gadgets, asses := countSelected()
+	assert.Equal(t, 2, gadgets)
+	assert.Equal(t, 3, asses)
+
+	// a rectangle crossing only the line from A to B
+	assert.NoError(t, diagram.UnselectAllComponents())
+	ab := diagram.GetDrawData().Associations
+	for _, a := range ab {
+		if a.AssType == int(component.Dependency) && a.StartY < 100 && a.EndY < 100 {
+			mid := utils.Point{X: (a.StartX + a.EndX) / 2, Y: (a.StartY + a.EndY) / 2}
+			assert.NoError(t, diagram.SelectComponentsInRect(utils.AddPoints(mid, utils.Point{X: -2, Y: -2}), utils.AddPoints(mid, utils.Point{X: 2, Y: 2})))
+		}
+	}
+	gadgets, asses = countSelected()
+	assert.Equal(t, 0, gadgets)
+	assert.Equal(t, 1, asses)
+
+	// nothing there
+	assert.NoError(t, diagram.UnselectAllComponents())
+	assert.NoError(t, diagram.SelectComponentsInRect(utils.Point{X: 500, Y: 500}, utils.Point{X: 600, Y: 600}))
+	gadgets, asses = countSelected()
+	assert.Equal(t, 0, gadgets+asses)
+
+	// select all and invert
+	assert.NoError(t, diagram.SelectAllComponents())
+	gadgets, asses = countSelected()
+	assert.Equal(t, 3, gadgets)
+	assert.Equal(t, 3, asses)
+	assert.NoError(t, diagram.InvertSelection())
+	gadgets, asses = countSelected()
+	assert.Equal(t, 0, gadgets+asses)
+	assert.NoError(t, diagram.SelectComponent(utils.Point{X: 5, Y: 40}))
+	assert.NoError(t, diagram.InvertSelection())
+	gadgets, asses = countSelected()
+	assert.Equal(t, 2, gadgets)
+	assert.Equal(t, 3, asses)
+
+	// select by type
+	assert.NoError(t, diagram.UnselectAllComponents())
+	assert.NoError(t, diagram.SelectAllGadgets())
+	gadgets, asses = countSelected()
+	assert.Equal(t, 3, gadgets)
+	assert.Equal(t, 0, asses)
+	assert.NoError(t, diagram.UnselectAllComponents())
+	assert.NoError(t, diagram.SelectAssociationsByType(component.Dependency))
+	gadgets, asses = countSelected()
+	assert.Equal(t, 0, gadgets)
+	assert.Equal(t, 2, asses)
+	assert.NoError(t, diagram.SelectAssociationsByType(component.Composition))
+	gadgets, asses = countSelected()
+	assert.Equal(t, 2, gadgets+asses)
+
+	// the selection can be removed at once
+	assert.NoError(t, diagram.SelectAllGadgets())
+	assert.NoError(t, diagram.RemoveSelectedComponents())
+	assert.Len(t, diagram.GetDrawData().Gadgets, 0)
+	assert.Len(t, diagram.GetDrawData().Associations, 0)
+	assert.NoError(t, diagram.Undo())
+	assert.Len(t, diagram.GetDrawData().Gadgets, 3)
+	assert.Len(t, diagram.GetDrawData().Associations, 3)
+}
+
+func TestUMLDiagram_MoveSelectedComponent(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("MoveSelected.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 200, Y: 0}, 0, drawdata.DefaultGadgetColor, "B"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 200, Y: 200}, 0, drawdata.DefaultGadgetColor, "C"))
+	_ = diagram.StartAddAssociation(utils.Point{X: 5, Y: 10})
+	assert.NoError(t, diagram.EndAddAssociation(component.Dependency, utils.Point{X: 205, Y: 10}))
+	_ = diagram.StartAddAssociation(utils.Point{X: 205, Y: 30})
+	assert.NoError(t, diagram.EndAddAssociation(component.Extension, utils.Point{X: 205, Y: 205}))
+	before := diagram.GetDrawData()
+
+	assert.NoError(t, diagram.SelectComponent(utils.Point{X: 5, Y: 5}))
+	assert.NoError(t, diagram.SelectComponent(utils.Point{X: 205, Y: 5}))
+	assert.NoError(t, diagram.MoveSelectedComponent(utils.Point{X: 10, Y: 20}))
+	after := diagram.GetDrawData()
+	byID := func(dd drawdata.Diagram) (map[string]drawdata.Gadget, map[string]drawdata.Association) {
+		gs := make(map[string]drawdata.Gadget)
+		for _, g := range dd.Gadgets {
+			gs[g.Attributes[0][0].Content] = g
+		}
+		as := make(map[string]drawdata.Association)
+		for _, a := range dd.Associations {
+			as[a.ID] = a
+		}
+		return gs, as
+	}
+	gsBefore, asBefore := byID(before)
+	gsAfter, asAfter := byID(after)
+	for _, name := range []string{"A", "B"} {
+		assert.Equal(t, gsBefore[name].X+10, gsAfter[name].X)
+		assert.Equal(t, gsBefore[name].Y+20, gsAfter[name].Y)
+	}
+	assert.Equal(t, gsBefore["C"].X, gsAfter["C"].X)
+	// the line between the moved gadgets moved along, the one to C was re-routed
+	for id, a := range asBefore {
+		assert.Equal(t, a.StartX+10, asAfter[id].StartX)
+		assert.Equal(t, a.StartY+20, asAfter[id].StartY)
+		if a.AssType == int(component.Dependency) {
+			assert.Equal(t, a.EndY+20, asAfter[id].EndY)
+		} else {
+			assert.Equal(t, a.EndY, asAfter[id].EndY)
+		}
+	}
+
+	// one step to undo
+	assert.NoError(t, diagram.Undo())
+	gsUndone, asUndone := byID(diagram.GetDrawData())
+	for name, g := range gsBefore {
+		assert.Equal(t, g.X, gsUndone[name].X)
+		assert.Equal(t, g.Y, gsUndone[name].Y)
+	}
+	for id, a := range asBefore {
+		assert.Equal(t, a.StartY, asUndone[id].StartY)
+	}
+
+	// moving out of the canvas moves nothing
+	assert.Error(t, diagram.MoveSelectedComponent(utils.Point{X: -5, Y: 0}))
+	gsFailed, _ := byID(diagram.GetDrawData())
+	assert.Equal(t, gsBefore["B"].X, gsFailed["B"].X)
+	assert.NoError(t, diagram.Redo())
+	assert.False(t, diagram.CanRedo())
+}
+
+func TestUMLDiagram_Dragging(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("Dragging.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 200, Y: 0}, 0, drawdata.DefaultGadgetColor, "B"))
+	assert.NoError(t, diagram.SelectAllGadgets())
+	points := func() []utils.Point {
+		ps := make([]utils.Point, 0)
+		for _, g := range diagram.GetDrawData().Gadgets {
+			ps = append(ps, utils.Point{X: g.X, Y: g.Y})
+		}
+		return ps
+	}
+
+	assert.Error(t, diagram.StopDragging(utils.Point{X: 0, Y: 0}))
+	assert.Error(t, diagram.StartDragging(utils.Point{X: -1, Y: 0}))
+
+	// live moves during the drag, then the drop
+	assert.NoError(t, diagram.StartDragging(utils.Point{X: 10, Y: 10}))
+	assert.Error(t, diagram.StartDragging(utils.Point{X: 10, Y: 10}))
+	assert.NoError(t, diagram.MoveSelectedComponent(utils.Point{X: 5, Y: 0}))
+	assert.NoError(t, diagram.MoveSelectedComponent(utils.Point{X: 5, Y: 5}))
+	assert.ElementsMatch(t, []utils.Point{{X: 10, Y: 5}, {X: 210, Y: 5}}, points())
+	assert.Error(t, diagram.Undo())
+	assert.NoError(t, diagram.StopDragging(utils.Point{X: 40, Y: 50}))
+	assert.ElementsMatch(t, []utils.Point{{X: 30, Y: 40}, {X: 230, Y: 40}}, points())
+
+	// the whole drag is one step
+	assert.NoError(t, diagram.Undo())
+	assert.ElementsMatch(t, []utils.Point{{X: 0, Y: 0}, {X: 200, Y: 0}}, points())
+	assert.NoError(t, diagram.Redo())
+	assert.ElementsMatch(t, []utils.Point{{X: 30, Y: 40}, {X: 230, Y: 40}}, points())
+
+	// a drag without moving leaves no trace
+	assert.NoError(t, diagram.StartDragging(utils.Point{X: 40, Y: 40}))
+	assert.NoError(t, diagram.StopDragging(utils.Point{X: 40, Y: 40}))
+	assert.NoError(t, diagram.Undo())
+	assert.ElementsMatch(t, []utils.Point{{X: 0, Y: 0}, {X: 200, Y: 0}}, points())
+	assert.NoError(t, diagram.Redo())
+
+	// a drop out of the canvas reverts the whole drag
+	assert.NoError(t, diagram.StartDragging(utils.Point{X: 40, Y: 40}))
+	assert.NoError(t, diagram.MoveSelectedComponent(utils.Point{X: 5, Y: 5}))
+	assert.Error(t, diagram.StopDragging(utils.Point{X: 0, Y: 0}))
+	assert.ElementsMatch(t, []utils.Point{{X: 30, Y: 40}, {X: 230, Y: 40}}, points())
+	assert.NoError(t, diagram.Undo())
+	assert.ElementsMatch(t, []utils.Point{{X: 0, Y: 0}, {X: 200, Y: 0}}, points())
+}
+
+func TestUMLDiagram_ContainerGrid(t *testing.T) {
+	_, err := CreateEmptyUMLDiagramWithContainer("Grid.uml", ClassDiagram, nil)
+	assert.Error(t, err)
+	used := components.NewContainerGrid()
+	g, _ := component.NewGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A")
+	assert.NoError(t, used.Insert(g))
+	_, err = CreateEmptyUMLDiagramWithContainer("Grid.uml", ClassDiagram, used)
+	assert.Error(t, err)
+
+	diagram, err := CreateEmptyUMLDiagramWithContainer("Grid.uml", ClassDiagram, components.NewContainerGrid())
+	assert.NoError(t, err)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 2000, Y: 0}, 0, drawdata.DefaultGadgetColor, "B"))
+	_ = diagram.StartAddAssociation(utils.Point{X: 5, Y: 10})
+	assert.NoError(t, diagram.EndAddAssociation(component.Dependency, utils.Point{X: 2005, Y: 10}))
+
+	// the grid follows a moved gadget and the association attached to it
+	assert.NoError(t, diagram.SelectComponent(utils.Point{X: 2005, Y: 5}))
+	assert.NoError(t, diagram.MoveSelectedComponent(utils.Point{X: -1800, Y: 600}))
+	assert.NoError(t, diagram.UnselectAllComponents())
+	c, err := diagram.componentsContainer.Search(utils.Point{X: 2005, Y: 5})
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+	b, err := diagram.componentsContainer.SearchGadget(utils.Point{X: 205, Y: 605})
+	assert.NoError(t, err)
+	assert.NotNil(t, b)
+	ass := diagram.GetDrawData().Associations[0]
+	c, err = diagram.componentsContainer.Search(utils.Point{X: (ass.StartX + ass.EndX) / 2, Y: (ass.StartY + ass.EndY) / 2})
+	assert.NoError(t, err)
+	assert.IsType(t, &component.Association{}, c)
+
+	// and an undone move
+	assert.NoError(t, diagram.Undo())
+	c, err = diagram.componentsContainer.Search(utils.Point{X: 2005, Y: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, b, c)
+	// B and the end of the line to it
+	assert.NoError(t, diagram.SelectComponentsInRect(utils.Point{X: 1900, Y: 0}, utils.Point{X: 2100, Y: 50}))
+	assert.Len(t, diagram.componentsSelected, 2)
+
+	// loaded into the container given, or into a grid once the diagram is large
+	fd, err := diagram.GetFileData()
+	assert.NoError(t, err)
+	loaded, err := LoadUMLDiagramFromFileDataWithContainer(fd, components.NewContainerGrid())
+	assert.NoError(t, err)
+	assert.IsType(t, components.NewContainerGrid(), loaded.componentsContainer)
+	_, err = LoadUMLDiagramFromFileDataWithContainer(fd, nil)
+	assert.Error(t, err)
+	loaded, err = LoadUMLDiagramFromFileData(fd)
+	assert.NoError(t, err)
+	assert.IsType(t, components.NewContainerMap(), loaded.componentsContainer)
+	for i := 0; len(fd.Gadgets) < gridDiagramSize; i++ {
+		g := fd.Gadgets[0]
+		g.ID, g.Order, g.X, g.Y = "", 0, i*10, 1000
+		fd.Gadgets = append(fd.Gadgets, g)
+	}
+	loaded, err = LoadUMLDiagramFromFileData(fd)
+	assert.NoError(t, err)
+	assert.IsType(t, components.NewContainerGrid(), loaded.componentsContainer)
+	assert.Len(t, loaded.GetDrawData().Gadgets, gridDiagramSize)
+}
+
+func TestUMLDiagram_MoveRedrawsOnce(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("Redraw.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 200, Y: 0}, 0, drawdata.DefaultGadgetColor, "B"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 400, Y: 0}, 0, drawdata.DefaultGadgetColor, "C"))
+	_ = diagram.StartAddAssociation(utils.Point{X: 5, Y: 5})
+	assert.NoError(t, diagram.EndAddAssociation(component.Dependency, utils.Point{X: 205, Y: 5}))
+	_ = diagram.StartAddAssociation(utils.Point{X: 205, Y: 5})
+	assert.NoError(t, diagram.EndAddAssociation(component.Dependency, utils.Point{X: 405, Y: 5}))
+	assert.NoError(t, diagram.SelectComponentByID(gadgetIDByHeader(diagram, "B")))
+	redraws := 0
+	assert.NoError(t, diagram.RegisterUpdateParentDraw(func() duerror.DUError {
+		redraws++
+		return nil
+	}))
+
+	// the gadget and both of its associations are drawn anew in one go
+	assert.NoError(t, diagram.MoveSelectedComponent(utils.Point{X: 0, Y: 100}))
+	assert.Equal(t, 1, redraws)
+	assert.Equal(t, 100, diagram.GetDrawData().Associations[0].EndY-diagram.GetDrawData().Associations[0].StartY)
+}
+
+func TestUMLDiagram_GrowsIntoContainerGrid(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("Grow.uml", ClassDiagram)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: i * 50, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	}
+	assert.NoError(t, diagram.SelectComponentsInRect(utils.Point{X: -10, Y: -10}, utils.Point{X: 2000, Y: 500}))
+	copied, err := diagram.CopySelectedComponents()
+	assert.NoError(t, err)
+
+	// pasting past gridDiagramSize moves every component into a grid, in the same stacking order
+	for i := 1; i < 9; i++ {
+		assert.NoError(t, diagram.PasteComponents(copied, utils.Point{X: 0, Y: i * 200}))
+	}
+	assert.IsType(t, components.NewContainerMap(), diagram.componentsContainer)
+	before := diagram.componentsContainer.GetAll()
+	assert.NoError(t, diagram.PasteComponents(copied, utils.Point{X: 0, Y: 1800}))
+	assert.IsType(t, components.NewContainerGrid(), diagram.componentsContainer)
+	assert.Equal(t, before, diagram.componentsContainer.GetAll()[:len(before)])
+	c, err := diagram.componentsContainer.SearchGadget(utils.Point{X: 5, Y: 1805})
+	assert.NoError(t, err)
+	assert.NotNil(t, c)
+
+	// a container given is kept however large the diagram grows
+	given, _ := CreateEmptyUMLDiagramWithContainer("Given.uml", ClassDiagram, components.NewContainerMap())
+	for i := 0; i < gridDiagramSize; i++ {
+		assert.NoError(t, given.AddGadget(component.Class, utils.Point{X: i * 50, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	}
+	assert.IsType(t, components.NewContainerMap(), given.componentsContainer)
+}
+
+func TestUMLDiagram_Navigability(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("Navigability.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 200, Y: 0}, 0, drawdata.DefaultGadgetColor, "B"))
+	_ = diagram.StartAddAssociation(utils.Point{X: 5, Y: 10})
+	assert.NoError(t, diagram.EndAddAssociation(component.Aggregation, utils.Point{X: 205, Y: 10}))
+	assert.Error(t, diagram.SetStartNavigabilityAssociation(component.Navigable))
+
+	assert.NoError(t, diagram.SelectComponentByID(diagram.GetDrawData().Associations[0].ID))
+	assert.NoError(t, diagram.SetStartNavigabilityAssociation(component.NonNavigable))
+	assert.Equal(t, drawdata.ArrowheadCross, diagram.GetDrawData().Associations[0].StartHead)
+	assert.Equal(t, drawdata.ArrowheadHollowDiamond, diagram.GetDrawData().Associations[0].EndHead)
+
+	// the diamond end shows no navigability
+	assert.Error(t, diagram.SetEndNavigabilityAssociation(component.Navigable))
+	assert.NoError(t, diagram.SetAssTypeAssociation(component.PlainAssociation))
+	assert.NoError(t, diagram.SetEndNavigabilityAssociation(component.Navigable))
+	assert.Equal(t, drawdata.ArrowheadOpen, diagram.GetDrawData().Associations[0].EndHead)
+
+	// saved and loaded back
+	fd, err := diagram.GetFileData()
+	assert.NoError(t, err)
+	loaded, err := LoadUMLDiagramFromFileData(fd)
+	assert.NoError(t, err)
+	assert.Equal(t, diagram.GetDrawData().Associations[0].StartHead, loaded.GetDrawData().Associations[0].StartHead)
+	assert.Equal(t, diagram.GetDrawData().Associations[0].EndHead, loaded.GetDrawData().Associations[0].EndHead)
+
+	// a type without navigability clears it, so the diagram still loads, and undo brings it back
+	assert.NoError(t, diagram.SetAssTypeAssociation(component.Extension))
+	fd, err = diagram.GetFileData()
+	assert.NoError(t, err)
+	assert.Equal(t, [2]int{0, 0}, fd.Associations[0].Navigability)
+	_, err = LoadUMLDiagramFromFileData(fd)
+	assert.NoError(t, err)
+	assert.NoError(t, diagram.Undo())
+	assert.Equal(t, drawdata.ArrowheadCross, diagram.GetDrawData().Associations[0].StartHead)
+	assert.Equal(t, drawdata.ArrowheadOpen, diagram.GetDrawData().Associations[0].EndHead)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, diagram.Undo())
+	}
+	assert.Equal(t, drawdata.ArrowheadNone, diagram.GetDrawData().Associations[0].StartHead)
+	assert.Equal(t, drawdata.ArrowheadHollowDiamond, diagram.GetDrawData().Associations[0].EndHead)
+}
+
+func TestUMLDiagram_AssociationClass(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("AssociationClass.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 300, Y: 0}, 0, drawdata.DefaultGadgetColor, "B"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 100, Y: 200}, 0, drawdata.DefaultGadgetColor, "C"))
+	_ = diagram.StartAddAssociation(utils.Point{X: 5, Y: 10})
+	assert.NoError(t, diagram.EndAddAssociation(component.AssociationClass, utils.Point{X: 305, Y: 10}))
+	classID := gadgetIDByHeader(diagram, "C")
+
+	assert.NoError(t, diagram.SelectComponentByID(diagram.GetDrawData().Associations[0].ID))
+	assert.Error(t, diagram.SetClassAssociation("missing"))
+	assert.Error(t, diagram.SetClassAssociation(gadgetIDByHeader(diagram, "A")))
+	assert.NoError(t, diagram.SetClassAssociation(classID))
+	link := diagram.GetDrawData().Associations[0].ClassLink
+	assert.NotNil(t, link)
+	assert.Equal(t, 200, link.EndY)
+
+	// the link follows the class
+	assert.NoError(t, diagram.SetPointGadgetByID(classID, utils.Point{X: 100, Y: 300}))
+	assert.Equal(t, 300, diagram.GetDrawData().Associations[0].ClassLink.EndY)
+
+	// saved and loaded back
+	fd, err := diagram.GetFileData()
+	assert.NoError(t, err)
+	loaded, err := LoadUMLDiagramFromFileData(fd)
+	assert.NoError(t, err)
+	assert.Equal(t, *diagram.GetDrawData().Associations[0].ClassLink, *loaded.GetDrawData().Associations[0].ClassLink)
+	fd.Associations[0].Class = new(int)
+	*fd.Associations[0].Class = 3
+	_, err = LoadUMLDiagramFromFileData(fd)
+	assert.Error(t, err)
+
+	// copied with the class, the copy links the copied class
+	assert.NoError(t, diagram.SelectAllGadgets())
+	components, err := diagram.CopySelectedComponents()
+	assert.NoError(t, err)
+	assert.NotNil(t, components.Associations[0].Class)
+	assert.NoError(t, diagram.PasteComponents(components, utils.Point{X: 0, Y: 500}))
+	for c := range diagram.componentsSelected {
+		if a, ok := c.(*component.Association); ok {
+			assert.Equal(t, a.GetAssociationClass().GetPoint().Y, 800)
+			assert.Contains(t, diagram.associationClasses[a.GetAssociationClass()], a)
+		}
+	}
+	assert.NoError(t, diagram.Undo())
+	assert.NoError(t, diagram.UnselectAllComponents())
+
+	// removing the class unlinks the association, and undoing it links it again
+	assert.NoError(t, diagram.RemoveComponentByID(classID))
+	assert.Len(t, diagram.GetDrawData().Associations, 1)
+	assert.Nil(t, diagram.GetDrawData().Associations[0].ClassLink)
+	assert.Len(t, diagram.associationClasses, 0)
+	assert.NoError(t, diagram.Undo())
+	assert.NotNil(t, diagram.GetDrawData().Associations[0].ClassLink)
+
+	// unlinking undoes too
+	assert.NoError(t, diagram.SelectComponentByID(diagram.GetDrawData().Associations[0].ID))
+	assert.NoError(t, diagram.SetClassAssociation(""))
+	assert.Nil(t, diagram.GetDrawData().Associations[0].ClassLink)
+	assert.NoError(t, diagram.Undo())
+	assert.NotNil(t, diagram.GetDrawData().Associations[0].ClassLink)
+}
+
+func TestUMLDiagram_Members(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("Members.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.AddGadget(component.Note, utils.Point{X: 300, Y: 0}, 0, drawdata.DefaultGadgetColor, "note"))
+	id := gadgetIDByHeader(diagram, "A")
+	_, err := diagram.GetMembersGadget(1)
+	assert.Error(t, err)
+
+	assert.NoError(t, diagram.SelectComponentByID(id))
+	assert.NoError(t, diagram.AddAttributeToGadget(1, "+count:int=0"))
+	assert.NoError(t, diagram.AddAttributeToGadget(1, "- name: string"))
+	assert.NoError(t, diagram.AddAttributeToGadget(1, "broken ("))
+	assert.NoError(t, diagram.AddAttributeToGadget(2, "{static}run( a:int )"))
+	members, err := diagram.GetMembersGadget(2)
+	assert.NoError(t, err)
+	assert.True(t, members.Members[0].Static)
+	members, err = diagram.GetMembersGadgetByID(id, 1)
+	assert.NoError(t, err)
+	assert.Len(t, members.Errors, 1)
+	assert.Equal(t, 2, members.Errors[0].Line)
+	_, err = diagram.GetMembersGadgetByID(gadgetIDByHeader(diagram, "note"), 0)
+	assert.Error(t, err)
+
+	// rewritten canonically as one step, the broken line is left alone
+	assert.NoError(t, diagram.FormatMembersGadget(1))
+	assert.NoError(t, diagram.FormatMembersGadget(2))
+	contents := func(section int) []string {
+		var list []string
+		for _, att := range diagram.GetDrawData().Gadgets[0].Attributes[section] {
+			list = append(list, att.Content)
+		}
+		return list
+	}
+	assert.Equal(t, []string{"+ count: int = 0", "- name: string", "broken ("}, contents(1))
+	assert.Equal(t, []string{"{static} run(a: int)"}, contents(2))
+	assert.NoError(t, diagram.Undo())
+	assert.NoError(t, diagram.Undo())
+	assert.Equal(t, []string{"+count:int=0", "- name: string", "broken ("}, contents(1))
+	assert.Error(t, diagram.FormatMembersGadget(0))
+}
+
+func TestUMLDiagram_ReconnectAssociation(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("Reconnect.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 300, Y: 0}, 0, drawdata.DefaultGadgetColor, "B"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 300, Y: 300}, 0, drawdata.DefaultGadgetColor, "C"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 300}, 0, drawdata.DefaultGadgetColor, "D"))
+	_ = diagram.StartAddAssociation(utils.Point{X: 5, Y: 10})
+	assert.NoError(t, diagram.EndAddAssociation(component.AssociationClass, utils.Point{X: 305, Y: 10}))
+	a, _ := diagram.getGadgetByID(gadgetIDByHeader(diagram, "A"))
+	b, _ := diagram.getGadgetByID(gadgetIDByHeader(diagram, "B"))
+	c, _ := diagram.getGadgetByID(gadgetIDByHeader(diagram, "C"))
+	assert.Error(t, diagram.ReconnectEndAssociation(utils.Point{X: 305, Y: 305}))
+
+	assert.NoError(t, diagram.SelectComponentByID(diagram.GetDrawData().Associations[0].ID))
+	ass, _ := diagram.getSelectedAssociation()
+	assert.NoError(t, diagram.SetClassAssociation(gadgetIDByHeader(diagram, "D")))
+	assert.Error(t, diagram.ReconnectEndAssociation(utils.Point{X: 1000, Y: 1000}))
+	assert.Error(t, diagram.ReconnectEndAssociation(utils.Point{X: -1, Y: 10}))
+	// the association class cannot be an end
+	assert.Error(t, diagram.ReconnectEndAssociation(utils.Point{X: 5, Y: 305}))
+	// onto the point the start is drawn at, it cannot be drawn
+	assert.Error(t, diagram.ReconnectEndAssociation(utils.Point{X: 5, Y: 0}))
+	assert.Equal(t, b, ass.GetParentEnd())
+	assert.Empty(t, diagram.associations[a][1])
+	assert.Equal(t, []*component.Association{ass}, diagram.associations[b][1])
+
+	ratio := ass.GetEndRatio()
+	assert.NoError(t, diagram.ReconnectEndAssociation(utils.Point{X: 305, Y: 305}))
+	assert.Equal(t, c, ass.GetParentEnd())
+	assert.Empty(t, diagram.associations[b][1])
+	assert.Equal(t, []*component.Association{ass}, diagram.associations[c][1])
+	assert.Equal(t, 300, diagram.GetDrawData().Associations[0].EndY)
+
+	// the association goes with its new end
+	assert.NoError(t, diagram.RemoveComponentByID(gadgetIDByHeader(diagram, "C")))
+	assert.Len(t, diagram.GetDrawData().Associations, 0)
+	assert.NoError(t, diagram.Undo())
+	assert.Len(t, diagram.GetDrawData().Associations, 1)
+
+	assert.NoError(t, diagram.Undo())
+	assert.Equal(t, b, ass.GetParentEnd())
+	assert.Equal(t, ratio, ass.GetEndRatio())
+	assert.Empty(t, diagram.associations[c][1])
+	assert.Equal(t, []*component.Association{ass}, diagram.associations[b][1])
+	assert.NoError(t, diagram.Redo())
+	assert.Equal(t, c, ass.GetParentEnd())
+
+	// onto the other end makes a self association
+	assert.NoError(t, diagram.SelectComponentByID(diagram.GetDrawData().Associations[0].ID))
+	assert.NoError(t, diagram.ReconnectStartAssociation(utils.Point{X: 310, Y: 310}))
+	assert.Equal(t, c, ass.GetParentStart())
+	assert.Empty(t, diagram.associations[a][0])
+	assert.Equal(t, []*component.Association{ass}, diagram.getAttachedAssociations(c))
+	assert.NoError(t, diagram.RemoveComponentByID(gadgetIDByHeader(diagram, "A")))
+	assert.Len(t, diagram.GetDrawData().Associations, 1)
+
+	// saved with its new ends
+	fd, err := diagram.GetFileData()
+	assert.NoError(t, err)
+	loaded, err := LoadUMLDiagramFromFileData(fd)
+	assert.NoError(t, err)
+	assert.Equal(t, diagram.GetDrawData().Associations[0].StartX, loaded.GetDrawData().Associations[0].StartX)
+}
+
+func TestUMLDiagram_AssociationLabels(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("AssociationLabels.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 300, Y: 0}, 0, drawdata.DefaultGadgetColor, "B"))
+	_ = diagram.StartAddAssociation(utils.Point{X: 5, Y: 10})
+	assert.NoError(t, diagram.EndAddAssociation(component.PlainAssociation, utils.Point{X: 305, Y: 10}))
+	assert.Error(t, diagram.SetLabelAssociation(component.EndMultiplicity, "1"))
+
+	assert.NoError(t, diagram.SelectComponentByID(diagram.GetDrawData().Associations[0].ID))
+	assert.NoError(t, diagram.SetLabelAssociation(component.EndMultiplicity, "0..*"))
+	assert.NoError(t, diagram.SetLabelAssociation(component.MiddleName, "owns"))
+	assert.NoError(t, diagram.SetReadingDirectionAssociation(component.ReadingTowardStart))
+	assert.Error(t, diagram.SetLabelAssociation(component.StartMultiplicity, "1...2"))
+	assert.Error(t, diagram.SetReadingDirectionAssociation(component.ReadingDirection(-1)))
+	labels := diagram.GetDrawData().Associations[0].Labels
+	assert.Len(t, labels, 2)
+	assert.Equal(t, int(component.ReadingTowardStart), labels[1].ReadingDirection)
+
+	// the labels follow a moved gadget
+	assert.NoError(t, diagram.SetPointGadgetByID(gadgetIDByHeader(diagram, "B"), utils.Point{X: 300, Y: 200}))
+	moved := diagram.GetDrawData().Associations[0].Labels
+	assert.Greater(t, moved[0].Y, labels[0].Y+100)
+
+	// saved and loaded back
+	fd, err := diagram.GetFileData()
+	assert.NoError(t, err)
+	loaded, err := LoadUMLDiagramFromFileData(fd)
+	assert.NoError(t, err)
+	assert.Equal(t, moved, loaded.GetDrawData().Associations[0].Labels)
+
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, diagram.Undo())
+	}
+	assert.Len(t, diagram.GetDrawData().Associations[0].Labels, 0)
+}
+
+func TestUMLDiagram_Waypoints(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("Waypoints.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 200}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 300, Y: 200}, 0, drawdata.DefaultGadgetColor, "B"))
+	_ = diagram.StartAddAssociation(utils.Point{X: 5, Y: 210})
+	assert.NoError(t, diagram.EndAddAssociation(component.PlainAssociation, utils.Point{X: 305, Y: 210}))
+	assert.Error(t, diagram.AddWaypointAssociation(0, utils.Point{X: 5, Y: 100}))
+
+	assert.NoError(t, diagram.SelectComponentByID(diagram.GetDrawData().Associations[0].ID))
+	assert.NoError(t, diagram.AddWaypointAssociation(0, utils.Point{X: 5, Y: 100}))
+	assert.NoError(t, diagram.AddWaypointAssociation(1, utils.Point{X: 305, Y: 100}))
+	assert.NoError(t, diagram.AddWaypointAssociation(1, utils.Point{X: 150, Y: 50}))
+	assert.NoError(t, diagram.MoveWaypointAssociation(1, utils.Point{X: 150, Y: 80}))
+	assert.NoError(t, diagram.RemoveWaypointAssociation(1))
+	assert.Error(t, diagram.AddWaypointAssociation(3, utils.Point{X: 5, Y: 100}))
+	assert.Error(t, diagram.AddWaypointAssociation(0, utils.Point{X: -5, Y: 100}))
+	assert.Error(t, diagram.MoveWaypointAssociation(2, utils.Point{X: 5, Y: 100}))
+	assert.Error(t, diagram.RemoveWaypointAssociation(-1))
+	path := diagram.GetDrawData().Associations[0].Path
+	assert.Len(t, path, 4)
+	assert.Equal(t, drawdata.PathPoint{X: 305, Y: 100}, path[2])
+
+	// the association is picked along its bends
+	assert.NoError(t, diagram.UnselectAllComponents())
+	assert.NoError(t, diagram.SelectComponent(utils.Point{X: 150, Y: 101}))
+	assert.Len(t, diagram.componentsSelected, 1)
+
+	// dragging both ends drags the waypoints, dragging one leaves them
+	assert.NoError(t, diagram.UnselectAllComponents())
+	assert.NoError(t, diagram.SelectComponentByID(gadgetIDByHeader(diagram, "A")))
+	assert.NoError(t, diagram.MoveSelectedComponent(utils.Point{X: 0, Y: 10}))
+	assert.Equal(t, 100, diagram.GetDrawData().Associations[0].Path[1].Y)
+	assert.NoError(t, diagram.SelectComponentByID(gadgetIDByHeader(diagram, "B")))
+	assert.NoError(t, diagram.MoveSelectedComponent(utils.Point{X: 10, Y: 10}))
+	assert.Equal(t, drawdata.PathPoint{X: 15, Y: 110}, diagram.GetDrawData().Associations[0].Path[1])
+	assert.Error(t, diagram.MoveSelectedComponent(utils.Point{X: 0, Y: -150}))
+
+	// copies keep their bends, moved with them
+	components, err := diagram.CopySelectedComponents()
+	assert.NoError(t, err)
+	assert.NoError(t, diagram.PasteComponents(components, utils.Point{X: 10, Y: 500}))
+	for c := range diagram.componentsSelected {
+		if a, ok := c.(*component.Association); ok {
+			assert.Equal(t, []utils.Point{{X: 15, Y: 400}, {X: 315, Y: 400}}, a.GetWaypoints())
+		}
+	}
+
+	for i := 0; i < 8; i++ {
+		assert.NoError(t, diagram.Undo())
+	}
+	assert.Len(t, diagram.GetDrawData().Associations, 1)
+	assert.Len(t, diagram.GetDrawData().Associations[0].Path, 2)
+}
+
+func TestUMLDiagram_OrthogonalRouting(t *testing.T) {
+	for _, container := range []components.Container{components.NewContainerMap(), components.NewContainerGrid()} {
+		diagram, _ := CreateEmptyUMLDiagramWithContainer("Orthogonal.uml", ClassDiagram, container)
+		assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 200}, 0, drawdata.DefaultGadgetColor, "A"))
+		assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 400, Y: 200}, 0, drawdata.DefaultGadgetColor, "B"))
+		// from the right side of A to the left side of B
+		_ = diagram.StartAddAssociation(utils.Point{X: 21, Y: 220})
+		assert.NoError(t, diagram.EndAddAssociation(component.PlainAssociation, utils.Point{X: 401, Y: 220}))
+		assert.NoError(t, diagram.SelectComponentByID(diagram.GetDrawData().Associations[0].ID))
+		assert.NoError(t, diagram.SetRoutingAssociation(component.RoutingOrthogonal))
+		assert.Error(t, diagram.SetRoutingAssociation(component.Routing(-1)))
+		straight := diagram.GetDrawData().Associations[0].Path
+		assert.Len(t, straight, 2)
+
+		// a gadget dropped in the way is routed around
+		assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 200, Y: 190}, 0, drawdata.DefaultGadgetColor, "C"))
+		c := gadgetsByHeader(diagram, "C")[0]
+		wall := utils.NewRect(utils.Point{X: c.X + 1, Y: c.Y + 1}, utils.Point{X: c.X + c.Width - 1, Y: c.Y + c.Height - 1})
+		path := diagram.GetDrawData().Associations[0].Path
+		assert.Greater(t, len(path), len(straight))
+		for i := 1; i < len(path); i++ {
+			assert.False(t, wall.IntersectsSegment(utils.Point{X: path[i-1].X, Y: path[i-1].Y}, utils.Point{X: path[i].X, Y: path[i].Y}))
+		}
+
+		// and saved that way
+		fd, err := diagram.GetFileData()
+		assert.NoError(t, err)
+		loaded, err := LoadUMLDiagramFromFileData(fd)
+		assert.NoError(t, err)
+		assert.Equal(t, path, loaded.GetDrawData().Associations[0].Path)
+
+		// moved out of the way, the line straightens again
+		assert.NoError(t, diagram.SetPointGadgetByID(c.ID, utils.Point{X: 200, Y: 500}))
+		assert.Equal(t, straight, diagram.GetDrawData().Associations[0].Path)
+		assert.NoError(t, diagram.Undo())
+		assert.Equal(t, path, diagram.GetDrawData().Associations[0].Path)
+
+		// and so it does when it goes away
+		assert.NoError(t, diagram.RemoveComponentByID(c.ID))
+		assert.Equal(t, straight, diagram.GetDrawData().Associations[0].Path)
+	}
+}
+
+// newBenchmarkDiagram lays out n components as components.fillBenchmarkContainer does:
+// four fifths are gadgets on a loose lattice, the rest associate neighbours
+func newBenchmarkDiagram(b *testing.B, container components.Container, n int) *UMLDiagram {
+	template, _ := CreateEmptyUMLDiagram("Benchmark.uml", ClassDiagram)
+	_ = template.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A")
+	_ = template.AddGadget(component.Class, utils.Point{X: 200, Y: 0}, 0, drawdata.DefaultGadgetColor, "B")
+	_ = template.StartAddAssociation(utils.Point{X: 5, Y: 5})
+	_ = template.EndAddAssociation(component.Dependency, utils.Point{X: 205, Y: 5})
+	fd, err := template.GetFileData()
+	if err != nil {
+		b.Fatal(err)
+	}
+	gFd, aFd := fd.Gadgets[0], fd.Associations[0]
+	fd.Gadgets, fd.Associations = nil, nil
+
+	r := rand.New(rand.NewSource(1))
+	gadgetCount := n * 4 / 5
+	side := int(math.Ceil(math.Sqrt(float64(gadgetCount))))
+	for i := 0; i < gadgetCount; i++ {
+		gFd.ID, gFd.Order = "", 0
+		gFd.X, gFd.Y = (i%side)*200+r.Intn(50), (i/side)*150+r.Intn(50)
+		fd.Gadgets = append(fd.Gadgets, gFd)
+	}
+	for i := 0; i < n-gadgetCount; i++ {
+		aFd.ID, aFd.Order, aFd.Parents = "", 0, [2]int{i, i + 1}
+		fd.Associations = append(fd.Associations, aFd)
+	}
+	ud, err := LoadUMLDiagramFromFileDataWithContainer(fd, container)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return ud
+}
+
+// moving a gadget with two associations attached, as dragging it does
+func BenchmarkUMLDiagram_MoveGadget(b *testing.B) {
+	containers := []struct {
+		name string
+		new  func() components.Container
+	}{
+		{"Map", components.NewContainerMap},
+		{"Grid", components.NewContainerGrid},
+	}
+	for _, bc := range containers {
+		for _, n := range []int{1000, 10000} {
+			b.Run(fmt.Sprintf("%s/%d", bc.name, n), func(b *testing.B) {
+				ud := newBenchmarkDiagram(b, bc.new(), n)
+				if err := ud.SelectComponentByID(ud.GetDrawData().Gadgets[1].ID); err != nil {
+					b.Fatal(err)
+				}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					delta := utils.Point{X: 10, Y: 10}
+					if i%2 == 1 {
+						delta = utils.Point{X: -10, Y: -10}
+					}
+					if err := ud.MoveSelectedComponent(delta); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
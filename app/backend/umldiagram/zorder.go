@@ -0,0 +1,183 @@
+package umldiagram
+
+import (
+	"slices"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/utils/duerror"
+)
+
+// BringSelectedToFront raises the selection above every other component, keeping its own stacking order
+func (ud *UMLDiagram) BringSelectedToFront() duerror.DUError {
+	selected, others, err := ud.splitBySelection()
+	if err != nil {
+		return err
+	}
+	if len(others) == 0 {
+		return nil
+	}
+	bottom, _ := getLayerRange(selected)
+	_, top := getLayerRange(others)
+	if bottom > top {
+		return nil
+	}
+	return ud.shiftLayers(selected, top+1-bottom)
+}
+
+// SendSelectedToBack lowers the selection below every other component, keeping its own stacking order
+func (ud *UMLDiagram) SendSelectedToBack() duerror.DUError {
+	selected, others, err := ud.splitBySelection()
+	if err != nil {
+		return err
+	}
+	if len(others) == 0 {
+		return nil
+	}
+	_, top := getLayerRange(selected)
+	bottom, _ := getLayerRange(others)
+	if top < bottom {
+		return nil
+	}
+	return ud.shiftLayers(selected, bottom-1-top)
+}
+
+// BringSelectedForward raises the selection past the nearest unselected component overlapping it from above
+func (ud *UMLDiagram) BringSelectedForward() duerror.DUError {
+	if _, _, err := ud.splitBySelection(); err != nil {
+		return err
+	}
+	all := ud.componentsContainer.GetAll()
+	start := slices.IndexFunc(all, func(c component.Component) bool { return ud.componentsSelected[c] })
+	for j := start + 1; j < len(all); j++ {
+		if ud.componentsSelected[all[j]] || !ud.overlapsSelected(all[j], all[start:j]) {
+			continue
+		}
+		// the unselected ones keep their order below the selected ones
+		rng := all[start : j+1]
+		order := make([]component.Component, 0, len(rng))
+		for _, c := range rng {
+			if !ud.componentsSelected[c] {
+				order = append(order, c)
+			}
+		}
+		for _, c := range rng {
+			if ud.componentsSelected[c] {
+				order = append(order, c)
+			}
+		}
+		return ud.reorderRange(rng, order)
+	}
+	return nil
+}
+
+// SendSelectedBackward lowers the selection past the nearest unselected component overlapping it from below
+func (ud *UMLDiagram) SendSelectedBackward() duerror.DUError {
+	if _, _, err := ud.splitBySelection(); err != nil {
+		return err
+	}
+	all := ud.componentsContainer.GetAll()
+	end := len(all) - 1
+	for !ud.componentsSelected[all[end]] {
+		end--
+	}
+	for j := end - 1; j >= 0; j-- {
+		if ud.componentsSelected[all[j]] || !ud.overlapsSelected(all[j], all[j+1:end+1]) {
+			continue
+		}
+		// the unselected ones keep their order above the selected ones
+		rng := all[j : end+1]
+		order := make([]component.Component, 0, len(rng))
+		for _, c := range rng {
+			if ud.componentsSelected[c] {
+				order = append(order, c)
+			}
+		}
+		for _, c := range rng {
+			if !ud.componentsSelected[c] {
+				order = append(order, c)
+			}
+		}
+		return ud.reorderRange(rng, order)
+	}
+	return nil
+}
+
+// overlapsSelected tells whether c overlaps any selected component in cs
+func (ud *UMLDiagram) overlapsSelected(c component.Component, cs []component.Component) bool {
+	bounds := c.GetBounds()
+	for _, s := range cs {
+		if ud.componentsSelected[s] && s.GetBounds().Intersects(bounds) {
+			return true
+		}
+	}
+	return false
+}
+
+// reorderRange restacks rng, which is in stacking order, as order by handing out the layers and
+// sequences of rng from the bottom up, as one undoable step
+func (ud *UMLDiagram) reorderRange(rng []component.Component, order []component.Component) duerror.DUError {
+	layers := make([]int, len(rng))
+	seqs := make([]int, len(rng))
+	for i, c := range rng {
+		layers[i] = c.GetLayer()
+		seqs[i] = ud.componentsContainer.GetSequence(c)
+	}
+	place := func(cs []component.Component) duerror.DUError {
+		for i, c := range cs {
+			if err := c.SetLayer(layers[i]); err != nil {
+				return err
+			}
+			if err := ud.componentsContainer.InsertWithSequence(c, seqs[i]); err != nil {
+				return err
+			}
+		}
+		return ud.updateDrawData()
+	}
+	return ud.executeFunc(
+		func() duerror.DUError { return place(order) },
+		func() duerror.DUError { return place(rng) },
+	)
+}
+
+// splitBySelection returns the selected and the unselected components, it fails when nothing is selected
+func (ud *UMLDiagram) splitBySelection() ([]component.Component, []component.Component, duerror.DUError) {
+	selected := make([]component.Component, 0, len(ud.componentsSelected))
+	others := make([]component.Component, 0)
+	for _, c := range ud.componentsContainer.GetAll() {
+		if ud.componentsSelected[c] {
+			selected = append(selected, c)
+		} else {
+			others = append(others, c)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, nil, duerror.NewInvalidArgumentError("no component selected")
+	}
+	return selected, others, nil
+}
+
+// shiftLayers moves cs by delta layers as one undoable step
+func (ud *UMLDiagram) shiftLayers(cs []component.Component, delta int) duerror.DUError {
+	return ud.transact(func() duerror.DUError {
+		for _, c := range cs {
+			old := c.GetLayer()
+			if err := ud.executeFunc(
+				func() duerror.DUError { return c.SetLayer(old + delta) },
+				func() duerror.DUError { return c.SetLayer(old) },
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// getLayerRange returns the lowest and highest layers in cs, which must not be empty
+func getLayerRange(cs []component.Component) (int, int) {
+	bottom, top := cs[0].GetLayer(), cs[0].GetLayer()
+	for _, c := range cs[1:] {
+		bottom = min(bottom, c.GetLayer())
+		top = max(top, c.GetLayer())
+	}
+	return bottom, top
+}
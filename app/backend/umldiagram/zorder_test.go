@@ -0,0 +1,245 @@
+package umldiagram
+
+import (
+	"testing"
+
+	"Dr.uml/backend/component"
+	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// three overlapping gadgets, named after their layers
+func newZOrderTestDiagram(t *testing.T) *UMLDiagram {
+	diagram, _ := CreateEmptyUMLDiagram("ZOrder.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "L0"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 10, Y: 10}, 1, drawdata.DefaultGadgetColor, "L1"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 20, Y: 20}, 2, drawdata.DefaultGadgetColor, "L2"))
+	return diagram
+}
+
+func getLayersByHeader(diagram *UMLDiagram) map[string]int {
+	layers := make(map[string]int)
+	for _, g := range diagram.GetDrawData().Gadgets {
+		layers[g.Attributes[0][0].Content] = g.Layer
+	}
+	return layers
+}
+
+func getHeaderAt(t *testing.T, diagram *UMLDiagram, p utils.Point) string {
+	g, err := diagram.componentsContainer.SearchGadget(p)
+	assert.NoError(t, err)
+	return g.GetDrawData().(drawdata.Gadget).Attributes[0][0].Content
+}
+
+func TestUMLDiagram_HitTestingUsesZOrder(t *testing.T) {
+	diagram := newZOrderTestDiagram(t)
+	p := utils.Point{X: 25, Y: 25}
+
+	// clicking and linking both reach the topmost gadget
+	assert.Equal(t, "L2", getHeaderAt(t, diagram, p))
+	assert.NoError(t, diagram.SelectComponent(p))
+	assert.Equal(t, gadgetIDByHeader(diagram, "L2"), diagram.GetDrawData().Gadgets[2].ID)
+	assert.True(t, diagram.GetDrawData().Gadgets[2].IsSelected)
+	assert.NoError(t, diagram.UnselectAllComponents())
+	assert.NoError(t, diagram.StartAddAssociation(utils.Point{X: 5, Y: 5}))
+	assert.NoError(t, diagram.EndAddAssociation(component.Dependency, p))
+	for g, list := range diagram.associations {
+		if len(list[1]) > 0 {
+			assert.Equal(t, gadgetIDByHeader(diagram, "L2"), g.GetID())
+		}
+	}
+
+	// equal layers are ordered by insertion, for drawing and for hit testing alike
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 20, Y: 20}, 2, drawdata.DefaultGadgetColor, "L2b"))
+	assert.Equal(t, "L2b", getHeaderAt(t, diagram, p))
+	headers := make([]string, 0)
+	for _, g := range diagram.GetDrawData().Gadgets {
+		headers = append(headers, g.Attributes[0][0].Content)
+	}
+	assert.Equal(t, []string{"L0", "L1", "L2", "L2b"}, headers)
+}
+
+func TestUMLDiagram_BringToFrontAndSendToBack(t *testing.T) {
+	diagram := newZOrderTestDiagram(t)
+	p := utils.Point{X: 25, Y: 25}
+	assert.Error(t, diagram.BringSelectedToFront())
+	assert.Error(t, diagram.SendSelectedToBack())
+
+	assert.NoError(t, diagram.SelectComponentByID(gadgetIDByHeader(diagram, "L0")))
+	assert.NoError(t, diagram.BringSelectedToFront())
+	assert.Equal(t, map[string]int{"L0": 3, "L1": 1, "L2": 2}, getLayersByHeader(diagram))
+	assert.Equal(t, "L0", getHeaderAt(t, diagram, p))
+
+	// already at the front, nothing to do
+	assert.NoError(t, diagram.BringSelectedToFront())
+	assert.Equal(t, 3, getLayersByHeader(diagram)["L0"])
+
+	assert.NoError(t, diagram.SendSelectedToBack())
+	assert.Equal(t, map[string]int{"L0": 0, "L1": 1, "L2": 2}, getLayersByHeader(diagram))
+	assert.Equal(t, "L2", getHeaderAt(t, diagram, p))
+	assert.NoError(t, diagram.SendSelectedToBack())
+	assert.Equal(t, 0, getLayersByHeader(diagram)["L0"])
+
+	// a selection keeps its own order
+	assert.NoError(t, diagram.SelectComponentByID(gadgetIDByHeader(diagram, "L1")))
+	assert.NoError(t, diagram.BringSelectedToFront())
+	assert.Equal(t, map[string]int{"L0": 3, "L1": 4, "L2": 2}, getLayersByHeader(diagram))
+	assert.Equal(t, "L1", getHeaderAt(t, diagram, p))
+
+	// one step each
+	assert.NoError(t, diagram.Undo())
+	assert.Equal(t, map[string]int{"L0": 0, "L1": 1, "L2": 2}, getLayersByHeader(diagram))
+	assert.NoError(t, diagram.Undo())
+	assert.Equal(t, 3, getLayersByHeader(diagram)["L0"])
+
+	// on the same layer, the selection still goes to the front
+	same, _ := CreateEmptyUMLDiagram("Same.uml", ClassDiagram)
+	assert.NoError(t, same.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "First"))
+	assert.NoError(t, same.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "Second"))
+	assert.Equal(t, "Second", getHeaderAt(t, same, utils.Point{X: 5, Y: 5}))
+	assert.NoError(t, same.SelectComponentByID(gadgetIDByHeader(same, "First")))
+	assert.NoError(t, same.BringSelectedToFront())
+	assert.Equal(t, "First", getHeaderAt(t, same, utils.Point{X: 5, Y: 5}))
+
+	// with nothing else in the diagram there is nothing to do
+	assert.NoError(t, same.SelectAllComponents())
+	assert.NoError(t, same.SendSelectedToBack())
+	assert.Equal(t, map[string]int{"First": 1, "Second": 0}, getLayersByHeader(same))
+}
+
+// what is drawn from the bottom to the top, associations named after their ends
+func getZOrder(diagram *UMLDiagram) []string {
+	header := func(g *component.Gadget) string {
+		return g.GetDrawData().(drawdata.Gadget).Attributes[0][0].Content
+	}
+	order := make([]string, 0)
+	for _, c := range diagram.componentsContainer.GetAll() {
+		switch c := c.(type) {
+		case *component.Gadget:
+			order = append(order, header(c))
+		case *component.Association:
+			order = append(order, header(c.GetParentStart())+"-"+header(c.GetParentEnd()))
+		}
+	}
+	return order
+}
+
+func TestUMLDiagram_BringForwardAndSendBackward(t *testing.T) {
+	diagram := newZOrderTestDiagram(t)
+	assert.Error(t, diagram.BringSelectedForward())
+	assert.Error(t, diagram.SendSelectedBackward())
+
+	// everything on one layer, Far overlaps nothing
+	diagram, _ = CreateEmptyUMLDiagram("ZOrder.uml", ClassDiagram)
+	for i, header := range []string{"A", "B", "C"} {
+		p := utils.Point{X: 10 * i, Y: 10 * i}
+		assert.NoError(t, diagram.AddGadget(component.Class, p, 0, drawdata.DefaultGadgetColor, header))
+	}
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 500, Y: 500}, 0, drawdata.DefaultGadgetColor, "Far"))
+	assert.NoError(t, diagram.SelectComponentByID(gadgetIDByHeader(diagram, "A")))
+
+	// one step at a time, past the next overlapping component only
+	assert.NoError(t, diagram.BringSelectedForward())
+	assert.Equal(t, []string{"B", "A", "C", "Far"}, getZOrder(diagram))
+	assert.Equal(t, "A", getHeaderAt(t, diagram, utils.Point{X: 15, Y: 15}))
+	assert.NoError(t, diagram.BringSelectedForward())
+	assert.Equal(t, []string{"B", "C", "A", "Far"}, getZOrder(diagram))
+	assert.NoError(t, diagram.BringSelectedForward())
+	assert.Equal(t, []string{"B", "C", "A", "Far"}, getZOrder(diagram))
+	assert.NoError(t, diagram.SendSelectedBackward())
+	assert.Equal(t, []string{"B", "A", "C", "Far"}, getZOrder(diagram))
+	for _, layer := range getLayersByHeader(diagram) {
+		assert.Equal(t, 0, layer)
+	}
+
+	// each step is undone at once
+	assert.NoError(t, diagram.Undo())
+	assert.Equal(t, []string{"B", "C", "A", "Far"}, getZOrder(diagram))
+	assert.NoError(t, diagram.Undo())
+	assert.NoError(t, diagram.Undo())
+	assert.Equal(t, []string{"A", "B", "C", "Far"}, getZOrder(diagram))
+	assert.NoError(t, diagram.Redo())
+	assert.Equal(t, []string{"B", "A", "C", "Far"}, getZOrder(diagram))
+}
+
+func TestUMLDiagram_BringForwardAndSendBackwardOnSparseLayers(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("ZOrder.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "L0"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 10, Y: 10}, 5, drawdata.DefaultGadgetColor, "L5"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 20, Y: 20}, 9, drawdata.DefaultGadgetColor, "L9"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 300, Y: 0}, 0, drawdata.DefaultGadgetColor, "Other"))
+	assert.NoError(t, diagram.StartAddAssociation(utils.Point{X: 5, Y: 5}))
+	assert.NoError(t, diagram.EndAddAssociation(component.Dependency, utils.Point{X: 305, Y: 5}))
+	assert.Equal(t, []string{"L0", "Other", "L0-Other", "L5", "L9"}, getZOrder(diagram))
+
+	// the selection moves together, past L5 and whatever lies between
+	assert.NoError(t, diagram.SelectComponentByID(gadgetIDByHeader(diagram, "L0")))
+	assert.NoError(t, diagram.SelectComponentByID(diagram.GetDrawData().Associations[0].ID))
+	assert.NoError(t, diagram.BringSelectedForward())
+	assert.Equal(t, []string{"Other", "L5", "L0", "L0-Other", "L9"}, getZOrder(diagram))
+	layers := getLayersByHeader(diagram)
+	assert.Equal(t, 0, layers["Other"])
+	assert.Equal(t, 0, layers["L5"])
+	assert.Equal(t, 0, layers["L0"])
+	assert.Equal(t, 5, diagram.GetDrawData().Associations[0].Layer)
+	assert.Equal(t, "L0", getHeaderAt(t, diagram, utils.Point{X: 15, Y: 15}))
+
+	assert.NoError(t, diagram.BringSelectedForward())
+	assert.Equal(t, []string{"Other", "L5", "L9", "L0", "L0-Other"}, getZOrder(diagram))
+	assert.Equal(t, 5, getLayersByHeader(diagram)["L0"])
+	assert.Equal(t, 0, getLayersByHeader(diagram)["L9"])
+	assert.Equal(t, 9, diagram.GetDrawData().Associations[0].Layer)
+
+	assert.NoError(t, diagram.SendSelectedBackward())
+	assert.Equal(t, []string{"Other", "L5", "L0", "L0-Other", "L9"}, getZOrder(diagram))
+	assert.NoError(t, diagram.Undo())
+	assert.NoError(t, diagram.Undo())
+	assert.NoError(t, diagram.Undo())
+	assert.Equal(t, []string{"L0", "Other", "L0-Other", "L5", "L9"}, getZOrder(diagram))
+	assert.Equal(t, 5, getLayersByHeader(diagram)["L5"])
+	assert.Equal(t, 0, diagram.GetDrawData().Associations[0].Layer)
+}
+
+func TestUMLDiagram_ZOrderIsKept(t *testing.T) {
+	diagram, _ := CreateEmptyUMLDiagram("ZOrder.uml", ClassDiagram)
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A"))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 300, Y: 0}, 0, drawdata.DefaultGadgetColor, "B"))
+	assert.NoError(t, diagram.StartAddAssociation(utils.Point{X: 5, Y: 10}))
+	assert.NoError(t, diagram.EndAddAssociation(component.Dependency, utils.Point{X: 305, Y: 10}))
+	assert.NoError(t, diagram.AddGadget(component.Class, utils.Point{X: 150, Y: 0}, 0, drawdata.DefaultGadgetColor, "C"))
+	want := []string{"A", "B", "A-B", "C"}
+	assert.Equal(t, want, getZOrder(diagram))
+
+	// undoing a removal puts the components back below the ones added after them
+	assert.NoError(t, diagram.RemoveComponentByID(gadgetIDByHeader(diagram, "A")))
+	assert.Equal(t, []string{"B", "C"}, getZOrder(diagram))
+	assert.NoError(t, diagram.Undo())
+	assert.Equal(t, want, getZOrder(diagram))
+	assert.NoError(t, diagram.Redo())
+	assert.NoError(t, diagram.Undo())
+	assert.Equal(t, want, getZOrder(diagram))
+
+	fd, err := diagram.GetFileData()
+	assert.NoError(t, err)
+	loaded, err := LoadUMLDiagramFromFileData(fd)
+	assert.NoError(t, err)
+	assert.Equal(t, want, getZOrder(loaded))
+	// and new ones still go on top
+	assert.NoError(t, loaded.AddGadget(component.Class, utils.Point{X: 150, Y: 150}, 0, drawdata.DefaultGadgetColor, "D"))
+	assert.Equal(t, append(want, "D"), getZOrder(loaded))
+
+	// files without the order put the associations above the gadgets
+	for i := range fd.Gadgets {
+		fd.Gadgets[i].Order = 0
+	}
+	for i := range fd.Associations {
+		fd.Associations[i].Order = 0
+	}
+	loaded, err = LoadUMLDiagramFromFileData(fd)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A", "B", "C", "A-B"}, getZOrder(loaded))
+	fd.Gadgets[0].Order = -1
+	_, err = LoadUMLDiagramFromFileData(fd)
+	assert.Error(t, err)
+}
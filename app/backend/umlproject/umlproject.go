@@ -2,12 +2,21 @@ package umlproject
 
 import (
 	"context"
+	"encoding/json"
 	"maps"
+	"os"
+	"path/filepath"
 	"slices"
 	"time"
 
 	"Dr.uml/backend/component"
+	"Dr.uml/backend/component/attribute"
 	"Dr.uml/backend/drawdata"
+	"Dr.uml/backend/export"
+	"Dr.uml/backend/filedata"
+	"Dr.uml/backend/gocode"
+	"Dr.uml/backend/mermaid"
+	"Dr.uml/backend/plantuml"
 	"Dr.uml/backend/umldiagram"
 	"Dr.uml/backend/utils"
 	"Dr.uml/backend/utils/duerror"
@@ -21,6 +30,9 @@ type UMLProject struct {
 	currentDiagram    *umldiagram.UMLDiagram            // The currently selected diagram
 	availableDiagrams map[string]bool                   // Use a map to store diagrams, keyed by their ID
 	activeDiagrams    map[string]*umldiagram.UMLDiagram // Keep track of active diagrams
+	closedDiagrams    map[string]filedata.Diagram       // Saved form of diagrams that are not active
+	filePath          string                            // Where Save writes to, empty until saved or loaded
+	clipboard         *filedata.Components              // Copied components, every diagram of the project can paste them
 	runFrontend       bool
 }
 
@@ -35,12 +47,43 @@ func CreateEmptyUMLProject(fileName string) (*UMLProject, duerror.DUError) {
 		lastModified:      time.Now(),
 		availableDiagrams: make(map[string]bool),
 		activeDiagrams:    make(map[string]*umldiagram.UMLDiagram),
+		closedDiagrams:    make(map[string]filedata.Diagram),
 	}, nil
 }
 
 func LoadExistUMLProject(fileName string) (*UMLProject, duerror.DUError) {
-	// TODO
-	return nil, nil
+	if err := utils.ValidateFilePath(fileName); err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, duerror.NewFileIOError(err.Error())
+	}
+	var fd filedata.Project
+	if err = json.Unmarshal(content, &fd); err != nil {
+		return nil, duerror.NewFileIOError(err.Error())
+	}
+	if fd.Version <= 0 || fd.Version > filedata.ProjectFileVersion {
+		return nil, duerror.NewFileIOError("unsupported project file version")
+	}
+
+	p := &UMLProject{
+		name:              fd.Name,
+		lastModified:      fd.LastModified,
+		availableDiagrams: make(map[string]bool),
+		activeDiagrams:    make(map[string]*umldiagram.UMLDiagram),
+		closedDiagrams:    make(map[string]filedata.Diagram),
+		filePath:          fileName,
+	}
+	// diagrams are rebuilt lazily by SelectDiagram
+	for _, d := range fd.Diagrams {
+		if _, ok := p.availableDiagrams[d.Name]; ok {
+			return nil, duerror.NewFileIOError("duplicate diagram name in project file")
+		}
+		p.availableDiagrams[d.Name] = true
+		p.closedDiagrams[d.Name] = d
+	}
+	return p, nil
 }
 
 // Getter
@@ -63,6 +106,10 @@ func (p *UMLProject) GetAvailableDiagramsNames() []string {
 	return slices.Collect(maps.Keys(p.availableDiagrams))
 }
 
+func (p *UMLProject) GetFilePath() string {
+	return p.filePath
+}
+
 func (p *UMLProject) GetActiveDiagramsNames() []string {
 	activeNames := make([]string, 0, len(p.activeDiagrams))
 	for _, d := range p.activeDiagrams {
@@ -106,6 +153,31 @@ func (p *UMLProject) SetColorGadget(colorHexStr string) duerror.DUError {
 	return nil
 }
 
+func (p *UMLProject) GetMembersGadget(section int) (attribute.MemberSection, duerror.DUError) {
+	if p.currentDiagram == nil {
+		return attribute.MemberSection{}, duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	return p.currentDiagram.GetMembersGadget(section)
+}
+
+func (p *UMLProject) GetMembersGadgetByID(id string, section int) (attribute.MemberSection, duerror.DUError) {
+	if p.currentDiagram == nil {
+		return attribute.MemberSection{}, duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	return p.currentDiagram.GetMembersGadgetByID(id, section)
+}
+
+func (p *UMLProject) FormatMembersGadget(section int) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.FormatMembersGadget(section); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
 func (p *UMLProject) SetAttrContentGadget(section int, index int, content string) duerror.DUError {
 	if p.currentDiagram == nil {
 		return duerror.NewInvalidArgumentError("No current diagram selected")
@@ -139,136 +211,1035 @@ func (p *UMLProject) SetAttrStyleGadget(section int, index int, style int) duerr
 	return nil
 }
 
-// methods
-func (p *UMLProject) Startup(ctx context.Context) {
-	p.ctx = ctx
-	// TODO: Remove this bcz can't handle error here
-	p.runFrontend = true
-	p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "new class diagram")
-	p.SelectDiagram("new class diagram")
+// Setters addressing a component of the current diagram by id
+func (p *UMLProject) SetPointGadgetByID(id string, point utils.Point) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.SetPointGadgetByID(id, point); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
 }
 
-func (p *UMLProject) SelectDiagram(diagramName string) duerror.DUError {
-	if _, ok := p.availableDiagrams[diagramName]; !ok {
-		return duerror.NewInvalidArgumentError("Diagram not found")
+func (p *UMLProject) SetLayerGadgetByID(id string, layer int) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
 	}
-	if _, ok := p.activeDiagrams[diagramName]; !ok {
-		diagram, err := umldiagram.LoadExistUMLDiagram(diagramName)
-		if err != nil {
-			return err
-		}
-		p.activeDiagrams[diagramName] = diagram
+	if err := p.currentDiagram.SetLayerGadgetByID(id, layer); err != nil {
+		return err
 	}
-	p.currentDiagram = p.activeDiagrams[diagramName]
-	// TODO: when multiple diagrams exists, unregister the old one
+	p.lastModified = time.Now()
+	return nil
+}
 
-	return p.currentDiagram.RegisterUpdateParentDraw(p.InvalidateCanvas)
+func (p *UMLProject) SetColorGadgetByID(id string, colorHexStr string) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.SetColorGadgetByID(id, colorHexStr); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
 }
 
-func (p *UMLProject) CreateEmptyUMLDiagram(diagramType umldiagram.DiagramType, diagramName string) duerror.DUError {
-	if _, ok := p.availableDiagrams[diagramName]; ok {
-		return duerror.NewInvalidArgumentError("Diagram name already exists")
+func (p *UMLProject) SetAttrContentGadgetByID(id string, section int, index int, content string) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
 	}
-	d, err := umldiagram.CreateEmptyUMLDiagram(diagramName, diagramType)
-	if err != nil {
+	if err := p.currentDiagram.SetAttrContentGadgetByID(id, section, index, content); err != nil {
 		return err
 	}
-	p.availableDiagrams[diagramName] = true
-	p.activeDiagrams[diagramName] = d
 	p.lastModified = time.Now()
 	return nil
 }
 
-func (p *UMLProject) CloseDiagram(diagramName string) duerror.DUError {
-	// TODO: save file?
-	if _, ok := p.activeDiagrams[diagramName]; !ok {
-		return duerror.NewInvalidArgumentError("Diagram not loaded")
+func (p *UMLProject) SetAttrSizeGadgetByID(id string, section int, index int, size int) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
 	}
-	if p.currentDiagram != nil && p.currentDiagram.GetName() == diagramName {
-		p.currentDiagram = nil
+	if err := p.currentDiagram.SetAttrSizeGadgetByID(id, section, index, size); err != nil {
+		return err
 	}
-	delete(p.activeDiagrams, diagramName)
+	p.lastModified = time.Now()
 	return nil
 }
 
-func (p *UMLProject) DeleteDiagram(diagramName string) duerror.DUError {
-	// TODO: remove the file
+func (p *UMLProject) SetAttrStyleGadgetByID(id string, section int, index int, style int) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.SetAttrStyleGadgetByID(id, section, index, style); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
 	return nil
 }
 
-func (p *UMLProject) AddGadget(gadgetType component.GadgetType, point utils.Point, layer int, colorHexStr string, header string) duerror.DUError {
+// Setters of the selected association of the current diagram
+func (p *UMLProject) SetAssTypeAssociation(assType component.AssociationType) duerror.DUError {
 	if p.currentDiagram == nil {
 		return duerror.NewInvalidArgumentError("No current diagram selected")
 	}
-	if err := p.currentDiagram.AddGadget(gadgetType, point, layer, colorHexStr, header); err != nil {
+	if err := p.currentDiagram.SetAssTypeAssociation(assType); err != nil {
 		return err
 	}
 	p.lastModified = time.Now()
 	return nil
 }
 
-func (p *UMLProject) StartAddAssociation(point utils.Point) duerror.DUError {
+func (p *UMLProject) SetStartNavigabilityAssociation(navigability component.Navigability) duerror.DUError {
 	if p.currentDiagram == nil {
 		return duerror.NewInvalidArgumentError("No current diagram selected")
 	}
-	return p.currentDiagram.StartAddAssociation(point)
+	if err := p.currentDiagram.SetStartNavigabilityAssociation(navigability); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
 }
 
-func (p *UMLProject) EndAddAssociation(associationType component.AssociationType, point utils.Point) duerror.DUError {
+func (p *UMLProject) SetEndNavigabilityAssociation(navigability component.Navigability) duerror.DUError {
 	if p.currentDiagram == nil {
 		return duerror.NewInvalidArgumentError("No current diagram selected")
 	}
-	if err := p.currentDiagram.EndAddAssociation(associationType, point); err != nil {
+	if err := p.currentDiagram.SetEndNavigabilityAssociation(navigability); err != nil {
 		return err
 	}
 	p.lastModified = time.Now()
 	return nil
 }
 
-func (p *UMLProject) RemoveSelectedComponents() duerror.DUError {
+func (p *UMLProject) SetRoutingAssociation(routing component.Routing) duerror.DUError {
 	if p.currentDiagram == nil {
 		return duerror.NewInvalidArgumentError("No current diagram selected")
 	}
-	if err := p.currentDiagram.RemoveSelectedComponents(); err != nil {
+	if err := p.currentDiagram.SetRoutingAssociation(routing); err != nil {
 		return err
 	}
 	p.lastModified = time.Now()
 	return nil
 }
 
-func (p *UMLProject) AddAttributeToGadget(section int, content string) duerror.DUError {
+func (p *UMLProject) AddWaypointAssociation(index int, point utils.Point) duerror.DUError {
 	if p.currentDiagram == nil {
 		return duerror.NewInvalidArgumentError("No current diagram selected")
 	}
-	if err := p.currentDiagram.AddAttributeToGadget(section, content); err != nil {
+	if err := p.currentDiagram.AddWaypointAssociation(index, point); err != nil {
 		return err
 	}
 	p.lastModified = time.Now()
 	return nil
 }
 
-func (p *UMLProject) RemoveAttributeFromGadget(section int, index int) duerror.DUError {
+func (p *UMLProject) MoveWaypointAssociation(index int, point utils.Point) duerror.DUError {
 	if p.currentDiagram == nil {
 		return duerror.NewInvalidArgumentError("No current diagram selected")
 	}
-	if err := p.currentDiagram.RemoveAttributeFromGadget(section, index); err != nil {
+	if err := p.currentDiagram.MoveWaypointAssociation(index, point); err != nil {
 		return err
 	}
 	p.lastModified = time.Now()
 	return nil
 }
 
-func (p *UMLProject) SelectComponent(point utils.Point) duerror.DUError {
+func (p *UMLProject) RemoveWaypointAssociation(index int) duerror.DUError {
 	if p.currentDiagram == nil {
 		return duerror.NewInvalidArgumentError("No current diagram selected")
 	}
-	if err := p.currentDiagram.SelectComponent(point); err != nil {
+	if err := p.currentDiagram.RemoveWaypointAssociation(index); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) SetLabelAssociation(kind component.LabelKind, content string) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.SetLabelAssociation(kind, content); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) SetReadingDirectionAssociation(direction component.ReadingDirection) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.SetReadingDirectionAssociation(direction); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) SetClassAssociation(classID string) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.SetClassAssociation(classID); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) SetLayerAssociation(layer int) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.SetLayerAssociation(layer); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) SetStartPointAssociation(point utils.Point) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.SetStartPointAssociation(point); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) SetEndPointAssociation(point utils.Point) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.SetEndPointAssociation(point); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) ReconnectStartAssociation(point utils.Point) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.ReconnectStartAssociation(point); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) ReconnectEndAssociation(point utils.Point) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.ReconnectEndAssociation(point); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+// methods
+func (p *UMLProject) Startup(ctx context.Context) {
+	p.ctx = ctx
+	// TODO: Remove this bcz can't handle error here
+	p.runFrontend = true
+	p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "new class diagram")
+	p.SelectDiagram("new class diagram")
+}
+
+func (p *UMLProject) SelectDiagram(diagramName string) duerror.DUError {
+	if _, ok := p.availableDiagrams[diagramName]; !ok {
+		return duerror.NewInvalidArgumentError("Diagram not found")
+	}
+	if _, ok := p.activeDiagrams[diagramName]; !ok {
+		var diagram *umldiagram.UMLDiagram
+		var err duerror.DUError
+		if fd, ok := p.closedDiagrams[diagramName]; ok {
+			diagram, err = umldiagram.LoadUMLDiagramFromFileData(fd)
+		} else {
+			diagram, err = umldiagram.LoadExistUMLDiagram(diagramName)
+		}
+		if err != nil {
+			return err
+		}
+		delete(p.closedDiagrams, diagramName)
+		p.activeDiagrams[diagramName] = diagram
+	}
+	p.currentDiagram = p.activeDiagrams[diagramName]
+	// TODO: when multiple diagrams exists, unregister the old one
+
+	return p.currentDiagram.RegisterUpdateParentDraw(p.InvalidateCanvas)
+}
+
+func (p *UMLProject) CreateEmptyUMLDiagram(diagramType umldiagram.DiagramType, diagramName string) duerror.DUError {
+	if _, ok := p.availableDiagrams[diagramName]; ok {
+		return duerror.NewInvalidArgumentError("Diagram name already exists")
+	}
+	d, err := umldiagram.CreateEmptyUMLDiagram(diagramName, diagramType)
+	if err != nil {
+		return err
+	}
+	p.availableDiagrams[diagramName] = true
+	p.activeDiagrams[diagramName] = d
+	p.lastModified = time.Now()
+	return nil
+}
+
+// ReverseEngineerGo adds a class diagram called diagramName of the Go package in dir, see gocode.ReverseEngineer
+func (p *UMLProject) ReverseEngineerGo(dir string, diagramName string) duerror.DUError {
+	if _, ok := p.availableDiagrams[diagramName]; ok {
+		return duerror.NewInvalidArgumentError("Diagram name already exists")
+	}
+	d, err := gocode.ReverseEngineer(dir, diagramName)
+	if err != nil {
 		return err
 	}
+	p.availableDiagrams[diagramName] = true
+	p.activeDiagrams[diagramName] = d
 	p.lastModified = time.Now()
 	return nil
 }
 
+// GenerateGo generates Go source of the current class diagram, see gocode.Generate. The files go into dir,
+// which must not hold them already, unless dryRun is set, then they are only returned as a preview.
+// An empty packageName is taken from the name of dir.
+func (p *UMLProject) GenerateGo(dir string, packageName string, dryRun bool) ([]gocode.File, duerror.DUError) {
+	if p.currentDiagram == nil {
+		return nil, duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if packageName == "" {
+		packageName = filepath.Base(dir)
+	}
+	fd, err := p.currentDiagram.GetFileData()
+	if err != nil {
+		return nil, err
+	}
+	files, err := gocode.Generate(fd, packageName)
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		if err := gocode.WriteFiles(dir, files); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// LoadExistUMLDiagram adds a standalone diagram file to the project, the file path becomes the diagram name.
+func (p *UMLProject) LoadExistUMLDiagram(fileName string) duerror.DUError {
+	if _, ok := p.availableDiagrams[fileName]; ok {
+		return duerror.NewInvalidArgumentError("Diagram name already exists")
+	}
+	d, err := umldiagram.LoadExistUMLDiagram(fileName)
+	if err != nil {
+		return err
+	}
+	p.availableDiagrams[fileName] = true
+	p.activeDiagrams[fileName] = d
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) CloseDiagram(diagramName string) duerror.DUError {
+	diagram, ok := p.activeDiagrams[diagramName]
+	if !ok {
+		return duerror.NewInvalidArgumentError("Diagram not loaded")
+	}
+	// keep the saved form so that the diagram can be selected or saved later
+	fd, err := diagram.GetFileData()
+	if err != nil {
+		return err
+	}
+	p.closedDiagrams[diagramName] = fd
+	if p.currentDiagram != nil && p.currentDiagram.GetName() == diagramName {
+		p.currentDiagram = nil
+	}
+	delete(p.activeDiagrams, diagramName)
+	return nil
+}
+
+// Save writes the whole project, active and closed diagrams alike, to the file it was loaded from or last saved as.
+func (p *UMLProject) Save() duerror.DUError {
+	if p.filePath == "" {
+		return duerror.NewInvalidArgumentError("Project has no file path, use SaveAs")
+	}
+	return p.SaveAs(p.filePath)
+}
+
+// SaveAs writes the whole project to fileName, which becomes the target of later calls to Save.
+func (p *UMLProject) SaveAs(fileName string) duerror.DUError {
+	if err := utils.ValidateFilePath(fileName); err != nil {
+		return err
+	}
+	fd, err := p.getFileData()
+	if err != nil {
+		return err
+	}
+	content, jsonErr := json.MarshalIndent(fd, "", "  ")
+	if jsonErr != nil {
+		return duerror.NewFileIOError(jsonErr.Error())
+	}
+	if err := os.WriteFile(fileName, content, 0644); err != nil {
+		return duerror.NewFileIOError(err.Error())
+	}
+	p.filePath = fileName
+	return nil
+}
+
+// ExportSVG writes the diagram called diagramName to path as a standalone SVG, see export.SVG.
+// The diagram does not have to be open, a closed one is read without opening it.
+func (p *UMLProject) ExportSVG(diagramName string, path string) duerror.DUError {
+	if err := utils.ValidateFilePath(path); err != nil {
+		return err
+	}
+	dd, err := p.diagramDrawData(diagramName)
+	if err != nil {
+		return err
+	}
+	content, err := export.SVG(dd)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return duerror.NewFileIOError(err.Error())
+	}
+	return nil
+}
+
+// ExportPNG writes the diagram called diagramName to path as a PNG, see export.PNG.
+// Like ExportSVG, the diagram does not have to be open.
+func (p *UMLProject) ExportPNG(diagramName string, path string, options export.PNGOptions) duerror.DUError {
+	if err := utils.ValidateFilePath(path); err != nil {
+		return err
+	}
+	dd, err := p.diagramDrawData(diagramName)
+	if err != nil {
+		return err
+	}
+	content, err := export.PNG(dd, options)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return duerror.NewFileIOError(err.Error())
+	}
+	return nil
+}
+
+// ExportPDF writes the diagram called diagramName to path as a PDF, see export.PDF.
+// Its pages are labelled with the name of the diagram unless options has a title.
+func (p *UMLProject) ExportPDF(diagramName string, path string, options export.PDFOptions) duerror.DUError {
+	if err := utils.ValidateFilePath(path); err != nil {
+		return err
+	}
+	dd, err := p.diagramDrawData(diagramName)
+	if err != nil {
+		return err
+	}
+	if options.Title == "" {
+		options.Title = filepath.Base(diagramName)
+	}
+	content, err := export.PDF(dd, options)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return duerror.NewFileIOError(err.Error())
+	}
+	return nil
+}
+
+// ImportPlantUML adds a class diagram called diagramName read from the PlantUML file at path,
+// see plantuml.Import. The warnings tell what of the file was left out or changed.
+func (p *UMLProject) ImportPlantUML(path string, diagramName string) ([]plantuml.Warning, duerror.DUError) {
+	if _, ok := p.availableDiagrams[diagramName]; ok {
+		return nil, duerror.NewInvalidArgumentError("Diagram name already exists")
+	}
+	src, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return nil, duerror.NewFileIOError(readErr.Error())
+	}
+	d, warnings, err := plantuml.Import(string(src), diagramName)
+	if err != nil {
+		return nil, err
+	}
+	p.availableDiagrams[diagramName] = true
+	p.activeDiagrams[diagramName] = d
+	p.lastModified = time.Now()
+	return warnings, nil
+}
+
+// ExportPlantUML writes the class diagram called diagramName to path as PlantUML, see plantuml.Export.
+// Like ExportSVG, the diagram does not have to be open. The warnings tell what PlantUML cannot show.
+func (p *UMLProject) ExportPlantUML(diagramName string, path string) ([]plantuml.Warning, duerror.DUError) {
+	if err := utils.ValidateFilePath(path); err != nil {
+		return nil, err
+	}
+	d, err := p.diagram(diagramName)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := d.GetFileData()
+	if err != nil {
+		return nil, err
+	}
+	content, warnings, err := plantuml.Export(fd)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return nil, duerror.NewFileIOError(err.Error())
+	}
+	return warnings, nil
+}
+
+// ImportMermaid adds a class diagram called diagramName read from the Mermaid file at path,
+// see mermaid.Import. The warnings tell what of the file was left out or changed.
+func (p *UMLProject) ImportMermaid(path string, diagramName string) ([]mermaid.Warning, duerror.DUError) {
+	if _, ok := p.availableDiagrams[diagramName]; ok {
+		return nil, duerror.NewInvalidArgumentError("Diagram name already exists")
+	}
+	src, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return nil, duerror.NewFileIOError(readErr.Error())
+	}
+	d, warnings, err := mermaid.Import(string(src), diagramName)
+	if err != nil {
+		return nil, err
+	}
+	p.availableDiagrams[diagramName] = true
+	p.activeDiagrams[diagramName] = d
+	p.lastModified = time.Now()
+	return warnings, nil
+}
+
+// ExportMermaid writes the class diagram called diagramName to path as Mermaid, see mermaid.Export.
+// The diagram does not have to be open. The warnings tell what Mermaid cannot hold.
+func (p *UMLProject) ExportMermaid(diagramName string, path string) ([]mermaid.Warning, duerror.DUError) {
+	if err := utils.ValidateFilePath(path); err != nil {
+		return nil, err
+	}
+	d, err := p.diagram(diagramName)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := d.GetFileData()
+	if err != nil {
+		return nil, err
+	}
+	content, warnings, err := mermaid.Export(fd)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return nil, duerror.NewFileIOError(err.Error())
+	}
+	return warnings, nil
+}
+
+// diagramDrawData returns the draw data of a diagram of the project, open or not
+func (p *UMLProject) diagramDrawData(diagramName string) (drawdata.Diagram, duerror.DUError) {
+	d, err := p.diagram(diagramName)
+	if err != nil {
+		return drawdata.Diagram{}, err
+	}
+	return d.GetDrawData(), nil
+}
+
+// diagram returns a diagram of the project, a closed one is loaded without opening it
+func (p *UMLProject) diagram(diagramName string) (*umldiagram.UMLDiagram, duerror.DUError) {
+	if _, ok := p.availableDiagrams[diagramName]; !ok {
+		return nil, duerror.NewInvalidArgumentError("Diagram not found")
+	}
+	if d, ok := p.activeDiagrams[diagramName]; ok {
+		return d, nil
+	}
+	if fd, ok := p.closedDiagrams[diagramName]; ok {
+		return umldiagram.LoadUMLDiagramFromFileData(fd)
+	}
+	return umldiagram.LoadExistUMLDiagram(diagramName)
+}
+
+func (p *UMLProject) DeleteDiagram(diagramName string) duerror.DUError {
+	// TODO: remove the file
+	return nil
+}
+
+func (p *UMLProject) AddGadget(gadgetType component.GadgetType, point utils.Point, layer int, colorHexStr string, header string) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.AddGadget(gadgetType, point, layer, colorHexStr, header); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) StartAddAssociation(point utils.Point) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	return p.currentDiagram.StartAddAssociation(point)
+}
+
+func (p *UMLProject) EndAddAssociation(associationType component.AssociationType, point utils.Point) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.EndAddAssociation(associationType, point); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) RemoveSelectedComponents() duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.RemoveSelectedComponents(); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) AddAttributeToGadget(section int, content string) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.AddAttributeToGadget(section, content); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) RemoveAttributeFromGadget(section int, index int) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.RemoveAttributeFromGadget(section, index); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) AddAttributeToAssociation(ratio float64, content string) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.AddAttributeToAssociation(ratio, content); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) MoveAttributeAssociation(index int, ratio float64) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.MoveAttributeAssociation(index, ratio); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) RemoveAttributeFromAssociation(index int) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.RemoveAttributeFromAssociation(index); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) SelectComponent(point utils.Point) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.SelectComponent(point); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) AddAttributeToGadgetByID(id string, section int, content string) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.AddAttributeToGadgetByID(id, section, content); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) RemoveAttributeFromGadgetByID(id string, section int, index int) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.RemoveAttributeFromGadgetByID(id, section, index); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) RemoveComponentByID(id string) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.RemoveComponentByID(id); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+// SelectComponentByID adds the component to the selection of the current diagram
+func (p *UMLProject) SelectComponentByID(id string) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	return p.currentDiagram.SelectComponentByID(id)
+}
+
+func (p *UMLProject) UnselectComponentByID(id string) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	return p.currentDiagram.UnselectComponentByID(id)
+}
+
+// CopyComponents copies the selection of the current diagram into the clipboard of the project.
+// It also returns the copy as plain text, which PasteComponentsFromText of any running app accepts.
+func (p *UMLProject) CopyComponents() (string, duerror.DUError) {
+	if p.currentDiagram == nil {
+		return "", duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	components, err := p.currentDiagram.CopySelectedComponents()
+	if err != nil {
+		return "", err
+	}
+	return p.setClipboard(components)
+}
+
+// CutComponents is CopyComponents, followed by removing the selection
+func (p *UMLProject) CutComponents() (string, duerror.DUError) {
+	if p.currentDiagram == nil {
+		return "", duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	components, err := p.currentDiagram.CutSelectedComponents()
+	if err != nil {
+		return "", err
+	}
+	p.lastModified = time.Now()
+	return p.setClipboard(components)
+}
+
+// PasteComponents pastes the clipboard of the project into the current diagram, its top-left corner at point
+func (p *UMLProject) PasteComponents(point utils.Point) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if p.clipboard == nil {
+		return duerror.NewInvalidArgumentError("Clipboard is empty")
+	}
+	if err := p.currentDiagram.PasteComponents(*p.clipboard, point); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+// PasteComponentsFromText pastes the plain text returned by CopyComponents into the current diagram
+func (p *UMLProject) PasteComponentsFromText(text string, point utils.Point) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	var clipboard filedata.Clipboard
+	if err := json.Unmarshal([]byte(text), &clipboard); err != nil || clipboard.Format != filedata.ClipboardFormat {
+		return duerror.NewInvalidArgumentError("Text is not copied components")
+	}
+	if clipboard.Version <= 0 || clipboard.Version > filedata.ClipboardVersion {
+		return duerror.NewInvalidArgumentError("Unsupported clipboard version")
+	}
+	if err := p.currentDiagram.PasteComponents(clipboard.Components, point); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+// DuplicateComponents places a copy of the selection of the current diagram next to it, the clipboard is untouched
+func (p *UMLProject) DuplicateComponents() duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.DuplicateSelectedComponents(); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+// ExportSubmodule saves the selection of the current diagram to target as a submodule called name
+func (p *UMLProject) ExportSubmodule(target string, name string) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	return p.currentDiagram.ExportSubmodule(target, name)
+}
+
+// ImportSubmodule inserts the submodule saved in source into the current diagram, its top-left corner at point
+func (p *UMLProject) ImportSubmodule(source string, point utils.Point) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.ImportSubmodule(source, point); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+// BringToFront raises the selection of the current diagram above every other component
+func (p *UMLProject) BringToFront() duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.BringSelectedToFront(); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+// SendToBack lowers the selection of the current diagram below every other component
+func (p *UMLProject) SendToBack() duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.SendSelectedToBack(); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+// BringForward raises the selection of the current diagram one step in the stacking order
+func (p *UMLProject) BringForward() duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.BringSelectedForward(); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+// SendBackward lowers the selection of the current diagram one step in the stacking order
+func (p *UMLProject) SendBackward() duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.SendSelectedBackward(); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+// StartDragging begins moving the selection of the current diagram from point
+func (p *UMLProject) StartDragging(point utils.Point) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	return p.currentDiagram.StartDragging(point)
+}
+
+// StopDragging drops the dragged selection at point, the whole drag undoes as one step
+func (p *UMLProject) StopDragging(point utils.Point) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.StopDragging(point); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) MoveSelectedComponent(delta utils.Point) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.MoveSelectedComponent(delta); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+// SelectComponentsInRect adds every component touched by the rectangle spanned by p1 and p2 to the selection
+func (p *UMLProject) SelectComponentsInRect(p1 utils.Point, p2 utils.Point) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	return p.currentDiagram.SelectComponentsInRect(p1, p2)
+}
+
+func (p *UMLProject) SelectAllComponents() duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	return p.currentDiagram.SelectAllComponents()
+}
+
+func (p *UMLProject) UnselectAllComponents() duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	return p.currentDiagram.UnselectAllComponents()
+}
+
+func (p *UMLProject) InvertSelection() duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	return p.currentDiagram.InvertSelection()
+}
+
+func (p *UMLProject) SelectAllGadgets() duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	return p.currentDiagram.SelectAllGadgets()
+}
+
+func (p *UMLProject) SelectAssociationsByType(assType component.AssociationType) duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	return p.currentDiagram.SelectAssociationsByType(assType)
+}
+
+// BeginTransaction groups the following edits of the current diagram into one undoable step
+func (p *UMLProject) BeginTransaction() duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	return p.currentDiagram.BeginTransaction()
+}
+
+func (p *UMLProject) CommitTransaction() duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	return p.currentDiagram.CommitTransaction()
+}
+
+func (p *UMLProject) RollbackTransaction() duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.RollbackTransaction(); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+// Undo reverts the latest edit of the current diagram, every diagram keeps its own history
+func (p *UMLProject) Undo() duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.Undo(); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+// Redo re-applies the latest undone edit of the current diagram
+func (p *UMLProject) Redo() duerror.DUError {
+	if p.currentDiagram == nil {
+		return duerror.NewInvalidArgumentError("No current diagram selected")
+	}
+	if err := p.currentDiagram.Redo(); err != nil {
+		return err
+	}
+	p.lastModified = time.Now()
+	return nil
+}
+
+func (p *UMLProject) CanUndo() bool {
+	if p.currentDiagram == nil {
+		return false
+	}
+	return p.currentDiagram.CanUndo()
+}
+
+func (p *UMLProject) CanRedo() bool {
+	if p.currentDiagram == nil {
+		return false
+	}
+	return p.currentDiagram.CanRedo()
+}
+
+// Private methods
+func (p *UMLProject) setClipboard(components filedata.Components) (string, duerror.DUError) {
+	content, err := json.Marshal(filedata.Clipboard{
+		Format:     filedata.ClipboardFormat,
+		Version:    filedata.ClipboardVersion,
+		Components: components,
+	})
+	if err != nil {
+		return "", duerror.NewInvalidArgumentError(err.Error())
+	}
+	p.clipboard = &components
+	return string(content), nil
+}
+
+func (p *UMLProject) getFileData() (filedata.Project, duerror.DUError) {
+	fd := filedata.Project{
+		Version:      filedata.ProjectFileVersion,
+		Name:         p.name,
+		LastModified: p.lastModified,
+		Diagrams:     make([]filedata.Diagram, 0, len(p.availableDiagrams)),
+	}
+	names := slices.Sorted(maps.Keys(p.availableDiagrams))
+	for _, name := range names {
+		if d, ok := p.activeDiagrams[name]; ok {
+			dFd, err := d.GetFileData()
+			if err != nil {
+				return filedata.Project{}, err
+			}
+			fd.Diagrams = append(fd.Diagrams, dFd)
+		} else if dFd, ok := p.closedDiagrams[name]; ok {
+			fd.Diagrams = append(fd.Diagrams, dFd)
+		}
+	}
+	return fd, nil
+}
+
 // draw
 func (p *UMLProject) GetDrawData() drawdata.Diagram {
 	if p.currentDiagram == nil {
@@ -2,10 +2,16 @@ package umlproject
 
 import (
 	"Dr.uml/backend/drawdata"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"Dr.uml/backend/component"
+	"Dr.uml/backend/component/attribute"
+	"Dr.uml/backend/export"
 	"Dr.uml/backend/umldiagram"
 	"Dr.uml/backend/utils"
 	"github.com/stretchr/testify/assert"
@@ -340,8 +346,1012 @@ func TestInvalidateCanvas(t *testing.T) {
 }
 
 func TestLoadExistUMLProject(t *testing.T) {
-	// Test the function (currently returns nil, nil)
-	p, err := LoadExistUMLProject("TestProject")
+	dir := t.TempDir()
+
+	// file does not exist
+	p, err := LoadExistUMLProject(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+	assert.Nil(t, p)
+
+	// not a project file
+	broken := filepath.Join(dir, "broken.json")
+	assert.NoError(t, os.WriteFile(broken, []byte("not json"), 0644))
+	p, err = LoadExistUMLProject(broken)
+	assert.Error(t, err)
+	assert.Nil(t, p)
+
+	// unsupported version
+	future := filepath.Join(dir, "future.json")
+	assert.NoError(t, os.WriteFile(future, []byte(`{"version": 999, "name": "p"}`), 0644))
+	p, err = LoadExistUMLProject(future)
+	assert.Error(t, err)
 	assert.Nil(t, p)
-	assert.Nil(t, err)
+}
+
+func TestSaveAndLoadProject(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "project.json")
+
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+
+	// no file path yet
+	err = p.Save()
+	assert.Error(t, err)
+
+	// one active diagram with content and one closed diagram
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "Start")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 200, Y: 200}, 1, "#123456", "End")
+	assert.NoError(t, err)
+	err = p.SelectComponent(utils.Point{X: 5, Y: 5})
+	assert.NoError(t, err)
+	err = p.AddAttributeToGadget(1, "field: int")
+	assert.NoError(t, err)
+	err = p.StartAddAssociation(utils.Point{X: 5, Y: 5})
+	assert.NoError(t, err)
+	err = p.EndAddAssociation(component.Composition, utils.Point{X: 205, Y: 205})
+	assert.NoError(t, err)
+	// selection is not saved, click away from the association line
+	err = p.SelectComponent(utils.Point{X: 30, Y: 5})
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "OtherDiagram")
+	assert.NoError(t, err)
+	err = p.CloseDiagram("OtherDiagram")
+	assert.NoError(t, err)
+
+	err = p.SaveAs(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t, fileName, p.GetFilePath())
+	before := p.GetDrawData()
+
+	// load it back
+	loaded, err := LoadExistUMLProject(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t, "TestProject", loaded.GetName())
+	assert.Equal(t, fileName, loaded.GetFilePath())
+	assert.ElementsMatch(t, []string{"TestDiagram", "OtherDiagram"}, loaded.GetAvailableDiagramsNames())
+	assert.Empty(t, loaded.GetActiveDiagramsNames())
+	assert.True(t, p.GetLastModified().Equal(loaded.GetLastModified()))
+
+	err = loaded.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	after := loaded.GetDrawData()
+	assert.ElementsMatch(t, before.Gadgets, after.Gadgets)
+	assert.ElementsMatch(t, before.Associations, after.Associations)
+
+	// saving the loaded project writes the same file
+	first, readErr := os.ReadFile(fileName)
+	assert.NoError(t, readErr)
+	err = loaded.Save()
+	assert.NoError(t, err)
+	second, readErr := os.ReadFile(fileName)
+	assert.NoError(t, readErr)
+	assert.Equal(t, string(first), string(second))
+}
+
+func TestSaveAndLoadProject_OverlappingClick(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "project.json")
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	assert.NoError(t, p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram"))
+	assert.NoError(t, p.SelectDiagram("TestDiagram"))
+	assert.NoError(t, p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "Start"))
+	assert.NoError(t, p.AddGadget(component.Class, utils.Point{X: 200, Y: 200}, 0, drawdata.DefaultGadgetColor, "End"))
+	assert.NoError(t, p.StartAddAssociation(utils.Point{X: 5, Y: 5}))
+	assert.NoError(t, p.EndAddAssociation(component.Composition, utils.Point{X: 205, Y: 205}))
+	assert.NoError(t, p.SaveAs(fileName))
+	loaded, err := LoadExistUMLProject(fileName)
+	assert.NoError(t, err)
+	assert.NoError(t, loaded.SelectDiagram("TestDiagram"))
+
+	// the association lies over the Start gadget where it leaves it, the one added last is on top
+	// and is hit every time, before and after a save
+	for _, project := range []*UMLProject{p, loaded} {
+		for i := 0; i < 20; i++ {
+			assert.NoError(t, project.SelectComponent(utils.Point{X: 5, Y: 5}))
+			assert.True(t, project.GetDrawData().Associations[0].IsSelected)
+			assert.False(t, project.GetDrawData().Gadgets[0].IsSelected)
+			assert.NoError(t, project.UnselectAllComponents())
+		}
+	}
+}
+
+func TestLoadExistUMLDiagramIntoProject(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "standalone.uml")
+	d, err := umldiagram.CreateEmptyUMLDiagram("standalone.uml", umldiagram.ClassDiagram)
+	assert.NoError(t, err)
+	err = d.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "sample header")
+	assert.NoError(t, err)
+	err = d.SaveToFile(fileName)
+	assert.NoError(t, err)
+
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.LoadExistUMLDiagram(fileName)
+	assert.NoError(t, err)
+	err = p.SelectDiagram(fileName)
+	assert.NoError(t, err)
+	assert.Len(t, p.GetDrawData().Gadgets, 1)
+
+	// already in the project
+	err = p.LoadExistUMLDiagram(fileName)
+	assert.Error(t, err)
+
+	// file does not exist
+	err = p.LoadExistUMLDiagram(filepath.Join(t.TempDir(), "missing.uml"))
+	assert.Error(t, err)
+}
+
+func TestCloseDiagramKeepsContent(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "sample header")
+	assert.NoError(t, err)
+
+	err = p.CloseDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	assert.Len(t, p.GetDrawData().Gadgets, 1)
+}
+
+func TestUndoRedo(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+
+	// no diagram selected
+	assert.False(t, p.CanUndo())
+	assert.False(t, p.CanRedo())
+	assert.Error(t, p.Undo())
+	assert.Error(t, p.Redo())
+
+	// every diagram has its own history
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram1")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram2")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram1")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "sample header")
+	assert.NoError(t, err)
+	assert.True(t, p.CanUndo())
+
+	err = p.SelectDiagram("TestDiagram2")
+	assert.NoError(t, err)
+	assert.False(t, p.CanUndo())
+	assert.Error(t, p.Undo())
+
+	err = p.SelectDiagram("TestDiagram1")
+	assert.NoError(t, err)
+	err = p.Undo()
+	assert.NoError(t, err)
+	assert.Empty(t, p.GetDrawData().Gadgets)
+	assert.True(t, p.CanRedo())
+	err = p.Redo()
+	assert.NoError(t, err)
+	assert.Len(t, p.GetDrawData().Gadgets, 1)
+}
+
+func TestTransaction(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+
+	// no diagram selected
+	assert.Error(t, p.BeginTransaction())
+	assert.Error(t, p.CommitTransaction())
+	assert.Error(t, p.RollbackTransaction())
+
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+
+	assert.NoError(t, p.BeginTransaction())
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 100, Y: 0}, 0, drawdata.DefaultGadgetColor, "B")
+	assert.NoError(t, err)
+	assert.NoError(t, p.CommitTransaction())
+	assert.Len(t, p.GetDrawData().Gadgets, 2)
+
+	assert.NoError(t, p.Undo())
+	assert.Empty(t, p.GetDrawData().Gadgets)
+	assert.False(t, p.CanUndo())
+
+	assert.NoError(t, p.BeginTransaction())
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A")
+	assert.NoError(t, err)
+	assert.NoError(t, p.RollbackTransaction())
+	assert.Empty(t, p.GetDrawData().Gadgets)
+}
+
+func TestComponentByID(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "sample header")
+	assert.NoError(t, err)
+	id := p.GetDrawData().Gadgets[0].ID
+
+	err = p.SelectComponentByID(id)
+	assert.NoError(t, err)
+	assert.True(t, p.GetDrawData().Gadgets[0].IsSelected)
+	err = p.UnselectComponentByID(id)
+	assert.NoError(t, err)
+	err = p.SetPointGadgetByID(id, utils.Point{X: 10, Y: 20})
+	assert.NoError(t, err)
+	err = p.SetLayerGadgetByID(id, 1)
+	assert.NoError(t, err)
+	err = p.SetColorGadgetByID(id, "#000000")
+	assert.NoError(t, err)
+	err = p.AddAttributeToGadgetByID(id, 1, "field: int")
+	assert.NoError(t, err)
+	err = p.SetAttrContentGadgetByID(id, 1, 0, "field: string")
+	assert.NoError(t, err)
+	err = p.SetAttrSizeGadgetByID(id, 1, 0, 20)
+	assert.NoError(t, err)
+	err = p.SetAttrStyleGadgetByID(id, 1, 0, 1)
+	assert.NoError(t, err)
+	err = p.RemoveAttributeFromGadgetByID(id, 1, 0)
+	assert.NoError(t, err)
+	gadget := p.GetDrawData().Gadgets[0]
+	assert.Equal(t, 10, gadget.X)
+	assert.Equal(t, "#000000", gadget.Color)
+	assert.Len(t, gadget.Attributes[1], 0)
+
+	err = p.RemoveComponentByID(id)
+	assert.NoError(t, err)
+	assert.Len(t, p.GetDrawData().Gadgets, 0)
+
+	// No diagram selected
+	err = p.CloseDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectComponentByID(id)
+	assert.Error(t, err)
+	err = p.RemoveComponentByID(id)
+	assert.Error(t, err)
+}
+
+func TestEditSelectedAssociation(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 200, Y: 0}, 0, drawdata.DefaultGadgetColor, "B")
+	assert.NoError(t, err)
+	err = p.StartAddAssociation(utils.Point{X: 5, Y: 10})
+	assert.NoError(t, err)
+	err = p.EndAddAssociation(component.Dependency, utils.Point{X: 205, Y: 10})
+	assert.NoError(t, err)
+	ass := p.GetDrawData().Associations[0]
+
+	err = p.SelectComponent(utils.Point{X: (ass.StartX + ass.EndX) / 2, Y: (ass.StartY + ass.EndY) / 2})
+	assert.NoError(t, err)
+	err = p.SetAssTypeAssociation(component.Extension)
+	assert.NoError(t, err)
+	err = p.SetLayerAssociation(2)
+	assert.NoError(t, err)
+	err = p.SetStartPointAssociation(utils.Point{X: 0, Y: 5})
+	assert.NoError(t, err)
+	err = p.SetEndPointAssociation(utils.Point{X: 200, Y: 5})
+	assert.NoError(t, err)
+	err = p.AddAttributeToAssociation(0.5, "uses")
+	assert.NoError(t, err)
+	err = p.MoveAttributeAssociation(0, 0.4)
+	assert.NoError(t, err)
+	ass = p.GetDrawData().Associations[0]
+	assert.True(t, ass.IsSelected)
+	assert.Equal(t, int(component.Extension), ass.AssType)
+	assert.Equal(t, 0.4, ass.Attributes[0].Ratio)
+	err = p.RemoveAttributeFromAssociation(0)
+	assert.NoError(t, err)
+	assert.Len(t, p.GetDrawData().Associations[0].Attributes, 0)
+
+	// No diagram selected
+	err = p.CloseDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.SetAssTypeAssociation(component.Extension)
+	assert.Error(t, err)
+	err = p.AddAttributeToAssociation(0.5, "uses")
+	assert.Error(t, err)
+}
+
+func TestSelectMany(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 200, Y: 0}, 0, drawdata.DefaultGadgetColor, "B")
+	assert.NoError(t, err)
+	err = p.StartAddAssociation(utils.Point{X: 5, Y: 10})
+	assert.NoError(t, err)
+	err = p.EndAddAssociation(component.Dependency, utils.Point{X: 205, Y: 10})
+	assert.NoError(t, err)
+	isSelected := func() []bool {
+		dd := p.GetDrawData()
+		return []bool{dd.Gadgets[0].IsSelected, dd.Gadgets[1].IsSelected, dd.Associations[0].IsSelected}
+	}
+
+	err = p.SelectAllComponents()
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, true, true}, isSelected())
+	err = p.InvertSelection()
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, false, false}, isSelected())
+	err = p.SelectAllGadgets()
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, true, false}, isSelected())
+	err = p.UnselectAllComponents()
+	assert.NoError(t, err)
+	err = p.SelectAssociationsByType(component.Dependency)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, false, true}, isSelected())
+	err = p.UnselectAllComponents()
+	assert.NoError(t, err)
+	err = p.SelectComponentsInRect(utils.Point{X: -1, Y: -1}, utils.Point{X: 1, Y: 1})
+	assert.NoError(t, err)
+	assert.Len(t, slices.DeleteFunc(isSelected(), func(b bool) bool { return !b }), 1)
+
+	// No diagram selected
+	err = p.CloseDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectAllComponents()
+	assert.Error(t, err)
+	err = p.SelectComponentsInRect(utils.Point{X: -1, Y: -1}, utils.Point{X: 1, Y: 1})
+	assert.Error(t, err)
+}
+
+func TestDragging(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A")
+	assert.NoError(t, err)
+	err = p.SelectComponent(utils.Point{X: 5, Y: 5})
+	assert.NoError(t, err)
+
+	err = p.StartDragging(utils.Point{X: 5, Y: 5})
+	assert.NoError(t, err)
+	err = p.MoveSelectedComponent(utils.Point{X: 10, Y: 0})
+	assert.NoError(t, err)
+	err = p.StopDragging(utils.Point{X: 25, Y: 35})
+	assert.NoError(t, err)
+	assert.Equal(t, 20, p.GetDrawData().Gadgets[0].X)
+	assert.Equal(t, 30, p.GetDrawData().Gadgets[0].Y)
+	err = p.Undo()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, p.GetDrawData().Gadgets[0].X)
+
+	// No diagram selected
+	err = p.CloseDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.StartDragging(utils.Point{X: 5, Y: 5})
+	assert.Error(t, err)
+	err = p.StopDragging(utils.Point{X: 5, Y: 5})
+	assert.Error(t, err)
+	err = p.MoveSelectedComponent(utils.Point{X: 5, Y: 5})
+	assert.Error(t, err)
+}
+
+func TestCopyPaste(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "First")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "Second")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("First")
+	assert.NoError(t, err)
+
+	// Nothing to copy or paste yet
+	_, err = p.CopyComponents()
+	assert.Error(t, err)
+	err = p.PasteComponents(utils.Point{X: 0, Y: 0})
+	assert.Error(t, err)
+
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A")
+	assert.NoError(t, err)
+	err = p.SelectComponent(utils.Point{X: 5, Y: 5})
+	assert.NoError(t, err)
+	text, err := p.CopyComponents()
+	assert.NoError(t, err)
+	assert.Contains(t, text, "\"A\"")
+
+	// The clipboard is shared by the diagrams of the project
+	err = p.SelectDiagram("Second")
+	assert.NoError(t, err)
+	err = p.PasteComponents(utils.Point{X: 50, Y: 60})
+	assert.NoError(t, err)
+	gadgets := p.GetDrawData().Gadgets
+	assert.Len(t, gadgets, 1)
+	assert.Equal(t, 50, gadgets[0].X)
+	assert.Equal(t, 60, gadgets[0].Y)
+
+	// Duplicate leaves the clipboard alone
+	err = p.DuplicateComponents()
+	assert.NoError(t, err)
+	assert.Len(t, p.GetDrawData().Gadgets, 2)
+	err = p.PasteComponents(utils.Point{X: 200, Y: 200})
+	assert.NoError(t, err)
+	assert.Len(t, p.GetDrawData().Gadgets, 3)
+
+	// Cut replaces the clipboard
+	_, err = p.CutComponents()
+	assert.NoError(t, err)
+	assert.Len(t, p.GetDrawData().Gadgets, 2)
+	err = p.Undo()
+	assert.NoError(t, err)
+	assert.Len(t, p.GetDrawData().Gadgets, 3)
+
+	// The text payload can be pasted into another project
+	other, err := CreateEmptyUMLProject("OtherProject")
+	assert.NoError(t, err)
+	err = other.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "Diagram")
+	assert.NoError(t, err)
+	err = other.SelectDiagram("Diagram")
+	assert.NoError(t, err)
+	err = other.PasteComponentsFromText(text, utils.Point{X: 10, Y: 10})
+	assert.NoError(t, err)
+	assert.Len(t, other.GetDrawData().Gadgets, 1)
+	assert.NotEqual(t, gadgets[0].ID, other.GetDrawData().Gadgets[0].ID)
+	err = other.PasteComponentsFromText("not json", utils.Point{X: 10, Y: 10})
+	assert.Error(t, err)
+	err = other.PasteComponentsFromText(`{"format":"something else"}`, utils.Point{X: 10, Y: 10})
+	assert.Error(t, err)
+	err = other.PasteComponentsFromText(`{"format":"dr.uml/components","version":99}`, utils.Point{X: 10, Y: 10})
+	assert.Error(t, err)
+
+	// No diagram selected
+	err = other.CloseDiagram("Diagram")
+	assert.NoError(t, err)
+	_, err = other.CopyComponents()
+	assert.Error(t, err)
+	_, err = other.CutComponents()
+	assert.Error(t, err)
+	err = other.PasteComponents(utils.Point{X: 0, Y: 0})
+	assert.Error(t, err)
+	err = other.PasteComponentsFromText(text, utils.Point{X: 0, Y: 0})
+	assert.Error(t, err)
+	err = other.DuplicateComponents()
+	assert.Error(t, err)
+}
+
+func TestSubmodule(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "pattern.json")
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "First")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "Second")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("First")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "Repository")
+	assert.NoError(t, err)
+	err = p.SelectComponent(utils.Point{X: 5, Y: 5})
+	assert.NoError(t, err)
+	err = p.ExportSubmodule(source, "Repository")
+	assert.NoError(t, err)
+
+	err = p.SelectDiagram("Second")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 3, drawdata.DefaultGadgetColor, "Service")
+	assert.NoError(t, err)
+	before := p.GetLastModified()
+	err = p.ImportSubmodule(source, utils.Point{X: 100, Y: 100})
+	assert.NoError(t, err)
+	assert.True(t, p.GetLastModified().After(before))
+	gadgets := p.GetDrawData().Gadgets
+	assert.Len(t, gadgets, 2)
+	for _, g := range gadgets {
+		if g.Attributes[0][0].Content == "Repository" {
+			assert.Equal(t, 100, g.X)
+			assert.Equal(t, 4, g.Layer)
+		}
+	}
+
+	// No diagram selected
+	err = p.CloseDiagram("Second")
+	assert.NoError(t, err)
+	err = p.ExportSubmodule(source, "Repository")
+	assert.Error(t, err)
+	err = p.ImportSubmodule(source, utils.Point{X: 0, Y: 0})
+	assert.Error(t, err)
+}
+
+func TestZOrder(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "Bottom")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "Top")
+	assert.NoError(t, err)
+	bottom := p.GetDrawData().Gadgets[0].ID
+	err = p.SelectComponentByID(bottom)
+	assert.NoError(t, err)
+
+	err = p.BringToFront()
+	assert.NoError(t, err)
+	assert.Equal(t, bottom, p.GetDrawData().Gadgets[1].ID)
+	err = p.SendToBack()
+	assert.NoError(t, err)
+	assert.Equal(t, bottom, p.GetDrawData().Gadgets[0].ID)
+	err = p.BringForward()
+	assert.NoError(t, err)
+	assert.Equal(t, bottom, p.GetDrawData().Gadgets[1].ID)
+	err = p.SendBackward()
+	assert.NoError(t, err)
+	assert.Equal(t, bottom, p.GetDrawData().Gadgets[0].ID)
+
+	// No diagram selected
+	err = p.CloseDiagram("TestDiagram")
+	assert.NoError(t, err)
+	assert.Error(t, p.BringToFront())
+	assert.Error(t, p.SendToBack())
+	assert.Error(t, p.BringForward())
+	assert.Error(t, p.SendBackward())
+}
+
+func TestAssociationKinds(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 300, Y: 0}, 0, drawdata.DefaultGadgetColor, "B")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 100, Y: 200}, 0, drawdata.DefaultGadgetColor, "C")
+	assert.NoError(t, err)
+	class := p.GetDrawData().Gadgets[2].ID
+	err = p.StartAddAssociation(utils.Point{X: 5, Y: 10})
+	assert.NoError(t, err)
+	err = p.EndAddAssociation(component.AssociationClass, utils.Point{X: 305, Y: 10})
+	assert.NoError(t, err)
+	err = p.SelectComponentByID(p.GetDrawData().Associations[0].ID)
+	assert.NoError(t, err)
+
+	err = p.SetStartNavigabilityAssociation(component.NonNavigable)
+	assert.NoError(t, err)
+	err = p.SetEndNavigabilityAssociation(component.Navigable)
+	assert.NoError(t, err)
+	err = p.SetClassAssociation(class)
+	assert.NoError(t, err)
+	ass := p.GetDrawData().Associations[0]
+	assert.Equal(t, drawdata.ArrowheadCross, ass.StartHead)
+	assert.Equal(t, drawdata.ArrowheadOpen, ass.EndHead)
+	assert.NotNil(t, ass.ClassLink)
+
+	// No diagram selected
+	err = p.CloseDiagram("TestDiagram")
+	assert.NoError(t, err)
+	assert.Error(t, p.SetStartNavigabilityAssociation(component.Navigable))
+	assert.Error(t, p.SetEndNavigabilityAssociation(component.Navigable))
+	assert.Error(t, p.SetClassAssociation(class))
+}
+
+func TestAssociationLabels(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 300, Y: 0}, 0, drawdata.DefaultGadgetColor, "B")
+	assert.NoError(t, err)
+	err = p.StartAddAssociation(utils.Point{X: 5, Y: 10})
+	assert.NoError(t, err)
+	err = p.EndAddAssociation(component.PlainAssociation, utils.Point{X: 305, Y: 10})
+	assert.NoError(t, err)
+	err = p.SelectComponentByID(p.GetDrawData().Associations[0].ID)
+	assert.NoError(t, err)
+
+	err = p.SetLabelAssociation(component.StartMultiplicity, "1")
+	assert.NoError(t, err)
+	err = p.SetLabelAssociation(component.EndMultiplicity, "two")
+	assert.Error(t, err)
+	err = p.SetReadingDirectionAssociation(component.ReadingTowardEnd)
+	assert.NoError(t, err)
+	assert.Len(t, p.GetDrawData().Associations[0].Labels, 1)
+
+	// No diagram selected
+	err = p.CloseDiagram("TestDiagram")
+	assert.NoError(t, err)
+	assert.Error(t, p.SetLabelAssociation(component.StartMultiplicity, "1"))
+	assert.Error(t, p.SetReadingDirectionAssociation(component.ReadingNone))
+}
+
+func TestWaypoints(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 200}, 0, drawdata.DefaultGadgetColor, "A")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 300, Y: 200}, 0, drawdata.DefaultGadgetColor, "B")
+	assert.NoError(t, err)
+	err = p.StartAddAssociation(utils.Point{X: 21, Y: 220})
+	assert.NoError(t, err)
+	err = p.EndAddAssociation(component.PlainAssociation, utils.Point{X: 301, Y: 220})
+	assert.NoError(t, err)
+	err = p.SelectComponentByID(p.GetDrawData().Associations[0].ID)
+	assert.NoError(t, err)
+
+	err = p.AddWaypointAssociation(0, utils.Point{X: 22, Y: 100})
+	assert.NoError(t, err)
+	err = p.MoveWaypointAssociation(0, utils.Point{X: 22, Y: 50})
+	assert.NoError(t, err)
+	assert.Equal(t, 50, p.GetDrawData().Associations[0].Path[1].Y)
+	err = p.RemoveWaypointAssociation(0)
+	assert.NoError(t, err)
+	err = p.SetRoutingAssociation(component.RoutingOrthogonal)
+	assert.NoError(t, err)
+	assert.Len(t, p.GetDrawData().Associations[0].Path, 2)
+
+	// No diagram selected
+	err = p.CloseDiagram("TestDiagram")
+	assert.NoError(t, err)
+	assert.Error(t, p.AddWaypointAssociation(0, utils.Point{X: 5, Y: 100}))
+	assert.Error(t, p.MoveWaypointAssociation(0, utils.Point{X: 5, Y: 100}))
+	assert.Error(t, p.RemoveWaypointAssociation(0))
+	assert.Error(t, p.SetRoutingAssociation(component.RoutingStraight))
+}
+
+func TestReconnectAssociation(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 300, Y: 0}, 0, drawdata.DefaultGadgetColor, "B")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 300, Y: 300}, 0, drawdata.DefaultGadgetColor, "C")
+	assert.NoError(t, err)
+	err = p.StartAddAssociation(utils.Point{X: 5, Y: 10})
+	assert.NoError(t, err)
+	err = p.EndAddAssociation(component.PlainAssociation, utils.Point{X: 305, Y: 10})
+	assert.NoError(t, err)
+	err = p.SelectComponentByID(p.GetDrawData().Associations[0].ID)
+	assert.NoError(t, err)
+
+	err = p.ReconnectEndAssociation(utils.Point{X: 305, Y: 305})
+	assert.NoError(t, err)
+	assert.Equal(t, 300, p.GetDrawData().Associations[0].EndY)
+	err = p.ReconnectStartAssociation(utils.Point{X: 1000, Y: 1000})
+	assert.Error(t, err)
+	err = p.ReconnectStartAssociation(utils.Point{X: 310, Y: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, 310, p.GetDrawData().Associations[0].StartX)
+
+	// No diagram selected
+	err = p.CloseDiagram("TestDiagram")
+	assert.NoError(t, err)
+	assert.Error(t, p.ReconnectStartAssociation(utils.Point{X: 5, Y: 10}))
+	assert.Error(t, p.ReconnectEndAssociation(utils.Point{X: 5, Y: 10}))
+}
+
+func TestMembers(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "A")
+	assert.NoError(t, err)
+	id := p.GetDrawData().Gadgets[0].ID
+	err = p.SelectComponentByID(id)
+	assert.NoError(t, err)
+	err = p.AddAttributeToGadget(2, "+getName(id:int):string")
+	assert.NoError(t, err)
+
+	members, err := p.GetMembersGadget(2)
+	assert.NoError(t, err)
+	assert.Equal(t, attribute.Public, members.Members[0].Visibility)
+	assert.Equal(t, "string", members.Members[0].Type)
+	members, err = p.GetMembersGadgetByID(id, 2)
+	assert.NoError(t, err)
+	assert.Len(t, members.Members, 1)
+	err = p.FormatMembersGadget(2)
+	assert.NoError(t, err)
+	assert.Equal(t, "+ getName(id: int): string", p.GetDrawData().Gadgets[0].Attributes[2][0].Content)
+
+	// No diagram selected
+	err = p.CloseDiagram("TestDiagram")
+	assert.NoError(t, err)
+	_, err = p.GetMembersGadget(2)
+	assert.Error(t, err)
+	_, err = p.GetMembersGadgetByID(id, 2)
+	assert.Error(t, err)
+	assert.Error(t, p.FormatMembersGadget(2))
+}
+
+func TestReverseEngineerGo(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	dir := t.TempDir()
+	source := "package zoo\n\ntype Animal interface{ Sound() string }\n\ntype Dog struct{ Name string }\n\nfunc (d Dog) Sound() string { return \"woof\" }\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "zoo.go"), []byte(source), 0644))
+
+	err = p.ReverseEngineerGo(dir, "zoo")
+	assert.NoError(t, err)
+	assert.Contains(t, p.GetActiveDiagramsNames(), "zoo")
+	err = p.SelectDiagram("zoo")
+	assert.NoError(t, err)
+	assert.Len(t, p.GetDrawData().Gadgets, 2)
+	assert.Len(t, p.GetDrawData().Associations, 1)
+
+	err = p.ReverseEngineerGo(dir, "zoo")
+	assert.Error(t, err)
+	err = p.ReverseEngineerGo(filepath.Join(dir, "missing"), "missing")
+	assert.Error(t, err)
+	assert.NotContains(t, p.GetAvailableDiagramsNames(), "missing")
+}
+
+func TestGenerateGo(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	src := t.TempDir()
+	source := "package zoo\n\ntype Animal interface{ Sound() string }\n\ntype Dog struct{ Name string }\n\nfunc (d Dog) Sound() string { return \"woof\" }\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "zoo.go"), []byte(source), 0644))
+	assert.NoError(t, p.ReverseEngineerGo(src, "zoo"))
+	assert.NoError(t, p.SelectDiagram("zoo"))
+
+	// a dry run only previews the files
+	dir := filepath.Join(t.TempDir(), "zoo")
+	files, err := p.GenerateGo(dir, "", true)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.Contains(t, files[1].Content, "package zoo")
+	assert.Contains(t, files[1].Content, "var _ Animal = (*Dog)(nil)")
+	_, statErr := os.Stat(dir)
+	assert.True(t, os.IsNotExist(statErr))
+
+	files, err = p.GenerateGo(dir, "animals", false)
+	assert.NoError(t, err)
+	content, readErr := os.ReadFile(filepath.Join(dir, files[0].Name))
+	assert.NoError(t, readErr)
+	assert.Equal(t, files[0].Content, string(content))
+	_, err = p.GenerateGo(dir, "animals", false)
+	assert.Error(t, err)
+
+	// No diagram selected
+	err = p.CloseDiagram("zoo")
+	assert.NoError(t, err)
+	_, err = p.GenerateGo(dir, "", true)
+	assert.Error(t, err)
+}
+
+func TestExportSVG(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "Exported")
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "diagram.svg")
+	err = p.ExportSVG("TestDiagram", path)
+	assert.NoError(t, err)
+	content, readErr := os.ReadFile(path)
+	assert.NoError(t, readErr)
+	assert.Contains(t, string(content), ">Exported</text>")
+
+	// a closed diagram is exported as it was left
+	err = p.CloseDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.ExportSVG("TestDiagram", path)
+	assert.NoError(t, err)
+	assert.NotContains(t, p.GetActiveDiagramsNames(), "TestDiagram")
+	closed, readErr := os.ReadFile(path)
+	assert.NoError(t, readErr)
+	assert.Equal(t, content, closed)
+
+	err = p.ExportSVG("Missing", path)
+	assert.Error(t, err)
+	err = p.ExportSVG("TestDiagram", "")
+	assert.Error(t, err)
+	err = p.ExportSVG("TestDiagram", filepath.Join(t.TempDir(), "missing", "diagram.svg"))
+	assert.Error(t, err)
+}
+
+func TestExportPNG(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "Exported")
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "diagram.png")
+	err = p.ExportPNG("TestDiagram", path, export.PNGOptions{DPI: 192, Transparent: true})
+	assert.NoError(t, err)
+	content, readErr := os.ReadFile(path)
+	assert.NoError(t, readErr)
+	assert.True(t, strings.HasPrefix(string(content), "\x89PNG"))
+
+	err = p.ExportPNG("TestDiagram", path, export.PNGOptions{Scale: -2})
+	assert.Error(t, err)
+	err = p.ExportPNG("Missing", path, export.PNGOptions{})
+	assert.Error(t, err)
+	err = p.ExportPNG("TestDiagram", "", export.PNGOptions{})
+	assert.Error(t, err)
+}
+
+func TestExportPDF(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "Exported")
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "diagram.pdf")
+	err = p.ExportPDF("TestDiagram", path, export.PDFOptions{Paper: export.PaperA4, FitToPage: true, Margin: 10})
+	assert.NoError(t, err)
+	content, readErr := os.ReadFile(path)
+	assert.NoError(t, readErr)
+	assert.True(t, strings.HasPrefix(string(content), "%PDF-1.4"))
+	assert.True(t, strings.HasSuffix(string(content), "%%EOF\n"))
+
+	err = p.ExportPDF("TestDiagram", path, export.PDFOptions{Margin: -1})
+	assert.Error(t, err)
+	err = p.ExportPDF("Missing", path, export.PDFOptions{})
+	assert.Error(t, err)
+	err = p.ExportPDF("TestDiagram", "", export.PDFOptions{})
+	assert.Error(t, err)
+}
+
+func TestImportPlantUML(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "zoo.puml")
+	source := "@startuml\nclass Dog <<pet>>\ninterface Animal\nDog ..|> Animal\n@enduml\n"
+	assert.NoError(t, os.WriteFile(path, []byte(source), 0644))
+
+	warnings, err := p.ImportPlantUML(path, "zoo")
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 1)
+	assert.Contains(t, p.GetActiveDiagramsNames(), "zoo")
+	err = p.SelectDiagram("zoo")
+	assert.NoError(t, err)
+	assert.Len(t, p.GetDrawData().Gadgets, 2)
+	assert.Len(t, p.GetDrawData().Associations, 1)
+
+	_, err = p.ImportPlantUML(path, "zoo")
+	assert.Error(t, err)
+	_, err = p.ImportPlantUML(filepath.Join(t.TempDir(), "missing.puml"), "missing")
+	assert.Error(t, err)
+	assert.NotContains(t, p.GetAvailableDiagramsNames(), "missing")
+}
+
+func TestExportPlantUML(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 0, Y: 0}, 0, drawdata.DefaultGadgetColor, "Exported")
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "diagram.puml")
+	warnings, err := p.ExportPlantUML("TestDiagram", path)
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+	content, readErr := os.ReadFile(path)
+	assert.NoError(t, readErr)
+	assert.Equal(t, "@startuml TestDiagram\nclass Exported\n@enduml\n", string(content))
+
+	// a closed diagram is exported as it was left
+	err = p.CloseDiagram("TestDiagram")
+	assert.NoError(t, err)
+	_, err = p.ExportPlantUML("TestDiagram", path)
+	assert.NoError(t, err)
+	closed, readErr := os.ReadFile(path)
+	assert.NoError(t, readErr)
+	assert.Equal(t, content, closed)
+
+	_, err = p.ExportPlantUML("Missing", path)
+	assert.Error(t, err)
+	_, err = p.ExportPlantUML("TestDiagram", "")
+	assert.Error(t, err)
+}
+
+func TestImportMermaid(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "zoo.mmd")
+	source := "classDiagram\nclass Dog {\n    <<pet>>\n}\nclass Animal {\n    <<interface>>\n}\nAnimal <|.. Dog\n"
+	assert.NoError(t, os.WriteFile(path, []byte(source), 0644))
+
+	warnings, err := p.ImportMermaid(path, "zoo")
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 1)
+	assert.Contains(t, p.GetActiveDiagramsNames(), "zoo")
+	err = p.SelectDiagram("zoo")
+	assert.NoError(t, err)
+	assert.Len(t, p.GetDrawData().Gadgets, 2)
+	assert.Len(t, p.GetDrawData().Associations, 1)
+
+	_, err = p.ImportMermaid(path, "zoo")
+	assert.Error(t, err)
+	_, err = p.ImportMermaid(filepath.Join(t.TempDir(), "missing.mmd"), "missing")
+	assert.Error(t, err)
+	assert.NotContains(t, p.GetAvailableDiagramsNames(), "missing")
+}
+
+func TestExportMermaid(t *testing.T) {
+	p, err := CreateEmptyUMLProject("TestProject")
+	assert.NoError(t, err)
+	err = p.CreateEmptyUMLDiagram(umldiagram.ClassDiagram, "TestDiagram")
+	assert.NoError(t, err)
+	err = p.SelectDiagram("TestDiagram")
+	assert.NoError(t, err)
+	err = p.AddGadget(component.Class, utils.Point{X: 10, Y: 20}, 0, drawdata.DefaultGadgetColor, "Exported")
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "diagram.mmd")
+	warnings, err := p.ExportMermaid("TestDiagram", path)
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+	content, readErr := os.ReadFile(path)
+	assert.NoError(t, readErr)
+	assert.Equal(t, "---\ntitle: \"TestDiagram\"\n---\nclassDiagram\nclass Exported\n%% dr.uml {\"x\":10,\"y\":20}\n", string(content))
+
+	// a closed diagram is exported as it was left
+	err = p.CloseDiagram("TestDiagram")
+	assert.NoError(t, err)
+	_, err = p.ExportMermaid("TestDiagram", path)
+	assert.NoError(t, err)
+	closed, readErr := os.ReadFile(path)
+	assert.NoError(t, readErr)
+	assert.Equal(t, content, closed)
+
+	_, err = p.ExportMermaid("Missing", path)
+	assert.Error(t, err)
+	_, err = p.ExportMermaid("TestDiagram", "")
+	assert.Error(t, err)
 }
@@ -0,0 +1,14 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// NewID returns a random 128-bit identifier encoded as 32 hex characters
+func NewID() string {
+	b := make([]byte, 16)
+	// crypto/rand.Read never returns an error and always fills b
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
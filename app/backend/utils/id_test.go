@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewID(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := NewID()
+		assert.Len(t, id, 32)
+		assert.False(t, seen[id], "duplicate id %s", id)
+		seen[id] = true
+	}
+}
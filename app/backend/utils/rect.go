@@ -0,0 +1,74 @@
+package utils
+
+// Rect is an axis-aligned rectangle, Min is the top-left and Max the bottom-right corner, both inclusive
+type Rect struct {
+	Min Point
+	Max Point
+}
+
+// NewRect returns the rectangle spanned by two opposite corners given in any order
+func NewRect(p1, p2 Point) Rect {
+	return Rect{
+		Min: Point{X: min(p1.X, p2.X), Y: min(p1.Y, p2.Y)},
+		Max: Point{X: max(p1.X, p2.X), Y: max(p1.Y, p2.Y)},
+	}
+}
+
+func (r Rect) Contains(p Point) bool {
+	return p.X >= r.Min.X && p.X <= r.Max.X && p.Y >= r.Min.Y && p.Y <= r.Max.Y
+}
+
+func (r Rect) Intersects(o Rect) bool {
+	return r.Min.X <= o.Max.X && o.Min.X <= r.Max.X && r.Min.Y <= o.Max.Y && o.Min.Y <= r.Max.Y
+}
+
+// Union returns the smallest rectangle containing both r and o
+func (r Rect) Union(o Rect) Rect {
+	return Rect{
+		Min: Point{X: min(r.Min.X, o.Min.X), Y: min(r.Min.Y, o.Min.Y)},
+		Max: Point{X: max(r.Max.X, o.Max.X), Y: max(r.Max.Y, o.Max.Y)},
+	}
+}
+
+// IntersectsSegment reports whether any point of the segment st-en lies inside r
+func (r Rect) IntersectsSegment(st, en Point) bool {
+	if r.Contains(st) || r.Contains(en) {
+		return true
+	}
+	if !r.Intersects(NewRect(st, en)) {
+		return false
+	}
+	// both ends are outside, so the segment has to cross one of the edges
+	tr := Point{X: r.Max.X, Y: r.Min.Y}
+	bl := Point{X: r.Min.X, Y: r.Max.Y}
+	return segmentsIntersect(st, en, r.Min, tr) ||
+		segmentsIntersect(st, en, tr, r.Max) ||
+		segmentsIntersect(st, en, r.Max, bl) ||
+		segmentsIntersect(st, en, bl, r.Min)
+}
+
+// orientation of c relative to the line a-b: 1 counter-clockwise, -1 clockwise, 0 collinear
+func orientation(a, b, c Point) int {
+	v := int64(b.X-a.X)*int64(c.Y-a.Y) - int64(b.Y-a.Y)*int64(c.X-a.X)
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+func segmentsIntersect(a, b, c, d Point) bool {
+	o1, o2 := orientation(a, b, c), orientation(a, b, d)
+	o3, o4 := orientation(c, d, a), orientation(c, d, b)
+	if o1 != o2 && o3 != o4 {
+		return true
+	}
+	// collinear cases: an end of one segment lies on the other
+	return (o1 == 0 && NewRect(a, b).Contains(c)) ||
+		(o2 == 0 && NewRect(a, b).Contains(d)) ||
+		(o3 == 0 && NewRect(c, d).Contains(a)) ||
+		(o4 == 0 && NewRect(c, d).Contains(b))
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRect(t *testing.T) {
+	r := NewRect(Point{X: 10, Y: 2}, Point{X: 3, Y: 8})
+	assert.Equal(t, Rect{Min: Point{X: 3, Y: 2}, Max: Point{X: 10, Y: 8}}, r)
+}
+
+func TestRect_Contains(t *testing.T) {
+	r := NewRect(Point{X: 0, Y: 0}, Point{X: 10, Y: 10})
+	assert.True(t, r.Contains(Point{X: 5, Y: 5}))
+	assert.True(t, r.Contains(Point{X: 10, Y: 0}))
+	assert.False(t, r.Contains(Point{X: 11, Y: 5}))
+	assert.False(t, r.Contains(Point{X: 5, Y: -1}))
+}
+
+func TestRect_Intersects(t *testing.T) {
+	r := NewRect(Point{X: 0, Y: 0}, Point{X: 10, Y: 10})
+	tests := []struct {
+		name     string
+		other    Rect
+		expected bool
+	}{
+		{"inside", NewRect(Point{X: 2, Y: 2}, Point{X: 3, Y: 3}), true},
+		{"around", NewRect(Point{X: -2, Y: -2}, Point{X: 13, Y: 13}), true},
+		{"overlapping", NewRect(Point{X: 5, Y: 5}, Point{X: 15, Y: 15}), true},
+		{"touching edge", NewRect(Point{X: 10, Y: 0}, Point{X: 20, Y: 10}), true},
+		{"right of", NewRect(Point{X: 11, Y: 0}, Point{X: 20, Y: 10}), false},
+		{"below", NewRect(Point{X: 0, Y: 11}, Point{X: 10, Y: 20}), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, r.Intersects(tt.other))
+			assert.Equal(t, tt.expected, tt.other.Intersects(r))
+		})
+	}
+}
+
+func TestRect_Union(t *testing.T) {
+	r := NewRect(Point{X: 0, Y: 5}, Point{X: 10, Y: 10})
+	o := NewRect(Point{X: 5, Y: 0}, Point{X: 20, Y: 7})
+	assert.Equal(t, NewRect(Point{X: 0, Y: 0}, Point{X: 20, Y: 10}), r.Union(o))
+}
+
+func TestRect_IntersectsSegment(t *testing.T) {
+	r := NewRect(Point{X: 0, Y: 0}, Point{X: 10, Y: 10})
+	tests := []struct {
+		name     string
+		st, en   Point
+		expected bool
+	}{
+		{"inside", Point{X: 1, Y: 1}, Point{X: 2, Y: 2}, true},
+		{"one end inside", Point{X: 5, Y: 5}, Point{X: 50, Y: 5}, true},
+		{"crossing", Point{X: -5, Y: 5}, Point{X: 15, Y: 5}, true},
+		{"diagonal crossing", Point{X: -5, Y: 20}, Point{X: 20, Y: -5}, true},
+		{"along an edge", Point{X: -5, Y: 0}, Point{X: 15, Y: 0}, true},
+		{"outside", Point{X: 11, Y: 0}, Point{X: 20, Y: 10}, false},
+		{"diagonal missing the corner", Point{X: 12, Y: 0}, Point{X: 20, Y: 8}, false},
+		{"bounding boxes overlap only", Point{X: -10, Y: 5}, Point{X: 5, Y: 20}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, r.IntersectsSegment(tt.st, tt.en))
+		})
+	}
+}
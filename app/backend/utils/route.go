@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"container/heap"
+	"math"
+	"slices"
+)
+
+// directions a route can head in, as unit vectors
+var routeDirections = [4]Point{{X: 1}, {X: -1}, {Y: 1}, {Y: -1}}
+
+// RouteOrthogonal returns the bends of a path of horizontal and vertical segments from start to end
+// that stays margin away from every obstacle. startOut and endOut are the unit vectors pointing out of
+// the rectangles start and end lie on, the path leaves and enters them that way. Turns cost bendCost
+// on top of the length. It returns nil when no such path exists.
+func RouteOrthogonal(start, startOut, end, endOut Point, obstacles []Rect, margin int, bendCost int) []Point {
+	inflated := make([]Rect, len(obstacles))
+	for i, o := range obstacles {
+		inflated[i] = Rect{
+			Min: Point{X: o.Min.X - margin, Y: o.Min.Y - margin},
+			Max: Point{X: o.Max.X + margin, Y: o.Max.Y + margin},
+		}
+	}
+	stPort := AddPoints(start, Point{X: startOut.X * margin, Y: startOut.Y * margin})
+	enPort := AddPoints(end, Point{X: endOut.X * margin, Y: endOut.Y * margin})
+
+	// the grid lines run along the ports, the obstacle edges and halfway between the ports
+	xs := []int{stPort.X, enPort.X, (stPort.X + enPort.X) / 2}
+	ys := []int{stPort.Y, enPort.Y, (stPort.Y + enPort.Y) / 2}
+	for _, r := range inflated {
+		xs = append(xs, r.Min.X, r.Max.X)
+		ys = append(ys, r.Min.Y, r.Max.Y)
+	}
+	slices.Sort(xs)
+	xs = slices.Compact(xs)
+	slices.Sort(ys)
+	ys = slices.Compact(ys)
+
+	g := routeGrid{xs: xs, ys: ys, obstacles: inflated}
+	stNode := g.node(slices.Index(xs, stPort.X), slices.Index(ys, stPort.Y))
+	enNode := g.node(slices.Index(xs, enPort.X), slices.Index(ys, enPort.Y))
+	stDir := slices.Index(routeDirections[:], startOut)
+	enDir := slices.Index(routeDirections[:], Point{X: -endOut.X, Y: -endOut.Y})
+	if stDir < 0 || enDir < 0 {
+		return nil
+	}
+
+	// dijkstra over (node, heading), so that turning can be charged for
+	states := len(xs) * len(ys) * len(routeDirections)
+	cost := make([]int, states)
+	prev := make([]int, states)
+	for i := range cost {
+		cost[i] = math.MaxInt
+		prev[i] = -1
+	}
+	first := stNode*len(routeDirections) + stDir
+	cost[first] = 0
+	queue := &routeQueue{{state: first}}
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(routeItem)
+		if item.cost > cost[item.state] {
+			continue
+		}
+		node, dir := item.state/len(routeDirections), item.state%len(routeDirections)
+		if node == enNode {
+			continue
+		}
+		i, j := g.index(node)
+		for next, d := range routeDirections {
+			if d.X == -routeDirections[dir].X && d.Y == -routeDirections[dir].Y {
+				continue
+			}
+			ni, nj := i+d.X, j+d.Y
+			if ni < 0 || nj < 0 || ni >= len(xs) || nj >= len(ys) {
+				continue
+			}
+			nNode := g.node(ni, nj)
+			if (nNode != enNode && g.blocked(ni, nj)) || g.crosses(i, j, ni, nj) {
+				continue
+			}
+			c := item.cost + AbsInt(xs[ni]-xs[i]) + AbsInt(ys[nj]-ys[j])
+			if next != dir {
+				c += bendCost
+			}
+			state := nNode*len(routeDirections) + next
+			if c < cost[state] {
+				cost[state] = c
+				prev[state] = item.state
+				heap.Push(queue, routeItem{state: state, cost: c})
+			}
+		}
+	}
+
+	last, best := -1, math.MaxInt
+	for dir := range routeDirections {
+		state := enNode*len(routeDirections) + dir
+		if cost[state] == math.MaxInt {
+			continue
+		}
+		c := cost[state]
+		if dir != enDir {
+			c += bendCost
+		}
+		if c < best {
+			last, best = state, c
+		}
+	}
+	if last < 0 {
+		return nil
+	}
+	path := []Point{end}
+	for state := last; state >= 0; state = prev[state] {
+		i, j := g.index(state / len(routeDirections))
+		path = append(path, Point{X: xs[i], Y: ys[j]})
+	}
+	path = append(path, start)
+	slices.Reverse(path)
+	path = simplifyPolyline(path)
+	return path[1 : len(path)-1]
+}
+
+// simplifyPolyline drops repeated points and the ones in the middle of a straight run
+func simplifyPolyline(line []Point) []Point {
+	simple := make([]Point, 0, len(line))
+	for _, p := range line {
+		if len(simple) > 0 && EqualPoints(simple[len(simple)-1], p) {
+			continue
+		}
+		if len(simple) > 1 {
+			a, b := simple[len(simple)-2], simple[len(simple)-1]
+			if (a.X == b.X && b.X == p.X) || (a.Y == b.Y && b.Y == p.Y) {
+				simple[len(simple)-1] = p
+				continue
+			}
+		}
+		simple = append(simple, p)
+	}
+	return simple
+}
+
+type routeGrid struct {
+	xs, ys    []int
+	obstacles []Rect
+}
+
+func (g routeGrid) node(i, j int) int {
+	return j*len(g.xs) + i
+}
+
+func (g routeGrid) index(node int) (int, int) {
+	return node % len(g.xs), node / len(g.xs)
+}
+
+// blocked reports whether the grid point lies strictly inside an obstacle
+func (g routeGrid) blocked(i, j int) bool {
+	x, y := g.xs[i], g.ys[j]
+	for _, r := range g.obstacles {
+		if x > r.Min.X && x < r.Max.X && y > r.Min.Y && y < r.Max.Y {
+			return true
+		}
+	}
+	return false
+}
+
+// crosses reports whether the segment between two neighbouring grid points runs through an obstacle,
+// running along its border is fine
+func (g routeGrid) crosses(i, j, ni, nj int) bool {
+	x1, x2 := min(g.xs[i], g.xs[ni]), max(g.xs[i], g.xs[ni])
+	y1, y2 := min(g.ys[j], g.ys[nj]), max(g.ys[j], g.ys[nj])
+	for _, r := range g.obstacles {
+		if x1 == x2 {
+			if x1 > r.Min.X && x1 < r.Max.X && y1 < r.Max.Y && y2 > r.Min.Y {
+				return true
+			}
+		} else if y1 > r.Min.Y && y1 < r.Max.Y && x1 < r.Max.X && x2 > r.Min.X {
+			return true
+		}
+	}
+	return false
+}
+
+type routeItem struct {
+	state int
+	cost  int
+}
+
+type routeQueue []routeItem
+
+func (q routeQueue) Len() int           { return len(q) }
+func (q routeQueue) Less(i, j int) bool { return q[i].cost < q[j].cost }
+func (q routeQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *routeQueue) Push(x any)        { *q = append(*q, x.(routeItem)) }
+func (q *routeQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
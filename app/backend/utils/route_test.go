@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// checkRoute asserts the whole path is orthogonal and keeps clear of the obstacles, ends aside
+func checkRoute(t *testing.T, start, end Point, bends []Point, obstacles []Rect) {
+	line := append(append([]Point{start}, bends...), end)
+	for i := 1; i < len(line); i++ {
+		a, b := line[i-1], line[i]
+		assert.True(t, a.X == b.X || a.Y == b.Y, "segment %v-%v is not orthogonal", a, b)
+		for _, o := range obstacles {
+			inner := Rect{Min: Point{X: o.Min.X + 1, Y: o.Min.Y + 1}, Max: Point{X: o.Max.X - 1, Y: o.Max.Y - 1}}
+			assert.False(t, inner.IntersectsSegment(a, b), "segment %v-%v crosses %v", a, b, o)
+		}
+	}
+}
+
+func TestRouteOrthogonal(t *testing.T) {
+	left := NewRect(Point{X: 0, Y: 0}, Point{X: 100, Y: 100})
+	right := NewRect(Point{X: 400, Y: 0}, Point{X: 500, Y: 100})
+	wall := NewRect(Point{X: 200, Y: -100}, Point{X: 300, Y: 200})
+
+	t.Run("straight", func(t *testing.T) {
+		start, end := Point{X: 100, Y: 50}, Point{X: 400, Y: 50}
+		bends := RouteOrthogonal(start, Point{X: 1}, end, Point{X: -1}, []Rect{left, right}, 10, 20)
+		assert.NotNil(t, bends)
+		assert.Len(t, bends, 0)
+	})
+
+	t.Run("one bend", func(t *testing.T) {
+		below := NewRect(Point{X: 400, Y: 300}, Point{X: 500, Y: 400})
+		start, end := Point{X: 100, Y: 50}, Point{X: 450, Y: 300}
+		bends := RouteOrthogonal(start, Point{X: 1}, end, Point{Y: -1}, []Rect{left, below}, 10, 20)
+		assert.Equal(t, []Point{{X: 450, Y: 50}}, bends)
+	})
+
+	t.Run("around a wall", func(t *testing.T) {
+		start, end := Point{X: 100, Y: 50}, Point{X: 400, Y: 50}
+		obstacles := []Rect{left, right, wall}
+		bends := RouteOrthogonal(start, Point{X: 1}, end, Point{X: -1}, obstacles, 10, 20)
+		assert.NotNil(t, bends)
+		assert.Len(t, bends, 4)
+		checkRoute(t, start, end, bends, obstacles)
+		// over the wall, it is shorter than going under
+		assert.Equal(t, -110, bends[1].Y)
+	})
+
+	t.Run("leaves the way it points", func(t *testing.T) {
+		// starting off the left side, the route has to go around its own rectangle
+		start, end := Point{X: 0, Y: 50}, Point{X: 400, Y: 50}
+		obstacles := []Rect{left, right}
+		bends := RouteOrthogonal(start, Point{X: -1}, end, Point{X: -1}, obstacles, 10, 20)
+		assert.NotNil(t, bends)
+		assert.Equal(t, Point{X: -10, Y: 50}, bends[0])
+		checkRoute(t, start, end, bends, obstacles)
+	})
+
+	t.Run("walled in", func(t *testing.T) {
+		start, end := Point{X: 100, Y: 50}, Point{X: 400, Y: 50}
+		box := []Rect{
+			left, right,
+			NewRect(Point{X: -50, Y: -50}, Point{X: 150, Y: -40}),
+			NewRect(Point{X: -50, Y: 140}, Point{X: 150, Y: 150}),
+			NewRect(Point{X: -50, Y: -50}, Point{X: -40, Y: 150}),
+			NewRect(Point{X: 140, Y: -50}, Point{X: 150, Y: 150}),
+		}
+		assert.Nil(t, RouteOrthogonal(start, Point{X: 1}, end, Point{X: -1}, box, 10, 20))
+	})
+
+	t.Run("not a unit vector", func(t *testing.T) {
+		assert.Nil(t, RouteOrthogonal(Point{}, Point{X: 1, Y: 1}, Point{X: 50}, Point{X: -1}, nil, 10, 20))
+	})
+}
+
+func TestSimplifyPolyline(t *testing.T) {
+	line := []Point{{X: 0, Y: 0}, {X: 0, Y: 0}, {X: 5, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 5}, {X: 10, Y: 10}}
+	assert.Equal(t, []Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}}, simplifyPolyline(line))
+}
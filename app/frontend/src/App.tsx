@@ -43,6 +43,7 @@ const App: React.FC = () => {
                 color: diagram.color,
                 lineWidth: diagram.lineWidth,
                 gadgets: diagram.gadgets.map(gadget => ({
+                    id: gadget.id,
                     gadgetType: gadget.gadgetType.toString(),
                     x: gadget.x,
                     y: gadget.y,
@@ -51,7 +52,11 @@ const App: React.FC = () => {
                     width: gadget.width,
                     color: gadget.color,
                     isSelected: gadget.isSelected,
-                    attributes: gadget.attributes
+                    attributes: gadget.attributes,
+                    stereotype: gadget.stereotype,
+                    tabWidth: gadget.tabWidth,
+                    tabHeight: gadget.tabHeight,
+                    foldSize: gadget.foldSize
                 }))
             };
             setBackendData(canvasData);
@@ -73,6 +78,7 @@ const App: React.FC = () => {
                     color: result.color,
                     lineWidth: result.lineWidth,
                     gadgets: result.gadgets?.map((gadget: {
+                        id: any;
                         gadgetType: { toString: () => any; };
                         x: any;
                         y: any;
@@ -82,7 +88,12 @@ const App: React.FC = () => {
                         color: any;
                         isSelected: any;
                         attributes: any;
+                        stereotype: any;
+                        tabWidth: any;
+                        tabHeight: any;
+                        foldSize: any;
                     }) => ({
+                        id: gadget.id,
                         gadgetType: gadget.gadgetType.toString(),
                         x: gadget.x,
                         y: gadget.y,
@@ -91,7 +102,11 @@ const App: React.FC = () => {
                         width: gadget.width,
                         color: gadget.color,
                         isSelected: gadget.isSelected,
-                        attributes: gadget.attributes
+                        attributes: gadget.attributes,
+                        stereotype: gadget.stereotype,
+                        tabWidth: gadget.tabWidth,
+                        tabHeight: gadget.tabHeight,
+                        foldSize: gadget.foldSize
                     }))
                 };
                 setBackendData(canvasData);
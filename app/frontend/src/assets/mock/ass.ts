@@ -1,6 +1,7 @@
 import { AssociationProps } from "../../utils/Props";
 
 export const mockAssociation: AssociationProps = {
+    id: "mock-association",
     assType: 1,
     layer: 0,
     startX: 100,
@@ -9,6 +10,12 @@ export const mockAssociation: AssociationProps = {
     endY: 500,
     deltaX: 0,
     deltaY: 0,
+    startHead: 0,
+    endHead: 2,
+    dashed: false,
+    path: [],
+    isSelected: false,
+    labels: [],
     attributes: [
         {
             content: "1",
@@ -35,6 +42,7 @@ export const mockAssociation: AssociationProps = {
 };
 
 export const mockSelfAssociation: AssociationProps = {
+    id: "mock-self-association",
     assType: 1,
     layer: 0,
     startX: 400,
@@ -43,6 +51,12 @@ export const mockSelfAssociation: AssociationProps = {
     endY: 600,
     deltaX: 100,
     deltaY: 100,
+    startHead: 0,
+    endHead: 2,
+    dashed: false,
+    path: [],
+    isSelected: false,
+    labels: [],
     attributes: [
         {
             content: "self",
@@ -55,6 +69,7 @@ export const mockSelfAssociation: AssociationProps = {
 };
 
 export const mockHorizontalAssociation: AssociationProps = {
+    id: "mock-horizontal-association",
     assType: 1,
     layer: 0,
     startX: 100,
@@ -63,6 +78,12 @@ export const mockHorizontalAssociation: AssociationProps = {
     endY: 200,
     deltaX: 0,
     deltaY: 0,
+    startHead: 0,
+    endHead: 2,
+    dashed: false,
+    path: [],
+    isSelected: false,
+    labels: [],
     attributes: [
         {
             content: "left",
@@ -82,6 +103,7 @@ export const mockHorizontalAssociation: AssociationProps = {
 };
 
 export const mockVerticalAssociation: AssociationProps = {
+    id: "mock-vertical-association",
     assType: 1,
     layer: 0,
     startX: 300,
@@ -90,6 +112,12 @@ export const mockVerticalAssociation: AssociationProps = {
     endY: 400,
     deltaX: 0,
     deltaY: 0,
+    startHead: 0,
+    endHead: 2,
+    dashed: false,
+    path: [],
+    isSelected: false,
+    labels: [],
     attributes: [
         {
             content: "top",
@@ -109,6 +137,7 @@ export const mockVerticalAssociation: AssociationProps = {
 };
 
 export const mockSelfAssociationLeft: AssociationProps = {
+    id: "mock-self-association-left",
     assType: 1,
     layer: 0,
     startX: 600,
@@ -117,6 +146,12 @@ export const mockSelfAssociationLeft: AssociationProps = {
     endY: 500,
     deltaX: -100,
     deltaY: 0,
+    startHead: 0,
+    endHead: 2,
+    dashed: false,
+    path: [],
+    isSelected: false,
+    labels: [],
     attributes: [
         {
             content: "self-left",
@@ -129,6 +164,7 @@ export const mockSelfAssociationLeft: AssociationProps = {
 };
 
 export const mockSelfAssociationUp: AssociationProps = {
+    id: "mock-self-association-up",
     assType: 1,
     layer: 0,
     startX: 800,
@@ -137,6 +173,12 @@ export const mockSelfAssociationUp: AssociationProps = {
     endY: 600,
     deltaX: 0,
     deltaY: -100,
+    startHead: 0,
+    endHead: 2,
+    dashed: false,
+    path: [],
+    isSelected: false,
+    labels: [],
     attributes: [
         {
             content: "self-up",
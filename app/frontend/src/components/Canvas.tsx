@@ -28,7 +28,7 @@ const DrawingCanvas: React.FC<{ backendData: CanvasProps | null }> = ({backendDa
                 let selectedGad: GadgetProps | null = null;
 
                 backendData?.gadgets?.forEach((gadget: GadgetProps) => {
-                    const gad = createGadget("Class", gadget, backendData.margin);
+                    const gad = createGadget(gadget.gadgetType, gadget, backendData.margin);
                     gad.draw(ctx, backendData.margin, backendData.lineWidth);
 
                     if (gadget.isSelected) {
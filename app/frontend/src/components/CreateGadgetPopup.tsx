@@ -90,6 +90,11 @@ export const GadgetPopup: React.FC<GadgetPopupProps> = ({isOpen, onCreate, onClo
                             }}
                         >
                             <option value={component.GadgetType.Class}>Class</option>
+                            <option value={component.GadgetType.Interface}>Interface</option>
+                            <option value={component.GadgetType.AbstractClass}>Abstract class</option>
+                            <option value={component.GadgetType.Enumeration}>Enumeration</option>
+                            <option value={component.GadgetType.Note}>Note</option>
+                            <option value={component.GadgetType.Package}>Package</option>
                         </select>
                     </div>
                     <div style={{marginBottom: '1rem'}}>
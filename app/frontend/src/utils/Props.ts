@@ -7,6 +7,7 @@ export interface CanvasProps {
 }
 
 export interface GadgetProps {
+    id: string;
     gadgetType: string;
     x: number;
     y: number;
@@ -23,9 +24,21 @@ export interface GadgetProps {
         fontStyle: number;
         fontFile: string;
     }[][];
+    stereotype?: {
+        content: string;
+        height: number;
+        width: number;
+        fontSize: number;
+        fontStyle: number;
+        fontFile: string;
+    };
+    tabWidth: number;
+    tabHeight: number;
+    foldSize: number;
 }
 
 export interface AssociationProps {
+    id: string;
     assType: number;
     layer: number;
     startX: number;
@@ -34,6 +47,17 @@ export interface AssociationProps {
     endY: number;
     deltaX: number;
     deltaY: number;
+    startHead: number;
+    endHead: number;
+    dashed: boolean;
+    path: { x: number; y: number }[];
+    classLink?: {
+        startX: number;
+        startY: number;
+        endX: number;
+        endY: number;
+    };
+    isSelected: boolean;
     attributes: {
         content: string;
         fontSize: number;
@@ -41,4 +65,14 @@ export interface AssociationProps {
         fontFile: string;
         ratio: number;
     }[];
+    labels: {
+        kind: number;
+        content: string;
+        x: number;
+        y: number;
+        width: number;
+        height: number;
+        fontSize: number;
+        readingDirection: number;
+    }[];
 }
\ No newline at end of file
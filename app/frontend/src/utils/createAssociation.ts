@@ -1,5 +1,22 @@
 import { AssociationProps } from "./Props";
 
+// the arrowheads of drawdata.Association
+enum ArrowHead {
+    None = 0,
+    Open,
+    HollowTriangle,
+    FilledDiamond,
+    HollowDiamond,
+    Cross,
+}
+
+// the reading directions of the middle name
+enum ReadingDirection {
+    None = 0,
+    TowardEnd,
+    TowardStart,
+}
+
 class AssociationElement {
     public assProps: AssociationProps;
 
@@ -9,17 +26,55 @@ class AssociationElement {
 
     draw(ctx: CanvasRenderingContext2D, margin: number, lineWidth: number) {
 
-        if (this.assProps.deltaX === 0 && this.assProps.deltaY === 0) {
+        ctx.setLineDash(this.assProps.dashed ? [6, 4] : []);
+        if (this.assProps.path && this.assProps.path.length > 1) {
+            this.drawPath(ctx, lineWidth);
+        }
+        else if (this.assProps.deltaX === 0 && this.assProps.deltaY === 0) {
             this.drawNormalAss(ctx, margin, lineWidth);
         }
         else{
             this.drawSelfAss(ctx, margin, lineWidth);
         }
+        ctx.setLineDash([]);
 
-        this.drawArrow(ctx, margin, lineWidth);
+        this.drawHeads(ctx, lineWidth);
+        this.drawClassLink(ctx, lineWidth);
 
         // Draw label text(s) if present
         this.drawText(ctx, margin);
+        this.drawLabels(ctx);
+    }
+
+    drawLabels(ctx: CanvasRenderingContext2D) {
+        const { startX, endX } = this.assProps;
+        (this.assProps.labels ?? []).forEach(label => {
+            ctx.font = `${label.fontSize}px Inkfree`;
+            ctx.fillStyle = "black";
+            ctx.textBaseline = "top";
+            ctx.fillText(label.content, label.x, label.y);
+            if (label.readingDirection === ReadingDirection.None) return;
+
+            // a small triangle after the name, pointing to the end it reads toward
+            let dx = endX - startX;
+            if (label.readingDirection === ReadingDirection.TowardStart) dx = -dx;
+            const size = label.height / 2;
+            const x = label.x + label.width + 4;
+            const y = label.y + label.height / 2;
+            ctx.beginPath();
+            if (dx >= 0) {
+                ctx.moveTo(x, y - size / 2);
+                ctx.lineTo(x + size, y);
+                ctx.lineTo(x, y + size / 2);
+            } else {
+                ctx.moveTo(x + size, y - size / 2);
+                ctx.lineTo(x, y);
+                ctx.lineTo(x + size, y + size / 2);
+            }
+            ctx.closePath();
+            ctx.fill();
+        });
+        ctx.textBaseline = "alphabetic";
     }
 
     drawText(ctx: CanvasRenderingContext2D, margin: number) {
@@ -36,6 +91,17 @@ class AssociationElement {
         }
     }
 
+    // drawPath draws the whole line, bends and self loops included
+    drawPath(ctx: CanvasRenderingContext2D, lineWidth: number) {
+        const path = this.assProps.path;
+        ctx.beginPath();
+        ctx.moveTo(path[0].x, path[0].y);
+        path.slice(1).forEach(p => ctx.lineTo(p.x, p.y));
+        ctx.strokeStyle = "black";
+        ctx.lineWidth = lineWidth;
+        ctx.stroke();
+    }
+
     drawNormalAss(ctx: CanvasRenderingContext2D, margin: number, lineWidth: number) {
         ctx.beginPath();
         ctx.moveTo(this.assProps.startX, this.assProps.startY);
@@ -63,23 +129,33 @@ class AssociationElement {
         ctx.stroke();
     }
 
-    drawArrow(ctx: CanvasRenderingContext2D, margin: number, lineWidth: number){
-        let fromX, fromY, toX, toY;
-
-        if (this.assProps.deltaX === 0 && this.assProps.deltaY === 0) {
-            // Normal association: arrow at end of straight line
-            fromX = this.assProps.startX;
-            fromY = this.assProps.startY;
-            toX = this.assProps.endX;
-            toY = this.assProps.endY;
-        } else {
-            // Self-association: arrow at end of last segment (from end+delta to end)
-            fromX = this.assProps.endX + this.assProps.deltaX;
-            fromY = this.assProps.endY + this.assProps.deltaY;
-            toX = this.assProps.endX;
-            toY = this.assProps.endY;
+    drawHeads(ctx: CanvasRenderingContext2D, lineWidth: number) {
+        const path = this.assProps.path;
+        if (path && path.length > 1) {
+            // each head points along the segment it ends
+            const n = path.length;
+            this.drawHead(ctx, lineWidth, this.assProps.startHead, path[1].x, path[1].y, path[0].x, path[0].y);
+            this.drawHead(ctx, lineWidth, this.assProps.endHead, path[n - 2].x, path[n - 2].y, path[n - 1].x, path[n - 1].y);
+            return;
         }
+        const { startX, startY, endX, endY, deltaX, deltaY } = this.assProps;
+        // a self association leaves and enters its gadget along the delta
+        this.drawHead(ctx, lineWidth, this.assProps.startHead, startX + deltaX, startY + deltaY, startX, startY);
+        this.drawHead(ctx, lineWidth, this.assProps.endHead, endX + deltaX, endY + deltaY, endX, endY);
+    }
 
+    // drawHead draws the arrowhead of kind at (toX, toY), pointing away from (fromX, fromY)
+    drawHead(ctx: CanvasRenderingContext2D, lineWidth: number, kind: number, fromX: number, fromY: number, toX: number, toY: number) {
+        if (fromX === toX && fromY === toY) {
+            // a straight line, the other end gives the direction
+            if (toX === this.assProps.startX && toY === this.assProps.startY) {
+                fromX = this.assProps.endX;
+                fromY = this.assProps.endY;
+            } else {
+                fromX = this.assProps.startX;
+                fromY = this.assProps.startY;
+            }
+        }
         const dx = toX - fromX;
         const dy = toY - fromY;
         const len = Math.sqrt(dx * dx + dy * dy);
@@ -88,20 +164,68 @@ class AssociationElement {
         const unitX = dx / len;
         const unitY = dy / len;
         const arrowSize = 10;
-
-        // Arrow tip at (toX, toY)
-        // Two base points of the arrowhead
         const baseX = toX - unitX * arrowSize;
         const baseY = toY - unitY * arrowSize;
 
         ctx.beginPath();
-        ctx.moveTo(toX, toY);
-        ctx.lineTo(baseX - unitY * 5, baseY + unitX * 5);
-        ctx.lineTo(baseX + unitY * 5, baseY - unitX * 5);
-        ctx.closePath();
-        ctx.fillStyle = "black";
-        ctx.fill();
+        switch (kind) {
+            case ArrowHead.Open:
+                ctx.moveTo(baseX - unitY * 5, baseY + unitX * 5);
+                ctx.lineTo(toX, toY);
+                ctx.lineTo(baseX + unitY * 5, baseY - unitX * 5);
+                break;
+            case ArrowHead.HollowTriangle:
+                ctx.moveTo(toX, toY);
+                ctx.lineTo(baseX - unitY * 6, baseY + unitX * 6);
+                ctx.lineTo(baseX + unitY * 6, baseY - unitX * 6);
+                ctx.closePath();
+                break;
+            case ArrowHead.FilledDiamond:
+            case ArrowHead.HollowDiamond: {
+                const backX = toX - unitX * arrowSize * 2;
+                const backY = toY - unitY * arrowSize * 2;
+                ctx.moveTo(toX, toY);
+                ctx.lineTo(baseX - unitY * 5, baseY + unitX * 5);
+                ctx.lineTo(backX, backY);
+                ctx.lineTo(baseX + unitY * 5, baseY - unitX * 5);
+                ctx.closePath();
+                break;
+            }
+            case ArrowHead.Cross: {
+                const crossX = toX - unitX * arrowSize;
+                const crossY = toY - unitY * arrowSize;
+                ctx.moveTo(crossX - (unitX + unitY) * 4, crossY - (unitY - unitX) * 4);
+                ctx.lineTo(crossX + (unitX + unitY) * 4, crossY + (unitY - unitX) * 4);
+                ctx.moveTo(crossX - (unitX - unitY) * 4, crossY - (unitY + unitX) * 4);
+                ctx.lineTo(crossX + (unitX - unitY) * 4, crossY + (unitY + unitX) * 4);
+                break;
+            }
+            default:
+                return;
+        }
+        if (kind === ArrowHead.HollowTriangle || kind === ArrowHead.HollowDiamond) {
+            ctx.fillStyle = "white";
+            ctx.fill();
+        } else if (kind === ArrowHead.FilledDiamond) {
+            ctx.fillStyle = "black";
+            ctx.fill();
+        }
+        ctx.strokeStyle = "black";
+        ctx.lineWidth = lineWidth;
+        ctx.stroke();
+    }
+
+    drawClassLink(ctx: CanvasRenderingContext2D, lineWidth: number) {
+        const link = this.assProps.classLink;
+        if (!link) return;
+        ctx.setLineDash([6, 4]);
+        ctx.beginPath();
+        ctx.moveTo(link.startX, link.startY);
+        ctx.lineTo(link.endX, link.endY);
+        ctx.strokeStyle = "black";
+        ctx.lineWidth = lineWidth;
         ctx.stroke();
+        ctx.setLineDash([]);
     }
 }
 
@@ -32,6 +32,9 @@ class ClassElement {
         };
 
         this.headerHeight = calculateSectionHeight(0, headerLen);
+        if (props.stereotype) {
+            this.headerHeight += props.stereotype.height + margin;
+        }
         this.attributesHeight = calculateSectionHeight(1, attributesLen);
         this.colorHexStr = props.color;
     }
@@ -55,39 +58,123 @@ class ClassElement {
         ctx.lineWidth = lineWidth;
         ctx.stroke();
         // ctx.font = "12px Georgia";
-        const drawText = (sectionIndex: number, yOffset: number) => {
-            yOffset += margin;
-            if (Array.isArray(this.gadgetProps.attributes[sectionIndex])) {
-                this.gadgetProps.attributes[sectionIndex].forEach((attr: any) => {
-                    if (attr && typeof attr.content === "string") {
-                        yOffset += attr.height / 2;
-                        ctx.font = `${attr.fontSize}px ${attr.fontFile}`;
-                        ctx.fillText(attr.content, this.gadgetProps.x + margin, yOffset);
-                        yOffset += attr.height / 2 + margin;
-                    }
-                });
-            }
-        }
-        drawText(0, this.gadgetProps.y);
-        drawText(1, this.gadgetProps.y + this.headerHeight);
-        drawText(2, this.gadgetProps.y + this.headerHeight + this.attributesHeight);
-        if (this.gadgetProps.isSelected) {
-            ctx.setLineDash([5, 3]);
-            ctx.strokeStyle = '#FFA500';
-            ctx.lineWidth = lineWidth * 2;
-            ctx.strokeRect(this.gadgetProps.x, this.gadgetProps.y, this.gadgetProps.width, this.gadgetProps.height);
-            ctx.setLineDash([]);
-            ctx.strokeStyle = 'black';
-            ctx.lineWidth = lineWidth;
+        let headerY = this.gadgetProps.y;
+        const stereotype = this.gadgetProps.stereotype;
+        if (stereotype) {
+            // «interface» and «enumeration» sit centered above the name
+            headerY += margin + stereotype.height;
+            ctx.font = `${stereotype.fontSize}px ${stereotype.fontFile}`;
+            ctx.fillText(stereotype.content, this.gadgetProps.x + (this.gadgetProps.width - stereotype.width) / 2, headerY - stereotype.height / 2);
         }
+        drawText(ctx, this.gadgetProps, 0, this.gadgetProps.x, headerY, margin);
+        drawText(ctx, this.gadgetProps, 1, this.gadgetProps.x, this.gadgetProps.y + this.headerHeight, margin);
+        drawText(ctx, this.gadgetProps, 2, this.gadgetProps.x, this.gadgetProps.y + this.headerHeight + this.attributesHeight, margin);
+        drawSelection(ctx, this.gadgetProps, lineWidth);
     }
 }
 
+// a package has its name in a tab on top of the body, which holds the contents
+class PackageElement {
+    public gadgetProps: GadgetProps;
+
+    constructor(props: GadgetProps) {
+        this.gadgetProps = props;
+    }
+
+    draw(ctx: CanvasRenderingContext2D, margin: number, lineWidth: number) {
+        const {x, y, width, height, tabWidth, tabHeight} = this.gadgetProps;
+        ctx.beginPath();
+        ctx.fillStyle = this.gadgetProps.color;
+        ctx.fillRect(x, y, tabWidth, tabHeight);
+        ctx.fillStyle = "white";
+        ctx.fillRect(x, y + tabHeight, width, height - tabHeight);
+        ctx.fillStyle = "black";
+        ctx.strokeStyle = "black";
+        ctx.lineWidth = lineWidth;
+        ctx.strokeRect(x, y, tabWidth, tabHeight);
+        ctx.strokeRect(x, y + tabHeight, width, height - tabHeight);
+
+        drawText(ctx, this.gadgetProps, 0, x, y, margin);
+        drawText(ctx, this.gadgetProps, 1, x, y + tabHeight, margin);
+        drawSelection(ctx, this.gadgetProps, lineWidth);
+    }
+}
+
+// a note is a sheet with its top right corner folded down
+class NoteElement {
+    public gadgetProps: GadgetProps;
+
+    constructor(props: GadgetProps) {
+        this.gadgetProps = props;
+    }
+
+    draw(ctx: CanvasRenderingContext2D, margin: number, lineWidth: number) {
+        const {x, y, width, height, foldSize} = this.gadgetProps;
+        ctx.beginPath();
+        ctx.moveTo(x, y);
+        ctx.lineTo(x + width - foldSize, y);
+        ctx.lineTo(x + width, y + foldSize);
+        ctx.lineTo(x + width, y + height);
+        ctx.lineTo(x, y + height);
+        ctx.closePath();
+        ctx.fillStyle = this.gadgetProps.color;
+        ctx.fill();
+        ctx.moveTo(x + width - foldSize, y);
+        ctx.lineTo(x + width - foldSize, y + foldSize);
+        ctx.lineTo(x + width, y + foldSize);
+        ctx.strokeStyle = "black";
+        ctx.lineWidth = lineWidth;
+        ctx.stroke();
+        ctx.fillStyle = "black";
+
+        drawText(ctx, this.gadgetProps, 0, x, y, margin);
+        drawSelection(ctx, this.gadgetProps, lineWidth);
+    }
+}
+
+// drawText writes the attributes of a section one under another, starting at yOffset
+function drawText(ctx: CanvasRenderingContext2D, props: GadgetProps, sectionIndex: number, x: number, yOffset: number, margin: number) {
+    yOffset += margin;
+    if (Array.isArray(props.attributes[sectionIndex])) {
+        props.attributes[sectionIndex].forEach((attr: any) => {
+            if (attr && typeof attr.content === "string") {
+                yOffset += attr.height / 2;
+                ctx.font = `${attr.fontSize}px ${attr.fontFile}`;
+                ctx.fillText(attr.content, x + margin, yOffset);
+                yOffset += attr.height / 2 + margin;
+            }
+        });
+    }
+}
+
+function drawSelection(ctx: CanvasRenderingContext2D, props: GadgetProps, lineWidth: number) {
+    if (props.isSelected) {
+        ctx.setLineDash([5, 3]);
+        ctx.strokeStyle = '#FFA500';
+        ctx.lineWidth = lineWidth * 2;
+        ctx.strokeRect(props.x, props.y, props.width, props.height);
+        ctx.setLineDash([]);
+        ctx.strokeStyle = 'black';
+        ctx.lineWidth = lineWidth;
+    }
+}
+
+// createGadget takes the gadget type the backend sends, the number of component.GadgetType as a string
 export function createGadget(type: string, config: GadgetProps, margin: number) {
     switch (type) {
-        case "Class": {
+        case "Class":
+        case "1":  // class
+        case "2":  // interface
+        case "4":  // abstract class
+        case "8": { // enumeration
             return new ClassElement(config, margin);
         }
+        case "16": {
+            return new NoteElement(config);
+        }
+        case "32": {
+            return new PackageElement(config);
+        }
         default:
             throw new Error(`Unknown gadget type: ${type}`);
     }
@@ -4,6 +4,8 @@ import (
 	"embed"
 
 	"Dr.uml/backend/component"
+	"Dr.uml/backend/component/attribute"
+	"Dr.uml/backend/export"
 	"Dr.uml/backend/umldiagram"
 	"Dr.uml/backend/umlproject"
 	"github.com/wailsapp/wails/v2"
@@ -34,6 +36,14 @@ func main() {
 		EnumBind: []interface{}{
 			umldiagram.AllDiagramTypes,
 			component.AllGadgetTypes,
+			component.AllAssociationTypes,
+			component.AllNavigabilities,
+			component.AllLabelKinds,
+			component.AllReadingDirections,
+			component.AllRoutings,
+			attribute.AllVisibilities,
+			attribute.AllMemberKinds,
+			export.AllPaperSizes,
 		},
 	})
 